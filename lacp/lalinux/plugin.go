@@ -0,0 +1,413 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// plugin.go
+package lalinux
+
+import (
+	hwconst "asicd/asicdCommonDefs"
+	"errors"
+	"fmt"
+	"github.com/vishvananda/netlink"
+	"l2/lacp/protocol/utils"
+	"net"
+	"sort"
+	"sync"
+	"time"
+	"utils/asicdClient"
+	"utils/commonDefs"
+)
+
+const (
+	// link events are ignored for this long after a port has been moved in
+	// or out of a bond, as the kernel closes and re-opens the port
+	PORT_HOLD_OFF_TIME = 5 * time.Second
+
+	NETLINK_UPDATE_CHANNEL_SIZE = 100
+)
+
+// LinuxPlugin is given to lacp in place of the asicd client
+var _ asicdClient.AsicdClientIntf = (*LinuxPlugin)(nil)
+
+// LinuxPlugin collects on lag members which are not distributing
+var _ utils.LagCollectingPlugin = (*LinuxPlugin)(nil)
+
+// netlink operations replaced by the tests
+var (
+	netlinkLinkList  = netlink.LinkList
+	netlinkQdiscAdd  = netlink.QdiscAdd
+	netlinkFilterAdd = netlink.FilterAdd
+	netlinkFilterDel = netlink.FilterDel
+)
+
+// LinuxPlugin programs aggregators as linux kernel bonds and reports link
+// state from netlink, it is used in place of asicd when lacpd runs on a plain
+// linux host or in a network namespace
+type LinuxPlugin struct {
+	// interfaces which may be aggregated, all ethernet interfaces are used if empty
+	ports []string
+	nHdl  commonDefs.AsicdNotificationHdl
+
+	mutex sync.Mutex
+	// ifindex to last oper state reported to lacp
+	portStates map[int32]uint8
+	// ifindex to end of the hold off time
+	holdOff map[int32]time.Time
+	// bond ifindex to the collecting ports given by lacp
+	collecting map[int32][]int
+	// bond ifindex to the ports redirected to the bond as they are
+	// collecting but not distributing
+	collectOnly map[int32][]int
+	done        chan struct{}
+}
+
+func NewLinuxPlugin(ports []string, nHdl commonDefs.AsicdNotificationHdl) *LinuxPlugin {
+	return &LinuxPlugin{
+		ports:       ports,
+		nHdl:        nHdl,
+		portStates:  make(map[int32]uint8),
+		holdOff:     make(map[int32]time.Time),
+		collecting:  make(map[int32][]int),
+		collectOnly: make(map[int32][]int),
+		done:        make(chan struct{}),
+	}
+}
+
+// isLagPort, port is usable as lag member if it is configured or if no ports
+// are configured then all the ethernet interfaces are usable
+func (lp *LinuxPlugin) isLagPort(link netlink.Link) bool {
+	attrs := link.Attrs()
+	if len(lp.ports) > 0 {
+		for _, port := range lp.ports {
+			if port == attrs.Name {
+				return true
+			}
+		}
+		return false
+	}
+	if attrs.EncapType != "ether" {
+		return false
+	}
+	switch link.Type() {
+	case "device", "veth":
+		return true
+	}
+	return false
+}
+
+// lagPortListGet returns the lag ports sorted by ifindex so that bulk gets
+// of port config and port state return the same order
+func (lp *LinuxPlugin) lagPortListGet() ([]netlink.Link, error) {
+	links, err := netlinkLinkList()
+	if err != nil {
+		return nil, err
+	}
+	var ports []netlink.Link
+	for _, link := range links {
+		if lp.isLagPort(link) {
+			ports = append(ports, link)
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool {
+		return ports[i].Attrs().Index < ports[j].Attrs().Index
+	})
+	return ports, nil
+}
+
+// linkOperStateGet, link is treated as operationally up if kernel says so,
+// virtual interfaces which do not report oper state are up if admin state is up
+func linkOperStateGet(attrs *netlink.LinkAttrs) uint8 {
+	if attrs.OperState == netlink.OperUp ||
+		(attrs.OperState == netlink.OperUnknown && attrs.Flags&net.FlagUp != 0) {
+		return hwconst.INTF_STATE_UP
+	}
+	return hwconst.INTF_STATE_DOWN
+}
+
+func linkOperStateStr(state uint8) string {
+	if state == hwconst.INTF_STATE_UP {
+		return "UP"
+	}
+	return "DOWN"
+}
+
+// GetSwitchMAC returns the mac of the lowest ifindex lag port, it is
+// used as the default lacp system id
+func (lp *LinuxPlugin) GetSwitchMAC(paramsPath string) string {
+	ports, err := lp.lagPortListGet()
+	if err != nil || len(ports) == 0 {
+		utils.GlobalLogger.Err(fmt.Sprintln("Unable to find a port for the switch mac", err))
+		return "00:00:00:00:00:00"
+	}
+	return ports[0].Attrs().HardwareAddr.String()
+}
+
+// Start subscribes to netlink link notifications, link up/down of lag ports
+// is sent to lacp the same way asicd notifications are
+func (lp *LinuxPlugin) Start() error {
+	ports, err := lp.lagPortListGet()
+	if err != nil {
+		return err
+	}
+	lp.mutex.Lock()
+	for _, link := range ports {
+		lp.portStates[int32(link.Attrs().Index)] = linkOperStateGet(link.Attrs())
+	}
+	lp.mutex.Unlock()
+
+	linkCh := make(chan netlink.LinkUpdate, NETLINK_UPDATE_CHANNEL_SIZE)
+	err = netlink.LinkSubscribe(linkCh, lp.done)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for {
+			select {
+			case update, ok := <-linkCh:
+				if !ok {
+					return
+				}
+				lp.handleLinkUpdate(update)
+			case <-lp.done:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func (lp *LinuxPlugin) Stop() {
+	close(lp.done)
+}
+
+func (lp *LinuxPlugin) handleLinkUpdate(update netlink.LinkUpdate) {
+	attrs := update.Attrs()
+	ifindex := int32(attrs.Index)
+	lp.mutex.Lock()
+	prevState, exists := lp.portStates[ifindex]
+	if !exists {
+		// not a lag port
+		lp.mutex.Unlock()
+		return
+	}
+	if end, ok := lp.holdOff[ifindex]; ok && time.Now().Before(end) {
+		lp.mutex.Unlock()
+		return
+	}
+	state := linkOperStateGet(attrs)
+	if state == prevState {
+		lp.mutex.Unlock()
+		return
+	}
+	lp.portStates[ifindex] = state
+	lp.mutex.Unlock()
+	lp.sendPortState(ifindex, state)
+}
+
+func (lp *LinuxPlugin) sendPortState(ifindex int32, state uint8) {
+	utils.GlobalLogger.Info(fmt.Sprintln("Netlink link notification for ifindex", ifindex, "state", linkOperStateStr(state)))
+	if lp.nHdl != nil {
+		lp.nHdl.ProcessNotification(commonDefs.L2IntfStateNotifyMsg{
+			IfIndex: ifindex,
+			IfState: state,
+		})
+	}
+}
+
+// portHoldOffStart suppresses link events of the port while it is moved in or
+// out of a bond, once the hold off expires the current state is reported if it
+// differs from what lacp was last told
+func (lp *LinuxPlugin) portHoldOffStart(ifindex int32) {
+	lp.mutex.Lock()
+	lp.holdOff[ifindex] = time.Now().Add(PORT_HOLD_OFF_TIME)
+	lp.mutex.Unlock()
+	time.AfterFunc(PORT_HOLD_OFF_TIME, func() {
+		lp.portHoldOffExpired(ifindex)
+	})
+}
+
+func (lp *LinuxPlugin) portHoldOffExpired(ifindex int32) {
+	lp.mutex.Lock()
+	if end, ok := lp.holdOff[ifindex]; !ok || time.Now().Before(end) {
+		// hold off was restarted
+		lp.mutex.Unlock()
+		return
+	}
+	delete(lp.holdOff, ifindex)
+	prevState, exists := lp.portStates[ifindex]
+	lp.mutex.Unlock()
+
+	link, err := netlink.LinkByIndex(int(ifindex))
+	if err != nil || !exists {
+		return
+	}
+	state := linkOperStateGet(link.Attrs())
+	if state == prevState {
+		return
+	}
+	lp.mutex.Lock()
+	lp.portStates[ifindex] = state
+	lp.mutex.Unlock()
+	lp.sendPortState(ifindex, state)
+}
+
+// GetPortLinkStatus returns the link state, during the hold off the last state
+// reported is used
+func (lp *LinuxPlugin) GetPortLinkStatus(port int32) bool {
+	lp.mutex.Lock()
+	state, exists := lp.portStates[port]
+	_, holdOff := lp.holdOff[port]
+	lp.mutex.Unlock()
+	if exists && holdOff {
+		return state == hwconst.INTF_STATE_UP
+	}
+	link, err := netlink.LinkByIndex(int(port))
+	if err != nil {
+		return false
+	}
+	return linkOperStateGet(link.Attrs()) == hwconst.INTF_STATE_UP
+}
+
+// EnablePacketReception, kernel delivers slow protocol frames to the packet
+// sockets of the port, nothing to program
+func (lp *LinuxPlugin) EnablePacketReception(mac string, vlan int, ifindex int32) error {
+	return nil
+}
+
+func (lp *LinuxPlugin) DisablePacketReception(mac string, vlan int, ifindex int32) error {
+	return nil
+}
+
+// GetBulkVlan, vlans are not learned from the kernel
+func (lp *LinuxPlugin) GetBulkVlan(curMark, count int) (*commonDefs.VlanGetInfo, error) {
+	return &commonDefs.VlanGetInfo{
+		StartIdx: int32(curMark),
+		EndIdx:   int32(curMark),
+	}, nil
+}
+
+// lagPortBulkGet returns the range of lag ports from curMark, the marks are
+// numbered from MIN_SYS_PORTS the same as the asicd port marks
+func (lp *LinuxPlugin) lagPortBulkGet(curMark, count int) (ports []netlink.Link, endIdx int, more bool, err error) {
+	links, err := lp.lagPortListGet()
+	if err != nil {
+		return nil, curMark, false, err
+	}
+	start := curMark - hwconst.MIN_SYS_PORTS
+	if start < 0 {
+		start = 0
+	}
+	if start >= len(links) {
+		return nil, curMark, false, nil
+	}
+	end := start + count
+	if end >= len(links) {
+		end = len(links)
+	} else {
+		more = true
+	}
+	return links[start:end], end + hwconst.MIN_SYS_PORTS, more, nil
+}
+
+func (lp *LinuxPlugin) GetBulkPortState(curMark, count int) (*commonDefs.PortStateGetInfo, error) {
+	ports, endIdx, more, err := lp.lagPortBulkGet(curMark, count)
+	if err != nil {
+		return nil, err
+	}
+	info := &commonDefs.PortStateGetInfo{
+		StartIdx: int32(curMark),
+		EndIdx:   int32(endIdx),
+		Count:    int32(len(ports)),
+		More:     more,
+	}
+	for _, link := range ports {
+		attrs := link.Attrs()
+		info.PortStateList = append(info.PortStateList, commonDefs.PortState{
+			IfIndex:   int32(attrs.Index),
+			Name:      attrs.Name,
+			OperState: linkOperStateStr(linkOperStateGet(attrs)),
+		})
+	}
+	return info, nil
+}
+
+func (lp *LinuxPlugin) GetBulkPort(curMark, count int) (*commonDefs.PortGetInfo, error) {
+	ports, endIdx, more, err := lp.lagPortBulkGet(curMark, count)
+	if err != nil {
+		return nil, err
+	}
+	info := &commonDefs.PortGetInfo{
+		StartIdx: int32(curMark),
+		EndIdx:   int32(endIdx),
+		Count:    int32(len(ports)),
+		More:     more,
+	}
+	for _, link := range ports {
+		attrs := link.Attrs()
+		info.PortList = append(info.PortList, commonDefs.Port{
+			IfIndex: int32(attrs.Index),
+			MacAddr: attrs.HardwareAddr.String(),
+		})
+	}
+	return info, nil
+}
+
+// Intra portal link programming is not supported by the linux backend
+func (lp *LinuxPlugin) IppIngressEgressDrop(srcIfIndex, dstIfIndex string) error {
+	return errors.New("Ipp ingress/egress drop not supported on linux")
+}
+
+func (lp *LinuxPlugin) IppIngressEgressPass(srcIfIndex, dstIfIndex string) error {
+	return errors.New("Ipp ingress/egress pass not supported on linux")
+}
+
+func (lp *LinuxPlugin) IppVlanConversationSet(vlan uint16, ifindex int32) error {
+	return errors.New("Ipp vlan conversation not supported on linux")
+}
+
+func (lp *LinuxPlugin) IppVlanConversationClear(vlan uint16, ifindex int32) error {
+	return errors.New("Ipp vlan conversation not supported on linux")
+}
+
+// Spanning tree groups are not programmed by the linux backend, lacpd does
+// not run stp
+func (lp *LinuxPlugin) CreateStgBridge(vlanList []uint16) int32 {
+	utils.GlobalLogger.Err(fmt.Sprintln("Stg bridge create not supported on linux, vlans", vlanList))
+	return -1
+}
+
+func (lp *LinuxPlugin) DeleteStgBridge(stgid int32, vlanList []uint16) error {
+	return errors.New("Stg bridge delete not supported on linux")
+}
+
+func (lp *LinuxPlugin) SetStgPortState(stgid int32, ifindex int32, state int) error {
+	return errors.New("Stg port state not supported on linux")
+}
+
+func (lp *LinuxPlugin) FlushStgFdb(stgid, ifindex int32) error {
+	return errors.New("Stg fdb flush not supported on linux")
+}
+
+func (lp *LinuxPlugin) BPDUGuardDetected(ifindex int32, enable bool) error {
+	return errors.New("BPDU guard not supported on linux")
+}
@@ -0,0 +1,122 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// plugin_test.go
+package lalinux
+
+import (
+	hwconst "asicd/asicdCommonDefs"
+	"github.com/vishvananda/netlink"
+	"net"
+	"testing"
+)
+
+func LinuxPluginTestSetup() {
+	netlinkLinkList = func() ([]netlink.Link, error) {
+		// returned out of ifindex order, loopback and vlans are not lag ports
+		return []netlink.Link{
+			&netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 4, Name: "eth2", EncapType: "ether",
+				HardwareAddr: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x04}}},
+			&netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 1, Name: "lo", EncapType: "loopback"}},
+			&netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 2, Name: "eth0", EncapType: "ether",
+				HardwareAddr: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x02}}},
+			&netlink.Vlan{LinkAttrs: netlink.LinkAttrs{Index: 5, Name: "eth0.100", EncapType: "ether"}, VlanId: 100},
+			&netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: 3, Name: "eth1", EncapType: "ether",
+				HardwareAddr: net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x03}}},
+		}, nil
+	}
+}
+
+func LinuxPluginTestTeardown() {
+	netlinkLinkList = netlink.LinkList
+}
+
+func TestLinuxPluginGetBulkPort(t *testing.T) {
+	LinuxPluginTestSetup()
+	defer LinuxPluginTestTeardown()
+	lp := NewLinuxPlugin(nil, nil)
+
+	// page through the ports the same way the port config map is built
+	var ifindexList []int32
+	var macList []string
+	currMarker := hwconst.MIN_SYS_PORTS
+	for i := 0; i < 10; i++ {
+		bulkInfo, err := lp.GetBulkPortState(currMarker, 2)
+		if err != nil {
+			t.Fatal("Unexpected error getting port state", err)
+		}
+		bulkCfgInfo, err := lp.GetBulkPort(currMarker, 2)
+		if err != nil {
+			t.Fatal("Unexpected error getting port", err)
+		}
+		if bulkInfo.StartIdx != int32(currMarker) ||
+			bulkCfgInfo.StartIdx != int32(currMarker) ||
+			bulkInfo.EndIdx != bulkCfgInfo.EndIdx ||
+			bulkInfo.EndIdx != int32(currMarker)+bulkInfo.Count ||
+			bulkInfo.Count != bulkCfgInfo.Count ||
+			bulkInfo.More != bulkCfgInfo.More {
+			t.Error("Port and port state pages do not agree", bulkInfo, bulkCfgInfo)
+		}
+		for j := 0; j < int(bulkCfgInfo.Count); j++ {
+			if bulkInfo.PortStateList[j].IfIndex != bulkCfgInfo.PortList[j].IfIndex {
+				t.Error("Port and port state order do not agree", bulkInfo.PortStateList[j], bulkCfgInfo.PortList[j])
+			}
+			ifindexList = append(ifindexList, bulkCfgInfo.PortList[j].IfIndex)
+			macList = append(macList, bulkCfgInfo.PortList[j].MacAddr)
+		}
+		currMarker = int(bulkInfo.EndIdx)
+		if !bulkInfo.More {
+			break
+		}
+	}
+
+	if len(ifindexList) != 3 ||
+		ifindexList[0] != 2 ||
+		ifindexList[1] != 3 ||
+		ifindexList[2] != 4 {
+		t.Error("Unexpected ports found", ifindexList)
+	}
+	if len(macList) != 3 ||
+		macList[0] != "00:11:22:33:44:02" {
+		t.Error("Unexpected port macs found", macList)
+	}
+
+	// past the last port
+	bulkCfgInfo, err := lp.GetBulkPort(currMarker, 2)
+	if err != nil || bulkCfgInfo.Count != 0 || bulkCfgInfo.More {
+		t.Error("Expected no ports past the end of the list", bulkCfgInfo, err)
+	}
+}
+
+func TestLinuxPluginGetSwitchMAC(t *testing.T) {
+	LinuxPluginTestSetup()
+	defer LinuxPluginTestTeardown()
+
+	// lowest ifindex lag port, or the configured port
+	if mac := NewLinuxPlugin(nil, nil).GetSwitchMAC(""); mac != "00:11:22:33:44:02" {
+		t.Error("Unexpected switch mac", mac)
+	}
+	if mac := NewLinuxPlugin([]string{"eth2"}, nil).GetSwitchMAC(""); mac != "00:11:22:33:44:04" {
+		t.Error("Unexpected switch mac for configured ports", mac)
+	}
+}
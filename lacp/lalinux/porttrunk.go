@@ -13,178 +13,425 @@
 //	 See the License for the specific language governing permissions and
 //	 limitations under the License.
 //
-// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __  
-// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  | 
-// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  | 
-// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   | 
-// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  | 
-// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__| 
-//                                                                                                           
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
 
 // porttrunk.go
-package main
+package lalinux
 
 import (
+	"errors"
 	"fmt"
 	"github.com/vishvananda/netlink"
-	"net"
-//	"os/exec"
+	"io/ioutil"
+	"l2/lacp/protocol/utils"
 	"strconv"
 	"strings"
-	"time"
+	"syscall"
 )
 
 // command to show status of lag
-// cat /proc/net/bonding/bond-0
+// cat /proc/net/bonding/<lag name>
 
-var BondedIfIdxBase int
+const (
+	SYSFS_NET_PATH = "/sys/class/net/"
+	// kernel interface names are limited to IFNAMSIZ - 1
+	BOND_NAME_MAX_LEN = 15
+	// priority of the ingress filter redirecting a collecting port to its bond
+	BOND_COLLECT_FILTER_PRIORITY = 0x8809
+)
 
-// the id of the agg should be part of the name
-// format expected <name>-<#number>
-func GetIdByName(AggName string) int {
-	var i int
-	if strings.Contains(AggName, "-") {
-		i, _ = strconv.Atoi(strings.Split(AggName, "-")[1])
-	} else {
-		i = 0
+type bondXmitHashPolicy struct {
+	name string
+	// lag hash selection, -1 if the policy is only reachable by its field set
+	sel int32
+	// fields hashed by the policy
+	fields int32
+	// src and dst are combined by xor so both directions of a flow hash
+	// to the same member
+	symmetric bool
+}
+
+var bondXmitHashPolicyList = []bondXmitHashPolicy{
+	{
+		name:      "layer2",
+		sel:       utils.LAG_HASH_SEL_SRCDSTMAC,
+		fields:    utils.LAG_HASH_FIELD_SRCMAC | utils.LAG_HASH_FIELD_DSTMAC,
+		symmetric: true,
+	},
+	{
+		name: "layer2+3",
+		sel:  utils.LAG_HASH_SEL_SRCDSTIP,
+		fields: utils.LAG_HASH_FIELD_SRCMAC | utils.LAG_HASH_FIELD_DSTMAC |
+			utils.LAG_HASH_FIELD_SRCIP | utils.LAG_HASH_FIELD_DSTIP,
+		symmetric: true,
+	},
+	{
+		name: "layer3+4",
+		sel:  utils.LAG_HASH_SEL_SRCDSTIP_L4PORT,
+		fields: utils.LAG_HASH_FIELD_SRCIP | utils.LAG_HASH_FIELD_DSTIP |
+			utils.LAG_HASH_FIELD_SRCL4PORT | utils.LAG_HASH_FIELD_DSTL4PORT,
+	},
+	{
+		name: "encap2+3",
+		sel:  utils.LAG_HASH_SEL_ENCAP_SRCDSTIP,
+		fields: utils.LAG_HASH_FIELD_INNER | utils.LAG_HASH_FIELD_SRCMAC | utils.LAG_HASH_FIELD_DSTMAC |
+			utils.LAG_HASH_FIELD_SRCIP | utils.LAG_HASH_FIELD_DSTIP,
+		symmetric: true,
+	},
+	{
+		name: "encap3+4",
+		sel:  utils.LAG_HASH_SEL_ENCAP_SRCDSTIP_L4PORT,
+		fields: utils.LAG_HASH_FIELD_INNER | utils.LAG_HASH_FIELD_SRCIP | utils.LAG_HASH_FIELD_DSTIP |
+			utils.LAG_HASH_FIELD_SRCL4PORT | utils.LAG_HASH_FIELD_DSTL4PORT,
+	},
+	{
+		name:   "vlan+srcmac",
+		sel:    -1,
+		fields: utils.LAG_HASH_FIELD_VLAN | utils.LAG_HASH_FIELD_SRCMAC,
+	},
+}
+
+// bondXmitHashPolicyGet converts the lag hash type to the kernel bonding
+// xmit_hash_policy.  A field set is only supported if it is exactly the set
+// hashed by one of the policies, as bonding has no arbitrary field selection
+func bondXmitHashPolicyGet(hashType int32) (string, error) {
+	sel := hashType & utils.LAG_HASH_SEL_MASK
+	fields := utils.LagHashFieldsGet(hashType)
+	for _, policy := range bondXmitHashPolicyList {
+		if sel == utils.LAG_HASH_SEL_FIELDS {
+			if fields != policy.fields {
+				continue
+			}
+		} else if sel != policy.sel {
+			continue
+		}
+		if hashType&utils.LAG_HASH_SYMMETRIC != 0 && !policy.symmetric {
+			return "", errors.New(fmt.Sprintf("Symmetric hashing not supported by linux bond xmit_hash_policy %s", policy.name))
+		}
+		return policy.name, nil
 	}
-	return i
+	return "", errors.New(fmt.Sprintf("Unsupported lag hash type 0x%x for linux bond", hashType))
+}
+
+// LagHashSupported, linux bonds support all the lag hash selections and the
+// field sets matching a xmit_hash_policy
+func (lp *LinuxPlugin) LagHashSupported(hashType int32) error {
+	_, err := bondXmitHashPolicyGet(hashType)
+	return err
 }
 
-// BondedLinkCreate will create a bonded interface
-// bondname must be in the format of name-<number>
-func BondLinkCreate(bondname string, mac string, hashmode int) (link netlink.Link, err error) {
-	fmt.Println("in bonnded create for bondname ", bondname)
-	hwmac, _ := net.ParseMAC(mac)
-	var linkAttrs = netlink.LinkAttrs{
-		//	Index:        BondedIfIdxBase + GetIdByName(bondname),
-		Name:         bondname,
-		HardwareAddr: hwmac,
+// bondPortListGet parses the comma separated ifindex list given to the lag
+// operations, same format which is sent to asicd
+func bondPortListGet(ports string) ([]int, error) {
+	var ifindexList []int
+	for _, port := range strings.Split(ports, ",") {
+		port = strings.TrimSpace(port)
+		if port == "" {
+			continue
+		}
+		ifindex, err := strconv.Atoi(port)
+		if err != nil || ifindex <= 0 {
+			return nil, errors.New(fmt.Sprintln("Invalid lag member ifindex", port))
+		}
+		ifindexList = append(ifindexList, ifindex)
 	}
+	return ifindexList, nil
+}
 
-	fmt.Println("linkAttrs.Name=", linkAttrs.Name)
-	bondedif := netlink.NewLinkBond(linkAttrs)
-	bondedif.Mode = netlink.BOND_MODE_BALANCE_RR
-	bondedif.XmitHashPolicy = netlink.BondXmitHashPolicy(hashmode)
-	bondedif.MinLinks = 1
-	err = netlink.LinkAdd(bondedif)
+// bondXmitHashPolicySet changes the hash policy of an existing bond, sysfs is
+// used as the policy may be changed while the bond has members
+func bondXmitHashPolicySet(bondname string, hashType int32) error {
+	policy, err := bondXmitHashPolicyGet(hashType)
 	if err != nil {
-		fmt.Println("err from Bond LinkAdd = ", err)
-		return bondedif, err
+		return err
+	}
+	return ioutil.WriteFile(SYSFS_NET_PATH+bondname+"/bonding/xmit_hash_policy", []byte(policy), 0644)
+}
+
+// BondLinkCreate will create a bonded interface in balance-xor mode, the kernel
+// does not run lacp on it, members are only added by lacp when they are
+// distributing.  An existing bond with the same name is reused so that
+// a restart of lacpd does not tear down the lag
+func BondLinkCreate(bondname string, hashType int32) (link netlink.Link, err error) {
+	if len(bondname) > BOND_NAME_MAX_LEN {
+		return nil, errors.New(fmt.Sprintf("Lag name %s longer than %d characters", bondname, BOND_NAME_MAX_LEN))
 	}
-	/*
-		time.Sleep(time.Second * 1)
-		err = netlink.LinkSetUp(bondedif)
+	link, err = netlink.LinkByName(bondname)
+	if err == nil {
+		if link.Type() != "bond" {
+			return nil, errors.New(fmt.Sprintf("Interface %s exists and is not a bond", bondname))
+		}
+	} else {
+		bondedif := netlink.NewLinkBond(netlink.LinkAttrs{Name: bondname})
+		bondedif.Mode = netlink.BOND_MODE_BALANCE_XOR
+		err = netlink.LinkAdd(bondedif)
 		if err != nil {
-			fmt.Println("err from Bond LinkSetUp = ", err)
-			return bondedif, err
+			return nil, err
 		}
-	*/
-	return bondedif, err
+		// read back in order to learn the ifindex assigned by the kernel
+		link, err = netlink.LinkByName(bondname)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = bondXmitHashPolicySet(bondname, hashType)
+	if err != nil {
+		return link, err
+	}
+	err = netlink.LinkSetUp(link)
+	return link, err
 }
 
-func BondLinkDelete(bondname string) (err error) {
-	if bondedif, err := netlink.LinkByName(bondname); err == nil {
-		err = netlink.LinkDel(bondedif)
+// BondLinkDelete will delete the bonded interface, members are released first
+// so that they are left up
+func (lp *LinuxPlugin) BondLinkDelete(bondedif netlink.Link) error {
+	for _, linkif := range bondSlaveListGet(bondedif.Attrs().Index) {
+		err := lp.DelLinkFromBond(linkif)
+		if err != nil {
+			return err
+		}
 	}
-	return err
+	return netlink.LinkDel(bondedif)
 }
 
-func AddLinkToBond(bondname string, linkname string) (err error) {
-	if bondedif, err := netlink.LinkByName(bondname); err == nil {
-		if linkif, err := netlink.LinkByName(linkname); err == nil {
+// bondSlaveListGet returns the current members of the bond
+func bondSlaveListGet(bondIfIndex int) (slaves []netlink.Link) {
+	links, err := netlinkLinkList()
+	if err != nil {
+		return nil
+	}
+	for _, link := range links {
+		if link.Attrs().MasterIndex == bondIfIndex {
+			slaves = append(slaves, link)
+		}
+	}
+	return slaves
+}
 
-			// link should be down before we add it to the bonded interface
-			err = netlink.LinkSetDown(linkif)
-			if err != nil {
-				fmt.Println("err from Link LinkSetDown = ", err)
-				return err
-			}
+// AddLinkToBond will enslave the link to the bond, link events caused by the
+// kernel re-opening the link are suppressed
+func (lp *LinuxPlugin) AddLinkToBond(bondedif netlink.Link, linkif netlink.Link) error {
+	lp.portHoldOffStart(int32(linkif.Attrs().Index))
+	err := netlink.LinkSetMasterByIndex(linkif, bondedif.Attrs().Index)
+	if err != nil {
+		return err
+	}
+	utils.GlobalLogger.Info(fmt.Sprintln("Adding interface", linkif.Attrs().Name, "to bonded interface", bondedif.Attrs().Name))
+	return netlink.LinkSetUp(linkif)
+}
 
-			time.Sleep(time.Second * 1)
+// DelLinkFromBond will release the link from its bond, the kernel closes
+// released links so the link is brought back up in order for lacp to keep
+// running on it
+func (lp *LinuxPlugin) DelLinkFromBond(linkif netlink.Link) error {
+	lp.portHoldOffStart(int32(linkif.Attrs().Index))
+	err := netlink.LinkSetNoMaster(linkif)
+	if err != nil {
+		return err
+	}
+	utils.GlobalLogger.Info(fmt.Sprintln("Deleting interface", linkif.Attrs().Name, "from bonded interface"))
+	return netlink.LinkSetUp(linkif)
+}
 
-			linkif.Attrs().ParentIndex = bondedif.Attrs().Index
-			err = netlink.LinkSetMasterByIndex(linkif, bondedif.Attrs().Index)
-			if err != nil {
-				fmt.Println("err from Add Link to Bond LinkSetMasterByIndex = ", err)
-				return err
-			}
-			fmt.Println("Adding interface", linkname, "to bonded interface", bondname)
-		}
+// CreateLag creates the kernel bond for the aggregator, returned ifindex is
+// used by lacp as the hw aggregator id
+func (lp *LinuxPlugin) CreateLag(ifName string, hashType int32, ports string) (int32, error) {
+	bondedif, err := BondLinkCreate(ifName, hashType)
+	if err != nil {
+		return 0, err
 	}
-	return err
+	ifindex := int32(bondedif.Attrs().Index)
+	return ifindex, lp.UpdateLag(ifindex, hashType, ports)
 }
 
-func DelLinkFromBond(bondname string, linkname string) (err error) {
-	if bondedif, err := netlink.LinkByName(bondname); err == nil {
-		if linkif, err := netlink.LinkByName(linkname); err == nil {
+// DeleteLag deletes the kernel bond along with the redirect filters of the
+// ports which were collecting
+func (lp *LinuxPlugin) DeleteLag(ifIndex int32) error {
+	bondedif, err := netlink.LinkByIndex(int(ifIndex))
+	if err != nil {
+		return err
+	}
+	lp.mutex.Lock()
+	delete(lp.collecting, ifIndex)
+	lp.mutex.Unlock()
+	err = lp.bondCollectingSync(ifIndex)
+	if err != nil {
+		utils.GlobalLogger.Err(fmt.Sprintln("Deleting collecting port filters of bond", bondedif.Attrs().Name, "failed", err))
+	}
+	lp.mutex.Lock()
+	delete(lp.collectOnly, ifIndex)
+	lp.mutex.Unlock()
+	return lp.BondLinkDelete(bondedif)
+}
 
-			// link should be down before we add it to the bonded interface
-			err = netlink.LinkSetDown(linkif)
-			if err != nil {
-				fmt.Println("err from LinkSetDown = ", err)
-				return err
-			}
+// UpdateLag sets the hash policy and makes the bond members match the
+// distributing ports given by lacp
+func (lp *LinuxPlugin) UpdateLag(ifIndex, hashType int32, ports string) error {
+	bondedif, err := netlink.LinkByIndex(int(ifIndex))
+	if err != nil {
+		return err
+	}
+	ifindexList, err := bondPortListGet(ports)
+	if err != nil {
+		return err
+	}
+	err = bondXmitHashPolicySet(bondedif.Attrs().Name, hashType)
+	if err != nil {
+		return err
+	}
 
-			time.Sleep(time.Second * 1)
-			linkif.Attrs().ParentIndex = bondedif.Attrs().Index
-			err = netlink.LinkSetNoMaster(linkif)
+	members := make(map[int]bool)
+	for _, ifindex := range ifindexList {
+		members[ifindex] = true
+	}
+	for _, linkif := range bondSlaveListGet(int(ifIndex)) {
+		if _, ok := members[linkif.Attrs().Index]; ok {
+			delete(members, linkif.Attrs().Index)
+			continue
+		}
+		err = lp.DelLinkFromBond(linkif)
+		if err != nil {
+			return err
+		}
+	}
+	for _, ifindex := range ifindexList {
+		if _, ok := members[ifindex]; !ok {
+			continue
+		}
+		linkif, err := netlink.LinkByIndex(ifindex)
+		if err != nil {
+			return err
+		}
+		// port can only belong to one bond
+		if linkif.Attrs().MasterIndex != 0 {
+			err = lp.DelLinkFromBond(linkif)
 			if err != nil {
-				fmt.Println("err from LinkSetNoMaster = ", err)
 				return err
 			}
-			fmt.Println("Deleting interface", linkname, "from bonded interface", bondname)
+		}
+		err = lp.AddLinkToBond(bondedif, linkif)
+		if err != nil {
+			return err
 		}
 	}
-	return err
+	// ports which are no longer distributing may still be collecting
+	return lp.bondCollectingSync(ifIndex)
 }
 
-func main() {
-
-	BondedIfIdxBase = 51
-	bondname := "bond0"
-	linkname := "eth0"
-	BondLinkCreate(bondname, "00:DD:EE:AA:DD:00", 0)
-
-	time.Sleep(time.Second * 1)
-	AddLinkToBond(bondname, linkname)
-/*
-	time.Sleep(time.Second * 1)
-
-	binary, lookErr := exec.LookPath("ifconfig")
-	if lookErr != nil {
-		fmt.Println("ifconfig not found lookerr = ", lookErr)
+// UpdateLagCollecting, a bond receives only on its members and members are
+// the distributing ports, so a port which is collecting but not distributing
+// is kept out of the bond and the frames it receives are redirected to the
+// bond by a tc ingress filter.  Packet sockets get the frame before tc ingress
+// and hence lacp still receives its LACPDUs on the port
+func (lp *LinuxPlugin) UpdateLagCollecting(ifIndex int32, ports string) error {
+	ifindexList, err := bondPortListGet(ports)
+	if err != nil {
+		return err
 	}
+	lp.mutex.Lock()
+	lp.collecting[ifIndex] = ifindexList
+	lp.mutex.Unlock()
+	return lp.bondCollectingSync(ifIndex)
+}
 
-	out, err := exec.Command(binary).Output()
-	if err != nil {
-		fmt.Println("Error executing ifconfig")
+// bondCollectOnlyGet returns the collecting ports which are not members of
+// the bond
+func bondCollectOnlyGet(collecting []int, slaves []netlink.Link) (collectOnly []int) {
+	members := make(map[int]bool)
+	for _, linkif := range slaves {
+		members[linkif.Attrs().Index] = true
 	}
-	fmt.Println(string(out))
-	time.Sleep(time.Second * 1)
-	binary2, lookErr2 := exec.LookPath("cat")
-	if lookErr2 != nil {
-		fmt.Println("cat not found lookerr = ", lookErr2)
+	for _, ifindex := range collecting {
+		if !members[ifindex] {
+			collectOnly = append(collectOnly, ifindex)
+		}
 	}
+	return collectOnly
+}
 
-	out, err = exec.Command(binary2, fmt.Sprintf("/proc/net/bonding/%s", bondname)).Output()
-	if err != nil {
-		fmt.Println("Error executing ifconfig")
+func bondCollectFilterAttrs(ifindex int) netlink.FilterAttrs {
+	return netlink.FilterAttrs{
+		LinkIndex: ifindex,
+		Parent:    netlink.MakeHandle(0xffff, 0),
+		Priority:  BOND_COLLECT_FILTER_PRIORITY,
+		Protocol:  syscall.ETH_P_ALL,
 	}
-	fmt.Println(string(out))
-
-	fmt.Println("Deleting link from Bond")
-	DelLinkFromBond(bondname, linkname)
+}
 
-	time.Sleep(time.Second * 1)
-	fmt.Println("Deleting Bond")
-	BondLinkDelete(bondname)
+// bondCollectFilterAdd redirects every frame received on the port to the bond
+func bondCollectFilterAdd(bondIfIndex int32, ifindex int) error {
+	qdisc := &netlink.GenericQdisc{
+		QdiscAttrs: netlink.QdiscAttrs{
+			LinkIndex: ifindex,
+			Handle:    netlink.MakeHandle(0xffff, 0),
+			Parent:    netlink.HANDLE_INGRESS,
+		},
+		QdiscType: "ingress",
+	}
+	// ingress qdisc may have been added by another daemon
+	err := netlinkQdiscAdd(qdisc)
+	if err != nil && err != syscall.EEXIST {
+		return err
+	}
+	redirect := netlink.NewMirredAction(int(bondIfIndex))
+	redirect.MirredAction = netlink.TCA_INGRESS_REDIR
+	return netlinkFilterAdd(&netlink.U32{
+		FilterAttrs: bondCollectFilterAttrs(ifindex),
+		Sel: &netlink.TcU32Sel{
+			Flags: netlink.TC_U32_TERMINAL,
+			// match all
+			Keys: []netlink.TcU32Key{{}},
+		},
+		Actions: []netlink.Action{redirect},
+	})
+}
 
-	time.Sleep(time.Second * 1)
-	out, err = exec.Command(binary).Output()
-	if err != nil {
-		fmt.Println("Error executing ifconfig")
+// bondCollectingSync makes the redirect filters match the ports which are
+// collecting but not distributing, ports whose filter failed to be added or
+// deleted are retried on the next sync
+func (lp *LinuxPlugin) bondCollectingSync(ifIndex int32) (err error) {
+	slaves := bondSlaveListGet(int(ifIndex))
+	lp.mutex.Lock()
+	defer lp.mutex.Unlock()
+	collectOnly := bondCollectOnlyGet(lp.collecting[ifIndex], slaves)
+	wanted := make(map[int]bool)
+	for _, ifindex := range collectOnly {
+		wanted[ifindex] = true
+	}
+	var programmed []int
+	for _, ifindex := range lp.collectOnly[ifIndex] {
+		if wanted[ifindex] {
+			delete(wanted, ifindex)
+			programmed = append(programmed, ifindex)
+			continue
+		}
+		// deleting the filter by priority leaves the filters of other daemons
+		delErr := netlinkFilterDel(&netlink.U32{FilterAttrs: bondCollectFilterAttrs(ifindex)})
+		if delErr != nil {
+			err = delErr
+			programmed = append(programmed, ifindex)
+			continue
+		}
+		utils.GlobalLogger.Info(fmt.Sprintln("Stopped redirecting collecting port", ifindex, "to bond", ifIndex))
+	}
+	for _, ifindex := range collectOnly {
+		if !wanted[ifindex] {
+			continue
+		}
+		addErr := bondCollectFilterAdd(ifIndex, ifindex)
+		if addErr != nil {
+			err = addErr
+			continue
+		}
+		utils.GlobalLogger.Info(fmt.Sprintln("Redirecting collecting port", ifindex, "to bond", ifIndex))
+		programmed = append(programmed, ifindex)
 	}
-	fmt.Println(string(out))
-*/
+	lp.collectOnly[ifIndex] = programmed
+	return err
 }
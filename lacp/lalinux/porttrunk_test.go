@@ -0,0 +1,226 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// porttrunk_test.go
+package lalinux
+
+import (
+	"github.com/vishvananda/netlink"
+	"l2/lacp/protocol/utils"
+	"sort"
+	"syscall"
+	"testing"
+	"utils/logging"
+)
+
+func TestBondPortListGet(t *testing.T) {
+	ifindexList, err := bondPortListGet("3,4, 10")
+	if err != nil {
+		t.Error("Unexpected error parsing port list", err)
+	}
+	if len(ifindexList) != 3 ||
+		ifindexList[0] != 3 ||
+		ifindexList[1] != 4 ||
+		ifindexList[2] != 10 {
+		t.Error("Unexpected port list", ifindexList)
+	}
+
+	ifindexList, err = bondPortListGet("")
+	if err != nil || len(ifindexList) != 0 {
+		t.Error("Expected empty port list", ifindexList, err)
+	}
+
+	_, err = bondPortListGet("3,eth1")
+	if err == nil {
+		t.Error("Expected error for invalid port list")
+	}
+}
+
+func TestBondXmitHashPolicyGet(t *testing.T) {
+	for _, tc := range []struct {
+		hashType int32
+		policy   string
+	}{
+		{utils.LAG_HASH_SEL_SRCDSTMAC, "layer2"},
+		{utils.LAG_HASH_SEL_SRCDSTIP, "layer2+3"},
+		{utils.LAG_HASH_SEL_SRCDSTIP_L4PORT, "layer3+4"},
+		{utils.LAG_HASH_SEL_ENCAP_SRCDSTIP, "encap2+3"},
+		{utils.LAG_HASH_SEL_ENCAP_SRCDSTIP_L4PORT, "encap3+4"},
+		{utils.LAG_HASH_SEL_SRCDSTIP | utils.LAG_HASH_SYMMETRIC, "layer2+3"},
+		{utils.LAG_HASH_SEL_FIELDS |
+			(utils.LAG_HASH_FIELD_VLAN|utils.LAG_HASH_FIELD_SRCMAC)<<utils.LAG_HASH_FIELDS_SHIFT, "vlan+srcmac"},
+		{utils.LAG_HASH_SEL_FIELDS |
+			(utils.LAG_HASH_FIELD_SRCIP|utils.LAG_HASH_FIELD_DSTIP|
+				utils.LAG_HASH_FIELD_SRCL4PORT|utils.LAG_HASH_FIELD_DSTL4PORT)<<utils.LAG_HASH_FIELDS_SHIFT, "layer3+4"},
+	} {
+		policy, err := bondXmitHashPolicyGet(tc.hashType)
+		if err != nil || policy != tc.policy {
+			t.Error("Unexpected policy for hash type", tc.hashType, "expected", tc.policy, "actual", policy, err)
+		}
+	}
+
+	// layer3+4 hashes the ports without combining src and dst
+	_, err := bondXmitHashPolicyGet(utils.LAG_HASH_SEL_SRCDSTIP_L4PORT | utils.LAG_HASH_SYMMETRIC)
+	if err == nil {
+		t.Error("Expected error for symmetric layer3+4")
+	}
+	// no policy hashes on ip protocol
+	_, err = bondXmitHashPolicyGet(utils.LAG_HASH_SEL_FIELDS | utils.LAG_HASH_FIELD_IPPROTO<<utils.LAG_HASH_FIELDS_SHIFT)
+	if err == nil {
+		t.Error("Expected error for unsupported hash field set")
+	}
+	_, err = bondXmitHashPolicyGet(100)
+	if err == nil {
+		t.Error("Expected error for unsupported hash type")
+	}
+}
+
+// bondCollectTestSetup mocks the kernel links and the tc filters, bond 10 has
+// the lag ports 2, 3 and 4 whose master is taken from slaves
+func bondCollectTestSetup(slaves map[int]int) (filters map[int]int) {
+	if utils.GlobalLogger == nil {
+		logger, _ := logging.NewLogger("lacpd", "TEST", false)
+		utils.SetLaLogger(logger)
+	}
+	filters = make(map[int]int)
+	netlinkLinkList = func() ([]netlink.Link, error) {
+		var links []netlink.Link
+		for _, ifindex := range []int{2, 3, 4} {
+			links = append(links, &netlink.Device{LinkAttrs: netlink.LinkAttrs{Index: ifindex,
+				EncapType: "ether", MasterIndex: slaves[ifindex]}})
+		}
+		return links, nil
+	}
+	netlinkQdiscAdd = func(q netlink.Qdisc) error {
+		return nil
+	}
+	netlinkFilterAdd = func(f netlink.Filter) error {
+		u32 := f.(*netlink.U32)
+		redirect := u32.Actions[0].(*netlink.MirredAction)
+		if u32.Priority != BOND_COLLECT_FILTER_PRIORITY ||
+			redirect.MirredAction != netlink.TCA_INGRESS_REDIR {
+			return syscall.EINVAL
+		}
+		filters[u32.LinkIndex] = redirect.Ifindex
+		return nil
+	}
+	netlinkFilterDel = func(f netlink.Filter) error {
+		if _, ok := filters[f.Attrs().LinkIndex]; !ok {
+			return syscall.ENOENT
+		}
+		delete(filters, f.Attrs().LinkIndex)
+		return nil
+	}
+	return filters
+}
+
+func bondCollectTestTeardown() {
+	netlinkLinkList = netlink.LinkList
+	netlinkQdiscAdd = netlink.QdiscAdd
+	netlinkFilterAdd = netlink.FilterAdd
+	netlinkFilterDel = netlink.FilterDel
+}
+
+func bondCollectTestFilters(filters map[int]int) (ports []int) {
+	for ifindex, bond := range filters {
+		if bond != 10 {
+			return nil
+		}
+		ports = append(ports, ifindex)
+	}
+	sort.Ints(ports)
+	return ports
+}
+
+// TestLinuxPluginCollectingDistributing toggles collecting and distributing of
+// the ports separately, only the ports which are collecting and are not bond
+// members are redirected to the bond
+func TestLinuxPluginCollectingDistributing(t *testing.T) {
+	slaves := make(map[int]int)
+	filters := bondCollectTestSetup(slaves)
+	defer bondCollectTestTeardown()
+	lp := NewLinuxPlugin(nil, nil)
+
+	for i, tc := range []struct {
+		desc         string
+		collecting   string
+		distributing []int
+		expected     []int
+	}{
+		{"port 2 collecting", "2", nil, []int{2}},
+		{"port 3 collecting", "2,3", nil, []int{2, 3}},
+		{"port 2 distributing", "2,3", []int{2}, []int{3}},
+		{"port 3 distributing", "2,3", []int{2, 3}, nil},
+		{"port 2 stops distributing", "2,3", []int{3}, []int{2}},
+		{"port 2 stops collecting", "3", []int{3}, nil},
+		{"port 3 stops distributing", "3", nil, []int{3}},
+		{"port 3 stops collecting", "", nil, nil},
+	} {
+		// bond members are the distributing ports, as programmed by UpdateLag
+		for _, ifindex := range []int{2, 3, 4} {
+			delete(slaves, ifindex)
+		}
+		for _, ifindex := range tc.distributing {
+			slaves[ifindex] = 10
+		}
+		err := lp.UpdateLagCollecting(10, tc.collecting)
+		if err != nil {
+			t.Error(i, tc.desc, "unexpected error", err)
+		}
+		ports := bondCollectTestFilters(filters)
+		if len(ports) != len(tc.expected) {
+			t.Error(i, tc.desc, "expected redirected ports", tc.expected, "actual", ports)
+			continue
+		}
+		for j := range ports {
+			if ports[j] != tc.expected[j] {
+				t.Error(i, tc.desc, "expected redirected ports", tc.expected, "actual", ports)
+			}
+		}
+	}
+
+	// filter which failed to be added is retried on the next update
+	netlinkFilterAdd = func(f netlink.Filter) error {
+		return syscall.ENOMEM
+	}
+	if err := lp.UpdateLagCollecting(10, "4"); err == nil {
+		t.Error("Expected error adding the redirect filter")
+	}
+	netlinkFilterAdd = func(f netlink.Filter) error {
+		filters[f.Attrs().LinkIndex] = 10
+		return nil
+	}
+	lp.bondCollectingSync(10)
+	if ports := bondCollectTestFilters(filters); len(ports) != 1 || ports[0] != 4 {
+		t.Error("Expected redirect filter of port 4 to be retried", ports)
+	}
+
+	// lag deletion clears the collecting ports, deleting the filters
+	lp.mutex.Lock()
+	delete(lp.collecting, 10)
+	lp.mutex.Unlock()
+	lp.bondCollectingSync(10)
+	if len(filters) != 0 {
+		t.Error("Expected redirect filters to be deleted", filters)
+	}
+}
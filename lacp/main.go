@@ -27,9 +27,12 @@ package main
 import (
 	"flag"
 	"l2/lacp/asicdMgr"
+	"l2/lacp/lalinux"
+	"l2/lacp/protocol/lacp"
 	"l2/lacp/protocol/utils"
 	"l2/lacp/rpc"
 	"l2/lacp/server"
+	"strings"
 	"utils/asicdClient"
 	"utils/commonDefs"
 	"utils/keepalive"
@@ -42,7 +45,14 @@ func main() {
 
 	// lookup port
 	paramsDir := flag.String("params", "./params", "Params directory")
+	pluginName := flag.String("plugin", "flexswitch", "Plugin set to use: flexswitch or linux")
+	lagPorts := flag.String("ports", "", "Comma separated interfaces which may be aggregated by the linux "+
+		"plugin, all ethernet interfaces are used if not provided")
+	muxControl := flag.String("mux", "independent", "Mux machine control used by lags which do not "+
+		"configure one: independent or coupled")
 	flag.Parse()
+	// coupled control enables collection and distribution together
+	lacp.LacpMuxCouplingDefault = *muxControl == "coupled"
 	path := *paramsDir
 	if path[len(path)-1] != '/' {
 		path = path + "/"
@@ -55,18 +65,35 @@ func main() {
 
 	// lets setup north bound notifications
 	nHdl, nMap := asicdMgr.NewNotificationHdl(laServer)
-	asicdHdl := commonDefs.AsicdClientStruct{
-		Logger: logger,
-		NHdl:   nHdl,
-		NMap:   nMap,
-	}
-	asicdPlugin := asicdClient.NewAsicdClientInit("Flexswitch", clientInfoFile, asicdHdl)
+	switch *pluginName {
+	case "linux":
+		// lags are programmed as kernel bonds, no asicd is needed
+		var ports []string
+		if *lagPorts != "" {
+			ports = strings.Split(*lagPorts, ",")
+		}
+		linuxPlugin := lalinux.NewLinuxPlugin(ports, nHdl)
+		utils.SetAsicDPlugin(linuxPlugin)
+		utils.SaveSwitchMac(linuxPlugin.GetSwitchMAC(path))
+		err = linuxPlugin.Start()
+		if err != nil {
+			logger.Err("Failed to subscribe to netlink link notifications", err)
+		}
+
+	default:
+		asicdHdl := commonDefs.AsicdClientStruct{
+			Logger: logger,
+			NHdl:   nHdl,
+			NMap:   nMap,
+		}
+		asicdPlugin := asicdClient.NewAsicdClientInit("Flexswitch", clientInfoFile, asicdHdl)
 
-	utils.SetAsicDPlugin(asicdPlugin)
-	utils.SaveSwitchMac(asicdPlugin.GetSwitchMAC(path))
+		utils.SetAsicDPlugin(asicdPlugin)
+		utils.SaveSwitchMac(asicdPlugin.GetSwitchMAC(path))
 
-	// Start keepalive routine
-	go keepalive.InitKeepAlive("lacpd", path)
+		// Start keepalive routine
+		go keepalive.InitKeepAlive("lacpd", path)
+	}
 
 	laServer.InitServer()
 	confIface := rpc.NewLACPDServiceHandler(laServer)
@@ -41,6 +41,14 @@ const (
 	LacpAggUnSelected
 )
 
+// Reasons an aggregator is held operationally down, reported alongside
+// the oper state
+const (
+	LacpAggOperDownReasonNone           = ""
+	LacpAggOperDownReasonNoDistributing = "NO MEMBERS DISTRIBUTING"
+	LacpAggOperDownReasonMinLinks       = "MIN LINKS NOT MET"
+)
+
 type LacpAggregatorStats struct {
 	// does not include lacp or marker pdu
 	octetsTx              int
@@ -132,6 +140,8 @@ type LaAggregator struct {
 	AggName        string // 255 max chars
 	AggType        uint32 // LACP/STATIC
 	AggMinLinks    uint16
+	// max number of ports selected, others are standby
+	AggMaxLinks uint16
 
 	// lacp configuration info
 	Config LacpConfigInfo
@@ -152,7 +162,16 @@ type LaAggregator struct {
 	AggPriority   uint16   // ADMIN: AggActorSystemPriority
 	PortAlgorithm [4]uint8 // AggPortAlgorithm
 	PartnerDWC    bool
-	//AggConversationAdminLink [4096]uint16
+	// LACP version run by the member ports, 0 uses LacpActorSystemLacpVersion
+	AggLacpVersion uint8
+	// aAggConversationAdminLink, Link Number IDs in priority order indexed
+	// by Port Conversation ID
+	ConversationAdminLink map[uint16][]uint16
+	// aAggAdminServiceConversationMap, Service IDs indexed by Port
+	// Conversation ID
+	AdminServiceConversationMap map[uint16][]uint16
+	// aAggAdminDiscardWrongConversation
+	AdminDiscardWrongConversation bool
 
 	// If attached to a DR then this will be set
 	DrniName string
@@ -174,6 +193,8 @@ type LaAggregator struct {
 	// UP/DOWN
 	AdminState bool
 	OperState  bool
+	// why the aggregator is oper down, empty when up
+	OperDownReason string
 
 	// date of last oper change
 	timeOfLastOperChange time.Time
@@ -199,12 +220,17 @@ type LaAggregator struct {
 	// Ports in Distributed State
 	DistributedPortNumList []string
 
-	// Distributed Relay attribute
-	// TODO This variable is updated by the updateConvesationPortList function,
+	// Ports in Collecting State, only given to plugins which are able to
+	// collect on a port which is not distributing
+	CollectingPortNumList []string
+
+	// This variable is updated by LacpAggConversationUpdate,
 	// which is always invoked when a new aAggConversationAdminLink[] (7.3.1.1.35) or new
-	// aAggPortLinkNumberID (7.3.2.1.27) operator command is issued
-	// Port priority will be 1:1 with the port value
-	//ConversationPortList [4096][]uint16
+	// aAggPortLinkNumberID (7.3.2.1.27) operator command is issued, or the
+	// distributing ports change.  Holds the port carrying each conversation
+	ConversationPortList [LacpConversationIdMax]uint16
+	// conversation sensitive collection/distribution is in use
+	ConversationSensitive bool
 
 	// For now this value assumes the value of the linux modes
 	// 0 - L2
@@ -212,7 +238,16 @@ type LaAggregator struct {
 	// 2 - L3+L4
 	// 3 - ENCAP
 	// 4 - ENCAP2
+	// 5 - FIELDS, hash on LagHashFields
 	LagHash uint32
+	// both directions of a flow are hashed to the same link
+	LagHashSymmetric bool
+	// utils.LAG_HASH_FIELD_* bits used when LagHash is FIELDS
+	LagHashFields uint32
+
+	// mux machine control of the member ports, LaAggMuxControlSystem
+	// follows the mux coupling of the system
+	AggMuxControl uint8
 
 	// Similar to Port attrute L2/l3/Internal
 	ConfigMode string
@@ -237,13 +272,23 @@ func NewLaAggregator(ac *LaAggConfig) *LaAggregator {
 		ActorOperKey:           ac.Key,
 		AggType:                ac.Type,
 		AggMinLinks:            ac.MinLinks,
+		AggMaxLinks:            ac.MaxLinks,
+		AggLacpVersion:         ac.Version,
+		ConversationAdminLink:  ac.ConversationAdminLink,
 		Config:                 ac.Lacp,
 		PartnerSystemId:        [6]uint8{0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
 		ready:                  true,
 		PortNumList:            make([]uint16, 0),
 		DistributedPortNumList: make([]string, 0),
+		CollectingPortNumList:  make([]string, 0),
 		LagHash:                ac.HashMode,
+		LagHashSymmetric:       ac.HashSymmetric,
+		LagHashFields:          ac.HashFields,
+		AggMuxControl:          ac.MuxControl,
 		DrniName:               "",
+
+		AdminServiceConversationMap:   ac.AdminServiceConversationMap,
+		AdminDiscardWrongConversation: ac.DiscardWrongConversation,
 	}
 
 	// add port agg map and register port oper state events
@@ -277,14 +322,20 @@ func NewLaAggregator(ac *LaAggConfig) *LaAggregator {
 	}
 
 	if a != nil {
+		a.OperDownReason = LacpAggOperDownReasonNoDistributing
 		// The Lag must exist in the HW in order for IP interfaces to be created
-		for _, client := range utils.GetAsicDPluginList() {
-			if client != nil {
-				ifindex, err := client.CreateLag(a.AggName, asicDHashModeGet(a.LagHash), "")
-				if err != nil {
-					a.LacpAggLog(fmt.Sprintln("Error creating LAG Group in HW", err))
-				} else {
-					a.HwAggId = ifindex
+		laghash, err := a.LacpAggHashGet()
+		if err != nil {
+			a.LacpAggLog(fmt.Sprintln("Error creating LAG Group in HW", err))
+		} else {
+			for _, client := range utils.GetAsicDPluginList() {
+				if client != nil {
+					ifindex, err := client.CreateLag(a.AggName, laghash, "")
+					if err != nil {
+						a.LacpAggLog(fmt.Sprintln("Error creating LAG Group in HW", err))
+					} else {
+						a.HwAggId = ifindex
+					}
 				}
 			}
 		}
@@ -298,6 +349,92 @@ func NewLaAggregator(ac *LaAggConfig) *LaAggregator {
 	return a
 }
 
+// LacpAggMinLinksGet returns the number of ports which must be distributing
+// before the aggregator is considered operationally up
+func (a *LaAggregator) LacpAggMinLinksGet() int {
+	if a.AggMinLinks == 0 {
+		return 1
+	}
+	return int(a.AggMinLinks)
+}
+
+// LacpAggHashGet returns the hash type which is given to the plugins
+func (a *LaAggregator) LacpAggHashGet() (int32, error) {
+	return asicDHashModeGet(a.LagHash, a.LagHashSymmetric, a.LagHashFields)
+}
+
+// LacpAggHwPortListGet returns the port list which should be programmed
+// in HW, when MinLinks is not met no ports are given to the asic so that
+// the remaining links are not overloaded
+func (a *LaAggregator) LacpAggHwPortListGet() []string {
+	if len(a.DistributedPortNumList) < a.LacpAggMinLinksGet() {
+		return nil
+	}
+	return a.DistributedPortNumList
+}
+
+// LacpAggOperStateUpdate will re-evaluate the oper state of the aggregator
+// based on the number of ports distributing and the configured MinLinks.
+// The HW lag is updated with the distributing ports, or withdrawn when MinLinks
+// is not met, and oper state change notifications are sent on transition
+func (a *LaAggregator) LacpAggOperStateUpdate() {
+
+	operState := len(a.DistributedPortNumList) >= a.LacpAggMinLinksGet()
+	if len(a.DistributedPortNumList) == 0 {
+		a.OperDownReason = LacpAggOperDownReasonNoDistributing
+	} else if !operState {
+		a.OperDownReason = fmt.Sprintf("%s %d of %d", LacpAggOperDownReasonMinLinks, len(a.DistributedPortNumList), a.LacpAggMinLinksGet())
+	} else {
+		a.OperDownReason = LacpAggOperDownReasonNone
+	}
+
+	laghash, err := a.LacpAggHashGet()
+	if err != nil {
+		a.LacpAggLog(fmt.Sprintln("ERROR Updating Lag in HW", err))
+	} else {
+		for _, client := range utils.GetAsicDPluginList() {
+			err = client.UpdateLag(a.HwAggId, laghash, asicDPortBmpFormatGet(a.LacpAggHwPortListGet()))
+			if err != nil {
+				a.LacpAggLog(fmt.Sprintln("ERROR Updating Lag in HW", err))
+			}
+		}
+	}
+
+	// distributing ports changed, conversations need to be re-assigned
+	a.LacpAggConversationUpdate()
+
+	if operState == a.OperState {
+		return
+	}
+	a.OperState = operState
+	a.timeOfLastOperChange = time.Now()
+
+	if operState {
+		for name, upcb := range LacpCbDb.AggOperUpDbList {
+			a.LacpAggLog(fmt.Sprintf("Notify %s Agg OperState UP %s", name, a.AggName))
+			upcb(int32(a.AggId))
+		}
+	} else {
+		for name, downcb := range LacpCbDb.AggOperDownDbList {
+			a.LacpAggLog(fmt.Sprintf("Notify %s Agg OperState DOWN %s reason %s", name, a.AggName, a.OperDownReason))
+			downcb(int32(a.AggId))
+		}
+	}
+}
+
+// LacpAggCollectingUpdate will give the collecting ports to the plugins
+// which are able to collect on a port independent of distribution
+func (a *LaAggregator) LacpAggCollectingUpdate() {
+	for _, client := range utils.GetAsicDPluginList() {
+		if collectingPlugin, ok := client.(utils.LagCollectingPlugin); ok {
+			err := collectingPlugin.UpdateLagCollecting(a.HwAggId, asicDPortBmpFormatGet(a.CollectingPortNumList))
+			if err != nil {
+				a.LacpAggLog(fmt.Sprintln("ERROR Updating Lag collecting ports in HW", err))
+			}
+		}
+	}
+}
+
 // warning for each call the map may change
 func LaGetAggNext(agg **LaAggregator) bool {
 	returnNext := false
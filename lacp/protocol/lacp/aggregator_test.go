@@ -26,6 +26,7 @@ package lacp
 import (
 	"fmt"
 	"l2/lacp/protocol/utils"
+	"strings"
 	"testing"
 	asicdmock "utils/asicdClient/mock"
 	"utils/logging"
@@ -510,3 +511,204 @@ func TestScaleAggCreate(t *testing.T) {
 	}
 	LacpSysGlobalInfoDestroy(sysId)
 }
+
+type MinLinksMockAsicdClientMgr struct {
+	asicdmock.MockAsicdClientMgr
+	ports string
+}
+
+func (m *MinLinksMockAsicdClientMgr) UpdateLag(ifIndex, hashType int32, ports string) error {
+	m.ports = ports
+	return nil
+}
+
+func TestLaAggregatorMinLinks(t *testing.T) {
+	defer MemoryCheck(t)
+	OnlyForTestSetup()
+	defer OnlyForTestTeardown()
+	mock := &MinLinksMockAsicdClientMgr{}
+	utils.DeleteAllAsicDPlugins()
+	utils.SetAsicDPlugin(mock)
+
+	sysId := LacpSystem{Actor_System_priority: 128,
+		Actor_System: [6]uint8{0x00, 0x01, 0x02, 0x03, 0x04, 0x05}}
+
+	LacpSysGlobalInfoInit(sysId)
+
+	aconf := &LaAggConfig{
+		Name:     "agg2000",
+		Mac:      [6]uint8{0x00, 0x00, 0x01, 0x02, 0x03, 0x04},
+		Id:       2000,
+		Key:      50,
+		MinLinks: 2,
+		Lacp: LacpConfigInfo{Interval: LacpSlowPeriodicTime,
+			Mode:           LacpModeActive,
+			SystemIdMac:    "00:01:02:03:04:05",
+			SystemPriority: 128},
+	}
+
+	upCnt := 0
+	downCnt := 0
+	RegisterLaAggOperStateUpCb("minlinkstest", func(ifindex int32) { upCnt++ })
+	RegisterLaAggOperStateDownCb("minlinkstest", func(ifindex int32) { downCnt++ })
+	defer delete(LacpCbDb.AggOperUpDbList, "minlinkstest")
+	defer delete(LacpCbDb.AggOperDownDbList, "minlinkstest")
+
+	agg := NewLaAggregator(aconf)
+
+	// one port distributing is not enough
+	agg.DistributedPortNumList = append(agg.DistributedPortNumList, "fpPort1")
+	agg.LacpAggOperStateUpdate()
+	if agg.OperState {
+		t.Error("Agg is oper UP with less than MinLinks ports distributing")
+	}
+	if !strings.HasPrefix(agg.OperDownReason, LacpAggOperDownReasonMinLinks) {
+		t.Error("Agg oper down reason expected", LacpAggOperDownReasonMinLinks, "actual", agg.OperDownReason)
+	}
+	if mock.ports != "" {
+		t.Error("Agg programmed in HW with less than MinLinks ports distributing", mock.ports)
+	}
+	if upCnt != 0 {
+		t.Error("Agg oper UP notification sent with less than MinLinks ports distributing")
+	}
+
+	// MinLinks met
+	agg.DistributedPortNumList = append(agg.DistributedPortNumList, "fpPort2")
+	agg.LacpAggOperStateUpdate()
+	if !agg.OperState {
+		t.Error("Agg is not oper UP with MinLinks ports distributing")
+	}
+	if agg.OperDownReason != LacpAggOperDownReasonNone {
+		t.Error("Agg oper down reason expected none actual", agg.OperDownReason)
+	}
+	if mock.ports == "" {
+		t.Error("Agg not programmed in HW with MinLinks ports distributing")
+	}
+	if upCnt != 1 {
+		t.Error("Agg oper UP notification expected 1 actual", upCnt)
+	}
+
+	// raising MinLinks should withdraw the lag
+	SetLaAggMinLinks(agg.AggId, 3)
+	if agg.OperState {
+		t.Error("Agg is oper UP after MinLinks raised above ports distributing")
+	}
+	if mock.ports != "" {
+		t.Error("Agg not withdrawn from HW after MinLinks raised", mock.ports)
+	}
+	if downCnt != 1 {
+		t.Error("Agg oper DOWN notification expected 1 actual", downCnt)
+	}
+
+	agg.DistributedPortNumList = agg.DistributedPortNumList[:0]
+	agg.LacpAggOperStateUpdate()
+	if agg.OperDownReason != LacpAggOperDownReasonNoDistributing {
+		t.Error("Agg oper down reason expected", LacpAggOperDownReasonNoDistributing, "actual", agg.OperDownReason)
+	}
+	if downCnt != 1 {
+		t.Error("Agg oper DOWN notification expected 1 actual", downCnt)
+	}
+
+	agg.DeleteLaAgg()
+	LacpSysGlobalInfoDestroy(sysId)
+}
+
+type LagHashMockAsicdClientMgr struct {
+	MinLinksMockAsicdClientMgr
+	hashType int32
+}
+
+func (m *LagHashMockAsicdClientMgr) UpdateLag(ifIndex, hashType int32, ports string) error {
+	m.hashType = hashType
+	return m.MinLinksMockAsicdClientMgr.UpdateLag(ifIndex, hashType, ports)
+}
+
+func (m *LagHashMockAsicdClientMgr) LagHashSupported(hashType int32) error {
+	return nil
+}
+
+func TestLaAggregatorHashMode(t *testing.T) {
+	defer MemoryCheck(t)
+	OnlyForTestSetup()
+	defer OnlyForTestTeardown()
+
+	// asicd only supports src/dst mac and src/dst ip
+	for _, hashmode := range []uint32{LaAggHashModeL2, LaAggHashModeL2L3} {
+		if _, err := asicDHashModeGet(hashmode, false, 0); err != nil {
+			t.Error("Unexpected error for hash mode supported by asicd", hashmode, err)
+		}
+	}
+	for _, hashmode := range []uint32{LaAggHashModeL3L4, LaAggHashModeEncap, LaAggHashModeEncap2} {
+		if _, err := asicDHashModeGet(hashmode, false, 0); err == nil {
+			t.Error("Expected error for hash mode not supported by asicd", hashmode)
+		}
+	}
+	if _, err := asicDHashModeGet(LaAggHashModeL2, true, 0); err == nil {
+		t.Error("Expected error for symmetric hash not supported by asicd")
+	}
+
+	mock := &LagHashMockAsicdClientMgr{}
+	utils.DeleteAllAsicDPlugins()
+	utils.SetAsicDPlugin(mock)
+
+	if _, err := asicDHashModeGet(LaAggHashModeMax, false, 0); err == nil {
+		t.Error("Expected error for invalid hash mode")
+	}
+	if _, err := asicDHashModeGet(LaAggHashModeFields, false, 0); err == nil {
+		t.Error("Expected error for hash fields mode without fields")
+	}
+	if _, err := asicDHashModeGet(LaAggHashModeFields, false, utils.LAG_HASH_FIELD_ALL+1); err == nil {
+		t.Error("Expected error for invalid hash fields")
+	}
+
+	sysId := LacpSystem{Actor_System_priority: 128,
+		Actor_System: [6]uint8{0x00, 0x01, 0x02, 0x03, 0x04, 0x05}}
+
+	LacpSysGlobalInfoInit(sysId)
+
+	aconf := &LaAggConfig{
+		Name: "agg3000",
+		Mac:  [6]uint8{0x00, 0x00, 0x01, 0x02, 0x03, 0x04},
+		Id:   3000,
+		Key:  60,
+		Lacp: LacpConfigInfo{Interval: LacpSlowPeriodicTime,
+			Mode:           LacpModeActive,
+			SystemIdMac:    "00:01:02:03:04:05",
+			SystemPriority: 128},
+		HashMode:      LaAggHashModeL3L4,
+		HashSymmetric: true,
+	}
+
+	agg := NewLaAggregator(aconf)
+	agg.DistributedPortNumList = append(agg.DistributedPortNumList, "fpPort1")
+	agg.LacpAggOperStateUpdate()
+	if mock.hashType != utils.LAG_HASH_SEL_SRCDSTIP_L4PORT|utils.LAG_HASH_SYMMETRIC {
+		t.Error("Unexpected hash type programmed", mock.hashType)
+	}
+
+	fields := uint32(utils.LAG_HASH_FIELD_SRCIP | utils.LAG_HASH_FIELD_DSTIP | utils.LAG_HASH_FIELD_IPPROTO)
+	err := SetLaAggHashConfig(agg.AggId, LaAggHashModeFields, false, fields)
+	if err != nil {
+		t.Error("Unexpected error setting hash fields", err)
+	}
+	if mock.hashType&utils.LAG_HASH_SEL_MASK != utils.LAG_HASH_SEL_FIELDS ||
+		uint32(utils.LagHashFieldsGet(mock.hashType)) != fields ||
+		mock.hashType&utils.LAG_HASH_SYMMETRIC != 0 {
+		t.Error("Unexpected hash type programmed for hash fields", mock.hashType)
+	}
+
+	// hash mode change keeps the field set
+	err = SetLaAggHashMode(agg.AggId, LaAggHashModeMax)
+	if err == nil {
+		t.Error("Expected error setting invalid hash mode")
+	}
+	if agg.LagHash != LaAggHashModeFields ||
+		agg.LagHashFields != fields {
+		t.Error("Hash config changed by invalid hash mode", agg.LagHash, agg.LagHashFields)
+	}
+
+	agg.DistributedPortNumList = agg.DistributedPortNumList[:0]
+	agg.LacpAggOperStateUpdate()
+	agg.DeleteLaAgg()
+	LacpSysGlobalInfoDestroy(sysId)
+}
@@ -38,6 +38,29 @@ const (
 	LaAggTypeSTATIC
 )
 
+// Lag hash modes, values match the model LagHash
+const (
+	LaAggHashModeL2 = iota
+	LaAggHashModeL2L3
+	LaAggHashModeL3L4
+	LaAggHashModeEncap
+	LaAggHashModeEncap2
+	// hash on the configured HashFields
+	LaAggHashModeFields
+	LaAggHashModeMax
+)
+
+// Mux machine control, 802.1ax-2014 Section 6.4.15
+const (
+	// use the mux control of the system the aggregator is attached to
+	LaAggMuxControlSystem = iota
+	// COLLECTING and DISTRIBUTING are separate states, Figure 6-21
+	LaAggMuxControlIndependent
+	// collection and distribution are enabled together, Figure 6-22
+	LaAggMuxControlCoupled
+	LaAggMuxControlMax
+)
+
 const PortConfigModuleStr = "Port Config"
 
 // 802.1.AX-2014 7.3.1.1 Aggregator attributes GET-SET
@@ -93,10 +116,22 @@ type LaAggConfig struct {
 	Type uint32
 	// Minimum number of links
 	MinLinks uint16
+	// Maximum number of links selected, remaining links are standby
+	MaxLinks uint16
+	// LACP version 1 or 2, 0 uses the default version
+	Version uint8
+	// Link Number IDs in priority order indexed by Port Conversation ID
+	ConversationAdminLink map[uint16][]uint16
+	// Service IDs indexed by Port Conversation ID
+	AdminServiceConversationMap map[uint16][]uint16
+	// discard frames received on a link not selected for the conversation
+	DiscardWrongConversation bool
 	// Enabled
 	Enabled bool
 	// LAG_ports
 	LagMembers []uint16
+	// Link Number IDs of the LAG_ports, ports not present use the port number
+	LagMemberLinkNumberIds map[uint16]uint16
 
 	// System to attach this agg to
 	Lacp LacpConfigInfo
@@ -106,6 +141,13 @@ type LaAggConfig struct {
 
 	// hash config
 	HashMode uint32
+	// both directions of a flow use the same link
+	HashSymmetric bool
+	// utils.LAG_HASH_FIELD_* bits, only used with LaAggHashModeFields
+	HashFields uint32
+
+	// mux machine control System, Independent or Coupled
+	MuxControl uint8
 }
 
 type AggPortConfig struct {
@@ -167,6 +209,9 @@ type LaAggPortConfig struct {
 	// Linux If
 	TraceEna bool
 	IntfId   string
+
+	// aAggPortLinkNumberID, 0 uses the port number
+	LinkNumberId uint16
 }
 
 // The following dbs are used to keep track of
@@ -303,10 +348,51 @@ func LaAggConfigParamCheck(ac *LaAggConfig) error {
 		return errors.New("ERROR Invalid LACP Mode Configured Should be ACTIVE(0) or PASSIVE(1)")
 	}
 
-	if ac.HashMode != 0 &&
-		ac.HashMode != 1 &&
-		ac.HashMode != 2 {
-		return errors.New("ERROR Invalid LACP Mode Configured Should be LAYER2(0) or LAYER3_4(2) or LAYER2_3(1)")
+	if _, err := asicDHashModeGet(ac.HashMode, ac.HashSymmetric, ac.HashFields); err != nil {
+		return err
+	}
+
+	if ac.MaxLinks != 0 &&
+		ac.MinLinks > ac.MaxLinks {
+		return errors.New(fmt.Sprintf("ERROR Invalid MinLinks %d Configured Should not be greater than MaxLinks %d", ac.MinLinks, ac.MaxLinks))
+	}
+
+	if ac.Version > 2 {
+		return errors.New(fmt.Sprintf("ERROR Invalid LACP Version %d Configured Should be 1 or 2", ac.Version))
+	} else if ac.Version == 2 {
+		// version 2 requires conversation sensitive collection and distribution
+		if err := utils.LagConversationSupported(); err != nil {
+			return errors.New(fmt.Sprintf("ERROR Invalid LACP Version 2 Configured %s", err))
+		}
+	}
+
+	if ac.MuxControl >= LaAggMuxControlMax {
+		return errors.New(fmt.Sprintf("ERROR Invalid Mux Control %d Configured Should be SYSTEM(0), INDEPENDENT(1) or COUPLED(2)", ac.MuxControl))
+	}
+
+	for cid, links := range ac.ConversationAdminLink {
+		if cid >= LacpConversationIdMax {
+			return errors.New(fmt.Sprintf("ERROR Invalid Conversation Id %d Configured Should be less than %d", cid, LacpConversationIdMax))
+		}
+		for _, l := range links {
+			if l == 0 {
+				return errors.New(fmt.Sprintf("ERROR Invalid Link Number Id 0 Configured for Conversation Id %d", cid))
+			}
+		}
+	}
+
+	for cid := range ac.AdminServiceConversationMap {
+		if cid >= LacpConversationIdMax {
+			return errors.New(fmt.Sprintf("ERROR Invalid Conversation Id %d Configured Should be less than %d", cid, LacpConversationIdMax))
+		}
+	}
+
+	linkNumberIds := make(map[uint16]uint16)
+	for pid, l := range ac.LagMemberLinkNumberIds {
+		if other, ok := linkNumberIds[l]; ok && l != 0 {
+			return errors.New(fmt.Sprintf("ERROR Link Number Id %d Configured on both port %d and port %d", l, other, pid))
+		}
+		linkNumberIds[l] = pid
 	}
 
 	// lets make sure the port associated with the lag are not associated with another lag
@@ -339,8 +425,18 @@ func SaveLaAggConfig(ac *LaAggConfig) {
 		a.ActorAdminKey = ac.Key
 		a.AggType = ac.Type
 		a.AggMinLinks = ac.MinLinks
+		a.AggMaxLinks = ac.MaxLinks
 		a.Config = ac.Lacp
 		a.LagHash = ac.HashMode
+		a.LagHashSymmetric = ac.HashSymmetric
+		a.LagHashFields = ac.HashFields
+		a.AggMuxControl = ac.MuxControl
+		a.AggLacpVersion = ac.Version
+		conversationMutex.Lock()
+		a.ConversationAdminLink = ac.ConversationAdminLink
+		a.AdminServiceConversationMap = ac.AdminServiceConversationMap
+		a.AdminDiscardWrongConversation = ac.DiscardWrongConversation
+		conversationMutex.Unlock()
 	}
 }
 
@@ -734,22 +830,263 @@ func SetLaAggPortCheckSelectionDistributedRelayIsSynced(pId uint16, sync bool) {
 	}
 }
 
-func SetLaAggHashMode(aggId int, hashmode uint32) {
+// SetLaAggHashMode will set the lag hash mode, keeping the symmetric and
+// hash field config of the aggregator
+func SetLaAggHashMode(aggId int, hashmode uint32) error {
 	var a *LaAggregator
 	if LaFindAggById(aggId, &a) {
-		a.LagHash = hashmode
-		if len(a.DistributedPortNumList) > 0 {
-			for _, client := range utils.GetAsicDPluginList() {
-				err := client.UpdateLag(a.HwAggId, asicDHashModeGet(hashmode), asicDPortBmpFormatGet(a.DistributedPortNumList))
-				if err != nil {
-					a.LacpAggLog(fmt.Sprintln("SetLaAggHashMode: Error updating LAG in HW", err))
-				}
+		return SetLaAggHashConfig(aggId, hashmode, a.LagHashSymmetric, a.LagHashFields)
+	}
+	fmt.Println("SetLaAggHashMode: Unable to find aggId", aggId)
+	return errors.New(fmt.Sprintf("ERROR Unable to find aggId %d", aggId))
+}
+
+// SetLaAggHashConfig will set the lag hash mode, symmetric hashing and the
+// hash field set.  The config is rejected, and the current hash is left
+// programmed, if a plugin is not able to program it
+func SetLaAggHashConfig(aggId int, hashmode uint32, symmetric bool, fields uint32) error {
+	var a *LaAggregator
+	if !LaFindAggById(aggId, &a) {
+		fmt.Println("SetLaAggHashConfig: Unable to find aggId", aggId)
+		return errors.New(fmt.Sprintf("ERROR Unable to find aggId %d", aggId))
+	}
+	laghash, err := asicDHashModeGet(hashmode, symmetric, fields)
+	if err != nil {
+		a.LacpAggLog(fmt.Sprintln("SetLaAggHashConfig:", err))
+		return err
+	}
+	a.LagHash = hashmode
+	a.LagHashSymmetric = symmetric
+	a.LagHashFields = fields
+	if ac, ok := ConfigAggMap[a.AggName]; ok {
+		ac.HashMode = hashmode
+		ac.HashSymmetric = symmetric
+		ac.HashFields = fields
+	}
+	if len(a.LacpAggHwPortListGet()) > 0 {
+		for _, client := range utils.GetAsicDPluginList() {
+			err = client.UpdateLag(a.HwAggId, laghash, asicDPortBmpFormatGet(a.LacpAggHwPortListGet()))
+			if err != nil {
+				a.LacpAggLog(fmt.Sprintln("SetLaAggHashConfig: Error updating LAG in HW", err))
 			}
-		} else {
-			a.LacpAggLog("SetLaAggHashMode: Agg not active in HW")
 		}
 	} else {
-		fmt.Println("SetLaAggHashMode: Unable to find aggId", aggId)
+		a.LacpAggLog("SetLaAggHashConfig: Agg not active in HW")
+	}
+	return err
+}
+
+// SetLaAggMinLinks will set the minimum number of ports which must be
+// distributing in order for the aggregator to be operationally up
+func SetLaAggMinLinks(aggId int, minLinks uint16) {
+	var a *LaAggregator
+	if LaFindAggById(aggId, &a) {
+		a.AggMinLinks = minLinks
+		a.LacpAggOperStateUpdate()
+	} else {
+		fmt.Println("SetLaAggMinLinks: Unable to find aggId", aggId)
+	}
+}
+
+// SetLaAggMaxLinks will set the maximum number of ports which can be
+// selected, the remaining ports will be put in standby
+func SetLaAggMaxLinks(aggId int, maxLinks uint16) {
+	var a *LaAggregator
+	if LaFindAggById(aggId, &a) {
+		a.AggMaxLinks = maxLinks
+		a.LacpAggSelectionUpdate(nil)
+	} else {
+		fmt.Println("SetLaAggMaxLinks: Unable to find aggId", aggId)
+	}
+}
+
+// laAggMuxControlChanged will restart the mux machine of the ports of the
+// aggregator whose mux control no longer matches the configured control
+func laAggMuxControlChanged(a *LaAggregator) {
+	for _, pId := range a.PortNumList {
+		var p *LaAggPort
+		if LaFindPortById(pId, &p) &&
+			p.MuxMachineFsm != nil &&
+			p.MuxMachineFsm.collDistCoupled != p.LacpMuxCouplingGet() {
+			p.MuxMachineFsm.MuxmEvents <- utils.MachineEvent{
+				E:   LacpMuxmEventBegin,
+				Src: PortConfigModuleStr}
+		}
+	}
+}
+
+// SetLaAggMuxControl will set whether the ports of the aggregator run the
+// independent or coupled control mux machine, or follow the system
+func SetLaAggMuxControl(aggId int, muxControl uint8) error {
+	var a *LaAggregator
+	if !LaFindAggById(aggId, &a) {
+		fmt.Println("SetLaAggMuxControl: Unable to find aggId", aggId)
+		return errors.New(fmt.Sprintf("ERROR Unable to find aggId %d", aggId))
+	}
+	if muxControl >= LaAggMuxControlMax {
+		return errors.New(fmt.Sprintf("ERROR Invalid Mux Control %d Configured Should be SYSTEM(0), INDEPENDENT(1) or COUPLED(2)", muxControl))
+	}
+	a.AggMuxControl = muxControl
+	if ac, ok := ConfigAggMap[a.AggName]; ok {
+		ac.MuxControl = muxControl
+	}
+	laAggMuxControlChanged(a)
+	return nil
+}
+
+// SetLacpSysMuxCoupling will set the mux control used by the aggregators of
+// the system which do not configure their own mux control
+func SetLacpSysMuxCoupling(sysId LacpSystem, coupling bool) {
+	sgi := LacpSysGlobalInfoByIdGet(sysId)
+	sgi.muxCoupling = coupling
+	for _, a := range sgi.LacpSysGlobalAggListGet() {
+		if a.AggMuxControl == LaAggMuxControlSystem {
+			laAggMuxControlChanged(a)
+		}
+	}
+}
+
+// lacpSysMuxCouplingDefaultGet returns the mux coupling of newly created
+// systems, the configured system mux control takes precedence over
+// LacpMuxCouplingDefault
+func lacpSysMuxCouplingDefaultGet() bool {
+	switch LacpSysMuxControl {
+	case LaAggMuxControlIndependent:
+		return false
+	case LaAggMuxControlCoupled:
+		return true
+	}
+	return LacpMuxCouplingDefault
+}
+
+// LacpSysConfig is the configuration shared by all systems
+type LacpSysConfig struct {
+	// mux machine control of the aggregators which use the system mux
+	// control, LaAggMuxControlSystem uses LacpMuxCouplingDefault
+	MuxControl uint8
+}
+
+// SetLacpSysMuxControl will set the mux control of every system,
+// LaAggMuxControlSystem restores LacpMuxCouplingDefault
+func SetLacpSysMuxControl(muxControl uint8) error {
+	if muxControl >= LaAggMuxControlMax {
+		return errors.New(fmt.Sprintf("ERROR Invalid Mux Control %d Configured Should be SYSTEM(0), INDEPENDENT(1) or COUPLED(2)", muxControl))
+	}
+	LacpSysMuxControl = muxControl
+	coupling := lacpSysMuxCouplingDefaultGet()
+	for _, sgi := range LacpSysGlobalInfoGet() {
+		if sgi.muxCoupling != coupling {
+			SetLacpSysMuxCoupling(sgi.SysKey, coupling)
+		}
+	}
+	return nil
+}
+
+// laAggV2ConfigChanged will re-evaluate the conversation assignment of the
+// aggregator and inform the partner of the new version 2 information
+func laAggV2ConfigChanged(a *LaAggregator) {
+	a.LacpAggConversationUpdate()
+	for _, pId := range a.PortNumList {
+		var p *LaAggPort
+		if LaFindPortById(pId, &p) &&
+			p.TxMachineFsm != nil {
+			p.TxMachineFsm.TxmEvents <- utils.MachineEvent{
+				E:   LacpTxmEventNtt,
+				Src: PortConfigModuleStr}
+		}
+	}
+}
+
+// SetLaAggLacpVersion will set the LACP version run by the ports of the
+// aggregator, version 2 is rejected when a plugin is unable to distribute
+// and collect by conversation
+func SetLaAggLacpVersion(aggId int, version uint8) error {
+	var a *LaAggregator
+	if !LaFindAggById(aggId, &a) {
+		fmt.Println("SetLaAggLacpVersion: Unable to find aggId", aggId)
+		return errors.New(fmt.Sprintf("ERROR Unable to find aggId %d", aggId))
+	}
+	if version > 2 {
+		return errors.New(fmt.Sprintf("ERROR Invalid LACP Version %d Configured Should be 1 or 2", version))
+	} else if version == 2 {
+		if err := utils.LagConversationSupported(); err != nil {
+			return errors.New(fmt.Sprintf("ERROR Invalid LACP Version 2 Configured %s", err))
+		}
+	}
+	a.AggLacpVersion = version
+	if ac, ok := ConfigAggMap[a.AggName]; ok {
+		ac.Version = version
+	}
+	laAggV2ConfigChanged(a)
+	return nil
+}
+
+// SetLaAggConversationAdminLink will set the Link Number IDs, in priority
+// order, which may carry each conversation.  Conversations without an entry
+// are spread across the distributing ports
+func SetLaAggConversationAdminLink(aggId int, adminLink map[uint16][]uint16) {
+	var a *LaAggregator
+	if LaFindAggById(aggId, &a) {
+		conversationMutex.Lock()
+		a.ConversationAdminLink = adminLink
+		conversationMutex.Unlock()
+		if ac, ok := ConfigAggMap[a.AggName]; ok {
+			ac.ConversationAdminLink = adminLink
+		}
+		laAggV2ConfigChanged(a)
+	} else {
+		fmt.Println("SetLaAggConversationAdminLink: Unable to find aggId", aggId)
+	}
+}
+
+// SetLaAggAdminServiceConversationMap will set the Service IDs which map
+// to each conversation
+func SetLaAggAdminServiceConversationMap(aggId int, serviceMap map[uint16][]uint16) {
+	var a *LaAggregator
+	if LaFindAggById(aggId, &a) {
+		conversationMutex.Lock()
+		a.AdminServiceConversationMap = serviceMap
+		conversationMutex.Unlock()
+		if ac, ok := ConfigAggMap[a.AggName]; ok {
+			ac.AdminServiceConversationMap = serviceMap
+		}
+		laAggV2ConfigChanged(a)
+	} else {
+		fmt.Println("SetLaAggAdminServiceConversationMap: Unable to find aggId", aggId)
+	}
+}
+
+// SetLaAggDiscardWrongConversation will set whether frames received on a
+// port other than the one carrying their conversation are discarded
+func SetLaAggDiscardWrongConversation(aggId int, discard bool) {
+	var a *LaAggregator
+	if LaFindAggById(aggId, &a) {
+		conversationMutex.Lock()
+		a.AdminDiscardWrongConversation = discard
+		// conversation assignment is not affected, program the plugins
+		a.lacpAggConversationHwUpdate()
+		conversationMutex.Unlock()
+		if ac, ok := ConfigAggMap[a.AggName]; ok {
+			ac.DiscardWrongConversation = discard
+		}
+		laAggV2ConfigChanged(a)
+	} else {
+		fmt.Println("SetLaAggDiscardWrongConversation: Unable to find aggId", aggId)
+	}
+}
+
+// SetLaAggPortLinkNumberId will set the Link Number ID of the port
+func SetLaAggPortLinkNumberId(pId uint16, linkNumberId uint16) {
+	var p *LaAggPort
+	if LaFindPortById(pId, &p) {
+		conversationMutex.Lock()
+		p.LinkNumberId = linkNumberId
+		conversationMutex.Unlock()
+		if p.AggAttached != nil {
+			laAggV2ConfigChanged(p.AggAttached)
+		}
+	} else {
+		fmt.Println("SetLaAggPortLinkNumberId: Unable to find port", pId)
 	}
 }
 
@@ -0,0 +1,370 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// conversation.go
+package lacp
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"l2/lacp/protocol/utils"
+	"sort"
+	"sync"
+)
+
+const ConversationModuleStr = "Conversation"
+
+// conversationMutex serializes the version 2 state of the ports and the
+// conversation assignment of the aggregators.  The assignment is re-evaluated
+// from the rx, mux and config contexts and updates the state of all the ports
+// of the aggregator, while the tx context reads it to build the LACPDU
+var conversationMutex sync.Mutex
+
+// LacpPortAlgorithmUnspecified 802.1ax-2014 Table 6-4, distribution
+// algorithm is unspecified
+var LacpPortAlgorithmUnspecified = [4]uint8{0x00, 0x80, 0xC2, 0x00}
+
+// LacpPortAlgorithmCVid 802.1ax-2014 Table 6-4, distribution based on C-VID
+var LacpPortAlgorithmCVid = [4]uint8{0x00, 0x80, 0xC2, 0x01}
+
+// LacpActorVersionGet returns the LACP version the port runs, which is
+// configured on the aggregator
+func (p *LaAggPort) LacpActorVersionGet() uint8 {
+	if p.AggAttached != nil &&
+		p.AggAttached.AggLacpVersion != 0 {
+		return p.AggAttached.AggLacpVersion
+	}
+	return uint8(LacpActorSystemLacpVersion)
+}
+
+// LacpPortAdminLinkNumberIdGet returns aAggPortLinkNumberID, when not
+// configured the port number is used
+func (p *LaAggPort) LacpPortAdminLinkNumberIdGet() uint16 {
+	if p.LinkNumberId != 0 {
+		return p.LinkNumberId
+	}
+	return p.PortNum
+}
+
+// LacpPortOperLinkNumberIdGet 802.1ax-2014 Section 6.6.2.2
+// Both ends of a link must use the same Link Number ID, the value
+// advertised by the System with the numerically lower System Identifier is
+// used
+func (p *LaAggPort) LacpPortOperLinkNumberIdGet() uint16 {
+	if p.partnerV2 != nil &&
+		p.partnerV2.LinkNumberId != 0 {
+		actorSysId := LacpSystemIdGet(p.ActorOper.System)
+		partnerSysId := LacpSystemIdGet(p.PartnerOper.System)
+		if bytes.Compare(partnerSysId[:], actorSysId[:]) < 0 {
+			return p.partnerV2.LinkNumberId
+		}
+	}
+	return p.LacpPortAdminLinkNumberIdGet()
+}
+
+// lacpConversationListDigest computes the MD5 digest over a list indexed by
+// Conversation ID.  Each entry is encoded as its 2 octet values followed by a
+// 2 octet zero separator, so that both systems get the same digest for the
+// same configuration
+func lacpConversationListDigest(list map[uint16][]uint16) [16]uint8 {
+	h := md5.New()
+	b := make([]byte, 0, 16)
+	for cid := 0; cid < LacpConversationIdMax; cid++ {
+		b = b[:0]
+		for _, v := range list[uint16(cid)] {
+			b = append(b, uint8(v>>8), uint8(v))
+		}
+		b = append(b, 0, 0)
+		h.Write(b)
+	}
+	var digest [16]uint8
+	copy(digest[:], h.Sum(nil))
+	return digest
+}
+
+// LacpAggConversationLinkListDigest returns Actor_Conversation_LinkList_Digest
+// which is the digest of aAggConversationAdminLink
+func (a *LaAggregator) LacpAggConversationLinkListDigest() [16]uint8 {
+	return lacpConversationListDigest(a.ConversationAdminLink)
+}
+
+// LacpAggConversationServiceMappingDigest returns
+// Actor_Conversation_Service_Mapping_Digest which is the digest of
+// aAggAdminServiceConversationMap
+func (a *LaAggregator) LacpAggConversationServiceMappingDigest() [16]uint8 {
+	return lacpConversationListDigest(a.AdminServiceConversationMap)
+}
+
+// LacpAggConversationIdGet maps a Service ID (VID) to a Port Conversation ID
+// using aAggAdminServiceConversationMap, Service IDs which are not mapped use
+// the Service ID as the Conversation ID
+func (a *LaAggregator) LacpAggConversationIdGet(serviceId uint16) uint16 {
+	for cid, services := range a.AdminServiceConversationMap {
+		for _, sid := range services {
+			if sid == serviceId {
+				return cid
+			}
+		}
+	}
+	return serviceId % LacpConversationIdMax
+}
+
+// LacpPduV2InfoGet returns the version 2 TLV information the port advertises
+func (p *LaAggPort) LacpPduV2InfoGet() *LacpPduV2Info {
+	conversationMutex.Lock()
+	defer conversationMutex.Unlock()
+
+	info := &LacpPduV2Info{
+		PortAlgorithm:    LacpPortAlgorithmUnspecified,
+		LinkNumberId:     p.LacpPortAdminLinkNumberIdGet(),
+		LongPdu:          p.enableLongPduXmit,
+		ConversationMask: p.operConversationMask,
+	}
+	if a := p.AggAttached; a != nil {
+		info.PortAlgorithm = a.PortAlgorithm
+		info.ConversationLinkListDigest = a.LacpAggConversationLinkListDigest()
+		info.ConversationServiceMappingDigest = a.LacpAggConversationServiceMappingDigest()
+		if a.AdminDiscardWrongConversation {
+			info.ConversationMaskState |= LacpConversationMaskStateDiscardWrongConversationBit
+		}
+	}
+	if p.actParSync {
+		info.ConversationMaskState |= LacpConversationMaskStateActParSyncBit
+	}
+	return info
+}
+
+// lacpPortV2DifferUpdate 802.1ax-2014 Section 6.6.2.2 compares the partner
+// version 2 information with the local configuration, returns TRUE when the
+// port can take part in conversation sensitive collection/distribution
+func (p *LaAggPort) lacpPortV2DifferUpdate(a *LaAggregator) bool {
+	if p.LacpActorVersionGet() < 0x2 ||
+		p.partnerV2 == nil {
+		p.differPortAlgorithms = false
+		p.differConversationDigests = false
+		p.differServiceDigests = false
+		return false
+	}
+	p.differPortAlgorithms = p.partnerV2.PortAlgorithm != a.PortAlgorithm ||
+		a.PortAlgorithm == LacpPortAlgorithmUnspecified
+	p.differConversationDigests = p.partnerV2.ConversationLinkListDigest != a.LacpAggConversationLinkListDigest()
+	p.differServiceDigests = p.partnerV2.ConversationServiceMappingDigest != a.LacpAggConversationServiceMappingDigest()
+	return !p.differPortAlgorithms &&
+		!p.differConversationDigests &&
+		!p.differServiceDigests
+}
+
+type sortPortLinkNumberId []*LaAggPort
+
+func (s sortPortLinkNumberId) Len() int      { return len(s) }
+func (s sortPortLinkNumberId) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s sortPortLinkNumberId) Less(i, j int) bool {
+	return s[i].LacpPortOperLinkNumberIdGet() < s[j].LacpPortOperLinkNumberIdGet()
+}
+
+// LacpAggConversationUpdate 802.1ax-2014 Section 6.6.2.4 updateConversationPortList
+// and updateConversationMask.  Each conversation is assigned to the first
+// distributing port found in the aAggConversationAdminLink entry for the
+// conversation, conversations without an entry are spread across the
+// distributing ports in Link Number ID order.  Since both systems agree on
+// the Link Number IDs and on the digests of the admin tables, both ends pick
+// the same link for each conversation
+func (a *LaAggregator) LacpAggConversationUpdate() {
+	conversationMutex.Lock()
+	defer conversationMutex.Unlock()
+	a.lacpAggConversationUpdate()
+}
+
+// lacpAggConversationUpdate is LacpAggConversationUpdate, the caller must
+// hold conversationMutex
+func (a *LaAggregator) lacpAggConversationUpdate() {
+
+	distributing := make([]*LaAggPort, 0)
+	held := make([]*LacpConversationMask, 0)
+	sensitive := true
+	for _, pId := range a.PortNumList {
+		var p *LaAggPort
+		if LaFindPortById(pId, &p) {
+			if p.conversationHeld {
+				held = append(held, &p.heldConversationMask)
+			}
+			agree := p.lacpPortV2DifferUpdate(a)
+			for _, intf := range a.DistributedPortNumList {
+				if intf == p.IntfNum {
+					distributing = append(distributing, p)
+					sensitive = sensitive && agree
+				}
+			}
+		}
+	}
+	sensitive = sensitive && len(distributing) > 0
+	sort.Stable(sortPortLinkNumberId(distributing))
+
+	byLinkNumberId := make(map[uint16]*LaAggPort)
+	for _, p := range distributing {
+		byLinkNumberId[p.LacpPortOperLinkNumberIdGet()] = p
+	}
+
+	var portList [LacpConversationIdMax]uint16
+	masks := make(map[uint16]*LacpConversationMask)
+	for cid := 0; cid < LacpConversationIdMax && len(distributing) > 0; cid++ {
+		if lacpConversationHeld(held, uint16(cid)) {
+			// not distributed until the marker transaction completes
+			continue
+		}
+		var owner *LaAggPort
+		if links, ok := a.ConversationAdminLink[uint16(cid)]; ok && len(links) > 0 {
+			for _, l := range links {
+				if p, ok := byLinkNumberId[l]; ok {
+					owner = p
+					break
+				}
+			}
+		} else {
+			owner = distributing[cid%len(distributing)]
+		}
+		if owner != nil {
+			portList[cid] = owner.PortNum
+			if _, ok := masks[owner.PortNum]; !ok {
+				masks[owner.PortNum] = &LacpConversationMask{}
+			}
+			masks[owner.PortNum].Set(uint16(cid))
+		}
+	}
+
+	changed := sensitive != a.ConversationSensitive ||
+		portList != a.ConversationPortList
+	a.ConversationPortList = portList
+	a.ConversationSensitive = sensitive
+
+	for _, pId := range a.PortNumList {
+		var p *LaAggPort
+		if LaFindPortById(pId, &p) {
+			var mask LacpConversationMask
+			if m, ok := masks[p.PortNum]; ok {
+				mask = *m
+			}
+			actParSync := p.partnerV2 != nil &&
+				p.partnerV2.LongPdu &&
+				p.partnerV2.ConversationMask == mask
+			if mask != p.operConversationMask ||
+				actParSync != p.actParSync {
+				p.operConversationMask = mask
+				p.actParSync = actParSync
+				// partner needs to be informed of the new mask, the update
+				// may run in the context of another port so don't block on
+				// a busy tx machine, NTT is left pending for the next tx
+				if p.enableLongPduXmit &&
+					p.TxMachineFsm != nil {
+					if !p.TxMachineFsm.LacpTxmNttPendingSet(ConversationModuleStr) {
+						a.LacpAggLog(fmt.Sprintf("Port %s tx machine busy, NTT pending for conversation mask update", p.IntfNum))
+					}
+				}
+			}
+		}
+	}
+
+	if changed {
+		a.LacpAggLog(fmt.Sprintf("Conversation sensitive %t distributing ports %d", sensitive, len(distributing)))
+		a.lacpAggConversationHwUpdate()
+	}
+}
+
+// lacpAggConversationHwUpdate programs the conversation to port assignment
+// and Discard Wrong Conversation into the plugins able to distribute and
+// collect by Port Conversation ID, the caller must hold conversationMutex
+func (a *LaAggregator) lacpAggConversationHwUpdate() {
+	var ports []int32
+	if a.ConversationSensitive {
+		ports = make([]int32, LacpConversationIdMax)
+		for cid := range ports {
+			var p *LaAggPort
+			if pId, ok := a.LacpAggConversationPortGet(uint16(cid)); ok &&
+				LaFindPortById(pId, &p) {
+				ports[cid] = utils.GetIfIndexFromName(p.IntfNum)
+			}
+		}
+	}
+	discardWrongConversation := a.ConversationSensitive && a.AdminDiscardWrongConversation
+	for _, client := range utils.GetAsicDPluginList() {
+		if conversationPlugin, ok := client.(utils.LagConversationPlugin); ok {
+			err := conversationPlugin.UpdateLagConversation(a.HwAggId, ports, discardWrongConversation)
+			if err != nil {
+				a.LacpAggLog(fmt.Sprintln("ERROR Updating Lag conversations in HW", err))
+			}
+		}
+	}
+}
+
+// lacpConversationHeld returns TRUE when the conversation was carried by a
+// port waiting on a marker transaction
+func lacpConversationHeld(held []*LacpConversationMask, cid uint16) bool {
+	for _, mask := range held {
+		if mask.IsSet(cid) {
+			return true
+		}
+	}
+	return false
+}
+
+// LacpPortConversationHold 802.1ax-2014 Section 6.5.1 the port is about to
+// stop distributing, the conversations it carries are not moved to another
+// port until LacpPortConversationRelease so that the partner collects all
+// frames already sent on this port before any frame sent on the new port
+func (p *LaAggPort) LacpPortConversationHold() {
+	conversationMutex.Lock()
+	defer conversationMutex.Unlock()
+	p.conversationHeld = true
+	p.heldConversationMask = p.operConversationMask
+}
+
+// LacpPortConversationRelease the held conversations may be moved again,
+// the caller re-evaluates the conversation assignment of the aggregator
+func (p *LaAggPort) LacpPortConversationRelease() {
+	conversationMutex.Lock()
+	defer conversationMutex.Unlock()
+	p.conversationHeld = false
+	p.heldConversationMask = LacpConversationMask{}
+}
+
+// LacpAggConversationSensitive returns TRUE when conversation sensitive
+// collection and distribution is in use on the aggregator
+func (a *LaAggregator) LacpAggConversationSensitive() bool {
+	conversationMutex.Lock()
+	defer conversationMutex.Unlock()
+	return a.ConversationSensitive
+}
+
+// LacpAggConversationPortGet 802.1ax-2014 Section 6.6.1.1 returns the port
+// which distributes the conversation.  FALSE is returned when conversation
+// sensitive distribution is not in use, frames are then distributed by the
+// hash
+func (a *LaAggregator) LacpAggConversationPortGet(cid uint16) (uint16, bool) {
+	if !a.ConversationSensitive ||
+		cid >= LacpConversationIdMax ||
+		a.ConversationPortList[cid] == 0 {
+		return 0, false
+	}
+	return a.ConversationPortList[cid], true
+}
@@ -0,0 +1,384 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package lacp
+
+import (
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"l2/lacp/protocol/utils"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+	asicdmock "utils/asicdClient/mock"
+)
+
+func TestLacpPduV2InfoEncodeDecode(t *testing.T) {
+
+	info := &LacpPduV2Info{
+		PortAlgorithm:                    LacpPortAlgorithmCVid,
+		LinkNumberId:                     5,
+		ConversationLinkListDigest:       [16]uint8{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		ConversationServiceMappingDigest: [16]uint8{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1},
+	}
+
+	decoded := LacpPduV2InfoDecode(LacpPduV2InfoEncode(info))
+	if decoded == nil || *decoded != *info {
+		t.Error("Short LACPDU v2 TLVs did not decode to what was encoded", decoded)
+	}
+
+	info.LongPdu = true
+	info.ConversationMaskState = LacpConversationMaskStateActParSyncBit
+	info.ConversationMask.Set(0)
+	info.ConversationMask.Set(1500)
+	info.ConversationMask.Set(LacpConversationIdMax - 1)
+	decoded = LacpPduV2InfoDecode(LacpPduV2InfoEncode(info))
+	if decoded == nil || *decoded != *info {
+		t.Error("Long LACPDU v2 TLVs did not decode to what was encoded", decoded)
+	}
+	if !decoded.ConversationMask.IsSet(1500) ||
+		decoded.ConversationMask.IsSet(1501) {
+		t.Error("Conversation mask bits not decoded correctly")
+	}
+
+	// malformed tlv length
+	b := LacpPduV2InfoEncode(info)
+	b[1] = 5
+	if LacpPduV2InfoDecode(b) != nil {
+		t.Error("Expected malformed TLV to be rejected")
+	}
+}
+
+func TestLacpV2PduSerialize(t *testing.T) {
+
+	info := &LacpPduV2Info{
+		PortAlgorithm: LacpPortAlgorithmCVid,
+		LinkNumberId:  7,
+		LongPdu:       true,
+	}
+	info.ConversationMask.Set(100)
+
+	lacp := &layers.LACP{
+		Version: layers.LACPVersion2,
+		Actor: layers.LACPInfoTlv{TlvType: layers.LACPTLVActorInfo,
+			Length: layers.LACPActorTlvLength,
+			Info: layers.LACPPortInfo{
+				System: layers.LACPSystem{SystemId: [6]uint8{0x00, 0x00, 0x00, 0x00, 0x00, 0x64},
+					SystemPriority: 128,
+				},
+				Key:     100,
+				PortPri: 0x80,
+				Port:    1,
+				State:   LacpStateAggregatibleUp,
+			},
+		},
+		Partner: layers.LACPInfoTlv{TlvType: layers.LACPTLVPartnerInfo,
+			Length: layers.LACPActorTlvLength,
+		},
+		Collector: layers.LACPCollectorInfoTlv{
+			TlvType: layers.LACPTLVCollectorInfo,
+			Length:  layers.LACPCollectorTlvLength,
+		},
+	}
+
+	eth := layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x01, 0x00, 0x01, 0x01, 0x01},
+		DstMAC:       layers.SlowProtocolDMAC,
+		EthernetType: layers.EthernetTypeSlowProtocol,
+	}
+	buf := lacpV2PduSerialize(&eth, &LacpV2Pdu{Lacp: lacp, V2: info})
+
+	pkt := gopacket.NewPacket(buf.Bytes(), layers.LinkTypeEthernet, gopacket.Default)
+	lacpLayer := pkt.Layer(layers.LayerTypeLACP)
+	if lacpLayer == nil {
+		t.Fatal("Unable to decode LACP layer from v2 LACPDU")
+	}
+	rxLacp := lacpLayer.(*layers.LACP)
+	if rxLacp.Version != layers.LACPVersion2 ||
+		rxLacp.Actor.Info.Port != 1 ||
+		rxLacp.Actor.Info.Key != 100 {
+		t.Error("Version 1 fields not decoded correctly from v2 LACPDU", rxLacp)
+	}
+
+	rxInfo := LacpPduV2FrameParse(pkt.Layer(layers.LayerTypeEthernet).LayerPayload())
+	if rxInfo == nil || *rxInfo != *info {
+		t.Error("Version 2 TLVs not decoded correctly from v2 LACPDU", rxInfo)
+	}
+}
+
+func ConversationTestSetup() (*LaAggregator, []*LaAggPort) {
+	a, ports := SelectionTestSetup(0)
+	a.AggLacpVersion = 2
+	for _, p := range ports {
+		p.AggAttached = a
+		p.ActorOper.System.Actor_System_priority = 128
+		p.PartnerOper.System.Actor_System_priority = 128
+		p.PartnerOper.System.Actor_System = [6]uint8{0x00, 0x00, 0x00, 0x00, 0x00, 0xc8}
+		p.partnerV2 = &LacpPduV2Info{
+			PortAlgorithm:                    a.PortAlgorithm,
+			LinkNumberId:                     p.PortNum + 10,
+			ConversationLinkListDigest:       a.LacpAggConversationLinkListDigest(),
+			ConversationServiceMappingDigest: a.LacpAggConversationServiceMappingDigest(),
+		}
+		a.DistributedPortNumList = append(a.DistributedPortNumList, p.IntfNum)
+	}
+	return a, ports
+}
+
+func ConversationTestTeardown(a *LaAggregator, ports []*LaAggPort) {
+	for _, p := range ports {
+		p.AggAttached = nil
+	}
+	SelectionTestTeardown(a, ports)
+}
+
+func TestLaAggConversationDefaultSpread(t *testing.T) {
+	a, ports := ConversationTestSetup()
+	defer ConversationTestTeardown(a, ports)
+
+	a.LacpAggConversationUpdate()
+	if !a.ConversationSensitive {
+		t.Fatal("Expected conversation sensitive distribution when both ends agree")
+	}
+
+	// actor has the lower system id so its link numbers are used
+	for cid := uint16(0); cid < 6; cid++ {
+		port, ok := a.LacpAggConversationPortGet(cid)
+		expected := ports[cid%3].PortNum
+		if !ok || port != expected {
+			t.Error("Conversation", cid, "expected on port", expected, "actual", port, ok)
+		}
+		if !ports[cid%3].operConversationMask.IsSet(cid) ||
+			ports[(cid+1)%3].operConversationMask.IsSet(cid) {
+			t.Error("Conversation", cid, "mask not set on the correct port")
+		}
+	}
+
+	// port leaves distribution, conversations move to the remaining ports
+	a.DistributedPortNumList = a.DistributedPortNumList[1:]
+	a.LacpAggConversationUpdate()
+	if port, _ := a.LacpAggConversationPortGet(0); port != ports[1].PortNum {
+		t.Error("Conversation 0 expected to move to port", ports[1].PortNum, "actual", port)
+	}
+	if ports[0].operConversationMask != (LacpConversationMask{}) {
+		t.Error("Port not distributing should not carry any conversation")
+	}
+}
+
+func TestLaAggConversationAdminLinkPartnerControlling(t *testing.T) {
+	a, ports := ConversationTestSetup()
+	defer ConversationTestTeardown(a, ports)
+
+	// partner has the lower system id, its link numbers are used
+	for _, p := range ports {
+		p.PartnerOper.System.Actor_System = [6]uint8{0x00, 0x00, 0x00, 0x00, 0x00, 0x01}
+	}
+	a.ConversationAdminLink = map[uint16][]uint16{10: {13, 11}}
+	for _, p := range ports {
+		p.partnerV2.ConversationLinkListDigest = a.LacpAggConversationLinkListDigest()
+	}
+	a.LacpAggConversationUpdate()
+
+	if port, ok := a.LacpAggConversationPortGet(10); !ok || port != ports[2].PortNum {
+		t.Error("Conversation 10 expected on link 13 port", ports[2].PortNum, "actual", port, ok)
+	}
+
+	// preferred link goes away, next link in the admin list is used
+	a.DistributedPortNumList = a.DistributedPortNumList[:2]
+	a.LacpAggConversationUpdate()
+	if port, ok := a.LacpAggConversationPortGet(10); !ok || port != ports[0].PortNum {
+		t.Error("Conversation 10 expected on link 11 port", ports[0].PortNum, "actual", port, ok)
+	}
+}
+
+func TestLaAggConversationDigestMismatch(t *testing.T) {
+	a, ports := ConversationTestSetup()
+	defer ConversationTestTeardown(a, ports)
+
+	ports[1].partnerV2.ConversationLinkListDigest[0] ^= 0xff
+	a.LacpAggConversationUpdate()
+	if a.ConversationSensitive {
+		t.Error("Expected conversation sensitive distribution to be disabled on digest mismatch")
+	}
+	if !ports[1].differConversationDigests {
+		t.Error("Expected Differ_Port_Conversation_Digests to be set")
+	}
+	if _, ok := a.LacpAggConversationPortGet(0); ok {
+		t.Error("Expected hash distribution when not conversation sensitive")
+	}
+
+	// partner running version 1
+	ports[1].partnerV2 = nil
+	a.LacpAggConversationUpdate()
+	if a.ConversationSensitive {
+		t.Error("Expected conversation sensitive distribution to be disabled with version 1 partner")
+	}
+}
+
+func TestLaAggConversationUpdateConcurrentRx(t *testing.T) {
+	a, ports := ConversationTestSetup()
+	defer ConversationTestTeardown(a, ports)
+
+	// nobody is servicing the tx machines, mask updates must not block
+	for _, p := range ports {
+		p.enableLongPduXmit = true
+		p.TxMachineFsm = &LacpTxMachine{p: p,
+			TxmEvents: make(chan utils.MachineEvent)}
+	}
+	defer func() {
+		for _, p := range ports {
+			p.TxMachineFsm = nil
+		}
+	}()
+
+	done := make(chan bool)
+	for _, p := range ports {
+		go func(p *LaAggPort) {
+			rxm := &LacpRxMachine{p: p}
+			for i := 0; i < 100; i++ {
+				info := *p.LacpPduV2InfoGet()
+				info.LinkNumberId = p.PortNum + 10
+				info.LongPdu = i%2 == 0
+				rxm.recordV2PDU(&info)
+			}
+			done <- true
+		}(p)
+	}
+	for range ports {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Conversation update blocked")
+		}
+	}
+	if !a.ConversationSensitive {
+		t.Error("Expected conversation sensitive distribution when both ends agree")
+	}
+	// busy tx machines pick up the mask update with their next transmit
+	for _, p := range ports {
+		if atomic.LoadInt32(&p.TxMachineFsm.nttPending) != 1 {
+			t.Error("Port", p.IntfNum, "expected NTT to be pending for conversation mask update")
+		}
+	}
+}
+
+type ConversationMockAsicdClientMgr struct {
+	asicdmock.MockAsicdClientMgr
+	ports                    []int32
+	discardWrongConversation bool
+}
+
+func (m *ConversationMockAsicdClientMgr) UpdateLagConversation(ifIndex int32, ports []int32, discardWrongConversation bool) error {
+	m.ports = ports
+	m.discardWrongConversation = discardWrongConversation
+	return nil
+}
+
+func TestLaAggConversationHwUpdate(t *testing.T) {
+	a, ports := ConversationTestSetup()
+	defer ConversationTestTeardown(a, ports)
+	mock := &ConversationMockAsicdClientMgr{}
+	utils.DeleteAllAsicDPlugins()
+	utils.SetAsicDPlugin(mock)
+	// other tests leave ports with the same names behind
+	portConfigMap := utils.PortConfigMap
+	defer func() { utils.PortConfigMap = portConfigMap }()
+	utils.PortConfigMap = make(map[int32]utils.PortConfig)
+	for _, p := range ports {
+		pcfg := portConfigMap[int32(p.PortNum)]
+		pcfg.IfIndex = int32(p.PortNum)
+		utils.PortConfigMap[int32(p.PortNum)] = pcfg
+	}
+
+	a.AdminDiscardWrongConversation = true
+	a.LacpAggConversationUpdate()
+	if len(mock.ports) != LacpConversationIdMax {
+		t.Fatal("Expected conversation port list to be programmed", len(mock.ports))
+	}
+	for cid := 0; cid < 6; cid++ {
+		if mock.ports[cid] != int32(ports[cid%3].PortNum) {
+			t.Error("Conversation", cid, "programmed on port", mock.ports[cid], "expected", ports[cid%3].PortNum)
+		}
+	}
+	if !mock.discardWrongConversation {
+		t.Error("Expected Discard Wrong Conversation to be programmed")
+	}
+
+	// partner no longer agrees, frames are distributed by the lag hash
+	ports[1].partnerV2.ConversationLinkListDigest[0] ^= 0xff
+	a.LacpAggConversationUpdate()
+	if mock.ports != nil || mock.discardWrongConversation {
+		t.Error("Expected lag hash distribution to be programmed on digest mismatch", len(mock.ports), mock.discardWrongConversation)
+	}
+}
+
+func TestLaAggLacpVersion2NotSupported(t *testing.T) {
+	a, ports := ConversationTestSetup()
+	defer ConversationTestTeardown(a, ports)
+
+	// default test plugin can not distribute by conversation
+	if err := SetLaAggLacpVersion(a.AggId, 2); err == nil {
+		t.Error("Expected version 2 to be rejected by plugin without conversation support")
+	}
+	if err := LaAggConfigParamCheck(&LaAggConfig{Version: 2, Lacp: a.Config}); err == nil {
+		t.Error("Expected version 2 config to be rejected by plugin without conversation support")
+	}
+
+	utils.DeleteAllAsicDPlugins()
+	utils.SetAsicDPlugin(&ConversationMockAsicdClientMgr{})
+	if err := SetLaAggLacpVersion(a.AggId, 2); err != nil {
+		t.Error("Expected version 2 to be accepted by plugin with conversation support", err)
+	}
+}
+
+func TestLaAggConversationConfig(t *testing.T) {
+	a, ports := ConversationTestSetup()
+	defer ConversationTestTeardown(a, ports)
+	mock := &ConversationMockAsicdClientMgr{}
+	utils.DeleteAllAsicDPlugins()
+	utils.SetAsicDPlugin(mock)
+
+	// partner computes its digest from the same admin table
+	adminLink := map[uint16][]uint16{7: {2}}
+	for _, p := range ports {
+		p.partnerV2.ConversationLinkListDigest = lacpConversationListDigest(adminLink)
+	}
+	SetLaAggConversationAdminLink(a.AggId, adminLink)
+	if port, ok := a.LacpAggConversationPortGet(7); !ok || port != ports[1].PortNum {
+		t.Error("Conversation 7 expected on link 2 port", ports[1].PortNum, "actual", port, ok)
+	}
+
+	// assignment does not change, the plugins must still be programmed
+	SetLaAggDiscardWrongConversation(a.AggId, true)
+	if !mock.discardWrongConversation {
+		t.Error("Expected Discard Wrong Conversation to be programmed")
+	}
+
+	// actor has the lower system id, conversation follows its link number
+	SetLaAggPortLinkNumberId(ports[1].PortNum, 20)
+	SetLaAggPortLinkNumberId(ports[2].PortNum, 2)
+	if port, ok := a.LacpAggConversationPortGet(7); !ok || port != ports[2].PortNum {
+		t.Error("Conversation 7 expected on link 2 port", ports[2].PortNum, "actual", port, ok)
+	}
+}
@@ -90,3 +90,10 @@ func (mr *LampMarkerResponderMachine) LampMarkerResponderLog(msg string) {
 		utils.GlobalLogger.Info(strings.Join([]string{p.IntfNum, "MARKER RESPONDER", msg}, ":"))
 	}
 }
+
+func (mg *LampMarkerGenerator) LampMarkerGeneratorLog(msg string) {
+	if mg.p.logEna {
+		p := mg.p
+		utils.GlobalLogger.Info(strings.Join([]string{p.IntfNum, "MARKER GENERATOR", msg}, ":"))
+	}
+}
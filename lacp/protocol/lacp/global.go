@@ -34,6 +34,14 @@ import (
 var LacpStartTime time.Time
 var MacCaptureSet bool = false
 
+// mux machine coupling used by newly created systems
+// false == INDEPENDENT CONTROL, true == COUPLED CONTROL
+var LacpMuxCouplingDefault bool = false
+
+// mux control of all systems set by configuration, LaAggMuxControlSystem
+// uses LacpMuxCouplingDefault
+var LacpSysMuxControl uint8 = LaAggMuxControlSystem
+
 type TxCallback func(port uint16, data interface{})
 
 type PortIdKey struct {
@@ -79,6 +87,7 @@ func (g *LacpSysGlobalInfo) String() (s string) {
 	s += fmt.Sprintln("\nActorStateDefaultParams:", g.ActorStateDefaultParams)
 	s += fmt.Sprintln("\nPartnerStateDefaultParams:", g.PartnerStateDefaultParams)
 	s += fmt.Sprintln("\nLacpEnabled:", g.LacpEnabled)
+	s += fmt.Sprintln("\nMuxCoupling:", g.muxCoupling)
 	s += fmt.Sprintln("\nPortMap:", g.PortMap)
 	s += fmt.Sprintln("\nPortList:", g.PortList)
 	s += fmt.Sprintln("\nAggMap:", g.AggMap)
@@ -144,6 +153,7 @@ func LacpSysGlobalInfoInit(sysId LacpSystem) *LacpSysGlobalInfo {
 			PartnerSystemDefaultParams: LacpSystem{Actor_System_priority: 0x0},
 			TxCallbacks:                make(map[string][]TxCallback),
 			SysKey:                     sysKey,
+			muxCoupling:                lacpSysMuxCouplingDefaultGet(),
 		}
 
 		gLacpSysGlobalInfoList = append(gLacpSysGlobalInfoList, gLacpSysGlobalInfo[sysKey])
@@ -25,7 +25,7 @@
 package lacp
 
 import (
-	hwconst "asicd/asicdCommonDefs"
+	"errors"
 	"fmt"
 	"l2/lacp/protocol/utils"
 )
@@ -50,19 +50,37 @@ func asicDPortBmpFormatGet(distPortList []string) string {
 
 }
 
-// convert the model value to asic value
-func asicDHashModeGet(hashmode uint32) (laghash int32) {
+// convert the model value to asic value, an error is returned if the
+// hash config is invalid or any of the plugins is unable to program it
+func asicDHashModeGet(hashmode uint32, symmetric bool, fields uint32) (laghash int32, err error) {
 	switch hashmode {
-	case 0: //L2
-		laghash = hwconst.HASH_SEL_SRCDSTMAC
-		break
-	case 1: //L2 + L3
-		laghash = hwconst.HASH_SEL_SRCDSTIP
-		break
-	//case 2: //L3 + L4
-	//break
+	case LaAggHashModeL2:
+		laghash = utils.LAG_HASH_SEL_SRCDSTMAC
+	case LaAggHashModeL2L3:
+		laghash = utils.LAG_HASH_SEL_SRCDSTIP
+	case LaAggHashModeL3L4:
+		laghash = utils.LAG_HASH_SEL_SRCDSTIP_L4PORT
+	case LaAggHashModeEncap:
+		laghash = utils.LAG_HASH_SEL_ENCAP_SRCDSTIP
+	case LaAggHashModeEncap2:
+		laghash = utils.LAG_HASH_SEL_ENCAP_SRCDSTIP_L4PORT
+	case LaAggHashModeFields:
+		if fields == 0 ||
+			fields&^utils.LAG_HASH_FIELD_ALL != 0 {
+			return 0, errors.New(fmt.Sprintf("ERROR Invalid Lag Hash Fields 0x%x Configured", fields))
+		}
+		laghash = utils.LAG_HASH_SEL_FIELDS | int32(fields)<<utils.LAG_HASH_FIELDS_SHIFT
 	default:
-		laghash = hwconst.HASH_SEL_SRCDSTMAC
+		return 0, errors.New(fmt.Sprintf("ERROR Invalid Lag Hash %d Configured Should be LAYER2(0) or LAYER2_3(1) or LAYER3_4(2) or ENCAP(3) or ENCAP2(4) or FIELDS(5)", hashmode))
+	}
+	if symmetric {
+		laghash |= utils.LAG_HASH_SYMMETRIC
+	}
+
+	for _, client := range utils.GetAsicDPluginList() {
+		if err = utils.LagHashSupported(client, laghash); err != nil {
+			return 0, err
+		}
 	}
-	return laghash
+	return laghash, nil
 }
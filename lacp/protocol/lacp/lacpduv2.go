@@ -0,0 +1,229 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// lacpduv2.go
+package lacp
+
+import (
+	"encoding/binary"
+	"github.com/google/gopacket/layers"
+)
+
+// 802.1ax-2014 Section 6.4.2.4 Version 2 TLVs
+const (
+	LacpTlvTypeTerminator                 uint8 = 0x00
+	LacpTlvTypePortAlgorithm              uint8 = 0x04
+	LacpTlvTypePortConversationIdDigest   uint8 = 0x05
+	LacpTlvTypePortConversationMask1      uint8 = 0x06
+	LacpTlvTypePortConversationMask2      uint8 = 0x07
+	LacpTlvTypePortConversationMask3      uint8 = 0x08
+	LacpTlvTypePortConversationMask4      uint8 = 0x09
+	LacpTlvTypePortConversationServiceMap uint8 = 0x0A
+)
+
+const (
+	LacpTlvPortAlgorithmLength              uint8 = 6
+	LacpTlvPortConversationIdDigestLength   uint8 = 20
+	LacpTlvPortConversationMask1Length      uint8 = 131
+	LacpTlvPortConversationMaskLength       uint8 = 130
+	LacpTlvPortConversationServiceMapLength uint8 = 18
+)
+
+// Port_Conversation_Mask_State bits 802.1ax-2014 Section 6.4.2.4.3
+const (
+	LacpConversationMaskStateActParSyncBit = 1 << iota
+	LacpConversationMaskStatePortalSystemIsolatedBit
+	LacpConversationMaskStateDiscardWrongConversationBit
+)
+
+// number of conversation ids carried by each Port Conversation Mask TLV
+const LacpConversationMaskTlvIds = 1024
+
+// LacpConversationIdMax number of Port Conversation IDs
+const LacpConversationIdMax = 4096
+
+// octet offset, within the slow protocol payload (subtype included), of the
+// end of the Collector Information TLV.  Version 2 TLVs are placed here,
+// ahead of the Terminator TLV
+const LacpPduV1TlvEndOffset = 58
+
+// length of the reserved field following the Terminator TLV
+const LacpPduPadLength = 50
+
+// LacpConversationMask is a bit per Port Conversation ID, conversation 0 is
+// the most significant bit of the first octet
+type LacpConversationMask [LacpConversationIdMax / 8]uint8
+
+func (m *LacpConversationMask) Set(cid uint16) {
+	m[cid>>3] |= 0x80 >> (cid & 0x7)
+}
+
+func (m *LacpConversationMask) Clear(cid uint16) {
+	m[cid>>3] &= ^uint8(0x80 >> (cid & 0x7))
+}
+
+func (m *LacpConversationMask) IsSet(cid uint16) bool {
+	return m[cid>>3]&(0x80>>(cid&0x7)) != 0
+}
+
+// LacpPduV2Info holds the contents of the version 2 TLVs sent or received
+// in an LACPDU
+type LacpPduV2Info struct {
+	// Port Algorithm TLV
+	PortAlgorithm [4]uint8
+	// Port Conversation ID Digest TLV
+	LinkNumberId               uint16
+	ConversationLinkListDigest [16]uint8
+	// Port Conversation Service Mapping TLV
+	ConversationServiceMappingDigest [16]uint8
+	// Port Conversation Mask TLVs, only present in a Long LACPDU
+	LongPdu               bool
+	ConversationMaskState uint8
+	ConversationMask      LacpConversationMask
+}
+
+// LacpV2Pdu is handed to the tx callbacks when a version 2 LACPDU is to be
+// transmitted, the version 1 fields are carried in Lacp
+type LacpV2Pdu struct {
+	Lacp *layers.LACP
+	V2   *LacpPduV2Info
+}
+
+// LacpPduV2InfoEncode will encode the version 2 TLVs
+func LacpPduV2InfoEncode(info *LacpPduV2Info) []byte {
+	b := make([]byte, 0, 2*LacpConversationIdMax/8)
+
+	b = append(b, LacpTlvTypePortAlgorithm, LacpTlvPortAlgorithmLength)
+	b = append(b, info.PortAlgorithm[:]...)
+
+	b = append(b, LacpTlvTypePortConversationIdDigest, LacpTlvPortConversationIdDigestLength)
+	b = append(b, uint8(info.LinkNumberId>>8), uint8(info.LinkNumberId))
+	b = append(b, info.ConversationLinkListDigest[:]...)
+
+	if info.LongPdu {
+		for i := 0; i < LacpConversationIdMax/LacpConversationMaskTlvIds; i++ {
+			mask := info.ConversationMask[i*LacpConversationMaskTlvIds/8 : (i+1)*LacpConversationMaskTlvIds/8]
+			if i == 0 {
+				b = append(b, LacpTlvTypePortConversationMask1, LacpTlvPortConversationMask1Length)
+				b = append(b, info.ConversationMaskState)
+			} else {
+				b = append(b, LacpTlvTypePortConversationMask1+uint8(i), LacpTlvPortConversationMaskLength)
+			}
+			b = append(b, mask...)
+		}
+	}
+
+	b = append(b, LacpTlvTypePortConversationServiceMap, LacpTlvPortConversationServiceMapLength)
+	b = append(b, info.ConversationServiceMappingDigest[:]...)
+	return b
+}
+
+// LacpPduV2InfoDecode will decode the version 2 TLVs which follow the
+// Collector Information TLV.  Unknown TLVs are skipped, nil is returned if
+// no version 2 TLV was found or the TLVs are malformed
+func LacpPduV2InfoDecode(data []byte) *LacpPduV2Info {
+	info := &LacpPduV2Info{}
+	found := false
+	masks := 0
+	for len(data) >= 2 {
+		tlvType := data[0]
+		length := int(data[1])
+		if tlvType == LacpTlvTypeTerminator {
+			break
+		}
+		if length < 2 || length > len(data) {
+			return nil
+		}
+		v := data[2:length]
+		switch tlvType {
+		case LacpTlvTypePortAlgorithm:
+			if length != int(LacpTlvPortAlgorithmLength) {
+				return nil
+			}
+			copy(info.PortAlgorithm[:], v)
+			found = true
+		case LacpTlvTypePortConversationIdDigest:
+			if length != int(LacpTlvPortConversationIdDigestLength) {
+				return nil
+			}
+			info.LinkNumberId = binary.BigEndian.Uint16(v[0:2])
+			copy(info.ConversationLinkListDigest[:], v[2:])
+			found = true
+		case LacpTlvTypePortConversationMask1:
+			if length != int(LacpTlvPortConversationMask1Length) {
+				return nil
+			}
+			info.ConversationMaskState = v[0]
+			copy(info.ConversationMask[0:LacpConversationMaskTlvIds/8], v[1:])
+			masks++
+		case LacpTlvTypePortConversationMask2,
+			LacpTlvTypePortConversationMask3,
+			LacpTlvTypePortConversationMask4:
+			if length != int(LacpTlvPortConversationMaskLength) {
+				return nil
+			}
+			i := int(tlvType - LacpTlvTypePortConversationMask1)
+			copy(info.ConversationMask[i*LacpConversationMaskTlvIds/8:(i+1)*LacpConversationMaskTlvIds/8], v)
+			masks++
+		case LacpTlvTypePortConversationServiceMap:
+			if length != int(LacpTlvPortConversationServiceMapLength) {
+				return nil
+			}
+			copy(info.ConversationServiceMappingDigest[:], v)
+			found = true
+		}
+		data = data[length:]
+	}
+	if !found {
+		return nil
+	}
+	info.LongPdu = masks == LacpConversationIdMax/LacpConversationMaskTlvIds
+	return info
+}
+
+// LacpPduV2FrameBuild takes a serialized version 1 LACP frame and inserts the
+// version 2 TLVs after the Collector Information TLV. The frame is terminated
+// and padded as described in 802.1ax-2014 Section 6.4.2.3. slowOffset is the
+// offset of the slow protocol subtype within the frame
+func LacpPduV2FrameBuild(frame []byte, slowOffset int, info *LacpPduV2Info) []byte {
+	end := slowOffset + LacpPduV1TlvEndOffset
+	if len(frame) < end {
+		return frame
+	}
+	tlvs := LacpPduV2InfoEncode(info)
+	b := make([]byte, 0, end+len(tlvs)+2+LacpPduPadLength)
+	b = append(b, frame[:end]...)
+	b = append(b, tlvs...)
+	b = append(b, LacpTlvTypeTerminator, 0)
+	b = append(b, make([]byte, LacpPduPadLength)...)
+	return b
+}
+
+// LacpPduV2FrameParse will return the version 2 TLVs carried in a slow protocol
+// payload, data starts with the slow protocol subtype
+func LacpPduV2FrameParse(data []byte) *LacpPduV2Info {
+	if len(data) <= LacpPduV1TlvEndOffset {
+		return nil
+	}
+	return LacpPduV2InfoDecode(data[LacpPduV1TlvEndOffset:])
+}
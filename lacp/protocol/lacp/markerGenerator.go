@@ -0,0 +1,174 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// markerGenerator.go
+package lacp
+
+import (
+	"fmt"
+	"github.com/google/gopacket/layers"
+	"sync"
+	"time"
+)
+
+const MarkerGeneratorModuleStr = "LAMP Marker Generator"
+
+// time to wait for a Marker Response before the conversations of a port
+// are moved regardless
+const LampMarkerResponseTimeout time.Duration = (time.Second * 1)
+
+// LampMarkerGenerator 802.1ax-2014 Section 6.5.1 originates Marker PDUs on a
+// port and tracks the outstanding transactions until the Marker Response
+// arrives or the response timer expires
+type LampMarkerGenerator struct {
+	p *LaAggPort
+
+	// last Requester_Transaction_ID used
+	transactionId uint32
+
+	// outstanding transactions and their response timers
+	pending map[uint32]*time.Timer
+	mutex   sync.Mutex
+
+	// no more responses are reported to the mux machine once stopped
+	stopped   bool
+	stopMutex sync.RWMutex
+}
+
+// NewLampMarkerGenerator will create a new instance of the LampMarkerGenerator
+func NewLampMarkerGenerator(port *LaAggPort) *LampMarkerGenerator {
+	mg := &LampMarkerGenerator{
+		p:       port,
+		pending: make(map[uint32]*time.Timer),
+	}
+
+	port.MarkerGenerator = mg
+
+	return mg
+}
+
+// Stop should clean up all resources
+func (mg *LampMarkerGenerator) Stop() {
+	mg.stopMutex.Lock()
+	mg.stopped = true
+	mg.stopMutex.Unlock()
+
+	mg.mutex.Lock()
+	defer mg.mutex.Unlock()
+	for tid, timer := range mg.pending {
+		timer.Stop()
+		delete(mg.pending, tid)
+	}
+}
+
+// LampMarkerGeneratorSend will transmit a Marker PDU and start the response
+// timer, the transaction id of the Marker is returned
+func (mg *LampMarkerGenerator) LampMarkerGeneratorSend() uint32 {
+	p := mg.p
+
+	mg.mutex.Lock()
+	mg.transactionId++
+	tid := mg.transactionId
+	mg.pending[tid] = time.AfterFunc(LampMarkerResponseTimeout, func() {
+		mg.lampMarkerGeneratorComplete(tid, false)
+	})
+	mg.mutex.Unlock()
+
+	lamp := &layers.LAMP{
+		Version: layers.LAMPVersion1,
+		Marker: layers.LAMPMarkerTlv{TlvType: layers.LAMPTLVMarkerInfo,
+			Length:                 layers.LAMPMarkerTlvLength,
+			RequesterPort:          p.ActorOper.port,
+			RequesterSystem:        p.ActorOper.System.Actor_System,
+			RequesterTransactionId: tid,
+		},
+		Terminator: layers.LAMPTerminatorTlv{},
+	}
+
+	mg.LampMarkerGeneratorLog(fmt.Sprintf("Sending Marker transaction %d", tid))
+	for _, ftx := range LaSysGlobalTxCallbackListGet(p) {
+		ftx(p.PortNum, lamp)
+		p.LacpCounter.AggPortStatsMarkerPDUsTx += 1
+	}
+	return tid
+}
+
+// LampMarkerGeneratorPending returns TRUE while the transaction is waiting
+// for a Marker Response
+func (mg *LampMarkerGenerator) LampMarkerGeneratorPending(tid uint32) bool {
+	mg.mutex.Lock()
+	defer mg.mutex.Unlock()
+	_, ok := mg.pending[tid]
+	return ok
+}
+
+// LampMarkerGeneratorResponse is called when a Marker Response PDU is
+// received on the port, returns FALSE if the response does not match an
+// outstanding Marker sent by this port
+func (mg *LampMarkerGenerator) LampMarkerGeneratorResponse(lamp *layers.LAMP) bool {
+	p := mg.p
+	if lamp.Marker.RequesterPort != p.ActorOper.port ||
+		lamp.Marker.RequesterSystem != p.ActorOper.System.Actor_System ||
+		!mg.LampMarkerGeneratorPending(lamp.Marker.RequesterTransactionId) {
+		p.LacpCounter.AggPortStatsMarkerResponseUnexpectedRx += 1
+		return false
+	}
+	mg.lampMarkerGeneratorComplete(lamp.Marker.RequesterTransactionId, true)
+	return true
+}
+
+// lampMarkerGeneratorComplete ends the transaction and informs the mux
+// machine, which may be waiting on the transaction to move conversations
+func (mg *LampMarkerGenerator) lampMarkerGeneratorComplete(tid uint32, responseRx bool) {
+	p := mg.p
+
+	mg.mutex.Lock()
+	timer, ok := mg.pending[tid]
+	if ok {
+		timer.Stop()
+		delete(mg.pending, tid)
+	}
+	mg.mutex.Unlock()
+	if !ok {
+		return
+	}
+
+	if !responseRx {
+		mg.LampMarkerGeneratorLog(fmt.Sprintf("Marker transaction %d timed out", tid))
+		p.LacpCounter.AggPortStatsMarkerResponseTimeouts += 1
+	}
+
+	mg.stopMutex.RLock()
+	stopped := mg.stopped
+	mg.stopMutex.RUnlock()
+	// don't block the timer or rx context on a busy mux machine, a single
+	// pending response is enough as the mux checks the transaction state
+	if !stopped &&
+		p.MuxMachineFsm != nil &&
+		p.MuxMachineFsm.MuxmMarkerResponseEvent != nil {
+		select {
+		case p.MuxMachineFsm.MuxmMarkerResponseEvent <- true:
+		default:
+		}
+	}
+}
@@ -0,0 +1,309 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package lacp
+
+import (
+	"github.com/google/gopacket/layers"
+	"l2/lacp/protocol/utils"
+	"testing"
+	"time"
+	asicdmock "utils/asicdClient/mock"
+)
+
+type MarkerMockAsicdClientMgr struct {
+	asicdmock.MockAsicdClientMgr
+	txLamp chan *layers.LAMP
+	ports  string
+	// lag was programmed while a Marker PDU was already sent
+	updateAfterMarker bool
+}
+
+func (m *MarkerMockAsicdClientMgr) UpdateLag(ifIndex, hashType int32, ports string) error {
+	m.ports = ports
+	m.updateAfterMarker = len(m.txLamp) > 0
+	return nil
+}
+
+func MarkerGeneratorTestSetup() (*LaAggregator, []*LaAggPort, chan *layers.LAMP) {
+	a, ports := SelectionTestSetup(0)
+
+	txLamp := make(chan *layers.LAMP, 10)
+	sgi := LacpSysGlobalInfoByIdGet(LacpSystem{Actor_System_priority: 128,
+		Actor_System: [6]uint8{0x00, 0x00, 0x00, 0x00, 0x00, 0x64}})
+	for _, p := range ports {
+		p.AggAttached = a
+		p.MuxMachineFsm = nil
+		p.LacpMuxMachineFSMBuild()
+		LacpStateClear(&p.ActorOper.State, LacpStateDefaultedBit)
+		sgi.LaSysGlobalRegisterTxCallback(p.IntfNum, func(port uint16, pdu interface{}) {
+			if lamp, ok := pdu.(*layers.LAMP); ok {
+				txLamp <- lamp
+			}
+		})
+	}
+	a.DistributedPortNumList = []string{ports[0].IntfNum, ports[1].IntfNum}
+	a.ConversationSensitive = true
+	return a, ports, txLamp
+}
+
+func MarkerGeneratorTestTeardown(a *LaAggregator, ports []*LaAggPort) {
+	sgi := LacpSysGlobalInfoByIdGet(LacpSystem{Actor_System_priority: 128,
+		Actor_System: [6]uint8{0x00, 0x00, 0x00, 0x00, 0x00, 0x64}})
+	for _, p := range ports {
+		sgi.LaSysGlobalDeRegisterTxCallback(p.IntfNum)
+		p.AggAttached = nil
+	}
+	SelectionTestTeardown(a, ports)
+}
+
+func TestLampMarkerGeneratorDisableDistributing(t *testing.T) {
+	a, ports, txLamp := MarkerGeneratorTestSetup()
+	defer MarkerGeneratorTestTeardown(a, ports)
+	mock := &MarkerMockAsicdClientMgr{txLamp: txLamp}
+	utils.DeleteAllAsicDPlugins()
+	utils.SetAsicDPlugin(mock)
+
+	p := ports[0]
+	muxm := p.MuxMachineFsm
+	muxm.DisableDistributing()
+
+	// port must no longer carry frames when the Marker is sent
+	if len(a.DistributedPortNumList) != 1 ||
+		a.DistributedPortNumList[0] != ports[1].IntfNum {
+		t.Error("Port not removed from distribution before Marker", a.DistributedPortNumList)
+	}
+	if mock.updateAfterMarker {
+		t.Error("Lag programmed without the port after the Marker was sent")
+	}
+
+	var marker *layers.LAMP
+	select {
+	case marker = <-txLamp:
+	default:
+		t.Fatal("Expected Marker PDU to be sent before moving conversations")
+	}
+	if marker.Marker.TlvType != layers.LAMPTLVMarkerInfo ||
+		marker.Marker.RequesterPort != p.ActorOper.port ||
+		p.LacpCounter.AggPortStatsMarkerPDUsTx != 1 {
+		t.Error("Invalid Marker PDU sent", marker.Marker)
+	}
+	if !muxm.markerPending ||
+		!p.conversationHeld {
+		t.Error("Expected conversations of the port to be held until the Marker Response")
+	}
+
+	// response from an unknown transaction is ignored
+	unknown := *marker
+	unknown.Marker.TlvType = layers.LAMPTLVMarkerResponder
+	unknown.Marker.RequesterTransactionId++
+	if p.MarkerGenerator.LampMarkerGeneratorResponse(&unknown) ||
+		p.LacpCounter.AggPortStatsMarkerResponseUnexpectedRx != 1 {
+		t.Error("Expected Marker Response with unknown transaction to be ignored")
+	}
+
+	response := *marker
+	response.Marker.TlvType = layers.LAMPTLVMarkerResponder
+	if !p.MarkerGenerator.LampMarkerGeneratorResponse(&response) {
+		t.Error("Expected Marker Response to match outstanding Marker")
+	}
+	select {
+	case <-muxm.MuxmMarkerResponseEvent:
+	default:
+		t.Fatal("Mux machine not informed of Marker Response")
+	}
+	muxm.LampMarkerResponse()
+	if muxm.markerPending ||
+		p.conversationHeld {
+		t.Error("Expected conversations to be released after Marker Response")
+	}
+
+	// last distributing port, no conversations to move
+	ports[1].MuxMachineFsm.DisableDistributing()
+	select {
+	case <-txLamp:
+		t.Error("Marker PDU not expected when no other port is distributing")
+	default:
+	}
+	if len(a.DistributedPortNumList) != 0 {
+		t.Error("Last port not removed from distribution", a.DistributedPortNumList)
+	}
+}
+
+func TestLampMarkerGeneratorResponseTimeout(t *testing.T) {
+	a, ports, txLamp := MarkerGeneratorTestSetup()
+	defer MarkerGeneratorTestTeardown(a, ports)
+
+	p := ports[0]
+	muxm := p.MuxMachineFsm
+	muxm.DisableDistributing()
+	<-txLamp
+
+	select {
+	case <-muxm.MuxmMarkerResponseEvent:
+	case <-time.After(LampMarkerResponseTimeout * 3):
+		t.Fatal("Marker Response timer did not expire")
+	}
+	if p.LacpCounter.AggPortStatsMarkerResponseTimeouts != 1 {
+		t.Error("Expected Marker Response timeout to be counted")
+	}
+	muxm.LampMarkerResponse()
+	if muxm.markerPending ||
+		p.conversationHeld {
+		t.Error("Expected conversations to be released after Marker Response timeout")
+	}
+}
+
+func TestLampMarkerGeneratorDistributingAgain(t *testing.T) {
+	a, ports, txLamp := MarkerGeneratorTestSetup()
+	defer MarkerGeneratorTestTeardown(a, ports)
+
+	p := ports[0]
+	muxm := p.MuxMachineFsm
+	muxm.DisableDistributing()
+	<-txLamp
+
+	// port returns to distributing before the response arrives
+	muxm.EnableDistributing()
+	if len(a.DistributedPortNumList) != 2 {
+		t.Error("Port should only be in distribution list once", a.DistributedPortNumList)
+	}
+	if p.conversationHeld {
+		t.Error("Port distributing again should carry its conversations")
+	}
+	p.MarkerGenerator.Stop()
+	muxm.LampMarkerResponse()
+	if len(a.DistributedPortNumList) != 2 {
+		t.Error("Port should remain distributing", a.DistributedPortNumList)
+	}
+}
+
+func TestLampMarkerGeneratorHoldsConversations(t *testing.T) {
+	a, ports, txLamp := MarkerGeneratorTestSetup()
+	defer MarkerGeneratorTestTeardown(a, ports)
+	mock := &ConversationMockAsicdClientMgr{}
+	utils.DeleteAllAsicDPlugins()
+	utils.SetAsicDPlugin(mock)
+
+	// other tests leave ports with the same names behind
+	portConfigMap := utils.PortConfigMap
+	defer func() { utils.PortConfigMap = portConfigMap }()
+	utils.PortConfigMap = make(map[int32]utils.PortConfig)
+	for _, p := range ports {
+		pcfg := portConfigMap[int32(p.PortNum)]
+		pcfg.IfIndex = int32(p.PortNum)
+		utils.PortConfigMap[int32(p.PortNum)] = pcfg
+	}
+
+	a.AggLacpVersion = 2
+	for _, p := range ports {
+		p.partnerV2 = &LacpPduV2Info{
+			PortAlgorithm:                    a.PortAlgorithm,
+			ConversationLinkListDigest:       a.LacpAggConversationLinkListDigest(),
+			ConversationServiceMappingDigest: a.LacpAggConversationServiceMappingDigest(),
+		}
+	}
+	a.LacpAggConversationUpdate()
+	if len(mock.ports) != LacpConversationIdMax ||
+		mock.ports[0] != int32(ports[0].PortNum) ||
+		mock.ports[1] != int32(ports[1].PortNum) {
+		t.Fatal("Expected conversations to be spread across the distributing ports", len(mock.ports))
+	}
+
+	p := ports[0]
+	muxm := p.MuxMachineFsm
+	muxm.DisableDistributing()
+	<-txLamp
+
+	// conversations of the port are not distributed until the response
+	if mock.ports[0] != 0 ||
+		mock.ports[2] != 0 {
+		t.Error("Conversations moved before Marker Response", mock.ports[0], mock.ports[2])
+	}
+	if mock.ports[1] != int32(ports[1].PortNum) {
+		t.Error("Conversations of the remaining port should not be affected", mock.ports[1])
+	}
+
+	// a partner update while waiting must not move them either
+	ports[1].partnerV2.LongPdu = true
+	a.LacpAggConversationUpdate()
+	if mock.ports[0] != 0 {
+		t.Error("Conversation moved by an update before Marker Response", mock.ports[0])
+	}
+
+	p.MarkerGenerator.Stop()
+	muxm.LampMarkerResponse()
+	if mock.ports[0] != int32(ports[1].PortNum) ||
+		mock.ports[2] != int32(ports[1].PortNum) {
+		t.Error("Conversations not moved after Marker Response", mock.ports[0], mock.ports[2])
+	}
+}
+
+func TestLampMarkerGeneratorNotConversationSensitive(t *testing.T) {
+	a, ports, txLamp := MarkerGeneratorTestSetup()
+	defer MarkerGeneratorTestTeardown(a, ports)
+
+	// frames are distributed by the hash, there are no conversations to hold
+	a.ConversationSensitive = false
+	p := ports[0]
+	muxm := p.MuxMachineFsm
+	muxm.DisableDistributing()
+	select {
+	case <-txLamp:
+		t.Error("Marker PDU not expected without conversation sensitive distribution")
+	default:
+	}
+	if muxm.markerPending ||
+		p.conversationHeld {
+		t.Error("Conversations of the port should not be held")
+	}
+	if len(a.DistributedPortNumList) != 1 ||
+		a.DistributedPortNumList[0] != ports[1].IntfNum {
+		t.Error("Port not removed from distribution", a.DistributedPortNumList)
+	}
+}
+
+func TestLampMarkerGeneratorBusyMux(t *testing.T) {
+	a, ports, txLamp := MarkerGeneratorTestSetup()
+	defer MarkerGeneratorTestTeardown(a, ports)
+
+	// nobody is servicing the mux machine, completing transactions must not block
+	p := ports[0]
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 3; i++ {
+			tid := p.MarkerGenerator.LampMarkerGeneratorSend()
+			<-txLamp
+			p.MarkerGenerator.lampMarkerGeneratorComplete(tid, true)
+		}
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Marker transaction completion blocked on the mux machine")
+	}
+	if len(p.MuxMachineFsm.MuxmMarkerResponseEvent) != 1 {
+		t.Error("Expected a single pending marker response actual", len(p.MuxMachineFsm.MuxmMarkerResponseEvent))
+	}
+}
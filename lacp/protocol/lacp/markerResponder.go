@@ -143,11 +143,15 @@ func (mr *LampMarkerResponderMachine) LampMarkerResponderRespondToMarker(m fsm.M
 		return LampMarkerResponderStateWaitForMarker
 	}
 
-	// we only want to handle marker pdu, not response since we are not
-	// generating
+	// we only want to respond to a marker pdu, a response is handed to the
+	// marker generator
 	if lampPduInfo.Marker.TlvType != layers.LAMPTLVMarkerInfo {
 		if lampPduInfo.Marker.TlvType == layers.LAMPTLVMarkerResponder {
 			p.LacpCounter.AggPortStatsMarkerResponsePDUsRx += 1
+			// response to a marker generated by this port
+			if p.MarkerGenerator != nil {
+				p.MarkerGenerator.LampMarkerGeneratorResponse(lampPduInfo)
+			}
 		} else {
 			p.LacpCounter.AggPortStatsIllegalRx += 1
 		}
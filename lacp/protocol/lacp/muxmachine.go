@@ -81,6 +81,7 @@ func MuxxMachineStrStateMapCreate() {
 	MuxmEventStrMap[LacpMuxmEventNotPartnerSync] = "Event Partner Oper Sync state is NOT set"
 	MuxmEventStrMap[LacpMuxmEventNotPartnerCollecting] = "Event Partner Oper Collecting state is not set"
 	MuxmEventStrMap[LacpMuxmEventSelectedEqualSelectedPartnerSyncCollecting] = "Event Selected equals Selected and Partner Oper Sync and Collecting state is set"
+	MuxmEventStrMap[LacpMuxmEventMarkerResponse] = "Event Marker Response received or timed out"
 
 }
 
@@ -94,6 +95,8 @@ const (
 	LacpMuxmEventNotPartnerSync
 	LacpMuxmEventNotPartnerCollecting
 	LacpMuxmEventSelectedEqualSelectedPartnerSyncCollecting
+	// not a State transition, marker generator transaction has completed
+	LacpMuxmEventMarkerResponse
 )
 
 // LacpRxMachine holds FSM and current State
@@ -122,8 +125,19 @@ type LacpMuxMachine struct {
 	// machine specific events
 	MuxmEvents         chan utils.MachineEvent
 	MuxmLogEnableEvent chan bool
+	// selection of other ports of the aggregator changed, a single
+	// pending reselect is enough thus it is never blocked on
+	MuxmReselectEvent chan bool
+	// marker transaction completed, sent by the marker generator timer
+	// and rx contexts which must not block on a busy mux machine
+	MuxmMarkerResponseEvent chan bool
 
 	actorSyncTransitionTimestamp time.Time
+
+	// distribution has been disabled and the conversations of the port
+	// are held until the marker transaction completes
+	markerPending       bool
+	markerTransactionId uint32
 }
 
 func (muxm *LacpMuxMachine) Stop() {
@@ -140,12 +154,14 @@ func (muxm *LacpMuxMachine) PrevStateSet(s fsm.State) { muxm.PreviousState = s }
 // NewLacpRxMachine will create a new instance of the LacpRxMachine
 func NewLacpMuxMachine(port *LaAggPort) *LacpMuxMachine {
 	muxm := &LacpMuxMachine{
-		p:                     port,
-		collDistCoupled:       false,
-		waitWhileTimerTimeout: LacpAggregateWaitTime,
-		PreviousState:         LacpMuxmStateNone,
-		MuxmEvents:            make(chan utils.MachineEvent, 10),
-		MuxmLogEnableEvent:    make(chan bool)}
+		p:                       port,
+		collDistCoupled:         false,
+		waitWhileTimerTimeout:   LacpAggregateWaitTime,
+		PreviousState:           LacpMuxmStateNone,
+		MuxmEvents:              make(chan utils.MachineEvent, 10),
+		MuxmLogEnableEvent:      make(chan bool),
+		MuxmReselectEvent:       make(chan bool, 1),
+		MuxmMarkerResponseEvent: make(chan bool, 1)}
 
 	port.MuxMachineFsm = muxm
 
@@ -184,6 +200,55 @@ func (muxm *LacpMuxMachine) SendTxMachineNtt() {
 	}
 }
 
+// LacpMuxCouplingGet returns TRUE when the port should run the coupled
+// control mux machine.  The mux control of the aggregator takes precedence
+// over the mux coupling of the system
+func (p *LaAggPort) LacpMuxCouplingGet() bool {
+	var a *LaAggregator
+	if p.AggId != 0 && LaFindAggById(p.AggId, &a) {
+		switch a.AggMuxControl {
+		case LaAggMuxControlIndependent:
+			return false
+		case LaAggMuxControlCoupled:
+			return true
+		}
+		sysId := LacpSystem{Actor_System: a.AggMacAddr,
+			Actor_System_priority: a.AggPriority}
+		if sgi, ok := gLacpSysGlobalInfo[sysId]; ok {
+			return sgi.muxCoupling
+		}
+	}
+	if sgi, ok := gLacpSysGlobalInfo[LacpSystem{}]; ok {
+		return sgi.muxCoupling
+	}
+	return lacpSysMuxCouplingDefaultGet()
+}
+
+// LacpMuxmCouplingSet will move the machine to the independent or coupled
+// control machine when the control changes.  A detached port is moved to the
+// equivalent DETACHED State, otherwise the machine is moved to the pseudo
+// begin State and the following BEGIN event will detach the port
+func (muxm *LacpMuxMachine) LacpMuxmCouplingSet(coupled bool) {
+	if coupled == muxm.collDistCoupled {
+		return
+	}
+	muxm.LacpMuxmLog(fmt.Sprintf("Mux Coupling changed to %t", coupled))
+	muxm.collDistCoupled = coupled
+	muxm.PrevStateSet(muxm.Machine.Curr.CurrentState())
+	switch muxm.Machine.Curr.CurrentState() {
+	case LacpMuxmStateDetached:
+		muxm.Machine.Start(LacpMuxmStateCDetached)
+	case LacpMuxmStateCDetached:
+		muxm.Machine.Start(LacpMuxmStateDetached)
+	default:
+		if coupled {
+			muxm.Machine.Start(LacpMuxmStateCNone)
+		} else {
+			muxm.Machine.Start(LacpMuxmStateNone)
+		}
+	}
+}
+
 // LacpMuxmDetached
 func (muxm *LacpMuxMachine) LacpMuxmDetached(m fsm.Machine, data interface{}) fsm.State {
 	p := muxm.p
@@ -210,6 +275,12 @@ func (muxm *LacpMuxMachine) LacpMuxmDetached(m fsm.Machine, data interface{}) fs
 	// Disable Collecting
 	muxm.DisableCollecting()
 
+	// port leaving the aggregator may allow a standby port to be selected
+	var a *LaAggregator
+	if LaFindAggById(p.AggId, &a) {
+		a.LacpAggSelectionUpdate(p)
+	}
+
 	// NTT = TRUE
 	// TODO: is this necessary? May only want to let TxMachine
 	//       set ntt to true based on NTT event
@@ -256,6 +327,12 @@ func (muxm *LacpMuxMachine) LacpMuxmWaiting(m fsm.Machine, data interface{}) fsm
 	return LacpMuxmStateWaiting
 }
 
+// LacpMuxmWaitingSelectedChange a standby port is held in the waiting State
+// until it becomes selected, at which point it will attach once ready
+func (muxm *LacpMuxMachine) LacpMuxmWaitingSelectedChange(m fsm.Machine, data interface{}) fsm.State {
+	return LacpMuxmStateWaiting
+}
+
 // LacpMuxmAttached
 func (muxm *LacpMuxMachine) LacpMuxmAttached(m fsm.Machine, data interface{}) fsm.State {
 	p := muxm.p
@@ -317,11 +394,6 @@ func (muxm *LacpMuxMachine) LacpMuxmCollecting(m fsm.Machine, data interface{})
 	//muxm.LacpMuxmLog("Clearing Actor Distributing Bit")
 	LacpStateClear(&p.ActorOper.State, LacpStateDistributingBit)
 
-	if p.AggAttached != nil &&
-		len(p.AggAttached.DistributedPortNumList) == 0 {
-		p.AggAttached.OperState = false
-	}
-
 	// indicate that NTT = TRUE
 	defer muxm.SendTxMachineNtt()
 
@@ -338,9 +410,6 @@ func (muxm *LacpMuxMachine) LacpMuxmDistributing(m fsm.Machine, data interface{}
 
 	// Enabled Distributing
 	muxm.EnableDistributing()
-	if p.AggAttached != nil {
-		p.AggAttached.OperState = true
-	}
 
 	// indicate that NTT = TRUE
 	defer muxm.SendTxMachineNtt()
@@ -352,8 +421,6 @@ func (muxm *LacpMuxMachine) LacpMuxmDistributing(m fsm.Machine, data interface{}
 func (muxm *LacpMuxMachine) LacpMuxmCDetached(m fsm.Machine, data interface{}) fsm.State {
 	p := muxm.p
 
-	// indicate that NTT = TRUE
-	defer muxm.SendTxMachineNtt()
 	// send event to user port and partner 	info don't
 	defer utils.ProcessLacpPortPartnerInfoMismatch(int32(p.PortNum))
 
@@ -377,19 +444,36 @@ func (muxm *LacpMuxMachine) LacpMuxmCDetached(m fsm.Machine, data interface{}) f
 	// Actor Oper State Distributing = FALSE
 	LacpStateClear(&p.ActorOper.State, LacpStateDistributingBit)
 
-	return LacpMuxmStateDetached
+	// port leaving the aggregator may allow a standby port to be selected
+	var a *LaAggregator
+	if LaFindAggById(p.AggId, &a) {
+		a.LacpAggSelectionUpdate(p)
+	}
+
+	// indicate that NTT = TRUE
+	if muxm.Machine.Curr.CurrentState() != LacpMuxmStateCNone {
+		muxm.SendTxMachineNtt()
+	}
+
+	return LacpMuxmStateCDetached
 }
 
 // LacpMuxmCWaiting
 func (muxm *LacpMuxMachine) LacpMuxmCWaiting(m fsm.Machine, data interface{}) fsm.State {
-	//p := muxm.p
 
-	muxm.WaitWhileTimerStart()
+	// same wait while handling as the independent control
+	muxm.LacpMuxmWaiting(m, data)
 
-	return LacpMuxmStateWaiting
+	return LacpMuxmStateCWaiting
 }
 
-// LacpMuxmAttached
+// LacpMuxmCWaitingSelectedChange a standby port is held in the waiting State
+// until it becomes selected, at which point it will attach once ready
+func (muxm *LacpMuxMachine) LacpMuxmCWaitingSelectedChange(m fsm.Machine, data interface{}) fsm.State {
+	return LacpMuxmStateCWaiting
+}
+
+// LacpMuxmCAttached
 func (muxm *LacpMuxMachine) LacpMuxmCAttached(m fsm.Machine, data interface{}) fsm.State {
 	p := muxm.p
 
@@ -421,26 +505,26 @@ func (muxm *LacpMuxMachine) LacpMuxmCAttached(m fsm.Machine, data interface{}) f
 	// Actor Oper State Distributing = FALSE
 	LacpStateClear(&p.ActorOper.State, LacpStateDistributingBit)
 
-	return LacpMuxmStateWaiting
+	return LacpMuxmStateCAttached
 }
 
-// LacpMuxmCollecting
+// LacpMuxmCCollectingDistributing
 func (muxm *LacpMuxMachine) LacpMuxmCCollectingDistributing(m fsm.Machine, data interface{}) fsm.State {
 	p := muxm.p
 
+	// indicate that NTT = TRUE
+	defer muxm.SendTxMachineNtt()
+
 	// Actor Oper State Distributing = TRUE
 	LacpStateSet(&p.ActorOper.State, LacpStateDistributingBit)
 
 	// Enable Collecting && Distributing
 	muxm.EnableCollectingDistributing()
 
-	// Actor Oper State Distributing == FALSE
-	LacpStateSet(&p.ActorOper.State, LacpStateDistributingBit)
-
-	// indicate that NTT = TRUE
-	defer muxm.SendTxMachineNtt()
+	// Actor Oper State Collecting = TRUE
+	LacpStateSet(&p.ActorOper.State, LacpStateCollectingBit)
 
-	return LacpMuxmStateWaiting
+	return LacpMuxStateCCollectingDistributing
 }
 
 // LacpMuxMachineFSMBuild:  802.1ax-2014 Figure 6-21 && 6-22
@@ -469,6 +553,9 @@ func (p *LaAggPort) LacpMuxMachineFSMBuild() *LacpMuxMachine {
 	rules.AddRule(LacpMuxmStateDetached, LacpMuxmEventSelectedEqualStandby, muxm.LacpMuxmWaiting)
 	// UNSELECTED -> DETACHED
 	rules.AddRule(LacpMuxmStateWaiting, LacpMuxmEventSelectedEqualUnselected, muxm.LacpMuxmDetached)
+	// SELECTED or STANDBY -> WAITING, standby port held in waiting
+	rules.AddRule(LacpMuxmStateWaiting, LacpMuxmEventSelectedEqualSelected, muxm.LacpMuxmWaitingSelectedChange)
+	rules.AddRule(LacpMuxmStateWaiting, LacpMuxmEventSelectedEqualStandby, muxm.LacpMuxmWaitingSelectedChange)
 	// SELECTED && READY -> ATTACHED
 	rules.AddRule(LacpMuxmStateWaiting, LacpMuxmEventSelectedEqualSelectedAndReady, muxm.LacpMuxmAttached)
 	// UNSELECTED or STANDBY -> DETACHED
@@ -490,7 +577,7 @@ func (p *LaAggPort) LacpMuxMachineFSMBuild() *LacpMuxMachine {
 
 	// MUX Coupled
 	//BEGIN -> DETACHED
-	rules.AddRule(LacpMuxmStateNone, LacpMuxmEventBegin, muxm.LacpMuxmCDetached)
+	rules.AddRule(LacpMuxmStateCNone, LacpMuxmEventBegin, muxm.LacpMuxmCDetached)
 	rules.AddRule(LacpMuxmStateCDetached, LacpMuxmEventBegin, muxm.LacpMuxmCDetached)
 	rules.AddRule(LacpMuxmStateCWaiting, LacpMuxmEventBegin, muxm.LacpMuxmCDetached)
 	rules.AddRule(LacpMuxmStateCAttached, LacpMuxmEventBegin, muxm.LacpMuxmCDetached)
@@ -501,17 +588,20 @@ func (p *LaAggPort) LacpMuxMachineFSMBuild() *LacpMuxMachine {
 	rules.AddRule(LacpMuxmStateCDetached, LacpMuxmEventSelectedEqualStandby, muxm.LacpMuxmCWaiting)
 	// UNSELECTED -> DETACHED
 	rules.AddRule(LacpMuxmStateCWaiting, LacpMuxmEventSelectedEqualUnselected, muxm.LacpMuxmCDetached)
+	// SELECTED or STANDBY -> WAITING, standby port held in waiting
+	rules.AddRule(LacpMuxmStateCWaiting, LacpMuxmEventSelectedEqualSelected, muxm.LacpMuxmCWaitingSelectedChange)
+	rules.AddRule(LacpMuxmStateCWaiting, LacpMuxmEventSelectedEqualStandby, muxm.LacpMuxmCWaitingSelectedChange)
 	// SELECTED && READY -> ATTACHED
-	rules.AddRule(LacpMuxmStateCWaiting, LacpMuxmEventSelectedEqualSelectedAndReady, muxm.LacpMuxmAttached)
+	rules.AddRule(LacpMuxmStateCWaiting, LacpMuxmEventSelectedEqualSelectedAndReady, muxm.LacpMuxmCAttached)
 	// UNSELECTED or STANDBY -> DETACHED
 	rules.AddRule(LacpMuxmStateCAttached, LacpMuxmEventSelectedEqualUnselected, muxm.LacpMuxmCDetached)
 	rules.AddRule(LacpMuxmStateCAttached, LacpMuxmEventSelectedEqualStandby, muxm.LacpMuxmCDetached)
 	// SELECTED && PARTNER SYNC -> COLLECTING-DISTRIBUTING
 	rules.AddRule(LacpMuxmStateCAttached, LacpMuxmEventSelectedEqualSelectedAndPartnerSync, muxm.LacpMuxmCCollectingDistributing)
 	// UNSELECTED or STANDBY or NOT PARTNER SYNC -> ATTACHED
-	rules.AddRule(LacpMuxmStateCollecting, LacpMuxmEventSelectedEqualUnselected, muxm.LacpMuxmCAttached)
-	rules.AddRule(LacpMuxmStateCollecting, LacpMuxmEventSelectedEqualStandby, muxm.LacpMuxmCAttached)
-	rules.AddRule(LacpMuxmStateCollecting, LacpMuxmEventNotPartnerSync, muxm.LacpMuxmCAttached)
+	rules.AddRule(LacpMuxStateCCollectingDistributing, LacpMuxmEventSelectedEqualUnselected, muxm.LacpMuxmCAttached)
+	rules.AddRule(LacpMuxStateCCollectingDistributing, LacpMuxmEventSelectedEqualStandby, muxm.LacpMuxmCAttached)
+	rules.AddRule(LacpMuxStateCCollectingDistributing, LacpMuxmEventNotPartnerSync, muxm.LacpMuxmCAttached)
 
 	// Create a new FSM and apply the rules
 	muxm.Apply(&rules)
@@ -529,13 +619,10 @@ func (p *LaAggPort) LacpMuxMachineMain() {
 	muxm := p.LacpMuxMachineFSMBuild()
 	p.wg.Add(1)
 
-	// TODO: Hw only supports mux coupling, this should be a param file for lacp
-	//if LacpSysGlobalInfoGet(LacpSystem{Actor_System: p.AggAttached.Config.SystemIdMac,
-	//	Actor_System_priority: p.AggAttached.Config.SystemPriority}).muxCoupling {
-	//	muxm.PrevStateSet(LacpMuxmStateCNone)
-	//}
 	// set the inital State
 	muxm.Machine.Start(muxm.PrevState())
+	// independent or coupled control is taken from the aggregator or system
+	muxm.LacpMuxmCouplingSet(p.LacpMuxCouplingGet())
 
 	// lets create a go routing which will wait for the specific events
 	// that the RxMachine should handle.
@@ -558,98 +645,22 @@ func (p *LaAggPort) LacpMuxMachineMain() {
 			case event, ok := <-m.MuxmEvents:
 
 				if ok {
-					p := m.p
-					//m.LacpMuxmLog(fmt.Sprintf("Event received %d src %s", event.E, event.Src))
-					eventStr := strings.Join([]string{"from", event.Src, MuxmEventStrMap[int(event.E)]}, " ")
-
-					// process the event
-					rv := m.Machine.ProcessEvent(event.Src, event.E, nil)
-
-					if rv != nil {
-						m.LacpMuxmLog(strings.Join([]string{error.Error(rv), event.Src, MuxmStateStrMap[m.Machine.Curr.CurrentState()], strconv.Itoa(int(event.E))}, ":"))
-					} else {
-
-						// continuation events
-						if m.Machine.Curr.CurrentState() == LacpMuxmStateDetached ||
-							m.Machine.Curr.CurrentState() == LacpMuxmStateCDetached {
-							// if port is attached then we know that provisioning found
-							// a valid agg thus port should be attached.
-							if p.AggAttached != nil &&
-								p.IsPortEnabled() &&
-								p.lacpEnabled {
-								// change the selection to be Selected
-								p.aggSelected = LacpAggSelected
-								//muxm.LacpMuxmLog("Setting Actor Aggregation Bit")
-								LacpStateSet(&p.ActorOper.State, LacpStateAggregationBit)
-
-								eventStr = strings.Join([]string{eventStr,
-									"and\nfrom", MuxMachineModuleStr, MuxmEventStrMap[LacpMuxmEventSelectedEqualSelected]}, " ")
-
-								m.Machine.ProcessEvent(MuxMachineModuleStr, LacpMuxmEventSelectedEqualSelected, nil)
-								event.E = LacpMuxmEventSelectedEqualSelected
-							}
-						}
-						if event.E == LacpMuxmEventSelectedEqualSelected &&
-							(m.Machine.Curr.CurrentState() == LacpMuxmStateWaiting ||
-								m.Machine.Curr.CurrentState() == LacpMuxmStateCWaiting) &&
-							!m.waitWhileTimerRunning {
-							// special case we may have a delayed event which will do a fast transition to next State
-							// Attached, trigger is the fact that the timer is not running
-							m.LacpMuxmWaitingEvaluateSelected(true)
-						}
-						if (m.Machine.Curr.CurrentState() == LacpMuxmStateAttached ||
-							m.Machine.Curr.CurrentState() == LacpMuxmStateCAttached) &&
-							p.aggSelected == LacpAggSelected &&
-							LacpStateIsSet(p.PartnerOper.State, LacpStateSyncBit) {
-
-							eventStr = strings.Join([]string{eventStr,
-								"and\nfrom", MuxMachineModuleStr, MuxmEventStrMap[LacpMuxmEventSelectedEqualSelectedAndPartnerSync]}, " ")
-
-							m.Machine.ProcessEvent(MuxMachineModuleStr, LacpMuxmEventSelectedEqualSelectedAndPartnerSync, nil)
-						}
-						if m.Machine.Curr.CurrentState() == LacpMuxmStateCollecting &&
-							p.aggSelected == LacpAggSelected &&
-							LacpStateIsSet(p.PartnerOper.State, LacpStateSyncBit) &&
-							LacpStateIsSet(p.PartnerOper.State, LacpStateCollectingBit) {
-
-							eventStr = strings.Join([]string{eventStr,
-								"and\nfrom", MuxMachineModuleStr, MuxmEventStrMap[LacpMuxmEventSelectedEqualSelectedPartnerSyncCollecting]}, " ")
-							m.Machine.ProcessEvent(MuxMachineModuleStr, LacpMuxmEventSelectedEqualSelectedPartnerSyncCollecting, nil)
-						}
-						if event.E == LacpMuxmEventSelectedEqualUnselected &&
-							(m.Machine.Curr.CurrentState() != LacpMuxmStateDetached &&
-								m.Machine.Curr.CurrentState() != LacpMuxmStateCDetached) {
-							// Unselected State will cause a downward transition to detached State
-							State := m.Machine.Curr.CurrentState()
-							endState := fsm.State(LacpMuxmStateDetached)
-							if m.Machine.Curr.CurrentState() > LacpMuxmStateDistributing {
-								endState = LacpMuxmStateCDetached
-							}
-							eventStr = strings.Join([]string{eventStr,
-								"and\nfrom", MuxMachineModuleStr, MuxmEventStrMap[LacpMuxmEventSelectedEqualUnselected]}, " ")
-
-							for ; State > endState; State-- {
-
-								m.Machine.ProcessEvent(MuxMachineModuleStr, LacpMuxmEventSelectedEqualUnselected, nil)
-							}
-						}
-					}
-
-					if len(eventStr) > 255 {
-						fmt.Println("WARNING string to long for MuxReason:", eventStr)
-						fmt.Println(eventStr)
-					}
-					p.AggPortDebug.AggPortDebugMuxReason = eventStr
-
-					if event.ResponseChan != nil {
-						//m.LacpMuxmLog("Sending response")
-						utils.SendResponse(MuxMachineModuleStr, event.ResponseChan)
-					}
+					m.LacpMuxmProcessEvent(event)
 				} else {
 					m.LacpMuxmLog("Machine End")
 					return
 				}
 
+			case <-m.MuxmReselectEvent:
+				if event, changed := m.LacpMuxmReselect(); changed {
+					m.LacpMuxmProcessEvent(event)
+				}
+
+			case <-m.MuxmMarkerResponseEvent:
+				m.LacpMuxmProcessEvent(utils.MachineEvent{
+					E:   LacpMuxmEventMarkerResponse,
+					Src: MarkerGeneratorModuleStr})
+
 			case ena := <-m.MuxmLogEnableEvent:
 				m.Machine.Curr.EnableLogging(ena)
 			}
@@ -657,6 +668,168 @@ func (p *LaAggPort) LacpMuxMachineMain() {
 	}(muxm)
 }
 
+// LacpMuxmProcessEvent will process the event and the continuation events
+// which follow from the resulting State
+func (m *LacpMuxMachine) LacpMuxmProcessEvent(event utils.MachineEvent) {
+	p := m.p
+	//m.LacpMuxmLog(fmt.Sprintf("Event received %d src %s", event.E, event.Src))
+	eventStr := strings.Join([]string{"from", event.Src, MuxmEventStrMap[int(event.E)]}, " ")
+
+	// marker transaction is not a State transition
+	if event.E == LacpMuxmEventMarkerResponse {
+		m.LampMarkerResponse()
+		if event.ResponseChan != nil {
+			utils.SendResponse(MuxMachineModuleStr, event.ResponseChan)
+		}
+		return
+	}
+
+	// mux control may have changed since the last begin or
+	// the port may have been added to another aggregator
+	if event.E == LacpMuxmEventBegin ||
+		m.Machine.Curr.CurrentState() == LacpMuxmStateDetached ||
+		m.Machine.Curr.CurrentState() == LacpMuxmStateCDetached {
+		m.LacpMuxmCouplingSet(p.LacpMuxCouplingGet())
+	}
+
+	// process the event
+	rv := m.Machine.ProcessEvent(event.Src, event.E, nil)
+
+	if rv != nil {
+		m.LacpMuxmLog(strings.Join([]string{error.Error(rv), event.Src, MuxmStateStrMap[m.Machine.Curr.CurrentState()], strconv.Itoa(int(event.E))}, ":"))
+	} else {
+
+		// continuation events
+		if m.Machine.Curr.CurrentState() == LacpMuxmStateDetached ||
+			m.Machine.Curr.CurrentState() == LacpMuxmStateCDetached {
+			// if port is attached then we know that provisioning found
+			// a valid agg thus port should be attached.
+			if p.AggAttached != nil &&
+				p.IsPortEnabled() &&
+				p.lacpEnabled {
+				// change the selection to be Selected, or Standby
+				// if MaxLinks higher priority ports are selected
+				p.aggSelected = p.AggAttached.LacpAggSelectionGet(p)
+				//muxm.LacpMuxmLog("Setting Actor Aggregation Bit")
+				LacpStateSet(&p.ActorOper.State, LacpStateAggregationBit)
+
+				selectedEvent := fsm.Event(LacpMuxmEventSelectedEqualSelected)
+				if p.aggSelected == LacpAggStandby {
+					selectedEvent = LacpMuxmEventSelectedEqualStandby
+				}
+				eventStr = strings.Join([]string{eventStr,
+					"and\nfrom", MuxMachineModuleStr, MuxmEventStrMap[int(selectedEvent)]}, " ")
+
+				m.Machine.ProcessEvent(MuxMachineModuleStr, selectedEvent, nil)
+				event.E = selectedEvent
+
+				// a higher priority port may bump a selected port to standby
+				p.AggAttached.LacpAggSelectionUpdate(p)
+			}
+		}
+		if event.E == LacpMuxmEventSelectedEqualSelected &&
+			(m.Machine.Curr.CurrentState() == LacpMuxmStateWaiting ||
+				m.Machine.Curr.CurrentState() == LacpMuxmStateCWaiting) &&
+			!m.waitWhileTimerRunning {
+			// special case we may have a delayed event which will do a fast transition to next State
+			// Attached, trigger is the fact that the timer is not running
+			m.LacpMuxmWaitingEvaluateSelected(true)
+		}
+		if (m.Machine.Curr.CurrentState() == LacpMuxmStateAttached ||
+			m.Machine.Curr.CurrentState() == LacpMuxmStateCAttached) &&
+			p.aggSelected == LacpAggSelected &&
+			LacpStateIsSet(p.PartnerOper.State, LacpStateSyncBit) {
+
+			eventStr = strings.Join([]string{eventStr,
+				"and\nfrom", MuxMachineModuleStr, MuxmEventStrMap[LacpMuxmEventSelectedEqualSelectedAndPartnerSync]}, " ")
+
+			m.Machine.ProcessEvent(MuxMachineModuleStr, LacpMuxmEventSelectedEqualSelectedAndPartnerSync, nil)
+		}
+		if m.Machine.Curr.CurrentState() == LacpMuxmStateCollecting &&
+			p.aggSelected == LacpAggSelected &&
+			LacpStateIsSet(p.PartnerOper.State, LacpStateSyncBit) &&
+			LacpStateIsSet(p.PartnerOper.State, LacpStateCollectingBit) {
+
+			eventStr = strings.Join([]string{eventStr,
+				"and\nfrom", MuxMachineModuleStr, MuxmEventStrMap[LacpMuxmEventSelectedEqualSelectedPartnerSyncCollecting]}, " ")
+			m.Machine.ProcessEvent(MuxMachineModuleStr, LacpMuxmEventSelectedEqualSelectedPartnerSyncCollecting, nil)
+		}
+		if event.E == LacpMuxmEventSelectedEqualUnselected &&
+			(m.Machine.Curr.CurrentState() != LacpMuxmStateDetached &&
+				m.Machine.Curr.CurrentState() != LacpMuxmStateCDetached) {
+			// Unselected State will cause a downward transition to detached State
+			State := m.Machine.Curr.CurrentState()
+			endState := fsm.State(LacpMuxmStateDetached)
+			if m.Machine.Curr.CurrentState() > LacpMuxmStateDistributing {
+				endState = LacpMuxmStateCDetached
+			}
+			eventStr = strings.Join([]string{eventStr,
+				"and\nfrom", MuxMachineModuleStr, MuxmEventStrMap[LacpMuxmEventSelectedEqualUnselected]}, " ")
+
+			for ; State > endState; State-- {
+
+				m.Machine.ProcessEvent(MuxMachineModuleStr, LacpMuxmEventSelectedEqualUnselected, nil)
+			}
+		}
+		if event.E == LacpMuxmEventSelectedEqualStandby &&
+			(m.Machine.Curr.CurrentState() == LacpMuxmStateAttached ||
+				m.Machine.Curr.CurrentState() == LacpMuxmStateCollecting ||
+				m.Machine.Curr.CurrentState() == LacpMuxmStateDistributing ||
+				m.Machine.Curr.CurrentState() == LacpMuxmStateCAttached ||
+				m.Machine.Curr.CurrentState() == LacpMuxStateCCollectingDistributing) {
+			// Standby State will cause a downward transition to detached State
+			// and then the port is held in waiting until selected
+			eventStr = strings.Join([]string{eventStr,
+				"and\nfrom", MuxMachineModuleStr, MuxmEventStrMap[LacpMuxmEventSelectedEqualStandby]}, " ")
+
+			for m.Machine.Curr.CurrentState() != LacpMuxmStateWaiting &&
+				m.Machine.Curr.CurrentState() != LacpMuxmStateCWaiting {
+				if m.Machine.ProcessEvent(MuxMachineModuleStr, LacpMuxmEventSelectedEqualStandby, nil) != nil {
+					break
+				}
+			}
+		}
+	}
+
+	if len(eventStr) > 255 {
+		fmt.Println("WARNING string to long for MuxReason:", eventStr)
+		fmt.Println(eventStr)
+	}
+	p.AggPortDebug.AggPortDebugMuxReason = eventStr
+
+	if event.ResponseChan != nil {
+		//m.LacpMuxmLog("Sending response")
+		utils.SendResponse(MuxMachineModuleStr, event.ResponseChan)
+	}
+}
+
+// LacpMuxmReselect re-evaluates whether the port is SELECTED or STANDBY after
+// a change in selection of other ports of the aggregator.  Only the mux
+// machine of the port changes its own selection, returns the event to be
+// processed when the selection changed
+func (m *LacpMuxMachine) LacpMuxmReselect() (utils.MachineEvent, bool) {
+	p := m.p
+	if p.AggAttached == nil ||
+		p.aggSelected == LacpAggUnSelected {
+		return utils.MachineEvent{}, false
+	}
+	selected := p.AggAttached.LacpAggSelectionGet(p)
+	if selected == p.aggSelected {
+		return utils.MachineEvent{}, false
+	}
+	p.aggSelected = selected
+	if selected == LacpAggSelected {
+		p.AggAttached.LacpAggLog(fmt.Sprintf("Port %s promoted from standby", p.IntfNum))
+		return utils.MachineEvent{
+			E:   LacpMuxmEventSelectedEqualSelected,
+			Src: MuxMachineModuleStr}, true
+	}
+	p.AggAttached.LacpAggLog(fmt.Sprintf("Port %s moved to standby MaxLinks %d", p.IntfNum, p.AggAttached.AggMaxLinks))
+	return utils.MachineEvent{
+		E:   LacpMuxmEventSelectedEqualStandby,
+		Src: MuxMachineModuleStr}, true
+}
+
 // LacpMuxmEvaluateSelected 802.1ax-2014 Section 6.4.15
 // d) If Selected is SELECTED, the wait_while_timer forces a delay to allow
 // for the possibility that other Aggregation Ports may be reconfiguring
@@ -735,8 +908,22 @@ func (muxm *LacpMuxMachine) DetachMuxFromAggregator() {
 // the Aggregation Port is attached to start collecting frames from the
 // Aggregation Port.
 func (muxm *LacpMuxMachine) EnableCollecting() {
-	// TODO send message to asic deamon
-	muxm.LacpMuxmLog("Sending Collection Enable to ASICD")
+	p := muxm.p
+	a := muxm.p.AggAttached
+
+	if a != nil {
+		for _, intf := range a.CollectingPortNumList {
+			if intf == p.IntfNum {
+				return
+			}
+		}
+		a.CollectingPortNumList = append(a.CollectingPortNumList, p.IntfNum)
+		sort.Strings(a.CollectingPortNumList)
+
+		muxm.LacpMuxmLog(fmt.Sprintf("Agg %d hwAggId %d EnableCollecting PortsListLen %d PortList %v", p.AggId, a.HwAggId, len(a.CollectingPortNumList), a.CollectingPortNumList))
+
+		a.LacpAggCollectingUpdate()
+	}
 }
 
 // DisableCollecting is a required function defined in 802.1ax-2014
@@ -745,10 +932,20 @@ func (muxm *LacpMuxMachine) EnableCollecting() {
 // the Aggregation Port is attached to stop collecting frames from the
 // Aggregation Port.
 func (muxm *LacpMuxMachine) DisableCollecting() {
-	// TODO send message to asic deamon
 	p := muxm.p
-	if LacpStateIsSet(p.ActorOper.State, LacpStateCollectingBit) {
-		muxm.LacpMuxmLog("Sending Collection Disable to ASICD")
+	a := muxm.p.AggAttached
+
+	if a != nil {
+		for j, intf := range a.CollectingPortNumList {
+			if intf == p.IntfNum {
+				a.CollectingPortNumList = append(a.CollectingPortNumList[:j], a.CollectingPortNumList[j+1:]...)
+
+				muxm.LacpMuxmLog(fmt.Sprintf("Agg %d hwAggId %d DisableCollecting PortsListLen %d PortList %v", p.AggId, a.HwAggId, len(a.CollectingPortNumList), a.CollectingPortNumList))
+
+				a.LacpAggCollectingUpdate()
+				return
+			}
+		}
 	}
 }
 
@@ -763,18 +960,20 @@ func (muxm *LacpMuxMachine) EnableDistributing() {
 
 	if a != nil {
 
+		// port is distributing again before the marker transaction completed,
+		// it gets its held conversations back when it is re-added
+		if muxm.markerPending {
+			muxm.LacpMuxmLog(fmt.Sprintf("EnableDistributing while waiting on marker transaction %d", muxm.markerTransactionId))
+			muxm.markerPending = false
+			p.LacpPortConversationRelease()
+		}
+
 		// asicd expects the port list to be a bitmap in string format
 
 		a.DistributedPortNumList = append(a.DistributedPortNumList, p.IntfNum)
 		sort.Strings(a.DistributedPortNumList)
 
 		muxm.LacpMuxmLog(fmt.Sprintf("Agg %d hwAggId %d EnableDistributing PortsListLen %d PortList %v", p.AggId, a.HwAggId, len(a.DistributedPortNumList), a.DistributedPortNumList))
-		for _, client := range utils.GetAsicDPluginList() {
-			err := client.UpdateLag(a.HwAggId, asicDHashModeGet(a.LagHash), asicDPortBmpFormatGet(a.DistributedPortNumList))
-			if err != nil {
-				a.LacpAggLog(fmt.Sprintln("EnableDistributing: Error updating LAG in HW", err))
-			}
-		}
 
 		// notify DR that port has been created
 		for name, upcb := range LacpCbDb.PortUpDbList {
@@ -782,12 +981,8 @@ func (muxm *LacpMuxMachine) EnableDistributing() {
 			upcb(int32(p.PortNum))
 		}
 
-		if len(a.DistributedPortNumList) == 1 {
-			for name, upcb := range LacpCbDb.AggOperUpDbList {
-				a.LacpAggLog(fmt.Sprintf("Notify %s Agg OperState UP %s", name, a.AggName))
-				upcb(int32(a.AggId))
-			}
-		}
+		// programs HW and notifies Agg OperState UP once MinLinks is met
+		a.LacpAggOperStateUpdate()
 	}
 }
 
@@ -797,6 +992,76 @@ func (muxm *LacpMuxMachine) EnableDistributing() {
 // to which the Aggregation Port is attached to stop distributing frames
 // to the Aggregation Port.
 func (muxm *LacpMuxMachine) DisableDistributing() {
+	p := muxm.p
+	a := muxm.p.AggAttached
+
+	if a != nil {
+		// 802.1ax-2014 Section 6.5.1 the port stops distributing right away,
+		// the conversations it carried are only moved to the remaining ports
+		// once the partner has collected all frames already sent on this port
+		markerRequired := !muxm.markerPending &&
+			muxm.lampMarkerRequired(a)
+		if markerRequired {
+			p.LacpPortConversationHold()
+		}
+		muxm.disableDistributingComplete()
+
+		if markerRequired {
+			muxm.markerTransactionId = p.MarkerGenerator.LampMarkerGeneratorSend()
+			muxm.markerPending = true
+			muxm.LacpMuxmLog(fmt.Sprintf("Agg %d DisableDistributing conversations held until marker transaction %d completes", p.AggId, muxm.markerTransactionId))
+		} else if muxm.markerPending &&
+			!p.IsPortEnabled() {
+			// nothing more will be collected from the link
+			muxm.markerPending = false
+			p.LacpPortConversationRelease()
+			a.LacpAggConversationUpdate()
+		}
+	}
+}
+
+// lampMarkerRequired returns TRUE when conversations of the port will be
+// moved to other distributing ports and a marker can be sent to the partner.
+// Without conversation sensitive distribution frames are distributed by the
+// hash and there are no conversations to hold
+func (muxm *LacpMuxMachine) lampMarkerRequired(a *LaAggregator) bool {
+	p := muxm.p
+	if p.MarkerGenerator == nil ||
+		!a.LacpAggConversationSensitive() ||
+		!p.IsPortEnabled() ||
+		!p.lacpEnabled ||
+		LacpStateIsSet(p.ActorOper.State, LacpStateDefaultedBit) ||
+		len(LaSysGlobalTxCallbackListGet(p)) == 0 {
+		return false
+	}
+	portFound := false
+	for _, intf := range a.DistributedPortNumList {
+		if intf == p.IntfNum {
+			portFound = true
+		}
+	}
+	return portFound && len(a.DistributedPortNumList) > 1
+}
+
+// LampMarkerResponse a marker transaction has completed, or timed out, the
+// conversations held by the port can now be moved to the remaining ports
+func (muxm *LacpMuxMachine) LampMarkerResponse() {
+	p := muxm.p
+	if muxm.markerPending &&
+		!p.MarkerGenerator.LampMarkerGeneratorPending(muxm.markerTransactionId) {
+		muxm.LacpMuxmLog(fmt.Sprintf("Marker transaction %d complete, moving conversations", muxm.markerTransactionId))
+		muxm.markerPending = false
+		p.LacpPortConversationRelease()
+		var a *LaAggregator
+		if LaFindAggById(p.AggId, &a) {
+			a.LacpAggConversationUpdate()
+		}
+	}
+}
+
+// disableDistributingComplete removes the port from the distributing ports
+// of the aggregator
+func (muxm *LacpMuxMachine) disableDistributingComplete() {
 	var portFound bool
 	p := muxm.p
 	a := muxm.p.AggAttached
@@ -816,40 +1081,14 @@ func (muxm *LacpMuxMachine) DisableDistributing() {
 
 			muxm.LacpMuxmLog(fmt.Sprintf("Agg %d HwId %d DisableDistributing PortsListLen %d PortList %v", p.AggId, a.HwAggId, len(a.DistributedPortNumList), a.DistributedPortNumList))
 
-			for _, client := range utils.GetAsicDPluginList() {
-				err := client.UpdateLag(a.HwAggId, asicDHashModeGet(a.LagHash), asicDPortBmpFormatGet(a.DistributedPortNumList))
-				if err != nil {
-					muxm.LacpMuxmLog(fmt.Sprintln("ERROR Updating Lag in HW", err))
-					return
-				}
-			}
-
-			if len(a.DistributedPortNumList) == 0 {
-				for name, upcb := range LacpCbDb.AggOperUpDbList {
-					a.LacpAggLog(fmt.Sprintf("Notify %s Agg OperState DOWN %s\n", name, a.AggName))
-					upcb(int32(a.AggId))
-				}
-			}
+			// withdraws the lag from HW and notifies Agg OperState DOWN
+			// when the remaining ports no longer satisfy MinLinks
+			a.LacpAggOperStateUpdate()
 
 			// notify DR that port has been created
 			for _, downcb := range LacpCbDb.PortDownDbList {
 				downcb(int32(p.PortNum))
 			}
-
-			if len(a.DistributedPortNumList) == 0 {
-				muxm.LacpMuxmLog("Sending Lag Delete to ASICD")
-				for _, client := range utils.GetAsicDPluginList() {
-					err := client.DeleteLag(a.HwAggId)
-					if err != nil {
-						muxm.LacpMuxmLog(fmt.Sprintln("ERROR Deleting Lag in HW", err))
-						return
-					}
-				}
-				a.HwAggId = 0
-				// no more ports active in group, lets mark the lag as operationally down
-				a.OperState = false
-				// TODO UPDATE SQL DB
-			}
 		}
 	}
 }
@@ -861,8 +1100,8 @@ func (muxm *LacpMuxMachine) DisableDistributing() {
 // Aggregation Port, and the Aggregator Multiplexer to start distributing
 // frames to the Aggregation Port.
 func (muxm *LacpMuxMachine) EnableCollectingDistributing() {
-	// TODO send message to asic deamon
-	muxm.LacpMuxmLog("Sending Collection-Distributing Enable to ASICD")
+	muxm.EnableCollecting()
+	muxm.EnableDistributing()
 }
 
 // DisableCollectingDistributing is a required function defined in 802.1ax-2014
@@ -872,6 +1111,6 @@ func (muxm *LacpMuxMachine) EnableCollectingDistributing() {
 // Port, and the Aggregator Multiplexer to stop distributing frames to the
 // Aggregation Port.
 func (muxm *LacpMuxMachine) DisableCollectingDistributing() {
-	// TODO send message to asic deamon
-	muxm.LacpMuxmLog("Sending Collection-Distributing Disable to ASICD")
+	muxm.DisableDistributing()
+	muxm.DisableCollecting()
 }
@@ -0,0 +1,439 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package lacp
+
+import (
+	"github.com/google/gopacket"
+	"l2/lacp/protocol/utils"
+	"net"
+	"testing"
+	"time"
+	"utils/fsm"
+)
+
+type MuxCollectingMockAsicdClientMgr struct {
+	MinLinksMockAsicdClientMgr
+	collectingPorts string
+}
+
+func (m *MuxCollectingMockAsicdClientMgr) UpdateLagCollecting(ifIndex int32, ports string) error {
+	m.collectingPorts = ports
+	return nil
+}
+
+func MuxMachineTestSetup(coupled bool) (*LaAggregator, *LaAggPort, *MuxCollectingMockAsicdClientMgr) {
+	a, ports := SelectionTestSetup(0)
+
+	mock := &MuxCollectingMockAsicdClientMgr{}
+	utils.DeleteAllAsicDPlugins()
+	utils.SetAsicDPlugin(mock)
+
+	p := ports[0]
+	p.MuxMachineFsm = nil
+	p.LacpMuxMachineFSMBuild()
+	LacpTxMachineFSMBuild(p).Machine.Start(LacpTxmStateOff)
+	LacpActorCdMachineFSMBuild(p)
+	p.MuxMachineFsm.LacpMuxmCouplingSet(coupled)
+	return a, p, mock
+}
+
+func MuxMachineTestTeardown(a *LaAggregator, p *LaAggPort) {
+	p.TxMachineFsm = nil
+	p.CdMachineFsm = nil
+	p.AggAttached = nil
+	ports := make([]*LaAggPort, 0)
+	for _, pId := range a.PortNumList {
+		var aggport *LaAggPort
+		if LaFindPortById(pId, &aggport) {
+			ports = append(ports, aggport)
+		}
+	}
+	SelectionTestTeardown(a, ports)
+	utils.DeleteAllAsicDPlugins()
+}
+
+func MuxMachineTestEvent(t *testing.T, muxm *LacpMuxMachine, e fsm.Event, expected fsm.State) {
+	if err := muxm.Machine.ProcessEvent(MuxMachineModuleStr, e, nil); err != nil {
+		t.Error("Event", MuxmEventStrMap[int(e)], "failed", err)
+	}
+	if muxm.Machine.Curr.CurrentState() != expected {
+		t.Error("Mux State expected", MuxmStateStrMap[expected], "actual", MuxmStateStrMap[muxm.Machine.Curr.CurrentState()])
+	}
+}
+
+func TestLacpMuxCouplingGet(t *testing.T) {
+	a, ports := SelectionTestSetup(0)
+	defer SelectionTestTeardown(a, ports)
+
+	sysId := LacpSystem{Actor_System_priority: 128,
+		Actor_System: [6]uint8{0x00, 0x00, 0x00, 0x00, 0x00, 0x64}}
+	p := ports[0]
+
+	if p.LacpMuxCouplingGet() {
+		t.Error("Expected independent control by default")
+	}
+
+	// aggregator follows the system
+	SetLacpSysMuxCoupling(sysId, true)
+	if !p.LacpMuxCouplingGet() {
+		t.Error("Expected coupled control from the system")
+	}
+	for _, aggport := range ports {
+		select {
+		case evt := <-aggport.MuxMachineFsm.MuxmEvents:
+			if evt.E != LacpMuxmEventBegin {
+				t.Error("Expected mux begin event actual", evt.E)
+			}
+		default:
+			t.Error("Mux machine not restarted on system mux coupling change", aggport.PortNum)
+		}
+	}
+
+	// aggregator takes precedence over the system
+	if err := SetLaAggMuxControl(a.AggId, LaAggMuxControlIndependent); err != nil {
+		t.Error("Unexpected error setting mux control", err)
+	}
+	if p.LacpMuxCouplingGet() {
+		t.Error("Expected independent control from the aggregator")
+	}
+	SetLacpSysMuxCoupling(sysId, false)
+	if err := SetLaAggMuxControl(a.AggId, LaAggMuxControlCoupled); err != nil {
+		t.Error("Unexpected error setting mux control", err)
+	}
+	if !p.LacpMuxCouplingGet() {
+		t.Error("Expected coupled control from the aggregator")
+	}
+
+	if err := SetLaAggMuxControl(a.AggId, LaAggMuxControlMax); err == nil ||
+		a.AggMuxControl != LaAggMuxControlCoupled {
+		t.Error("Expected invalid mux control to be rejected")
+	}
+	if err := SetLaAggMuxControl(a.AggId+1, LaAggMuxControlCoupled); err == nil {
+		t.Error("Expected error for unknown aggregator")
+	}
+}
+
+func TestLacpSysMuxControl(t *testing.T) {
+	a, ports := SelectionTestSetup(0)
+	defer SelectionTestTeardown(a, ports)
+	defer func() {
+		LacpSysMuxControl = LaAggMuxControlSystem
+	}()
+
+	p := ports[0]
+	sysId := LacpSystem{Actor_System_priority: 128,
+		Actor_System: [6]uint8{0x00, 0x00, 0x00, 0x00, 0x00, 0x64}}
+
+	for _, tc := range []struct {
+		muxControl uint8
+		coupled    bool
+	}{
+		{LaAggMuxControlCoupled, true},
+		{LaAggMuxControlIndependent, false},
+		{LaAggMuxControlCoupled, true},
+		{LaAggMuxControlSystem, LacpMuxCouplingDefault},
+	} {
+		if err := SetLacpSysMuxControl(tc.muxControl); err != nil {
+			t.Error("Unexpected error setting system mux control", tc.muxControl, err)
+		}
+		if p.LacpMuxCouplingGet() != tc.coupled ||
+			LacpSysGlobalInfoByIdGet(sysId).muxCoupling != tc.coupled ||
+			lacpSysMuxCouplingDefaultGet() != tc.coupled {
+			t.Error("Expected coupled", tc.coupled, "for system mux control", tc.muxControl)
+		}
+		// only a machine running the other control is restarted
+		for _, aggport := range ports {
+			select {
+			case evt := <-aggport.MuxMachineFsm.MuxmEvents:
+				if evt.E != LacpMuxmEventBegin ||
+					aggport.MuxMachineFsm.collDistCoupled == tc.coupled {
+					t.Error("Unexpected mux event", evt.E, "on system mux control change", aggport.PortNum)
+				}
+			default:
+				if aggport.MuxMachineFsm.collDistCoupled != tc.coupled {
+					t.Error("Mux machine not restarted on system mux control change", aggport.PortNum)
+				}
+			}
+		}
+	}
+
+	// aggregator mux control is not changed by the system
+	if err := SetLaAggMuxControl(a.AggId, LaAggMuxControlIndependent); err != nil {
+		t.Error("Unexpected error setting mux control", err)
+	}
+	if err := SetLacpSysMuxControl(LaAggMuxControlCoupled); err != nil {
+		t.Error("Unexpected error setting system mux control", err)
+	}
+	if p.LacpMuxCouplingGet() {
+		t.Error("Expected independent control from the aggregator")
+	}
+
+	if err := SetLacpSysMuxControl(LaAggMuxControlMax); err == nil ||
+		LacpSysMuxControl != LaAggMuxControlCoupled {
+		t.Error("Expected invalid system mux control to be rejected")
+	}
+}
+
+func TestLacpMuxCoupledControl(t *testing.T) {
+	a, p, mock := MuxMachineTestSetup(true)
+	defer MuxMachineTestTeardown(a, p)
+	muxm := p.MuxMachineFsm
+
+	if muxm.Machine.Curr.CurrentState() != LacpMuxmStateCNone {
+		t.Error("Mux State expected", MuxmStateStrMap[LacpMuxmStateCNone], "actual", MuxmStateStrMap[muxm.Machine.Curr.CurrentState()])
+	}
+	MuxMachineTestEvent(t, muxm, LacpMuxmEventBegin, LacpMuxmStateCDetached)
+	MuxMachineTestEvent(t, muxm, LacpMuxmEventSelectedEqualSelected, LacpMuxmStateCWaiting)
+	MuxMachineTestEvent(t, muxm, LacpMuxmEventSelectedEqualSelectedAndReady, LacpMuxmStateCAttached)
+	if !LacpStateIsSet(p.ActorOper.State, LacpStateSyncBit) ||
+		LacpStateIsSet(p.ActorOper.State, LacpStateCollectingBit|LacpStateDistributingBit) {
+		t.Error("Invalid Actor Oper State in ATTACHED", p.ActorOper.State)
+	}
+
+	// collection and distribution are enabled together
+	MuxMachineTestEvent(t, muxm, LacpMuxmEventSelectedEqualSelectedAndPartnerSync, LacpMuxStateCCollectingDistributing)
+	if !LacpStateIsSet(p.ActorOper.State, LacpStateCollectingBit) ||
+		!LacpStateIsSet(p.ActorOper.State, LacpStateDistributingBit) {
+		t.Error("Expected Actor Collecting and Distributing in COLLECTING_DISTRIBUTING", p.ActorOper.State)
+	}
+	if len(a.CollectingPortNumList) != 1 ||
+		len(a.DistributedPortNumList) != 1 ||
+		mock.collectingPorts != asicDPortBmpFormatGet([]string{p.IntfNum}) ||
+		mock.ports != asicDPortBmpFormatGet([]string{p.IntfNum}) {
+		t.Error("Port not collecting and distributing", a.CollectingPortNumList, a.DistributedPortNumList)
+	}
+
+	MuxMachineTestEvent(t, muxm, LacpMuxmEventNotPartnerSync, LacpMuxmStateCAttached)
+	if LacpStateIsSet(p.ActorOper.State, LacpStateCollectingBit|LacpStateDistributingBit) ||
+		len(a.CollectingPortNumList) != 0 ||
+		len(a.DistributedPortNumList) != 0 ||
+		mock.collectingPorts != "" {
+		t.Error("Port still collecting or distributing", p.ActorOper.State, a.CollectingPortNumList, a.DistributedPortNumList)
+	}
+
+	MuxMachineTestEvent(t, muxm, LacpMuxmEventSelectedEqualUnselected, LacpMuxmStateCDetached)
+	if LacpStateIsSet(p.ActorOper.State, LacpStateSyncBit) {
+		t.Error("Expected Actor Sync to be cleared in DETACHED")
+	}
+}
+
+func TestLacpMuxIndependentControl(t *testing.T) {
+	a, p, mock := MuxMachineTestSetup(false)
+	defer MuxMachineTestTeardown(a, p)
+	muxm := p.MuxMachineFsm
+
+	MuxMachineTestEvent(t, muxm, LacpMuxmEventBegin, LacpMuxmStateDetached)
+	MuxMachineTestEvent(t, muxm, LacpMuxmEventSelectedEqualSelected, LacpMuxmStateWaiting)
+	MuxMachineTestEvent(t, muxm, LacpMuxmEventSelectedEqualSelectedAndReady, LacpMuxmStateAttached)
+
+	// collecting without distributing
+	MuxMachineTestEvent(t, muxm, LacpMuxmEventSelectedEqualSelectedAndPartnerSync, LacpMuxmStateCollecting)
+	if len(a.CollectingPortNumList) != 1 ||
+		len(a.DistributedPortNumList) != 0 ||
+		mock.collectingPorts != asicDPortBmpFormatGet([]string{p.IntfNum}) {
+		t.Error("Port should only be collecting", a.CollectingPortNumList, a.DistributedPortNumList)
+	}
+
+	MuxMachineTestEvent(t, muxm, LacpMuxmEventSelectedEqualSelectedPartnerSyncCollecting, LacpMuxmStateDistributing)
+	if len(a.CollectingPortNumList) != 1 ||
+		len(a.DistributedPortNumList) != 1 {
+		t.Error("Port should be collecting and distributing", a.CollectingPortNumList, a.DistributedPortNumList)
+	}
+
+	// distribution is disabled, collection continues
+	MuxMachineTestEvent(t, muxm, LacpMuxmEventNotPartnerCollecting, LacpMuxmStateCollecting)
+	if len(a.CollectingPortNumList) != 1 ||
+		len(a.DistributedPortNumList) != 0 {
+		t.Error("Port should only be collecting", a.CollectingPortNumList, a.DistributedPortNumList)
+	}
+
+	MuxMachineTestEvent(t, muxm, LacpMuxmEventSelectedEqualUnselected, LacpMuxmStateAttached)
+	if len(a.CollectingPortNumList) != 0 ||
+		mock.collectingPorts != "" {
+		t.Error("Port still collecting", a.CollectingPortNumList)
+	}
+
+	// switching to coupled control restarts the machine
+	muxm.LacpMuxmCouplingSet(true)
+	if muxm.Machine.Curr.CurrentState() != LacpMuxmStateCNone {
+		t.Error("Mux State expected", MuxmStateStrMap[LacpMuxmStateCNone], "actual", MuxmStateStrMap[muxm.Machine.Curr.CurrentState()])
+	}
+	MuxMachineTestEvent(t, muxm, LacpMuxmEventBegin, LacpMuxmStateCDetached)
+
+	// a detached port moves directly between the machines
+	muxm.LacpMuxmCouplingSet(false)
+	if muxm.Machine.Curr.CurrentState() != LacpMuxmStateDetached {
+		t.Error("Mux State expected", MuxmStateStrMap[LacpMuxmStateDetached], "actual", MuxmStateStrMap[muxm.Machine.Curr.CurrentState()])
+	}
+	MuxMachineTestEvent(t, muxm, LacpMuxmEventSelectedEqualSelected, LacpMuxmStateWaiting)
+}
+
+// TestTwoAggsBackToBackSinglePortCoupled ports are created before the
+// aggregators, the coupled control is taken from the aggregator once the
+// port is selected
+func TestTwoAggsBackToBackSinglePortCoupled(t *testing.T) {
+	defer MemoryCheck(t)
+	const LaAggPortActor = 10
+	const LaAggPortPeer = 20
+	LaAggPortActorIf := "SIMeth0"
+	LaAggPortPeerIf := "SIM2eth0"
+	OnlyForTestSetup()
+	utils.PortConfigMap[LaAggPortActor] = utils.PortConfig{Name: LaAggPortActorIf,
+		HardwareAddr: net.HardwareAddr{0x00, 0x11, 0x11, 0x22, 0x22, 0x33},
+	}
+	utils.PortConfigMap[LaAggPortPeer] = utils.PortConfig{Name: LaAggPortPeerIf,
+		HardwareAddr: net.HardwareAddr{0x00, 0x44, 0x44, 0x22, 0x22, 0x33},
+	}
+
+	LaSystemActor := LacpSystem{Actor_System_priority: 128,
+		Actor_System: [6]uint8{0x00, 0x00, 0x00, 0x00, 0x00, 0x64}}
+	LaSystemPeer := LacpSystem{Actor_System_priority: 128,
+		Actor_System: [6]uint8{0x00, 0x00, 0x00, 0x00, 0x00, 0xC8}}
+
+	bridge := SimulationBridge{
+		Port1:       LaAggPortActor,
+		Port2:       LaAggPortPeer,
+		RxLacpPort1: make(chan gopacket.Packet, 10),
+		RxLacpPort2: make(chan gopacket.Packet, 10),
+	}
+
+	ActorSystem := LacpSysGlobalInfoInit(LaSystemActor)
+	PeerSystem := LacpSysGlobalInfoInit(LaSystemPeer)
+	ActorSystem.LaSysGlobalRegisterTxCallback(LaAggPortActorIf, bridge.TxViaGoChannel)
+	PeerSystem.LaSysGlobalRegisterTxCallback(LaAggPortPeerIf, bridge.TxViaGoChannel)
+
+	p1conf := &LaAggPortConfig{
+		Id:     LaAggPortActor,
+		Prio:   0x80,
+		Key:    100,
+		AggId:  100,
+		Enable: true,
+		Mode:   LacpModeActive,
+		Properties: PortProperties{
+			Mac:    net.HardwareAddr{0x00, LaAggPortActor, 0xDE, 0xAD, 0xBE, 0xEF},
+			Speed:  1000000000,
+			Duplex: LacpPortDuplexFull,
+			Mtu:    1500,
+		},
+		IntfId:   LaAggPortActorIf,
+		TraceEna: false,
+	}
+
+	p2conf := &LaAggPortConfig{
+		Id:     LaAggPortPeer,
+		Prio:   0x80,
+		Key:    200,
+		AggId:  200,
+		Enable: true,
+		Mode:   LacpModeActive,
+		Properties: PortProperties{
+			Mac:    net.HardwareAddr{0x00, LaAggPortPeer, 0xDE, 0xAD, 0xBE, 0xEF},
+			Speed:  1000000000,
+			Duplex: LacpPortDuplexFull,
+			Mtu:    1500,
+		},
+		IntfId:   LaAggPortPeerIf,
+		TraceEna: false,
+	}
+
+	CreateLaAggPort(p1conf)
+	CreateLaAggPort(p2conf)
+
+	LaRxMain(bridge.Port1, bridge.RxLacpPort1)
+	LaRxMain(bridge.Port2, bridge.RxLacpPort2)
+
+	a1conf := &LaAggConfig{
+		Name:       "agg1",
+		Mac:        [6]uint8{0x00, 0x00, 0x01, 0x01, 0x01, 0x01},
+		Id:         100,
+		Key:        100,
+		MuxControl: LaAggMuxControlCoupled,
+		Lacp: LacpConfigInfo{Interval: LacpSlowPeriodicTime,
+			Mode:           LacpModeActive,
+			SystemIdMac:    "00:00:00:00:00:64",
+			SystemPriority: 128},
+	}
+
+	a2conf := &LaAggConfig{
+		Name:       "agg2",
+		Mac:        [6]uint8{0x00, 0x00, 0x02, 0x02, 0x02, 0x02},
+		Id:         200,
+		Key:        200,
+		MuxControl: LaAggMuxControlCoupled,
+		Lacp: LacpConfigInfo{Interval: LacpSlowPeriodicTime,
+			Mode:           LacpModeActive,
+			SystemIdMac:    "00:00:00:00:00:C8",
+			SystemPriority: 128},
+	}
+
+	CreateLaAgg(a1conf)
+	CreateLaAgg(a2conf)
+
+	var p1 *LaAggPort
+	var p2 *LaAggPort
+	if LaFindPortById(p1conf.Id, &p1) &&
+		LaFindPortById(p2conf.Id, &p2) {
+
+		for i := 0; i < 10 &&
+			(p1.MuxMachineFsm.Machine.Curr.CurrentState() != LacpMuxStateCCollectingDistributing ||
+				p2.MuxMachineFsm.Machine.Curr.CurrentState() != LacpMuxStateCCollectingDistributing); i++ {
+			time.Sleep(time.Second * 1)
+		}
+
+		for _, p := range []*LaAggPort{p1, p2} {
+			if p.MuxMachineFsm.Machine.Curr.CurrentState() != LacpMuxStateCCollectingDistributing {
+				t.Error("Mux State expected", MuxmStateStrMap[LacpMuxStateCCollectingDistributing], "actual", MuxmStateStrMap[p.MuxMachineFsm.Machine.Curr.CurrentState()])
+			}
+
+			const portUpState = LacpStateActivityBit | LacpStateAggregationBit |
+				LacpStateSyncBit | LacpStateCollectingBit | LacpStateDistributingBit
+			if State := GetLaAggPortActorOperState(p.PortNum); !LacpStateIsSet(State, portUpState) {
+				t.Errorf("Port %d State 0x%x did not come up properly expected 0x%x", p.PortNum, State, portUpState)
+			}
+			if !p.AggAttached.OperState {
+				t.Error("OperState not UP as expected", p.AggAttached.AggName)
+			}
+		}
+	} else {
+		t.Error("Unable to find port just created")
+	}
+
+	close(bridge.RxLacpPort1)
+	close(bridge.RxLacpPort2)
+	bridge.RxLacpPort1 = nil
+	bridge.RxLacpPort2 = nil
+	DeleteLaAgg(a1conf.Id)
+	DeleteLaAgg(a2conf.Id)
+	for _, sgi := range LacpSysGlobalInfoGet() {
+		if len(sgi.AggList) > 0 || len(sgi.AggMap) > 0 {
+			t.Error("System Agg List or Map is not empty", sgi.AggList, sgi.AggMap)
+		}
+		if len(sgi.PortList) > 0 || len(sgi.PortMap) > 0 {
+			t.Error("System Port List or Map is not empty", sgi.PortList, sgi.PortMap)
+		}
+	}
+	OnlyForTestTeardown()
+	LacpSysGlobalInfoDestroy(LaSystemActor)
+	LacpSysGlobalInfoDestroy(LaSystemPeer)
+}
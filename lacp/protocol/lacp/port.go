@@ -192,6 +192,10 @@ type AggPortStatsObject struct {
 	AggPortStatsMarkerPDUsTx         uint64
 	AggPortStatsMarkerResponsePDUsTx uint64
 	AggPortStateMissMatchInfoRx      uint64
+	// Marker Responses not received within LampMarkerResponseTimeout
+	AggPortStatsMarkerResponseTimeouts uint64
+	// Marker Responses which did not match an outstanding Marker
+	AggPortStatsMarkerResponseUnexpectedRx uint64
 }
 
 //GET
@@ -280,6 +284,7 @@ type LaAggPort struct {
 	PCdMachineFsm      *LacpPartnerCdMachine
 	MuxMachineFsm      *LacpMuxMachine
 	MarkerResponderFsm *LampMarkerResponderMachine
+	MarkerGenerator    *LampMarkerGenerator
 
 	// Counters
 	LacpCounter AggPortStatsObject
@@ -306,6 +311,22 @@ type LaAggPort struct {
 	// packet is 1 byte, but spec says save as int.
 	// going to save as byte
 	partnerVersion uint8
+	// aAggPortLinkNumberID, 0 means the port number is used
+	LinkNumberId uint16
+	// version 2 TLVs last received from the partner, nil when the partner
+	// is defaulted or runs version 1
+	partnerV2                 *LacpPduV2Info
+	differPortAlgorithms      bool
+	differConversationDigests bool
+	differServiceDigests      bool
+	// Port_Oper_Conversation_Mask
+	operConversationMask LacpConversationMask
+	// ActPar_Sync, partner mask agrees with ours
+	actParSync bool
+	// the port stopped distributing and is waiting on a marker transaction,
+	// the conversations it carried are not given to another port meanwhile
+	conversationHeld     bool
+	heldConversationMask LacpConversationMask
 
 	sysId net.HardwareAddr
 }
@@ -426,8 +447,11 @@ func NewLaAggPort(config *LaAggPortConfig) *LaAggPort {
 		portChan:     make(chan string),
 		AggPortDebug: AggPortDebugInformationObject{AggPortDebugInformationID: int(config.Id)},
 		DrniName:     "",
+		LinkNumberId: config.LinkNumberId,
 	}
 
+	NewLampMarkerGenerator(p)
+
 	// register the events
 	utils.CreateEventMap(int32(p.PortNum))
 	utils.ProcessLacpPortOperStateDown(int32(p.PortNum))
@@ -576,6 +600,12 @@ func (p *LaAggPort) IsPortOperStatusUp() bool {
 	return p.LinkOperStatus
 }
 
+// IsPortStandby is true when the port has been put in standby because
+// the aggregator MaxLinks higher priority ports are selected
+func (p *LaAggPort) IsPortStandby() bool {
+	return p.aggSelected == LacpAggStandby
+}
+
 // IsPortEnabled will check if port is admin enabled
 // and link is operationally up
 func (p *LaAggPort) IsPortEnabled() bool {
@@ -636,6 +666,11 @@ func (p *LaAggPort) Stop() {
 		p.PCdMachineFsm.Stop()
 	}
 
+	// outstanding markers must not inform a stopped mux machine
+	if p.MarkerGenerator != nil {
+		p.MarkerGenerator.Stop()
+	}
+
 	if p.MuxMachineFsm != nil {
 		p.MuxMachineFsm.Stop()
 	}
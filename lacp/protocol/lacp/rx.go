@@ -67,7 +67,14 @@ func LaRxMain(pId uint16, rxPktChan chan gopacket.Packet) {
 								// lacp data
 								lacp := lacpLayer.(*layers.LACP)
 
-								ProcessLacpFrame(rxMainPort, lacp)
+								// version 2 TLVs follow the Collector TLV
+								var v2 *LacpPduV2Info
+								if lacp.Version >= layers.LACPVersion2 {
+									if ethernetLayer := packet.Layer(layers.LayerTypeEthernet); ethernetLayer != nil {
+										v2 = LacpPduV2FrameParse(ethernetLayer.LayerPayload())
+									}
+								}
+								ProcessLacpV2Frame(rxMainPort, lacp, v2)
 							}
 						} else if marker {
 							lampLayer := packet.Layer(layers.LayerTypeLAMP)
@@ -156,6 +163,12 @@ func IsControlFrame(pId uint16, packet gopacket.Packet) (bool, bool) {
 // ProcessLacpFrame will lookup the cooresponding port from which the
 // packet arrived and forward the packet to the Rx Machine for processing
 func ProcessLacpFrame(pId uint16, lacp *layers.LACP) {
+	ProcessLacpV2Frame(pId, lacp, nil)
+}
+
+// ProcessLacpV2Frame is the same as ProcessLacpFrame but also carries the
+// version 2 TLVs found in the packet
+func ProcessLacpV2Frame(pId uint16, lacp *layers.LACP, v2 *LacpPduV2Info) {
 	var p *LaAggPort
 
 	//fmt.Println(lacp)
@@ -165,6 +178,7 @@ func ProcessLacpFrame(pId uint16, lacp *layers.LACP) {
 		if p.RxMachineFsm != nil {
 			p.RxMachineFsm.RxmPktRxEvent <- LacpRxLacpPdu{
 				pdu: lacp,
+				v2:  v2,
 				src: RxModuleStr}
 		}
 	}
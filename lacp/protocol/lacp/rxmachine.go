@@ -77,7 +77,9 @@ const (
 )
 
 type LacpRxLacpPdu struct {
-	pdu          *layers.LACP
+	pdu *layers.LACP
+	// version 2 TLVs, nil for a version 1 pdu
+	v2           *LacpPduV2Info
 	src          string
 	responseChan chan string
 }
@@ -98,6 +100,9 @@ type LacpRxMachine struct {
 	// timers
 	currentWhileTimer *time.Timer
 
+	// version 2 TLVs of the pdu being processed
+	v2PduInfo *LacpPduV2Info
+
 	// machine specific events
 	RxmEvents         chan utils.MachineEvent
 	RxmPktRxEvent     chan LacpRxLacpPdu
@@ -329,13 +334,17 @@ func (rxm *LacpRxMachine) LacpRxMachineCurrent(m fsm.Machine, data interface{})
 	ntt := rxm.updateNTT(lacpPduInfo)
 
 	// Version 2 or higher check
-	if LacpActorSystemLacpVersion >= 0x2 {
+	if p.LacpActorVersionGet() >= 0x2 {
 		rxm.recordVersionNumber(lacpPduInfo)
 	}
 
 	// record the current packet State
 	rxm.recordPDU(lacpPduInfo)
 
+	if p.LacpActorVersionGet() >= 0x2 {
+		rxm.recordV2PDU(rxm.v2PduInfo)
+	}
+
 	//rxm.LacpRxmLog(fmt.Sprintf("Partner Oper %#v", p.PartnerOper))
 
 	// Current while should already be set to
@@ -398,7 +407,8 @@ func (rxm *LacpRxMachine) InformMachinesOfStateChanges() {
 			}
 		} else if !LacpStateIsSet(p.PartnerOper.State, LacpStateSyncBit) &&
 			(p.MuxMachineFsm.Machine.Curr.CurrentState() == LacpMuxmStateDistributing ||
-				p.MuxMachineFsm.Machine.Curr.CurrentState() == LacpMuxmStateCollecting) {
+				p.MuxMachineFsm.Machine.Curr.CurrentState() == LacpMuxmStateCollecting ||
+				p.MuxMachineFsm.Machine.Curr.CurrentState() == LacpMuxStateCCollectingDistributing) {
 			p.MuxMachineFsm.MuxmEvents <- utils.MachineEvent{
 				E:   LacpMuxmEventNotPartnerSync,
 				Src: RxMachineModuleStr}
@@ -583,6 +593,7 @@ func (p *LaAggPort) LacpRxMachineMain() {
 						// Expired/Defaulted/Current. each
 						// State will transition to current
 						// all other States should be ignored.
+						m.v2PduInfo = rx.v2
 						m.Machine.ProcessEvent(RxModuleStr, LacpRxmEventLacpPktRx, rx.pdu)
 					}
 
@@ -713,6 +724,11 @@ func (rxm *LacpRxMachine) recordDefault() {
 	p := rxm.p
 
 	LacpCopyLacpPortInfo(&p.partnerAdmin, &p.PartnerOper)
+	// 802.1ax-2014 Section 6.4.9 partner defaults to version 1
+	p.partnerVersion = 0x1
+	p.partnerLacpPduVersionNumber = 1
+	p.enableLongPduXmit = false
+	rxm.recordV2PDU(nil)
 	//rxm.LacpRxmLog("Setting Actor Defaulted Bit")
 	LacpStateSet(&p.ActorOper.State, LacpStateDefaultedBit)
 	if !LacpStateIsSet(p.PartnerOper.State, LacpStateSyncBit) {
@@ -778,6 +794,33 @@ func (rxm *LacpRxMachine) recordVersionNumber(lacpPduInfo *layers.LACP) {
 	p := rxm.p
 
 	p.partnerVersion = uint8(lacpPduInfo.Version)
+	p.partnerLacpPduVersionNumber = int(p.partnerVersion)
+	// 802.1ax-2014 Section 6.4.7 long LACPDUs are only sent when both
+	// ends run version 2 or higher
+	p.enableLongPduXmit = p.partnerVersion >= 0x2
+}
+
+// recordV2PDU: 802.1ax-2014 Section 6.4.9
+//
+// record the partner's version 2 TLVs and re-evaluate the conversation
+// assignment of the aggregator when they change
+func (rxm *LacpRxMachine) recordV2PDU(info *LacpPduV2Info) {
+
+	p := rxm.p
+
+	// the partner information is read by the conversation update of
+	// the other ports of the aggregator
+	conversationMutex.Lock()
+	defer conversationMutex.Unlock()
+
+	if (p.partnerV2 == nil && info == nil) ||
+		(p.partnerV2 != nil && info != nil && *p.partnerV2 == *info) {
+		return
+	}
+	p.partnerV2 = info
+	if p.AggAttached != nil {
+		p.AggAttached.lacpAggConversationUpdate()
+	}
 }
 
 // currentWhileTimerValid checks the State against
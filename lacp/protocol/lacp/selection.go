@@ -25,8 +25,10 @@
 package lacp
 
 import (
+	"bytes"
 	"fmt"
 	"l2/lacp/protocol/utils"
+	"sort"
 	"sync"
 
 	"github.com/google/gopacket/layers"
@@ -160,7 +162,8 @@ func (a *LaAggregator) LacpMuxCheckSelectionLogic(p *LaAggPort, sendResponse boo
 				var port *LaAggPort
 				p.MuxMachineFsm.LacpMuxmLog(fmt.Sprintf("LacpMuxCheckSelectionLogic: looking for port %d", id))
 				if LaFindPortById(id, &port) &&
-					port.readyN {
+					port.readyN &&
+					port.aggSelected == LacpAggSelected {
 					// trigger event to mux
 					// event should be defered in the processing
 					port.MuxMachineFsm.Machine.ProcessEvent(MuxMachineModuleStr, LacpMuxmEventSelectedEqualSelectedAndReady, nil)
@@ -173,6 +176,91 @@ func (a *LaAggregator) LacpMuxCheckSelectionLogic(p *LaAggPort, sendResponse boo
 	}
 }
 
+// lacpAggSelectionPortId 802.1ax-2014 Section 6.7.1
+// The System with the numerically lower System Identifier is the controlling
+// System, and its Port Identifier (priority and number) for the link is used
+// to determine which Aggregation Ports are selected vs put in standby
+func (p *LaAggPort) lacpAggSelectionPortId() [4]uint8 {
+	portInfo := &p.ActorOper
+	actorSysId := LacpSystemIdGet(p.ActorOper.System)
+	partnerSysId := LacpSystemIdGet(p.PartnerOper.System)
+	if p.PartnerOper.port != 0 &&
+		bytes.Compare(partnerSysId[:], actorSysId[:]) < 0 {
+		portInfo = &p.PartnerOper
+	}
+	return [4]uint8{uint8(portInfo.Port_pri >> 8), uint8(portInfo.Port_pri),
+		uint8(portInfo.port >> 8), uint8(portInfo.port)}
+}
+
+// lacpAggSelectionCandidates returns the ports of the aggregator which are
+// eligible for selection ordered by 802.1ax-2014 Section 6.7.1 priority,
+// port p is always considered a candidate
+func (a *LaAggregator) lacpAggSelectionCandidates(p *LaAggPort) []*LaAggPort {
+	candidates := make([]*LaAggPort, 0)
+	for _, pId := range a.PortNumList {
+		var port *LaAggPort
+		if LaFindPortById(pId, &port) {
+			if port == p ||
+				(port.aggSelected != LacpAggUnSelected &&
+					port.IsPortEnabled()) {
+				candidates = append(candidates, port)
+			}
+		}
+	}
+	sort.Stable(sortPortSelectionPriority(candidates))
+	return candidates
+}
+
+// sortPortSelectionPriority orders ports highest selection priority first
+type sortPortSelectionPriority []*LaAggPort
+
+func (s sortPortSelectionPriority) Len() int      { return len(s) }
+func (s sortPortSelectionPriority) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s sortPortSelectionPriority) Less(i, j int) bool {
+	iId := s[i].lacpAggSelectionPortId()
+	jId := s[j].lacpAggSelectionPortId()
+	return bytes.Compare(iId[:], jId[:]) < 0
+}
+
+// LacpAggSelectionGet will return whether port p should be SELECTED or
+// STANDBY. When MaxLinks is configured only the MaxLinks highest priority
+// ports are selected, the remaining ports are put in standby 802.1ax-2014
+// Section 6.7.1
+func (a *LaAggregator) LacpAggSelectionGet(p *LaAggPort) int {
+	if a.AggMaxLinks == 0 {
+		return LacpAggSelected
+	}
+	for i, port := range a.lacpAggSelectionCandidates(p) {
+		if port == p &&
+			i >= int(a.AggMaxLinks) {
+			return LacpAggStandby
+		}
+	}
+	return LacpAggSelected
+}
+
+// LacpAggSelectionUpdate will inform the other ports of the aggregator that
+// the selected and standby ports need to be re-evaluated.  Each port's mux
+// machine recomputes its own selection, which will promote a standby port
+// when an active member fails or demote the lowest priority port when a
+// higher priority port joins.  Port skip is the caller and is expected to
+// handle its own selection
+func (a *LaAggregator) LacpAggSelectionUpdate(skip *LaAggPort) {
+	for _, pId := range a.PortNumList {
+		var port *LaAggPort
+		if !LaFindPortById(pId, &port) ||
+			port == skip ||
+			port.MuxMachineFsm == nil {
+			continue
+		}
+		select {
+		case port.MuxMachineFsm.MuxmReselectEvent <- true:
+		default:
+			// reselect already pending
+		}
+	}
+}
+
 // updateSelected:  802.1ax Section 6.4.9
 // Sets the value of the Selected variable based on the following:
 //
@@ -318,22 +406,31 @@ func (p *LaAggPort) checkConfigForSelection() bool {
 				(p.DrniName == "" ||
 					p.DrniName != "" && p.DrniSynced) {
 
-				p.LaPortLog("checkConfigForSelection: selected")
-
-				// set port as selected
-				p.aggSelected = LacpAggSelected
+				// set port as selected, or standby when MaxLinks
+				// higher priority ports are already selected
+				p.aggSelected = a.LacpAggSelectionGet(p)
 				LacpStateSet(&p.ActorOper.State, LacpStateAggregationBit)
 
 				mEvtChan := make([]chan utils.MachineEvent, 0)
 				evt := make([]utils.MachineEvent, 0)
 
 				mEvtChan = append(mEvtChan, p.MuxMachineFsm.MuxmEvents)
-				evt = append(evt, utils.MachineEvent{
-					E:   LacpMuxmEventSelectedEqualSelected,
-					Src: PortConfigModuleStr})
+				if p.aggSelected == LacpAggStandby {
+					p.LaPortLog("checkConfigForSelection: standby")
+					evt = append(evt, utils.MachineEvent{
+						E:   LacpMuxmEventSelectedEqualStandby,
+						Src: PortConfigModuleStr})
+				} else {
+					p.LaPortLog("checkConfigForSelection: selected")
+					evt = append(evt, utils.MachineEvent{
+						E:   LacpMuxmEventSelectedEqualSelected,
+						Src: PortConfigModuleStr})
+				}
 				// inform mux that port has been selected
 				// wait for response
 				p.DistributeMachineEvents(mEvtChan, evt, true)
+				// a higher priority port may bump a selected port to standby
+				a.LacpAggSelectionUpdate(p)
 				//msg := <-p.portChan
 				return true
 			} else if p.MuxMachineFsm.Machine.Curr.CurrentState() != LacpMuxmStateDetached &&
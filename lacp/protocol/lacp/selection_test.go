@@ -0,0 +1,185 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package lacp
+
+import (
+	"fmt"
+	"l2/lacp/protocol/utils"
+	"net"
+	"testing"
+)
+
+func SelectionTestSetup(maxLinks uint16) (*LaAggregator, []*LaAggPort) {
+	OnlyForTestSetup()
+
+	sysId := LacpSystem{Actor_System_priority: 128,
+		Actor_System: [6]uint8{0x00, 0x00, 0x00, 0x00, 0x00, 0x64}}
+	LacpSysGlobalInfoInit(sysId)
+
+	aconf := &LaAggConfig{
+		Name:     "agg100",
+		Mac:      [6]uint8{0x00, 0x00, 0x01, 0x01, 0x01, 0x01},
+		Id:       100,
+		Key:      100,
+		MaxLinks: maxLinks,
+		Lacp: LacpConfigInfo{Interval: LacpSlowPeriodicTime,
+			Mode:           LacpModeActive,
+			SystemIdMac:    "00:00:00:00:00:64",
+			SystemPriority: 128},
+	}
+	a := NewLaAggregator(aconf)
+
+	// port 3 is highest priority followed by port 1 and 2
+	prio := map[uint16]uint16{1: 0x80, 2: 0x80, 3: 0x10}
+	ports := make([]*LaAggPort, 0)
+	for id := uint16(1); id <= 3; id++ {
+		utils.PortConfigMap[int32(id)] = utils.PortConfig{Name: fmt.Sprintf("SIMeth%d", id),
+			HardwareAddr: net.HardwareAddr{0x00, 0x11, 0x11, 0x22, 0x22, uint8(id)},
+		}
+		pconf := &LaAggPortConfig{
+			Id:     id,
+			Prio:   prio[id],
+			Key:    100,
+			AggId:  100,
+			Enable: true,
+			Mode:   LacpModeActive,
+		}
+		p := NewLaAggPort(pconf)
+		p.logEna = false
+		p.AggId = a.AggId
+		p.LinkOperStatus = true
+		p.aggSelected = LacpAggSelected
+		// only the mux events are of interest
+		p.MuxMachineFsm = &LacpMuxMachine{p: p,
+			MuxmEvents:        make(chan utils.MachineEvent, 10),
+			MuxmReselectEvent: make(chan bool, 1)}
+		a.PortNumList = append(a.PortNumList, p.PortNum)
+		ports = append(ports, p)
+	}
+	return a, ports
+}
+
+func SelectionTestTeardown(a *LaAggregator, ports []*LaAggPort) {
+	a.DeleteLaAgg()
+	for _, p := range ports {
+		p.MuxMachineFsm = nil
+		DeleteLaAggPort(p.PortNum)
+	}
+	LacpSysGlobalInfoDestroy(LacpSystem{Actor_System_priority: 128,
+		Actor_System: [6]uint8{0x00, 0x00, 0x00, 0x00, 0x00, 0x64}})
+	OnlyForTestTeardown()
+}
+
+func TestLaAggSelectionNoMaxLinks(t *testing.T) {
+	a, ports := SelectionTestSetup(0)
+	defer SelectionTestTeardown(a, ports)
+
+	for _, p := range ports {
+		if a.LacpAggSelectionGet(p) != LacpAggSelected {
+			t.Error("Port", p.IntfNum, "expected to be selected when MaxLinks is not set")
+		}
+	}
+}
+
+func TestLaAggSelectionMaxLinksStandby(t *testing.T) {
+	a, ports := SelectionTestSetup(2)
+	defer SelectionTestTeardown(a, ports)
+
+	expected := []int{LacpAggSelected, LacpAggStandby, LacpAggSelected}
+	for i, p := range ports {
+		if a.LacpAggSelectionGet(p) != expected[i] {
+			t.Error("Port", p.IntfNum, "selection expected", expected[i], "actual", a.LacpAggSelectionGet(p))
+		}
+	}
+
+	// all ports were selected, lowest priority port should be moved to standby
+	// by its own mux machine, reselect is coalesced and never blocks
+	for _, p := range ports {
+		p.AggAttached = a
+	}
+	a.LacpAggSelectionUpdate(nil)
+	a.LacpAggSelectionUpdate(nil)
+	for _, p := range ports {
+		if len(p.MuxMachineFsm.MuxmReselectEvent) != 1 {
+			t.Error("Port", p.IntfNum, "expected one pending reselect actual", len(p.MuxMachineFsm.MuxmReselectEvent))
+		}
+		<-p.MuxMachineFsm.MuxmReselectEvent
+		if p.aggSelected != LacpAggSelected {
+			t.Error("Port", p.IntfNum, "selection should only be changed by its mux machine")
+		}
+	}
+	evt, changed := ports[1].MuxMachineFsm.LacpMuxmReselect()
+	if !changed || evt.E != LacpMuxmEventSelectedEqualStandby || !ports[1].IsPortStandby() {
+		t.Error("Port", ports[1].IntfNum, "expected to be moved to standby actual", changed, evt.E)
+	}
+	for _, p := range []*LaAggPort{ports[0], ports[2]} {
+		if _, changed := p.MuxMachineFsm.LacpMuxmReselect(); changed || p.IsPortStandby() {
+			t.Error("Port", p.IntfNum, "selection should not have changed")
+		}
+	}
+
+	// active member fails, standby port should be promoted
+	ports[2].aggSelected = LacpAggUnSelected
+	ports[2].LinkOperStatus = false
+	a.LacpAggSelectionUpdate(ports[2])
+	if len(ports[2].MuxMachineFsm.MuxmReselectEvent) != 0 {
+		t.Error("Port", ports[2].IntfNum, "caller is not expected to be informed")
+	}
+	evt, changed = ports[1].MuxMachineFsm.LacpMuxmReselect()
+	if !changed || evt.E != LacpMuxmEventSelectedEqualSelected || ports[1].IsPortStandby() {
+		t.Error("Port", ports[1].IntfNum, "expected to be promoted from standby actual", changed, evt.E)
+	}
+	if _, changed := ports[0].MuxMachineFsm.LacpMuxmReselect(); changed {
+		t.Error("Port", ports[0].IntfNum, "selection should not have changed")
+	}
+	// unselected port is not reselected
+	if _, changed := ports[2].MuxMachineFsm.LacpMuxmReselect(); changed {
+		t.Error("Port", ports[2].IntfNum, "unselected port should not be reselected")
+	}
+	for _, p := range ports {
+		p.AggAttached = nil
+	}
+}
+
+func TestLaAggSelectionPartnerControlling(t *testing.T) {
+	a, ports := SelectionTestSetup(1)
+	defer SelectionTestTeardown(a, ports)
+
+	// partner has the lower system id so its port priorities are used
+	// and it prefers port 2
+	partnerPrio := []uint16{0x80, 0x10, 0x80}
+	for i, p := range ports {
+		p.PartnerOper.System = LacpSystem{Actor_System_priority: 1,
+			Actor_System: [6]uint8{0x00, 0x00, 0x00, 0x00, 0x00, 0xC8}}
+		p.PartnerOper.port = uint16(10 + i)
+		p.PartnerOper.Port_pri = partnerPrio[i]
+	}
+
+	expected := []int{LacpAggStandby, LacpAggSelected, LacpAggStandby}
+	for i, p := range ports {
+		if a.LacpAggSelectionGet(p) != expected[i] {
+			t.Error("Port", p.IntfNum, "selection expected", expected[i], "actual", a.LacpAggSelectionGet(p))
+		}
+	}
+}
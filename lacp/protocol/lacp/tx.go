@@ -68,6 +68,9 @@ func (bridge *SimulationBridge) TxViaGoChannel(port uint16, pdu interface{}) {
 
 			gopacket.SerializeLayers(buf, opts, &eth, &slow, lacp)
 
+		case *LacpV2Pdu:
+			buf = lacpV2PduSerialize(&eth, pdu.(*LacpV2Pdu))
+
 		case *layers.LAMP:
 			slow := layers.SlowProtocol{
 				SubType: layers.SlowProtocolTypeLAMP,
@@ -120,6 +123,9 @@ func TxViaLinuxIf(port uint16, pdu interface{}) {
 				lacp := pdu.(*layers.LACP)
 				gopacket.SerializeLayers(buf, opts, &eth, &slow, lacp)
 
+			case *LacpV2Pdu:
+				buf = lacpV2PduSerialize(&eth, pdu.(*LacpV2Pdu))
+
 			case *layers.LAMP:
 				slow := layers.SlowProtocol{
 					SubType: layers.SlowProtocolTypeLAMP,
@@ -139,3 +145,25 @@ func TxViaLinuxIf(port uint16, pdu interface{}) {
 		utils.GlobalLogger.Err(fmt.Sprintf("Unable to find port %d in tx", port))
 	}
 }
+
+// lacpV2PduSerialize serializes the version 1 portion of the LACPDU and then
+// inserts the version 2 TLVs
+func lacpV2PduSerialize(eth *layers.Ethernet, pdu *LacpV2Pdu) gopacket.SerializeBuffer {
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{
+		FixLengths:       true,
+		ComputeChecksums: true,
+	}
+	slow := layers.SlowProtocol{
+		SubType: layers.SlowProtocolTypeLACP,
+	}
+	gopacket.SerializeLayers(buf, opts, eth, &slow, pdu.Lacp)
+
+	// slow protocol follows the untagged ethernet header
+	frame := LacpPduV2FrameBuild(buf.Bytes(), 14, pdu.V2)
+
+	v2buf := gopacket.NewSerializeBuffer()
+	b, _ := v2buf.AppendBytes(len(frame))
+	copy(b, frame)
+	return v2buf
+}
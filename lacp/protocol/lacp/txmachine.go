@@ -31,6 +31,7 @@ import (
 	"l2/lacp/protocol/utils"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 	"utils/fsm"
 
@@ -90,6 +91,10 @@ type LacpTxMachine struct {
 	// the State machine will only clear
 	ntt bool
 
+	// set by other contexts when TxmEvents is full, the
+	// NTT is picked up with the next event, i.e. periodic tx
+	nttPending int32
+
 	// timer needed for 802.1ax-20014 section 6.4.16
 	txGuardTimer *time.Timer
 
@@ -205,16 +210,24 @@ func (txm *LacpTxMachine) LacpTxMachineOn(m fsm.Machine, data interface{}) fsm.S
 				},
 			}
 
+			// Version 2 LACPDU carries the version 2 TLVs, if enable_long_pdu_xmit
+			// is True the LACPDU will be a Long LACPDU formatted by 802.1ax-2014
+			// Section 6.4.2 and including Port Conversation Mask TLV 6.4.2.4.3
+			var pdu interface{} = lacp
+			if p.LacpActorVersionGet() >= 0x2 {
+				lacp.Version = layers.LACPVersion2
+				pdu = &LacpV2Pdu{
+					Lacp: lacp,
+					V2:   p.LacpPduV2InfoGet(),
+				}
+			}
+
 			// transmit the packet
 			for _, ftx := range LaSysGlobalTxCallbackListGet(p) {
 				//txm.LacpTxmLog(fmt.Sprintf("Sending Tx packet port %d pkts %d", p.PortNum, txm.txPkts))
-				ftx(p.PortNum, lacp)
+				ftx(p.PortNum, pdu)
 				p.LacpCounter.AggPortStatsLACPDUsTx += 1
 			}
-			// Version 2 consideration if enable_long_pdu_xmit and
-			// LongLACPPDUTransmit are True:
-			// LACPDU will be a Long LACPDU formatted by 802.1ax-2014 Section
-			// 6.4.2 and including Port Conversation Mask TLV 6.4.2.4.3
 			txm.ntt = false
 
 			// lets force another transmit
@@ -243,10 +256,6 @@ func (txm *LacpTxMachine) LacpTxMachineDelayed(m fsm.Machine, data interface{})
 	State = LacpTxmStateOn
 
 	// if more than 3 packets are being transmitted within time interval
-	// Version 2 consideration if enable_long_pdu_xmit and
-	// LongLACPPDUTransmit are True:
-	// LACPDU will be a Long LACPDU formatted by 802.1ax-2014 Section
-	// 6.4.2 and including Port Conversation Mask TLV 6.4.2.4.3
 	txm.LacpTxmLog(fmt.Sprintf("Delayed: txPending %d txPkts %d delaying tx", txm.txPending, txm.txPkts))
 	if txm.txPending > 0 && txm.txPkts > 3 {
 		State = LacpTxmStateDelayed
@@ -356,7 +365,8 @@ func (p *LaAggPort) LacpTxMachineMain() {
 					//m.LacpTxmLog(fmt.Sprintf("Event rx %d %s %s", event.E, event.Src, TxmStateStrMap[m.Machine.Curr.CurrentState()]))
 					// special case, another machine has a need to
 					// transmit a packet
-					if event.E == LacpTxmEventNtt {
+					if event.E == LacpTxmEventNtt ||
+						atomic.CompareAndSwapInt32(&m.nttPending, 1, 0) {
 						m.ntt = true
 					}
 
@@ -391,6 +401,21 @@ func (p *LaAggPort) LacpTxMachineMain() {
 	}(txm)
 }
 
+// LacpTxmNttPendingSet will request a transmit without blocking the caller,
+// the NTT event is sent when there is room otherwise NTT is left pending
+// and is sent with the next periodic transmit
+func (txm *LacpTxMachine) LacpTxmNttPendingSet(src string) bool {
+	select {
+	case txm.TxmEvents <- utils.MachineEvent{
+		E:   LacpTxmEventNtt,
+		Src: src}:
+		return true
+	default:
+		atomic.StoreInt32(&txm.nttPending, 1)
+		return false
+	}
+}
+
 // LacpTxGuardGeneration will generate an event to the Tx Machine
 // in order to clear the txPkts count
 func (txm *LacpTxMachine) LacpTxGuardGeneration() {
@@ -23,11 +23,43 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
 	"utils/asicdClient"
 )
 
 var ClientIntfs []asicdClient.AsicdClientIntf
 
+// LagCollectingPlugin is implemented by plugins which are able to collect
+// frames on a lag member which is not distributing.  Ports is the list of
+// collecting ports in the same format as the CreateLag/UpdateLag port list.
+// Plugins which do not implement it collect on the distributing ports only
+type LagCollectingPlugin interface {
+	UpdateLagCollecting(ifIndex int32, ports string) error
+}
+
+// LagConversationPlugin is implemented by plugins which are able to
+// distribute and collect frames by Port Conversation ID, which is required to
+// run LACP version 2.  Ports holds the ifindex of the port carrying each
+// conversation indexed by Port Conversation ID, 0 when the conversation is
+// not carried, and is nil when frames are distributed by the lag hash.  When
+// discardWrongConversation is set frames received on a port other than the
+// one carrying their conversation are discarded
+type LagConversationPlugin interface {
+	UpdateLagConversation(ifIndex int32, ports []int32, discardWrongConversation bool) error
+}
+
+// LagConversationSupported checks whether all plugins are able to program
+// conversation sensitive collection and distribution
+func LagConversationSupported() error {
+	for _, client := range GetAsicDPluginList() {
+		if _, ok := client.(LagConversationPlugin); !ok {
+			return errors.New(fmt.Sprintf("Conversation sensitive collection and distribution not supported by plugin %T", client))
+		}
+	}
+	return nil
+}
+
 func SetAsicDPlugin(clientif asicdClient.AsicdClientIntf) {
 	ClientIntfs = append(ClientIntfs, clientif)
 }
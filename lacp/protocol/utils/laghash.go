@@ -0,0 +1,102 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// laghash
+package utils
+
+import (
+	hwconst "asicd/asicdCommonDefs"
+	"errors"
+	"fmt"
+	"utils/asicdClient"
+)
+
+// Lag hash type given to the plugins on CreateLag/UpdateLag.  The low byte
+// holds the hash selection, the symmetric flag and the hash field set are
+// encoded above it.  Asicd only understands the src/dst mac and src/dst ip
+// selections, anything else is only given to plugins implementing LagHashPlugin.
+// Asicd numbers its selections from 0, the selections asicd does not define
+// are numbered from LAG_HASH_SEL_EXT so that a selection added to asicd never
+// collides with one of them
+const (
+	LAG_HASH_SEL_SRCDSTMAC = hwconst.HASH_SEL_SRCDSTMAC
+	LAG_HASH_SEL_SRCDSTIP  = hwconst.HASH_SEL_SRCDSTIP
+
+	LAG_HASH_SEL_EXT                   = 0x80
+	LAG_HASH_SEL_SRCDSTIP_L4PORT       = LAG_HASH_SEL_EXT + 0
+	LAG_HASH_SEL_ENCAP_SRCDSTIP        = LAG_HASH_SEL_EXT + 1
+	LAG_HASH_SEL_ENCAP_SRCDSTIP_L4PORT = LAG_HASH_SEL_EXT + 2
+	// hash on the field set encoded at LAG_HASH_FIELDS_SHIFT
+	LAG_HASH_SEL_FIELDS = LAG_HASH_SEL_EXT + 3
+	LAG_HASH_SEL_MASK   = 0xff
+
+	// both directions of a flow hash to the same link
+	LAG_HASH_SYMMETRIC = 0x100
+
+	LAG_HASH_FIELDS_SHIFT = 16
+)
+
+// Lag hash fields
+const (
+	LAG_HASH_FIELD_SRCMAC = 1 << iota
+	LAG_HASH_FIELD_DSTMAC
+	LAG_HASH_FIELD_VLAN
+	LAG_HASH_FIELD_ETHERTYPE
+	LAG_HASH_FIELD_SRCIP
+	LAG_HASH_FIELD_DSTIP
+	LAG_HASH_FIELD_IPPROTO
+	LAG_HASH_FIELD_SRCL4PORT
+	LAG_HASH_FIELD_DSTL4PORT
+	// fields are taken from the inner headers of tunneled frames
+	LAG_HASH_FIELD_INNER
+
+	LAG_HASH_FIELD_ALL = LAG_HASH_FIELD_INNER<<1 - 1
+)
+
+// the asicd selections must stay below LAG_HASH_SEL_EXT, the build fails
+// otherwise
+var _ = [LAG_HASH_SEL_EXT - 1 - LAG_HASH_SEL_SRCDSTMAC]struct{}{}
+var _ = [LAG_HASH_SEL_EXT - 1 - LAG_HASH_SEL_SRCDSTIP]struct{}{}
+
+// LagHashPlugin is implemented by plugins which support lag hash types beyond
+// the asicd selections
+type LagHashPlugin interface {
+	LagHashSupported(hashType int32) error
+}
+
+// LagHashFieldsGet returns the hash field set of a LAG_HASH_SEL_FIELDS hash type
+func LagHashFieldsGet(hashType int32) int32 {
+	return hashType >> LAG_HASH_FIELDS_SHIFT & LAG_HASH_FIELD_ALL
+}
+
+// LagHashSupported checks whether the plugin is able to program the hash type
+func LagHashSupported(client asicdClient.AsicdClientIntf, hashType int32) error {
+	if hashPlugin, ok := client.(LagHashPlugin); ok {
+		return hashPlugin.LagHashSupported(hashType)
+	}
+	if hashType != LAG_HASH_SEL_SRCDSTMAC &&
+		hashType != LAG_HASH_SEL_SRCDSTIP {
+		return errors.New(fmt.Sprintf("Lag hash type 0x%x not supported by asicd", hashType))
+	}
+	return nil
+}
@@ -166,6 +166,150 @@ func ConvertLaAggIntervalToLacpPeriod(interval time.Duration) int32 {
 	return period
 }
 
+// ConvertModelLagHashToLaAggHashMode, invalid model values are converted to
+// LaAggHashModeMax so that they are rejected by the param check rather than
+// being wrapped into a valid mode
+func ConvertModelLagHashToLaAggHashMode(yangLagHash int32) uint32 {
+	if yangLagHash < 0 || yangLagHash >= lacp.LaAggHashModeMax {
+		return lacp.LaAggHashModeMax
+	}
+	return uint32(yangLagHash)
+}
+
+func ConvertLaAggHashModeToModelLagHash(hashmode uint32) int32 {
+	return int32(hashmode)
+}
+
+// modelLagHashFieldList maps the model lag hash field names to the hash field
+// bits, in the order they are reported
+var modelLagHashFieldList = []struct {
+	name  string
+	field uint32
+}{
+	{"SRCMAC", utils.LAG_HASH_FIELD_SRCMAC},
+	{"DSTMAC", utils.LAG_HASH_FIELD_DSTMAC},
+	{"VLAN", utils.LAG_HASH_FIELD_VLAN},
+	{"ETHERTYPE", utils.LAG_HASH_FIELD_ETHERTYPE},
+	{"SRCIP", utils.LAG_HASH_FIELD_SRCIP},
+	{"DSTIP", utils.LAG_HASH_FIELD_DSTIP},
+	{"IPPROTO", utils.LAG_HASH_FIELD_IPPROTO},
+	{"SRCL4PORT", utils.LAG_HASH_FIELD_SRCL4PORT},
+	{"DSTL4PORT", utils.LAG_HASH_FIELD_DSTL4PORT},
+	{"INNER", utils.LAG_HASH_FIELD_INNER},
+}
+
+// ConvertModelLagHashFieldsToLaAggHashFields converts the model hash field
+// names, used when LagHash is FIELDS, to the hash field bits
+func ConvertModelLagHashFieldsToLaAggHashFields(names []string) (uint32, error) {
+	var fields uint32
+	for _, name := range names {
+		found := false
+		for _, f := range modelLagHashFieldList {
+			if strings.ToUpper(name) == f.name {
+				fields |= f.field
+				found = true
+			}
+		}
+		if !found {
+			return 0, errors.New(fmt.Sprintf("LACP: Invalid Lag Hash Field %s", name))
+		}
+	}
+	return fields, nil
+}
+
+func ConvertLaAggHashFieldsToModelLagHashFields(fields uint32) (names []string) {
+	for _, f := range modelLagHashFieldList {
+		if fields&f.field != 0 {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// ConvertModelLacpVersionToLaAggVersion, invalid model values are converted
+// to a version which is rejected by the param check
+func ConvertModelLacpVersionToLaAggVersion(yangVersion int32) uint8 {
+	if yangVersion < 0 || yangVersion > 2 {
+		return 0xff
+	}
+	return uint8(yangVersion)
+}
+
+// ConvertModelMuxControlToLaAggMuxControl, SYSTEM(0)/INDEPENDENT(1)/COUPLED(2),
+// invalid model values are converted to a control which is rejected
+func ConvertModelMuxControlToLaAggMuxControl(yangMuxControl int32) uint8 {
+	if yangMuxControl < 0 || yangMuxControl >= lacp.LaAggMuxControlMax {
+		return lacp.LaAggMuxControlMax
+	}
+	return uint8(yangMuxControl)
+}
+
+// ConvertModelConversationListToLaAggConversationList converts the model
+// entries "<conversation id>:<id>[,<id>...]" into lists of ids indexed by
+// Port Conversation ID
+func ConvertModelConversationListToLaAggConversationList(entries []string) (map[uint16][]uint16, error) {
+	list := make(map[uint16][]uint16)
+	for _, entry := range entries {
+		fields := strings.Split(entry, ":")
+		if len(fields) != 2 {
+			return nil, errors.New(fmt.Sprintf("LACP: Invalid conversation entry %s should be <conversation id>:<id>[,<id>...]", entry))
+		}
+		cid, err := strconv.ParseUint(strings.TrimSpace(fields[0]), 10, 16)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("LACP: Invalid conversation id in entry %s", entry))
+		}
+		if _, ok := list[uint16(cid)]; ok {
+			return nil, errors.New(fmt.Sprintf("LACP: Duplicate conversation id %d in entry %s", cid, entry))
+		}
+		for _, idStr := range strings.Split(fields[1], ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(idStr), 10, 16)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("LACP: Invalid id %s in conversation entry %s", idStr, entry))
+			}
+			list[uint16(cid)] = append(list[uint16(cid)], uint16(id))
+		}
+	}
+	return list, nil
+}
+
+// ConvertModelLinkNumberIdListToLaAggLinkNumberIds converts the model
+// entries "<interface>:<link number id>" into Link Number IDs indexed by the
+// ifindex of the port
+func ConvertModelLinkNumberIdListToLaAggLinkNumberIds(entries []string) (map[uint16]uint16, error) {
+	ids := make(map[uint16]uint16)
+	for _, entry := range entries {
+		i := strings.LastIndex(entry, ":")
+		if i <= 0 {
+			return nil, errors.New(fmt.Sprintf("LACP: Invalid link number id entry %s should be <interface>:<link number id>", entry))
+		}
+		ifindex := utils.GetIfIndexFromName(entry[:i])
+		if ifindex == 0 {
+			return nil, errors.New(fmt.Sprintf("LACP: Unknown interface in link number id entry %s", entry))
+		}
+		id, err := strconv.ParseUint(strings.TrimSpace(entry[i+1:]), 10, 16)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("LACP: Invalid link number id in entry %s", entry))
+		}
+		ids[uint16(ifindex)] = uint16(id)
+	}
+	return ids, nil
+}
+
+// ConvertModelLaPortChannelV2Config fills in the LACP version 2 config of
+// the lag from the model
+func ConvertModelLaPortChannelV2Config(config *lacpd.LaPortChannel, conf *lacp.LaAggConfig) (err error) {
+	conf.Version = ConvertModelLacpVersionToLaAggVersion(config.LacpVersion)
+	conf.DiscardWrongConversation = config.DiscardWrongConversation
+	if conf.ConversationAdminLink, err = ConvertModelConversationListToLaAggConversationList(config.ConversationAdminLink); err != nil {
+		return err
+	}
+	if conf.AdminServiceConversationMap, err = ConvertModelConversationListToLaAggConversationList(config.AdminServiceConversationMap); err != nil {
+		return err
+	}
+	conf.LagMemberLinkNumberIds, err = ConvertModelLinkNumberIdListToLaAggLinkNumberIds(config.LinkNumberIdList)
+	return err
+}
+
 func ConvertSqlBooleanToBool(sqlbool string) bool {
 	if sqlbool == "true" {
 		return true
@@ -188,6 +332,15 @@ func ConvertAdminStateStringToBool(s string) bool {
 	return false
 }
 
+// ConvertLaAggOperStateToModelOperState returns the oper state of the lag,
+// the reason the lag is down is reported separately in OperDownReason
+func ConvertLaAggOperStateToModelOperState(a *lacp.LaAggregator) string {
+	if a.OperState {
+		return "UP"
+	}
+	return "DOWN"
+}
+
 func ConvertRxMachineStateToYangState(state int) int32 {
 	var yangstate int32
 	switch state {
@@ -407,7 +560,26 @@ func (la *LACPDServiceHandler) ReadConfigFromDB(prevState int) error {
 	return nil
 }
 
+// SetLacpGlobalMuxControl will set the mux control used by the lags which do
+// not configure their own
+func (la *LACPDServiceHandler) SetLacpGlobalMuxControl(config *lacpd.LacpGlobal) error {
+	conf := &lacp.LacpSysConfig{
+		MuxControl: ConvertModelMuxControlToLaAggMuxControl(config.MuxControl),
+	}
+	if conf.MuxControl >= lacp.LaAggMuxControlMax {
+		return errors.New(fmt.Sprintf("LACP: Invalid Mux Control %d Should be SYSTEM(0), INDEPENDENT(1) or COUPLED(2)", config.MuxControl))
+	}
+	la.svr.ConfigCh <- server.LAConfig{
+		Msgtype: server.LAConfigMsgUpdateLacpGlobalMuxControl,
+		Msgdata: conf,
+	}
+	return nil
+}
+
 func (la *LACPDServiceHandler) CreateLacpGlobal(config *lacpd.LacpGlobal) (bool, error) {
+	if err := la.SetLacpGlobalMuxControl(config); err != nil {
+		return false, err
+	}
 	if config.AdminState == "UP" {
 		prevState := utils.LacpGlobalStateGet()
 		utils.LacpGlobalStateSet(utils.LACP_GLOBAL_ENABLE)
@@ -426,6 +598,12 @@ func (la *LACPDServiceHandler) DeleteLacpGlobal(config *lacpd.LacpGlobal) (bool,
 func (la *LACPDServiceHandler) UpdateLacpGlobal(origconfig *lacpd.LacpGlobal, updateconfig *lacpd.LacpGlobal, attrset []bool, op []*lacpd.PatchOpInfo) (bool, error) {
 	prevState := utils.LacpGlobalStateGet()
 
+	if origconfig.MuxControl != updateconfig.MuxControl {
+		if err := la.SetLacpGlobalMuxControl(updateconfig); err != nil {
+			return false, err
+		}
+	}
+
 	if updateconfig.AdminState == "UP" {
 		utils.LacpGlobalStateSet(utils.LACP_GLOBAL_ENABLE)
 	} else if updateconfig.AdminState == "DOWN" {
@@ -455,6 +633,15 @@ func (la *LACPDServiceHandler) UpdateLacpGlobal(origconfig *lacpd.LacpGlobal, up
 //	9 : i32 	LacpMode (0 == ACTIVE, 1 == PASSIVE)
 //	10 : string SystemIdMac
 //	11 : i16 	SystemPriority
+//	12 : i16 	MaxLinks
+//	13 : i32 	LacpVersion (1 or 2)
+//	14 : list<string> 	ConversationAdminLink (<conversation id>:<link number id>[,<link number id>...])
+//	15 : list<string> 	AdminServiceConversationMap (<conversation id>:<service id>[,<service id>...])
+//	16 : bool 	DiscardWrongConversation
+//	17 : list<string> 	LinkNumberIdList (<interface>:<link number id>)
+//	18 : bool 	LagHashSymmetric
+//	19 : list<string> 	LagHashFields
+//	20 : i32 	MuxControl (0 == SYSTEM, 1 == INDEPENDENT, 2 == COUPLED)
 func (la *LACPDServiceHandler) CreateLaPortChannel(config *lacpd.LaPortChannel) (bool, error) {
 
 	aggModeMap := map[uint32]uint32{
@@ -497,6 +684,7 @@ func (la *LACPDServiceHandler) CreateLaPortChannel(config *lacpd.LaPortChannel)
 			// Type of LAG STATIC or LACP
 			Type:     ConvertModelLagTypeToLaAggType(config.LagType),
 			MinLinks: uint16(config.MinLinks),
+			MaxLinks: uint16(config.MaxLinks),
 			Enabled:  ConvertAdminStateStringToBool(config.AdminState),
 			// lacp config
 			Lacp: lacp.LacpConfigInfo{
@@ -505,12 +693,22 @@ func (la *LACPDServiceHandler) CreateLaPortChannel(config *lacpd.LaPortChannel)
 				SystemIdMac:    switchIdMac,
 				SystemPriority: uint16(config.SystemPriority),
 			},
-			HashMode: uint32(config.LagHash),
+			HashMode:   ConvertModelLagHashToLaAggHashMode(config.LagHash),
+			MuxControl: ConvertModelMuxControlToLaAggMuxControl(config.MuxControl),
 		}
 		for _, intfref := range config.IntfRefList {
 			ifindex := utils.GetIfIndexFromName(intfref)
 			conf.LagMembers = append(conf.LagMembers, uint16(ifindex))
 		}
+		if err := ConvertModelLaPortChannelV2Config(config, conf); err != nil {
+			return false, err
+		}
+		conf.HashSymmetric = config.LagHashSymmetric
+		if fields, err := ConvertModelLagHashFieldsToLaAggHashFields(config.LagHashFields); err != nil {
+			return false, err
+		} else {
+			conf.HashFields = fields
+		}
 		err1 := lacp.LaAggConfigAggCreateCheck(conf)
 		err2 := lacp.LaAggConfigParamCheck(conf)
 		if err1 != nil {
@@ -539,14 +737,15 @@ func (la *LACPDServiceHandler) CreateLaPortChannel(config *lacpd.LaPortChannel)
 					}
 					ifindex := utils.GetIfIndexFromName(intfref)
 					conf := &lacp.LaAggPortConfig{
-						Id:       uint16(ifindex),
-						Prio:     uint16(conf.Lacp.SystemPriority),
-						Key:      uint16(conf.Key),
-						AggId:    int(conf.Id),
-						Enable:   conf.Enabled,
-						Mode:     int(mode),
-						Timeout:  timeout,
-						TraceEna: true,
+						Id:           uint16(ifindex),
+						Prio:         uint16(conf.Lacp.SystemPriority),
+						Key:          uint16(conf.Key),
+						AggId:        int(conf.Id),
+						Enable:       conf.Enabled,
+						Mode:         int(mode),
+						Timeout:      timeout,
+						TraceEna:     true,
+						LinkNumberId: conf.LagMemberLinkNumberIds[uint16(ifindex)],
 					}
 
 					cfg := server.LAConfig{
@@ -639,6 +838,7 @@ func (la *LACPDServiceHandler) UpdateLaPortChannel(origconfig *lacpd.LaPortChann
 		// Type of LAG STATIC or LACP
 		Type:     ConvertModelLagTypeToLaAggType(updateconfig.LagType),
 		MinLinks: uint16(updateconfig.MinLinks),
+		MaxLinks: uint16(updateconfig.MaxLinks),
 		Enabled:  ConvertAdminStateStringToBool(updateconfig.AdminState),
 		// lacp config
 		Lacp: lacp.LacpConfigInfo{
@@ -647,7 +847,18 @@ func (la *LACPDServiceHandler) UpdateLaPortChannel(origconfig *lacpd.LaPortChann
 			SystemIdMac:    updateconfig.SystemIdMac,
 			SystemPriority: uint16(updateconfig.SystemPriority),
 		},
-		HashMode: uint32(updateconfig.LagHash),
+		HashMode:   ConvertModelLagHashToLaAggHashMode(updateconfig.LagHash),
+		MuxControl: ConvertModelMuxControlToLaAggMuxControl(updateconfig.MuxControl),
+	}
+	conf.HashSymmetric = updateconfig.LagHashSymmetric
+	if fields, err := ConvertModelLagHashFieldsToLaAggHashFields(updateconfig.LagHashFields); err != nil {
+		return false, err
+	} else {
+		conf.HashFields = fields
+	}
+
+	if err := ConvertModelLaPortChannelV2Config(updateconfig, conf); err != nil {
+		return false, err
 	}
 
 	ifindexList := make([]int32, 0)
@@ -708,14 +919,15 @@ func (la *LACPDServiceHandler) UpdateLaPortChannel(origconfig *lacpd.LaPortChann
 								}
 								id := GetKeyByAggName(nameKey)
 								conf := &lacp.LaAggPortConfig{
-									Id:       uint16(ifindex),
-									Prio:     uint16(a.Config.SystemPriority),
-									Key:      id,
-									AggId:    int(id),
-									Enable:   conf.Enabled,
-									Mode:     mode,
-									Timeout:  timeout,
-									TraceEna: true,
+									Id:           uint16(ifindex),
+									Prio:         uint16(a.Config.SystemPriority),
+									Key:          id,
+									AggId:        int(id),
+									Enable:       conf.Enabled,
+									Mode:         mode,
+									Timeout:      timeout,
+									TraceEna:     true,
+									LinkNumberId: conf.LagMemberLinkNumberIds[uint16(ifindex)],
 								}
 
 								cfg := server.LAConfig{
@@ -735,8 +947,19 @@ func (la *LACPDServiceHandler) UpdateLaPortChannel(origconfig *lacpd.LaPortChann
 				"LagHash":        server.LAConfigMsgUpdateLaPortChannelLagHash,
 				"LacpMode":       server.LAConfigMsgUpdateLaPortChannelAggMode,
 				"Interval":       server.LAConfigMsgUpdateLaPortChannelPeriod,
+				"MinLinks":       server.LAConfigMsgUpdateLaPortChannelMinLinks,
+				"MaxLinks":       server.LAConfigMsgUpdateLaPortChannelMaxLinks,
 				"SystemIdMac":    server.LAConfigMsgUpdateLaPortChannelSystemIdMac,
 				"SystemPriority": server.LAConfigMsgUpdateLaPortChannelSystemPriority,
+
+				"LacpVersion":                 server.LAConfigMsgUpdateLaPortChannelLacpVersion,
+				"ConversationAdminLink":       server.LAConfigMsgUpdateLaPortChannelConversationAdminLink,
+				"AdminServiceConversationMap": server.LAConfigMsgUpdateLaPortChannelServiceConversationMap,
+				"DiscardWrongConversation":    server.LAConfigMsgUpdateLaPortChannelDiscardWrongConversation,
+				"LinkNumberIdList":            server.LAConfigMsgUpdateLaPortChannelLinkNumberId,
+				"LagHashSymmetric":            server.LAConfigMsgUpdateLaPortChannelLagHash,
+				"LagHashFields":               server.LAConfigMsgUpdateLaPortChannelLagHash,
+				"MuxControl":                  server.LAConfigMsgUpdateLaPortChannelMuxControl,
 			}
 
 			// important to note that the attrset starts at index 0 which is the BaseObj
@@ -804,8 +1027,7 @@ func SetLaAggMode(conf *lacp.LaAggConfig) error {
 }
 
 func SetLaAggHashMode(conf *lacp.LaAggConfig) error {
-	lacp.SetLaAggHashMode(conf.Id, conf.HashMode)
-	return nil
+	return lacp.SetLaAggHashConfig(conf.Id, conf.HashMode, conf.HashSymmetric, conf.HashFields)
 }
 
 func SetLaAggPeriod(conf *lacp.LaAggConfig) error {
@@ -871,16 +1093,18 @@ func (la *LACPDServiceHandler) GetLaPortChannelState(IntfRef string) (*lacpd.LaP
 			if a.AdminState {
 				pcs.AdminState = "UP"
 			}
-			pcs.OperState = "DOWN"
-			if a.OperState {
-				pcs.OperState = "UP"
-			}
+			pcs.OperState = ConvertLaAggOperStateToModelOperState(a)
+			pcs.OperDownReason = a.OperDownReason
 			pcs.MinLinks = int16(a.AggMinLinks)
+			pcs.MaxLinks = int16(a.AggMaxLinks)
+			pcs.MuxControl = int32(a.AggMuxControl)
 			pcs.Interval = ConvertLaAggIntervalToLacpPeriod(a.Config.Interval)
 			pcs.LacpMode = ConvertLaAggModeToModelLacpMode(a.Config.Mode)
 			pcs.SystemIdMac = a.Config.SystemIdMac
 			pcs.SystemPriority = int16(a.Config.SystemPriority)
-			pcs.LagHash = int32(a.LagHash)
+			pcs.LagHash = ConvertLaAggHashModeToModelLagHash(a.LagHash)
+			pcs.LagHashSymmetric = a.LagHashSymmetric
+			pcs.LagHashFields = ConvertLaAggHashFieldsToModelLagHashFields(a.LagHashFields)
 			//pcs.Ifindex = int32(a.HwAggId)
 			for _, m := range a.PortNumList {
 				name := utils.GetNameFromIfIndex(int32(m))
@@ -945,11 +1169,15 @@ func (la *LACPDServiceHandler) GetLaPortChannelState(IntfRef string) (*lacpd.LaP
 			*/
 			pcs.OperState = "DOWN"
 			pcs.MinLinks = int16(ac.MinLinks)
+			pcs.MaxLinks = int16(ac.MaxLinks)
+			pcs.MuxControl = int32(ac.MuxControl)
 			pcs.Interval = ConvertLaAggIntervalToLacpPeriod(ac.Lacp.Interval)
 			pcs.LacpMode = ConvertLaAggModeToModelLacpMode(ac.Lacp.Mode)
 			pcs.SystemIdMac = ac.Lacp.SystemIdMac
 			pcs.SystemPriority = int16(ac.Lacp.SystemPriority)
-			pcs.LagHash = int32(ac.HashMode)
+			pcs.LagHash = ConvertLaAggHashModeToModelLagHash(ac.HashMode)
+			pcs.LagHashSymmetric = ac.HashSymmetric
+			pcs.LagHashFields = ConvertLaAggHashFieldsToModelLagHashFields(ac.HashFields)
 			//pcs.Ifindex = int32(a.HwAggId)
 			for _, m := range ac.LagMembers {
 				name := utils.GetNameFromIfIndex(int32(m))
@@ -998,11 +1226,15 @@ func (la *LACPDServiceHandler) GetBulkLaPortChannelState(fromIndex lacpd.Int, co
 				}
 				nextLagState.OperState = "DOWN"
 				nextLagState.MinLinks = int16(ac.MinLinks)
+				nextLagState.MaxLinks = int16(ac.MaxLinks)
+				nextLagState.MuxControl = int32(ac.MuxControl)
 				nextLagState.Interval = ConvertLaAggIntervalToLacpPeriod(ac.Lacp.Interval)
 				nextLagState.LacpMode = ConvertLaAggModeToModelLacpMode(ac.Lacp.Mode)
 				nextLagState.SystemIdMac = ac.Lacp.SystemIdMac
 				nextLagState.SystemPriority = int16(ac.Lacp.SystemPriority)
-				nextLagState.LagHash = int32(ac.HashMode)
+				nextLagState.LagHash = ConvertLaAggHashModeToModelLagHash(ac.HashMode)
+				nextLagState.LagHashSymmetric = ac.HashSymmetric
+				nextLagState.LagHashFields = ConvertLaAggHashFieldsToModelLagHashFields(ac.HashFields)
 				for _, m := range ac.LagMembers {
 					name := utils.GetNameFromIfIndex(int32(m))
 					if name != "" {
@@ -1037,11 +1269,11 @@ func (la *LACPDServiceHandler) GetBulkLaPortChannelState(fromIndex lacpd.Int, co
 				if a.AdminState {
 					nextLagState.AdminState = "UP"
 				}
-				nextLagState.OperState = "DOWN"
-				if a.OperState {
-					nextLagState.OperState = "UP"
-				}
+				nextLagState.OperState = ConvertLaAggOperStateToModelOperState(a)
+				nextLagState.OperDownReason = a.OperDownReason
 				nextLagState.MinLinks = int16(a.AggMinLinks)
+				nextLagState.MaxLinks = int16(a.AggMaxLinks)
+				nextLagState.MuxControl = int32(a.AggMuxControl)
 				nextLagState.Interval = ConvertLaAggIntervalToLacpPeriod(a.Config.Interval)
 				nextLagState.LacpMode = ConvertLaAggModeToModelLacpMode(a.Config.Mode)
 				nextLagState.SystemIdMac = fmt.Sprintf("%02x:%02x:%02x:%02x:%02x:%02x", a.AggMacAddr[0],
@@ -1051,7 +1283,9 @@ func (la *LACPDServiceHandler) GetBulkLaPortChannelState(fromIndex lacpd.Int, co
 					a.AggMacAddr[4],
 					a.AggMacAddr[5])
 				nextLagState.SystemPriority = int16(a.AggPriority)
-				nextLagState.LagHash = int32(a.LagHash)
+				nextLagState.LagHash = ConvertLaAggHashModeToModelLagHash(a.LagHash)
+				nextLagState.LagHashSymmetric = a.LagHashSymmetric
+				nextLagState.LagHashFields = ConvertLaAggHashFieldsToModelLagHashFields(a.LagHashFields)
 				if len(a.PortNumList) > 0 {
 					nextLagState.IntfRefList = make([]string, 0)
 				}
@@ -1193,6 +1427,8 @@ func (la *LACPDServiceHandler) GetLaPortChannelIntfRefListState(intfref string)
 
 			if pcms.Distributing {
 				pcms.OperState = "UP"
+			} else if p.IsPortStandby() {
+				pcms.OperState = "STANDBY"
 			} else {
 				pcms.OperState = "DOWN"
 			}
@@ -1418,6 +1654,8 @@ func (la *LACPDServiceHandler) GetBulkLaPortChannelIntfRefListState(fromIndex la
 
 				if nextLagMemberState.Distributing {
 					nextLagMemberState.OperState = "UP"
+				} else if p.IsPortStandby() {
+					nextLagMemberState.OperState = "STANDBY"
 				} else {
 					nextLagMemberState.OperState = "DOWN"
 				}
@@ -1753,6 +1991,7 @@ func (la *LACPDServiceHandler) GetLacpGlobalState(vrf string) (*lacpd.LacpGlobal
 	obj := &lacpd.LacpGlobalState{}
 	obj.Vrf = "default"
 	obj.AdminState = "UP"
+	obj.MuxControl = int32(lacp.LacpSysMuxControl)
 	if utils.LacpGlobalStateGet() != utils.LACP_GLOBAL_ENABLE {
 		obj.AdminState = "DOWN"
 	}
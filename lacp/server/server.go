@@ -37,6 +37,15 @@ const (
 	LAConfigMsgDeleteConversationId
 	LAConfigMsgAddL3IntfType
 	LAConfigMsgAddL2IntfType
+	LAConfigMsgUpdateLaPortChannelMinLinks
+	LAConfigMsgUpdateLaPortChannelMaxLinks
+	LAConfigMsgUpdateLaPortChannelLacpVersion
+	LAConfigMsgUpdateLaPortChannelConversationAdminLink
+	LAConfigMsgUpdateLaPortChannelServiceConversationMap
+	LAConfigMsgUpdateLaPortChannelDiscardWrongConversation
+	LAConfigMsgUpdateLaPortChannelLinkNumberId
+	LAConfigMsgUpdateLaPortChannelMuxControl
+	LAConfigMsgUpdateLacpGlobalMuxControl
 )
 
 type LAConfig struct {
@@ -144,7 +153,10 @@ func (s *LAServer) processLaConfig(conf LAConfig) {
 	case LAConfigMsgUpdateLaPortChannelLagHash:
 		s.logger.Info("CONFIG: Link Aggregation Group / Port Channel Lag Hash Mode")
 		config := conf.Msgdata.(*lacp.LaAggConfig)
-		lacp.SetLaAggHashMode(config.Id, config.HashMode)
+		err := lacp.SetLaAggHashConfig(config.Id, config.HashMode, config.HashSymmetric, config.HashFields)
+		if err != nil {
+			s.logger.Err(fmt.Sprintln("CONFIG: Link Aggregation Group / Port Channel Lag Hash Mode failed", err))
+		}
 
 	case LAConfigMsgUpdateLaPortChannelSystemIdMac:
 		s.logger.Info("CONFIG: Link Aggregation Group / Port Channel SystemId MAC")
@@ -209,6 +221,57 @@ func (s *LAServer) processLaConfig(conf LAConfig) {
 				lacp.SetLaAggPortLacpPeriod(uint16(pId), config.Lacp.Interval)
 			}
 		}
+	case LAConfigMsgUpdateLaPortChannelMinLinks:
+		s.logger.Info("CONFIG: Link Aggregation Group / Port Channel Min Links")
+		config := conf.Msgdata.(*lacp.LaAggConfig)
+		lacp.SetLaAggMinLinks(config.Id, config.MinLinks)
+	case LAConfigMsgUpdateLaPortChannelMaxLinks:
+		s.logger.Info("CONFIG: Link Aggregation Group / Port Channel Max Links")
+		config := conf.Msgdata.(*lacp.LaAggConfig)
+		lacp.SetLaAggMaxLinks(config.Id, config.MaxLinks)
+	case LAConfigMsgUpdateLaPortChannelLacpVersion:
+		s.logger.Info("CONFIG: Link Aggregation Group / Port Channel LACP Version")
+		config := conf.Msgdata.(*lacp.LaAggConfig)
+		err := lacp.SetLaAggLacpVersion(config.Id, config.Version)
+		if err != nil {
+			s.logger.Err(fmt.Sprintln("CONFIG: Link Aggregation Group / Port Channel LACP Version failed", err))
+		}
+	case LAConfigMsgUpdateLaPortChannelConversationAdminLink:
+		s.logger.Info("CONFIG: Link Aggregation Group / Port Channel Conversation Admin Link")
+		config := conf.Msgdata.(*lacp.LaAggConfig)
+		lacp.SetLaAggConversationAdminLink(config.Id, config.ConversationAdminLink)
+	case LAConfigMsgUpdateLaPortChannelServiceConversationMap:
+		s.logger.Info("CONFIG: Link Aggregation Group / Port Channel Service Conversation Map")
+		config := conf.Msgdata.(*lacp.LaAggConfig)
+		lacp.SetLaAggAdminServiceConversationMap(config.Id, config.AdminServiceConversationMap)
+	case LAConfigMsgUpdateLaPortChannelDiscardWrongConversation:
+		s.logger.Info("CONFIG: Link Aggregation Group / Port Channel Discard Wrong Conversation")
+		config := conf.Msgdata.(*lacp.LaAggConfig)
+		lacp.SetLaAggDiscardWrongConversation(config.Id, config.DiscardWrongConversation)
+	case LAConfigMsgUpdateLaPortChannelLinkNumberId:
+		s.logger.Info("CONFIG: Link Aggregation Group / Port Channel Link Number Id")
+		config := conf.Msgdata.(*lacp.LaAggConfig)
+		var a *lacp.LaAggregator
+		if lacp.LaFindAggById(config.Id, &a) {
+			// configured ports, ports without an entry use their port number
+			for _, pId := range a.PortNumList {
+				lacp.SetLaAggPortLinkNumberId(pId, config.LagMemberLinkNumberIds[pId])
+			}
+		}
+	case LAConfigMsgUpdateLaPortChannelMuxControl:
+		s.logger.Info("CONFIG: Link Aggregation Group / Port Channel Mux Control")
+		config := conf.Msgdata.(*lacp.LaAggConfig)
+		err := lacp.SetLaAggMuxControl(config.Id, config.MuxControl)
+		if err != nil {
+			s.logger.Err(fmt.Sprintln("CONFIG: Link Aggregation Group / Port Channel Mux Control failed", err))
+		}
+	case LAConfigMsgUpdateLacpGlobalMuxControl:
+		s.logger.Info("CONFIG: LACP Global Mux Control")
+		config := conf.Msgdata.(*lacp.LacpSysConfig)
+		err := lacp.SetLacpSysMuxControl(config.MuxControl)
+		if err != nil {
+			s.logger.Err(fmt.Sprintln("CONFIG: LACP Global Mux Control failed", err))
+		}
 	case LAConfigMsgCreateLaAggPort:
 		s.logger.Info("CONFIG: Create Link Aggregation Port")
 		config := conf.Msgdata.(*lacp.LaAggPortConfig)
@@ -24,10 +24,13 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"l2/lldp/config"
 	"l2/lldp/server"
 	"l2/lldp/utils"
+	"strconv"
 	"sync"
 )
 
@@ -81,7 +84,12 @@ func SendIntfConfig(intfRef, txrxMode string, enable bool) (bool, error) {
 	default:
 		return false, errors.New("Invalid TxRxMode string provided")
 	}
-	lldpapi.server.IntfCfgCh <- &config.IntfConfig{ifIndex, enable, txrxModeEnum}
+	lldpapi.server.IntfCfgCh <- &config.IntfConfig{
+		IfIndex:  ifIndex,
+		Fields:   config.INTF_CFG_ENABLE | config.INTF_CFG_TXRX_MODE,
+		Enable:   enable,
+		TxRxMode: txrxModeEnum,
+	}
 	return proceed, err
 }
 
@@ -101,7 +109,312 @@ func UpdateIntfConfig(intfRef, txrxMode string, enable bool) (bool, error) {
 	default:
 		return false, errors.New("Invalid TxRxMode string provided")
 	}
-	lldpapi.server.IntfCfgCh <- &config.IntfConfig{ifIndex, enable, txrxModeEnum}
+	lldpapi.server.IntfCfgCh <- &config.IntfConfig{
+		IfIndex:  ifIndex,
+		Fields:   config.INTF_CFG_ENABLE | config.INTF_CFG_TXRX_MODE,
+		Enable:   enable,
+		TxRxMode: txrxModeEnum,
+	}
+	return proceed, err
+}
+
+func validateMedConfig(medCfg *config.MedConfig) error {
+	for _, policy := range medCfg.Policies {
+		if policy.VlanId < 0 || policy.VlanId > 4094 {
+			return errors.New("Invalid LLDP-MED network policy vlan id")
+		}
+		if policy.Priority < 0 || policy.Priority > 7 {
+			return errors.New("Invalid LLDP-MED network policy l2 priority")
+		}
+		if policy.Dscp < 0 || policy.Dscp > 63 {
+			return errors.New("Invalid LLDP-MED network policy dscp value")
+		}
+	}
+	if medCfg.Location.Elin != "" && (len(medCfg.Location.Elin) < 10 || len(medCfg.Location.Elin) > 25) {
+		return errors.New("LLDP-MED ELIN should be 10 to 25 digits")
+	}
+	if medCfg.Location.CountryCode != "" && len(medCfg.Location.CountryCode) != 2 {
+		return errors.New("LLDP-MED location country code should be 2 letters")
+	}
+	if medCfg.PowerPriority > 3 {
+		return errors.New("Invalid LLDP-MED power priority")
+	}
+	if medCfg.PowerValue < 0 || medCfg.PowerValue > 1023 {
+		return errors.New("LLDP-MED power value should be in the range of 0 to 102.3 Watt")
+	}
+	return nil
+}
+
+func UpdateIntfMedConfig(intfRef, medMode string, medCfg config.MedConfig) (bool, error) {
+	ifIndex, proceed, err := validateExistingIntfConfig(intfRef)
+	if !proceed {
+		return proceed, err
+	}
+	switch medMode {
+	case config.MED_MODE_Disabled:
+		medCfg.Mode = config.MED_DISABLED
+	case config.MED_MODE_Auto:
+		medCfg.Mode = config.MED_AUTO
+	case config.MED_MODE_Enabled:
+		medCfg.Mode = config.MED_ENABLED
+	default:
+		return false, errors.New("Invalid LLDP-MED Mode string provided")
+	}
+	err = validateMedConfig(&medCfg)
+	if err != nil {
+		return false, err
+	}
+	lldpapi.server.IntfCfgCh <- &config.IntfConfig{IfIndex: ifIndex, Fields: config.INTF_CFG_MED, Med: medCfg}
+	return proceed, err
+}
+
+func UpdateIntfMaxNeighbors(intfRef string, maxNeighbors int32) (bool, error) {
+	ifIndex, proceed, err := validateExistingIntfConfig(intfRef)
+	if !proceed {
+		return proceed, err
+	}
+	if maxNeighbors <= 0 {
+		return false, errors.New("Maximum neighbors per interface should be greater than 0")
+	}
+	lldpapi.server.IntfCfgCh <- &config.IntfConfig{IfIndex: ifIndex, Fields: config.INTF_CFG_MAX_NEIGHBORS,
+		MaxNeighbors: maxNeighbors}
+	return proceed, err
+}
+
+/*  Convert lldp agent names to agent bitmap
+ */
+func convertAgents(agents []string) (uint8, error) {
+	var agentTypes uint8
+	for _, agent := range agents {
+		switch agent {
+		case config.AGENT_NearestBridge:
+			agentTypes |= config.AGENT_NEAREST_BRIDGE
+		case config.AGENT_NearestNonTpmrBridge:
+			agentTypes |= config.AGENT_NEAREST_NON_TPMR
+		case config.AGENT_NearestCustomerBridge:
+			agentTypes |= config.AGENT_NEAREST_CUSTOMER
+		case config.AGENT_Cdp:
+			agentTypes |= config.AGENT_CDP
+		default:
+			return 0, errors.New("Invalid LLDP agent string provided " + agent)
+		}
+	}
+	if agentTypes == 0 {
+		return 0, errors.New("Atleast one LLDP agent needs to be configured, please disable LLDP instead")
+	}
+	return agentTypes, nil
+}
+
+func UpdateIntfAgents(intfRef string, agents []string) (bool, error) {
+	ifIndex, proceed, err := validateExistingIntfConfig(intfRef)
+	if !proceed {
+		return proceed, err
+	}
+	agentTypes, err := convertAgents(agents)
+	if err != nil {
+		return false, err
+	}
+	lldpapi.server.IntfCfgCh <- &config.IntfConfig{IfIndex: ifIndex, Fields: config.INTF_CFG_AGENTS, Agents: agentTypes}
+	return proceed, err
+}
+
+/*  Convert port id subtype name to port id selection
+ */
+func convertPortIdSubtype(subtype string) (uint8, error) {
+	switch subtype {
+	case config.PORT_ID_IfName:
+		return config.PORT_ID_IF_NAME, nil
+	case config.PORT_ID_IfAlias:
+		return config.PORT_ID_IF_ALIAS, nil
+	case config.PORT_ID_MacAddr:
+		return config.PORT_ID_MAC_ADDR, nil
+	case config.PORT_ID_Local:
+		return config.PORT_ID_LOCAL, nil
+	}
+	return 0, errors.New("Invalid port id subtype string provided " + subtype)
+}
+
+func UpdateIntfPortIdSubtype(intfRef, subtype string) (bool, error) {
+	ifIndex, proceed, err := validateExistingIntfConfig(intfRef)
+	if !proceed {
+		return proceed, err
+	}
+	portIdSubtype, err := convertPortIdSubtype(subtype)
+	if err != nil {
+		return false, err
+	}
+	lldpapi.server.IntfCfgCh <- &config.IntfConfig{IfIndex: ifIndex, Fields: config.INTF_CFG_PORT_ID_SUBTYPE,
+		PortIdSubtype: portIdSubtype}
+	return proceed, err
+}
+
+/*  Update chassis id advertised on all the ports, local string is only used with Local subtype
+ */
+func UpdateChassisId(subtype, local string) (bool, error) {
+	chassisId := &config.ChassisIdConfig{}
+	switch subtype {
+	case config.CHASSIS_ID_SystemMac:
+		chassisId.Subtype = config.CHASSIS_ID_SYSTEM_MAC
+	case config.CHASSIS_ID_Hostname:
+		chassisId.Subtype = config.CHASSIS_ID_HOSTNAME
+	case config.CHASSIS_ID_Local:
+		if len(local) == 0 || len(local) > config.CHASSIS_ID_MAX_LEN {
+			return false, errors.New("Local chassis id length should be 1-" +
+				strconv.Itoa(config.CHASSIS_ID_MAX_LEN))
+		}
+		chassisId.Subtype = config.CHASSIS_ID_LOCAL
+		chassisId.Local = local
+	default:
+		return false, errors.New("Invalid chassis id subtype string provided " + subtype)
+	}
+	lldpapi.server.ChassisIdCfgCh <- chassisId
+	return true, nil
+}
+
+/*  Convert optional tlv names to tlv bitmap, empty list means only mandatory tlv's are advertised
+ */
+func convertTLVs(tlvs []string) (uint8, error) {
+	var tlvBitmap uint8
+	for _, tlv := range tlvs {
+		switch tlv {
+		case config.TLV_PortDescription:
+			tlvBitmap |= config.TLV_PORT_DESCRIPTION
+		case config.TLV_SystemName:
+			tlvBitmap |= config.TLV_SYSTEM_NAME
+		case config.TLV_SystemDescription:
+			tlvBitmap |= config.TLV_SYSTEM_DESCRIPTION
+		case config.TLV_SystemCapabilities:
+			tlvBitmap |= config.TLV_SYSTEM_CAP
+		case config.TLV_ManagementAddress:
+			tlvBitmap |= config.TLV_MGMT_ADDRESS
+		case config.TLV_Dot1:
+			tlvBitmap |= config.TLV_DOT1
+		case config.TLV_Dot3:
+			tlvBitmap |= config.TLV_DOT3
+		default:
+			return 0, errors.New("Invalid optional TLV string provided " + tlv)
+		}
+	}
+	return tlvBitmap, nil
+}
+
+func UpdateIntfTLVs(intfRef string, tlvs []string) (bool, error) {
+	ifIndex, proceed, err := validateExistingIntfConfig(intfRef)
+	if !proceed {
+		return proceed, err
+	}
+	tlvBitmap, err := convertTLVs(tlvs)
+	if err != nil {
+		return false, err
+	}
+	lldpapi.server.IntfCfgCh <- &config.IntfConfig{
+		IfIndex: ifIndex,
+		Fields:  config.INTF_CFG_TLVS,
+		TLVs:    tlvBitmap,
+	}
+	return proceed, err
+}
+
+/*  Validate per port transmit timers, 0 means global/default value is used
+ */
+func validateTxTimers(txInterval, txHold, reinitDelay int32) error {
+	if txInterval != 0 && (txInterval < config.TX_INTERVAL_MIN || txInterval > config.TX_INTERVAL_MAX) {
+		return errors.New("Invalid transmit interval " + strconv.Itoa(int(txInterval)) + ", valid range is " +
+			strconv.Itoa(config.TX_INTERVAL_MIN) + "-" + strconv.Itoa(config.TX_INTERVAL_MAX))
+	}
+	if txHold != 0 && (txHold < config.TX_HOLD_MIN || txHold > config.TX_HOLD_MAX) {
+		return errors.New("Invalid transmit hold multiplier " + strconv.Itoa(int(txHold)) + ", valid range is " +
+			strconv.Itoa(config.TX_HOLD_MIN) + "-" + strconv.Itoa(config.TX_HOLD_MAX))
+	}
+	if reinitDelay != 0 && (reinitDelay < config.REINIT_DELAY_MIN || reinitDelay > config.REINIT_DELAY_MAX) {
+		return errors.New("Invalid reinit delay " + strconv.Itoa(int(reinitDelay)) + ", valid range is " +
+			strconv.Itoa(config.REINIT_DELAY_MIN) + "-" + strconv.Itoa(config.REINIT_DELAY_MAX))
+	}
+	return nil
+}
+
+/*  Update per port msgTxInterval, msgTxHold & reinitDelay, 0 means global/default value is used
+ */
+func UpdateIntfTxTimers(intfRef string, txInterval, txHold, reinitDelay int32) (bool, error) {
+	ifIndex, proceed, err := validateExistingIntfConfig(intfRef)
+	if !proceed {
+		return proceed, err
+	}
+	err = validateTxTimers(txInterval, txHold, reinitDelay)
+	if err != nil {
+		return false, err
+	}
+	lldpapi.server.IntfCfgCh <- &config.IntfConfig{
+		IfIndex:     ifIndex,
+		Fields:      config.INTF_CFG_TX_TIMERS,
+		TxInterval:  txInterval,
+		TxHold:      txHold,
+		ReinitDelay: reinitDelay,
+	}
+	return proceed, err
+}
+
+/*  Update complete interface configuration in one go. Used by plugins which get complete interface
+ *  config in one request
+ */
+func UpdateIntfFullConfig(userCfg config.IntfUserConfig) (bool, error) {
+	var txrxModeEnum uint8
+	ifIndex, proceed, err := validateExistingIntfConfig(userCfg.IntfRef)
+	if !proceed {
+		return proceed, err
+	}
+	switch userCfg.TxRxMode {
+	case config.TX_RX_MODE_TxRx:
+		txrxModeEnum = config.TXRX
+	case config.TX_RX_MODE_TxOnly:
+		txrxModeEnum = config.TX_ONLY
+	case config.TX_RX_MODE_RxOnly:
+		txrxModeEnum = config.RX_ONLY
+	default:
+		return false, errors.New("Invalid TxRxMode string provided")
+	}
+	if userCfg.MaxNeighbors < 0 {
+		return false, errors.New("Maximum neighbors per interface should be greater than 0")
+	}
+	err = validateTxTimers(userCfg.TxInterval, userCfg.TxHold, userCfg.ReinitDelay)
+	if err != nil {
+		return false, err
+	}
+	intfCfg := &config.IntfConfig{
+		IfIndex:     ifIndex,
+		Fields:      config.INTF_CFG_ENABLE | config.INTF_CFG_TXRX_MODE | config.INTF_CFG_TX_TIMERS,
+		Enable:      userCfg.Enable,
+		TxRxMode:    txrxModeEnum,
+		TxInterval:  userCfg.TxInterval,
+		TxHold:      userCfg.TxHold,
+		ReinitDelay: userCfg.ReinitDelay,
+	}
+	if len(userCfg.Agents) > 0 {
+		intfCfg.Agents, err = convertAgents(userCfg.Agents)
+		if err != nil {
+			return false, err
+		}
+		intfCfg.Fields |= config.INTF_CFG_AGENTS
+	}
+	if userCfg.MaxNeighbors > 0 {
+		intfCfg.MaxNeighbors = userCfg.MaxNeighbors
+		intfCfg.Fields |= config.INTF_CFG_MAX_NEIGHBORS
+	}
+	if userCfg.PortIdSubtype != "" {
+		intfCfg.PortIdSubtype, err = convertPortIdSubtype(userCfg.PortIdSubtype)
+		if err != nil {
+			return false, err
+		}
+		intfCfg.Fields |= config.INTF_CFG_PORT_ID_SUBTYPE
+	}
+	if userCfg.TLVs != nil {
+		intfCfg.TLVs, err = convertTLVs(userCfg.TLVs)
+		if err != nil {
+			return false, err
+		}
+		intfCfg.Fields |= config.INTF_CFG_TLVS
+	}
+	lldpapi.server.IntfCfgCh <- intfCfg
 	return proceed, err
 }
 
@@ -120,6 +433,9 @@ func SendGlobalConfig(vrf, txrxMode string, enable, snoopAndDrop bool, tranmitIn
 	default:
 		return false, errors.New("Invalid TxRxMode string provided")
 	}
+	if snoopAndDrop && !lldpapi.server.SnoopAndDropSupported() {
+		return false, errors.New("Snoop and drop is not supported by the asic plugin")
+	}
 	debug.Logger.Debug("LLDP API received auto-create global config:", vrf, enable, tranmitInterval, txrxMode, snoopAndDrop)
 	lldpapi.server.GblCfgCh <- &config.Global{vrf, enable, tranmitInterval, txrxModeEnum, snoopAndDrop}
 	debug.Logger.Debug("LLDP API pushed the global config on channel and returning true to confgMgr for create")
@@ -141,6 +457,9 @@ func UpdateGlobalConfig(vrf, txrxMode string, enable, snoopAndDrop bool, tranmit
 	default:
 		return false, errors.New("Invalid TxRxMode string provided")
 	}
+	if snoopAndDrop && !lldpapi.server.SnoopAndDropSupported() {
+		return false, errors.New("Snoop and drop is not supported by the asic plugin")
+	}
 	debug.Logger.Debug("LLDP API received global config:", vrf, enable, tranmitInterval, txrxModeEnum, snoopAndDrop)
 	lldpapi.server.GblCfgCh <- &config.Global{vrf, enable, tranmitInterval, txrxModeEnum, snoopAndDrop}
 	debug.Logger.Debug("LLDP API pushed the global config on channel and returning true to confgMgr")
@@ -151,6 +470,209 @@ func SendPortStateChange(ifIndex int32, state string) {
 	lldpapi.server.IfStateCh <- &config.PortState{ifIndex, state}
 }
 
+func SendPortL3StateChange(ifIndex int32, l3Enabled bool) {
+	lldpapi.server.IfL3StateCh <- &config.PortL3State{ifIndex, l3Enabled}
+}
+
+func SendVlanStateChange(vlanId int32, name string, tagPorts, untagPorts []int32) {
+	lldpapi.server.VlanStateCh <- &config.VlanState{vlanId, name, tagPorts, untagPorts}
+}
+
+func SendLagStateChange(ifIndex int32, members []int32) {
+	lldpapi.server.LagStateCh <- &config.LagState{ifIndex, members}
+}
+
+/*  Clear lldp statistics for the interface, if no interface is provided then statistics for all the
+ *  interfaces and global statistics are cleared
+ */
+func ClearStatistics(intfRef string) (bool, error) {
+	if intfRef == "" {
+		lldpapi.server.ClearStatsCh <- &config.ClearStats{All: true}
+		return true, nil
+	}
+	ifIndex, exists := lldpapi.server.EntryExist(intfRef)
+	if !exists {
+		return false, errors.New("Clearing statistics for " + intfRef + " failed as no entry found")
+	}
+	lldpapi.server.ClearStatsCh <- &config.ClearStats{IfIndex: ifIndex}
+	return true, nil
+}
+
+/*  Set lldpNotificationInterval in seconds, neighbor change events are notified atmost once in the
+ *  interval
+ */
+func SetNotificationInterval(interval int32) (bool, error) {
+	if interval < 1 || interval > server.LLDP_MAX_NOTIFICATION_INTERVAL {
+		return false, errors.New("Invalid notification interval " + strconv.Itoa(int(interval)) +
+			", valid range is 1-" + strconv.Itoa(server.LLDP_MAX_NOTIFICATION_INTERVAL))
+	}
+	lldpapi.server.NotificationIntervalCh <- interval
+	return true, nil
+}
+
+/*  Subscribe for neighbor change events, returns subscription id and the channel on which events
+ *  are delivered. Every event carries neighbor information before & after the change
+ */
+func SubscribeEvents() (int32, <-chan config.EventInfo) {
+	return lldpapi.server.Subscribe()
+}
+
+/*  Unsubscribe neighbor change events, channel returned during subscription is closed
+ */
+func UnsubscribeEvents(id int32) (bool, error) {
+	if !lldpapi.server.Unsubscribe(id) {
+		return false, errors.New("No subscription found for id " + strconv.Itoa(int(id)))
+	}
+	return true, nil
+}
+
+/*  Update cabling plan, every port can have only one expected neighbor. Existing plan is replaced
+ *  and empty plan disables cabling verification
+ */
+func UpdateCablingPlan(plan []config.ExpectedNeighbor) (bool, error) {
+	intfs := make(map[int32]bool, len(plan))
+	for _, expected := range plan {
+		ifIndex, exists := lldpapi.server.EntryExist(expected.IntfRef)
+		if !exists {
+			return false, errors.New("Invalid IntfRef " + expected.IntfRef + " in cabling plan")
+		}
+		if intfs[ifIndex] {
+			return false, errors.New("Duplicate cabling plan entry for " + expected.IntfRef)
+		}
+		if expected.SystemName == "" {
+			return false, errors.New("Expected system name is not provided for " + expected.IntfRef)
+		}
+		intfs[ifIndex] = true
+	}
+	lldpapi.server.CablingPlanCh <- plan
+	return true, nil
+}
+
+/*  Load cabling plan from json file, file has list of expected neighbors:
+ *	[{"IntfRef": "eth1", "SystemName": "spine1", "PortId": "Ethernet1"}]
+ */
+func LoadCablingPlan(fileName string) (bool, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return false, err
+	}
+	var plan []config.ExpectedNeighbor
+	err = json.Unmarshal(data, &plan)
+	if err != nil {
+		return false, errors.New("Parsing cabling plan " + fileName + " failed: " + err.Error())
+	}
+	return UpdateCablingPlan(plan)
+}
+
+func GetCablingPlan() []config.ExpectedNeighbor {
+	return lldpapi.server.GetCablingPlan()
+}
+
+func validateEtsConfig(ets *config.EtsConfig, maxTCs uint8) error {
+	for _, tc := range ets.PriorityTC {
+		if tc >= maxTCs {
+			return errors.New("ETS traffic class of priority should be less than max traffic classes")
+		}
+	}
+	var bandwidth int
+	var etsTCs bool
+	for tc, tsa := range ets.TSA {
+		switch tsa {
+		case config.TSA_ETS:
+			etsTCs = true
+			bandwidth += int(ets.TCBandwidth[tc])
+		case config.TSA_STRICT, config.TSA_CBS, config.TSA_VENDOR_SPECIFIC:
+			if ets.TCBandwidth[tc] != 0 {
+				return errors.New("ETS bandwidth can only be assigned to traffic class with ETS algorithm")
+			}
+		default:
+			return errors.New("Invalid ETS transmission selection algorithm")
+		}
+	}
+	if etsTCs && bandwidth != 100 {
+		return errors.New("ETS bandwidth of traffic classes should add up to 100 percent")
+	}
+	return nil
+}
+
+func validateDcbxConfig(dcbxCfg *config.DcbxConfig) error {
+	maxTCs := dcbxCfg.MaxTCs
+	if maxTCs == 0 {
+		maxTCs = config.DCBX_MAX_TCS
+	}
+	if maxTCs > config.DCBX_MAX_TCS {
+		return errors.New("DCBX max traffic classes should be in the range of 1 to 8")
+	}
+	err := validateEtsConfig(&dcbxCfg.Ets, maxTCs)
+	if err != nil {
+		return err
+	}
+	err = validateEtsConfig(&dcbxCfg.EtsRecommendation, maxTCs)
+	if err != nil {
+		return errors.New("ETS recommendation: " + err.Error())
+	}
+	if dcbxCfg.PfcCap > config.DCBX_MAX_TCS {
+		return errors.New("PFC capability should be in the range of 0 to 8")
+	}
+	var pfcPriorities uint8
+	for prio := uint(0); prio < config.DCBX_MAX_PRIORITIES; prio++ {
+		pfcPriorities += (dcbxCfg.PfcEnable >> prio) & 0x1
+	}
+	if pfcPriorities > dcbxCfg.PfcCap {
+		return errors.New("PFC is enabled on more priorities than PFC capability")
+	}
+	if len(dcbxCfg.Apps) > config.DCBX_MAX_APP_ENTRIES {
+		return errors.New("Application priority table can have at most " +
+			strconv.Itoa(config.DCBX_MAX_APP_ENTRIES) + " entries")
+	}
+	apps := make(map[config.AppPriority]bool, len(dcbxCfg.Apps))
+	for _, app := range dcbxCfg.Apps {
+		if app.Selector < config.APP_SEL_ETHERTYPE || app.Selector > config.APP_SEL_TCP_UDP {
+			return errors.New("Invalid application priority selector")
+		}
+		if app.Priority >= config.DCBX_MAX_PRIORITIES {
+			return errors.New("Application priority should be in the range of 0 to 7")
+		}
+		key := config.AppPriority{Selector: app.Selector, Protocol: app.Protocol}
+		if apps[key] {
+			return errors.New("Duplicate application priority entry for protocol " +
+				strconv.Itoa(int(app.Protocol)))
+		}
+		apps[key] = true
+	}
+	return nil
+}
+
+/*  Update IEEE 802.1Qaz DCBX configuration of the port, DCBX tlv's are advertised by nearest bridge
+ *  agent only
+ */
+func UpdateIntfDcbxConfig(intfRef string, dcbxCfg config.DcbxConfig) (bool, error) {
+	ifIndex, proceed, err := validateExistingIntfConfig(intfRef)
+	if !proceed {
+		return proceed, err
+	}
+	err = validateDcbxConfig(&dcbxCfg)
+	if err != nil {
+		return false, err
+	}
+	lldpapi.server.IntfCfgCh <- &config.IntfConfig{IfIndex: ifIndex, Fields: config.INTF_CFG_DCBX, Dcbx: dcbxCfg}
+	return proceed, err
+}
+
+/*  Get DCB operational parameters negotiated on the port, to be programmed by forwarding plane
+ */
+func GetIntfDcbxState(intfRef string) *config.DcbxState {
+	ifIndex, exists := lldpapi.server.EntryExist(intfRef)
+	if !exists {
+		return nil
+	}
+	return lldpapi.server.GetDcbxState(ifIndex)
+}
+
+func GetDcbxStates() []config.DcbxState {
+	return lldpapi.server.GetDcbxStates()
+}
+
 func GetIntfs(idx int, cnt int) (int, int, []config.Intf) {
 	n, c, result := lldpapi.server.GetIntfs(idx, cnt)
 	return n, c, result
@@ -165,6 +687,15 @@ func GetIntfState(intfRef string) *config.IntfState {
 	return lldpapi.server.GetIntfState(intfRef)
 }
 
+func GetNeighbors(idx int, cnt int) (int, int, []config.IntfState) {
+	n, c, result := lldpapi.server.GetNeighbors(idx, cnt)
+	return n, c, result
+}
+
+func GetIntfNeighbors(intfRef string) []config.IntfState {
+	return lldpapi.server.GetIntfNeighbors(intfRef)
+}
+
 func UpdateCache(sysInfo *config.SystemInfo) {
 	lldpapi.server.UpdateCacheCh <- sysInfo
 }
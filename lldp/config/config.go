@@ -32,6 +32,120 @@ const (
 	RX_ONLY           = 2
 )
 
+// LLDP-MED (TIA-1057) modes
+const (
+	MED_MODE_Disabled = "Disabled"
+	MED_DISABLED      = 0
+	MED_MODE_Auto     = "Auto" // advertise MED tlv's only after MED endpoint is detected
+	MED_AUTO          = 1
+	MED_MODE_Enabled  = "Enabled" // always advertise MED tlv's
+	MED_ENABLED       = 2
+)
+
+// LLDP agents per destination mac address, this is used as bitmap in per interface configuration
+const (
+	AGENT_NearestBridge         = "NearestBridge"
+	AGENT_NEAREST_BRIDGE        = 0x1 // 01:80:c2:00:00:0e
+	AGENT_NearestNonTpmrBridge  = "NearestNonTpmrBridge"
+	AGENT_NEAREST_NON_TPMR      = 0x2 // 01:80:c2:00:00:03
+	AGENT_NearestCustomerBridge = "NearestCustomerBridge"
+	AGENT_NEAREST_CUSTOMER      = 0x4 // 01:80:c2:00:00:00
+	AGENT_ALL                   = AGENT_NEAREST_BRIDGE | AGENT_NEAREST_NON_TPMR | AGENT_NEAREST_CUSTOMER
+	// Cisco Discovery Protocol version 2 agent, not part of AGENT_ALL which are lldp agents
+	AGENT_Cdp = "CDP"
+	AGENT_CDP = 0x8 // 01:00:0c:cc:cc:cc
+)
+
+// Chassis ID selection, global for the system
+const (
+	CHASSIS_ID_SystemMac  = "SystemMac" // mac address subtype with system mac
+	CHASSIS_ID_SYSTEM_MAC = 0
+	CHASSIS_ID_Hostname   = "Hostname" // local subtype with hostname
+	CHASSIS_ID_HOSTNAME   = 1
+	CHASSIS_ID_Local      = "Local" // local subtype with configured string
+	CHASSIS_ID_LOCAL      = 2
+	CHASSIS_ID_MAX_LEN    = 255
+)
+
+// Port ID selection per interface
+const (
+	PORT_ID_IfName   = "IfName" // interface name subtype with port name
+	PORT_ID_IF_NAME  = 0
+	PORT_ID_IfAlias  = "IfAlias" // interface alias subtype with port description
+	PORT_ID_IF_ALIAS = 1
+	PORT_ID_MacAddr  = "MacAddr" // mac address subtype with port mac
+	PORT_ID_MAC_ADDR = 2
+	PORT_ID_Local    = "Local" // local subtype with ifIndex
+	PORT_ID_LOCAL    = 3
+)
+
+// Optional TLV's advertised per interface, this is used as bitmap in per interface configuration.
+// LLDP-MED TLV's are controlled by MED mode
+const (
+	TLV_PortDescription    = "PortDescription"
+	TLV_PORT_DESCRIPTION   = 0x1
+	TLV_SystemName         = "SystemName"
+	TLV_SYSTEM_NAME        = 0x2
+	TLV_SystemDescription  = "SystemDescription"
+	TLV_SYSTEM_DESCRIPTION = 0x4
+	TLV_SystemCapabilities = "SystemCapabilities"
+	TLV_SYSTEM_CAP         = 0x8
+	TLV_ManagementAddress  = "ManagementAddress"
+	TLV_MGMT_ADDRESS       = 0x10
+	TLV_Dot1               = "Dot1" // IEEE 802.1 org specific tlv's
+	TLV_DOT1               = 0x20
+	TLV_Dot3               = "Dot3" // IEEE 802.3 org specific tlv's
+	TLV_DOT3               = 0x40
+	TLV_ALL                = 0x7f
+)
+
+// IEEE 802.1AB per port transmit timers range, 0 in configuration means global/default value is used
+const (
+	TX_INTERVAL_MIN  = 1
+	TX_INTERVAL_MAX  = 3600
+	TX_HOLD_MIN      = 1
+	TX_HOLD_MAX      = 100
+	REINIT_DELAY_MIN = 1
+	REINIT_DELAY_MAX = 10
+)
+
+// cabling plan status of the port, empty if port is not part of cabling plan
+const (
+	CABLING_Match    = "Match"    // expected neighbor is learned on the port
+	CABLING_Mismatch = "Mismatch" // neighbors learned on the port are not the expected one
+	CABLING_Missing  = "Missing"  // no neighbor is learned on the port
+)
+
+// IEEE 802.1Qaz DCBX limits
+const (
+	DCBX_MAX_PRIORITIES = 8
+	DCBX_MAX_TCS        = 8
+	// application priority tlv can carry at most 168 entries of 3 bytes
+	DCBX_MAX_APP_ENTRIES = 168
+)
+
+// IEEE 802.1Qaz ETS transmission selection algorithm of traffic class
+const (
+	TSA_STRICT          = 0
+	TSA_CBS             = 1 // credit based shaper
+	TSA_ETS             = 2
+	TSA_VENDOR_SPECIFIC = 255
+)
+
+// IEEE 802.1Qaz application priority selector, i.e. how protocol of the entry is interpreted
+const (
+	APP_SEL_ETHERTYPE = 1
+	APP_SEL_TCP       = 2 // well known tcp/sctp port
+	APP_SEL_UDP       = 3 // well known udp/dccp port
+	APP_SEL_TCP_UDP   = 4 // well known port for tcp, sctp, udp & dccp
+)
+
+// source of DCB operational parameters
+const (
+	DCBX_SOURCE_Local = "Local" // local configuration is in use
+	DCBX_SOURCE_Peer  = "Peer"  // parameters advertised by peer are accepted
+)
+
 type Global struct {
 	Vrf             string
 	Enable          bool
@@ -40,6 +154,11 @@ type Global struct {
 	SnoopAndDrop    bool
 }
 
+type ChassisIdConfig struct {
+	Subtype uint8
+	Local   string // only used for local subtype
+}
+
 // this is used for auto-discovery
 type Intf struct {
 	IntfRef string
@@ -47,10 +166,145 @@ type Intf struct {
 }
 
 // this is used to update configuration request coming from client to server
+// Interface configuration fields, used as bitmap in IntfConfig to tell which fields are updated
+const (
+	INTF_CFG_ENABLE          = 0x1
+	INTF_CFG_TXRX_MODE       = 0x2
+	INTF_CFG_MED             = 0x4
+	INTF_CFG_MAX_NEIGHBORS   = 0x8
+	INTF_CFG_AGENTS          = 0x10
+	INTF_CFG_PORT_ID_SUBTYPE = 0x20
+	INTF_CFG_TLVS            = 0x40
+	INTF_CFG_TX_TIMERS       = 0x80 // transmit interval, hold multiplier & reinit delay
+	INTF_CFG_DCBX            = 0x100
+)
+
+// partial interface configuration, only the fields set in Fields are applied on top of existing
+// configuration of the port
 type IntfConfig struct {
-	IfIndex  int32
-	Enable   bool
-	TxRxMode uint8
+	IfIndex       int32
+	Fields        uint16
+	Enable        bool
+	TxRxMode      uint8
+	Med           MedConfig
+	Dcbx          DcbxConfig
+	MaxNeighbors  int32
+	Agents        uint8
+	PortIdSubtype uint8
+	TLVs          uint8
+	// per port transmit timers, 0 means global/default value
+	TxInterval  int32
+	TxHold      int32
+	ReinitDelay int32
+}
+
+// interface configuration in user format, used by plugins which get complete interface config in
+// one request
+type IntfUserConfig struct {
+	IntfRef       string
+	Enable        bool
+	TxRxMode      string
+	MaxNeighbors  int32    // 0 leaves it unchanged
+	Agents        []string // empty leaves it unchanged
+	PortIdSubtype string   // empty leaves it unchanged
+	TLVs          []string // nil leaves it unchanged, empty list means only mandatory tlv's
+	TxInterval    int32
+	TxHold        int32
+	ReinitDelay   int32
+}
+
+// LLDP-MED configuration advertised by network connectivity device
+type MedConfig struct {
+	Mode     uint8
+	Policies []MedNetworkPolicy
+	Location MedLocation
+	// Extended Power-via-MDI, power value is in 0.1 Watt and 0 means power tlv is not advertised
+	PowerPriority uint8
+	PowerValue    int32
+}
+
+// LLDP-MED Network Policy, application type 1 is voice
+type MedNetworkPolicy struct {
+	AppType  uint8
+	VlanId   int32
+	Tagged   bool
+	Priority int32
+	Dscp     int32
+}
+
+// LLDP-MED Location Identification, either ELIN or civic address is advertised
+type MedLocation struct {
+	Elin        string
+	CountryCode string
+	CivicAddr   []MedCivicAddr
+}
+
+type MedCivicAddr struct {
+	CAType uint8
+	Value  string
+}
+
+// IEEE 802.1Qaz ETS parameters, traffic class of every priority and bandwidth (percentage) &
+// transmission selection algorithm of every traffic class
+type EtsConfig struct {
+	PriorityTC  [DCBX_MAX_PRIORITIES]uint8
+	TCBandwidth [DCBX_MAX_TCS]uint8
+	TSA         [DCBX_MAX_TCS]uint8
+}
+
+// IEEE 802.1Qaz application priority entry
+type AppPriority struct {
+	Selector uint8
+	Protocol uint16
+	Priority uint8
+}
+
+// IEEE 802.1Qaz DCBX configuration, only advertised by nearest bridge agent
+type DcbxConfig struct {
+	Enable bool
+	// local parameters can be overridden by peer
+	Willing bool
+	// number of traffic classes supported, 0 means 8
+	MaxTCs uint8
+	Ets    EtsConfig
+	// ETS parameters recommended to willing peer
+	EtsRecommendation EtsConfig
+	// number of traffic classes that can support PFC simultaneously & PFC enable bitmap per priority
+	PfcCap    uint8
+	PfcEnable uint8
+	Apps      []AppPriority
+}
+
+// IEEE 802.1Qaz DCBX tlv's received from peer
+type DcbxPeerInfo struct {
+	EtsWilling        bool
+	MaxTCs            uint8
+	Ets               *EtsConfig // nil if ETS configuration tlv is not received
+	EtsRecommendation *EtsConfig // nil if ETS recommendation tlv is not received
+	PfcValid          bool       // PFC configuration tlv is received
+	PfcWilling        bool
+	PfcCap            uint8
+	PfcEnable         uint8
+	Apps              []AppPriority // nil if application priority tlv is not received
+}
+
+// DCB operational parameters of the port, this is what forwarding plane should program
+type DcbxState struct {
+	IntfRef string
+	IfIndex int32
+	Enable  bool
+	Willing bool
+	// peer is detected only if exactly one neighbor advertised DCBX tlv's, with multiple peers
+	// local configuration is used
+	PeerDetected  bool
+	MultiplePeers bool
+	Peer          *DcbxPeerInfo
+	Ets           EtsConfig
+	EtsSource     string
+	PfcEnable     uint8
+	PfcSource     string
+	Apps          []AppPriority
+	AppsSource    string
 }
 
 type PortInfo struct {
@@ -59,6 +313,19 @@ type PortInfo struct {
 	OperState   string
 	MacAddr     string
 	Description string
+	L3Enabled   bool // port has l3 interface configured, i.e routed port
+	Mtu         int32
+	Speed       int32 // in Mbps
+	Duplex      string
+	Autoneg     bool
+	Vlans       []VlanInfo // vlan membership of the port
+	AggIfIndex  int32      // ifIndex of lag if the port is aggregated, 0 otherwise
+}
+
+type VlanInfo struct {
+	VlanId   int32
+	Name     string
+	Untagged bool
 }
 
 type PortState struct {
@@ -66,12 +333,46 @@ type PortState struct {
 	IfState string
 }
 
+// this is used to notify l2/l3 mode change on a port
+type PortL3State struct {
+	IfIndex   int32
+	L3Enabled bool
+}
+
+// this is used to notify vlan membership change, vlan with no ports is treated as delete
+type VlanState struct {
+	VlanId     int32
+	Name       string
+	TagPorts   []int32
+	UntagPorts []int32
+}
+
+// this is used to notify lag membership change, lag with no members is treated as delete
+type LagState struct {
+	IfIndex int32
+	Members []int32
+}
+
 type IntfState struct {
-	IntfRef             string
-	IfIndex             int32
-	Enable              bool
-	SendFrames          int32
-	ReceivedFrames      int32
+	IntfRef        string
+	IfIndex        int32
+	Enable         bool
+	SendFrames     int32
+	ReceivedFrames int32
+	// remote table information, peer information below is for neighbor with RemIndex learned by
+	// agent
+	Agent            string
+	RemIndex         int32
+	Neighbors        int32
+	MaxNeighbors     int32
+	TooManyNeighbors bool
+	// IEEE 802.1AB statistics
+	FramesDiscarded     int32
+	FramesInErrors      int32
+	TLVsDiscarded       int32
+	TLVsUnrecognized    int32
+	AgeoutsTotal        int32
+	LldpduLengthErrors  int32
 	LocalPort           string
 	PeerMac             string
 	PeerPort            string
@@ -80,6 +381,52 @@ type IntfState struct {
 	SystemDescription   string
 	SystemCapabilities  string
 	EnabledCapabilities string
+	// capabilities advertised by local system on this port
+	LocalSystemCapabilities  string
+	LocalEnabledCapabilities string
+	// IEEE 802.1 & IEEE 802.3 org specific information
+	LocalPortVlanId          int32
+	LocalMaxFrameSize        int32
+	PeerPortVlanId           int32
+	PeerProtocolVlanIds      []int32
+	PeerVlans                []VlanInfo
+	PeerAggCapable           bool
+	PeerAggEnabled           bool
+	PeerAggPortId            int32
+	PeerAutoNegSupported     bool
+	PeerAutoNegEnabled       bool
+	PeerAutoNegAdvertisedCap int32
+	PeerMAUType              int32
+	PeerMaxFrameSize         int32
+	// LLDP-MED information
+	MedMode                 string
+	MedEndpoint             bool
+	PeerMedDeviceClass      string
+	PeerMedCapabilities     string
+	PeerMedNetworkPolicy    []MedNetworkPolicy
+	PeerMedHardwareRevision string
+	PeerMedFirmwareRevision string
+	PeerMedSoftwareRevision string
+	PeerMedSerialNumber     string
+	PeerMedManufacturer     string
+	PeerMedModel            string
+	PeerMedAssetId          string
+	PeerMgmtAddrs           []MgmtAddr
+	// platform advertised by CDP neighbor
+	PeerPlatform string
+	// chassis id & port id subtypes, PeerMac & PeerPort carry the id rendered as per subtype
+	PortIdSubtype        string
+	PeerChassisIdSubtype string
+	PeerPortIdSubtype    string
+	// optional tlv's & transmit timers in use on the port
+	TLVs        []string
+	TxInterval  int32
+	TxHold      int32
+	ReinitDelay int32
+	// cabling plan expected neighbor & status of the port
+	CablingStatus        string
+	ExpectedPeerHostName string
+	ExpectedPeerPort     string
 }
 
 type GlobalState struct {
@@ -89,11 +436,36 @@ type GlobalState struct {
 	Neighbors       int32
 	TotalTxFrames   int32
 	TotalRxFrames   int32
+	// IEEE 802.1AB remote table statistics
+	RemTablesInserts        int32
+	RemTablesDeletes        int32
+	RemTablesDrops          int32
+	RemTablesAgeouts        int32
+	RemTablesLastChangeTime string
+	// chassis id advertised by the system
+	ChassisIdSubtype string
+	ChassisId        string
+}
+
+// this is used to clear statistics for the port or all the ports & global statistics
+type ClearStats struct {
+	IfIndex int32
+	All     bool
 }
 
 type EventInfo struct {
 	IfIndex   int32
+	Agent     uint8
+	RemIndex  int32
 	EventType int
+	// neighbor information before & after the change, Before is nil for Learned and After is nil for
+	// Removed event
+	Before *IntfState
+	After  *IntfState
+	// names of the neighbor information fields changed, only for Updated event
+	Changes []string
+	// DCB operational parameters, only for DcbxChanged event
+	Dcbx *DcbxState
 }
 
 const (
@@ -102,8 +474,20 @@ const (
 	Updated
 	Removed
 	NoOp
+	// port is out of compliance with cabling plan, After has port status & mismatching neighbor if any
+	CablingMismatch
+	// DCB operational parameters of the port changed, Dcbx has the new parameters
+	DcbxChanged
 )
 
+// expected neighbor of the port as per cabling plan, peer port id is compared with neighbor port id
+// rendered as per its subtype and empty PortId matches any peer port
+type ExpectedNeighbor struct {
+	IntfRef    string
+	SystemName string
+	PortId     string
+}
+
 type SystemInfo struct {
 	Vrf         string
 	MgmtIp      string
@@ -111,4 +495,12 @@ type SystemInfo struct {
 	SwitchMac   string
 	SwVersion   string
 	Description string
+	// list of ipv4/ipv6 management addresses, if empty then MgmtIp is used
+	MgmtAddrs []MgmtAddr
+}
+
+type MgmtAddr struct {
+	Addr     string // ip address
+	OID      string // optional object identifier in dotted notation, e.g. 1.3.6.1.2.1.2.2.1.1
+	IfNumber int32  // interface number, only used for information received from peer
 }
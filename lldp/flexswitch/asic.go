@@ -32,12 +32,20 @@ import (
 	nanomsg "github.com/op/go-nanomsg"
 	"l2/lldp/api"
 	"l2/lldp/config"
+	"l2/lldp/packet"
 	"l2/lldp/utils"
 	"strconv"
 	"time"
 	"utils/ipcutils"
 )
 
+const (
+	// asicd acl which traps lldp frames when snoop and drop is enabled, one rule per lldp address
+	LLDP_SNOOP_ACL_NAME = "LLDPSnoopAndDrop"
+	// frame is sent to cpu only and it is not forwarded
+	LLDP_SNOOP_ACL_ACTION = "TRAP"
+)
+
 type AsicPlugin struct {
 	asicdClient    *asicdServices.ASICDServicesClient
 	asicdSubSocket *nanomsg.SubSocket
@@ -130,6 +138,10 @@ func (p *AsicPlugin) getPortStates() []*config.PortInfo {
 			} else {
 				port.MacAddr = pObj.MacAddr
 				port.Description = pObj.Description
+				port.Mtu = pObj.Mtu
+				port.Speed = pObj.Speed
+				port.Duplex = pObj.Duplex
+				port.Autoneg = pObj.Autoneg == "ON"
 			}
 			debug.Logger.Debug("Adding port Name, OperState, IfIndex:", port.Name, port.OperState, port.IfIndex,
 				"to portStates")
@@ -143,11 +155,160 @@ func (p *AsicPlugin) getPortStates() []*config.PortInfo {
 	return portStates
 }
 
+/*  Helper function to get all the ports which have ipv4 interface configured, i.e. routed ports
+ */
+func (p *AsicPlugin) getL3Ports() map[int32]bool {
+	debug.Logger.Info("Get IPv4 Intf State List")
+	currMarker := int64(0)
+	more := false
+	objCount := 0
+	count := 500
+	l3Ports := make(map[int32]bool)
+	for {
+		bulkInfo, err := p.asicdClient.GetBulkIPv4IntfState(asicdServices.Int(currMarker), asicdServices.Int(count))
+		if err != nil {
+			debug.Logger.Err(fmt.Sprintln(": getting bulk ipv4 intf state"+
+				" from asicd failed with reason", err))
+			break
+		}
+		objCount = int(bulkInfo.Count)
+		more = bool(bulkInfo.More)
+		currMarker = int64(bulkInfo.EndIdx)
+		for i := 0; i < objCount; i++ {
+			l3Ports[bulkInfo.IPv4IntfStateList[i].IfIndex] = true
+		}
+		if more == false {
+			break
+		}
+	}
+	debug.Logger.Info("Done with IPv4 Intf State list")
+	return l3Ports
+}
+
+/*  Helper function to get vlan membership for all the ports, key is port name
+ */
+func (p *AsicPlugin) getPortVlans() map[string][]config.VlanInfo {
+	debug.Logger.Info("Get Vlan List")
+	vlanNames := make(map[int32]string)
+	portVlans := make(map[string][]config.VlanInfo)
+	currMarker := int64(0)
+	more := false
+	objCount := 0
+	count := 500
+	for {
+		bulkInfo, err := p.asicdClient.GetBulkVlanState(asicdServices.Int(currMarker), asicdServices.Int(count))
+		if err != nil {
+			debug.Logger.Err(fmt.Sprintln(": getting bulk vlan state"+
+				" from asicd failed with reason", err))
+			break
+		}
+		objCount = int(bulkInfo.Count)
+		more = bool(bulkInfo.More)
+		currMarker = int64(bulkInfo.EndIdx)
+		for i := 0; i < objCount; i++ {
+			obj := bulkInfo.VlanStateList[i]
+			vlanNames[obj.VlanId] = obj.VlanName
+		}
+		if more == false {
+			break
+		}
+	}
+	currMarker = 0
+	for {
+		bulkInfo, err := p.asicdClient.GetBulkVlan(asicdServices.Int(currMarker), asicdServices.Int(count))
+		if err != nil {
+			debug.Logger.Err(fmt.Sprintln(": getting bulk vlan config"+
+				" from asicd failed with reason", err))
+			break
+		}
+		objCount = int(bulkInfo.Count)
+		more = bool(bulkInfo.More)
+		currMarker = int64(bulkInfo.EndIdx)
+		for i := 0; i < objCount; i++ {
+			obj := bulkInfo.VlanList[i]
+			for _, intfRef := range obj.IntfList {
+				portVlans[intfRef] = append(portVlans[intfRef], config.VlanInfo{
+					VlanId: obj.VlanId,
+					Name:   vlanNames[obj.VlanId],
+				})
+			}
+			for _, intfRef := range obj.UntagIntfList {
+				portVlans[intfRef] = append(portVlans[intfRef], config.VlanInfo{
+					VlanId:   obj.VlanId,
+					Name:     vlanNames[obj.VlanId],
+					Untagged: true,
+				})
+			}
+		}
+		if more == false {
+			break
+		}
+	}
+	debug.Logger.Info("Done with Vlan list")
+	return portVlans
+}
+
+/*  Lag membership is not part of port info, it is learned via asicd lag notifications
+ */
 func (p *AsicPlugin) GetPortsInfo() []*config.PortInfo {
 	portStates := p.getPortStates()
+	l3Ports := p.getL3Ports()
+	portVlans := p.getPortVlans()
+	for _, port := range portStates {
+		port.L3Enabled = l3Ports[port.IfIndex]
+		port.Vlans = portVlans[port.Name]
+	}
 	return portStates
 }
 
+/*  Trap lldp frames sent to nearest bridge, nearest non-TPMR and nearest customer bridge addresses to cpu
+ *  and drop them instead of forwarding them. Acl is applied on all the ports
+ */
+func (p *AsicPlugin) SetSnoopAndDrop(enable bool) error {
+	dstMACs := []string{packet.LLDP_PROTO_DST_MAC, packet.LLDP_NEAREST_NON_TPMR_DST_MAC,
+		packet.LLDP_NEAREST_CUSTOMER_DST_MAC}
+	ruleNames := make([]string, 0, len(dstMACs))
+	for idx := range dstMACs {
+		ruleNames = append(ruleNames, LLDP_SNOOP_ACL_NAME+strconv.Itoa(idx))
+	}
+	if !enable {
+		_, err := p.asicdClient.DeleteAcl(&asicdServices.Acl{AclName: LLDP_SNOOP_ACL_NAME})
+		if err != nil {
+			return err
+		}
+		for _, ruleName := range ruleNames {
+			_, err = p.asicdClient.DeleteAclRule(&asicdServices.AclRule{RuleName: ruleName})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for idx, dstMAC := range dstMACs {
+		_, err := p.asicdClient.CreateAclRule(&asicdServices.AclRule{
+			RuleName: ruleNames[idx],
+			DestMac:  dstMAC,
+			Action:   LLDP_SNOOP_ACL_ACTION,
+		})
+		if err != nil {
+			debug.Logger.Err("Creating acl rule for", dstMAC, "failed with reason", err)
+			return err
+		}
+	}
+	intfList := make([]string, 0)
+	for _, port := range p.getPortStates() {
+		intfList = append(intfList, port.Name)
+	}
+	_, err := p.asicdClient.CreateAcl(&asicdServices.Acl{
+		AclName:      LLDP_SNOOP_ACL_NAME,
+		AclType:      "MAC",
+		IntfList:     intfList,
+		RuleNameList: ruleNames,
+		Direction:    "IN",
+	})
+	return err
+}
+
 func (p *AsicPlugin) connectSubSocket() error {
 	var err error
 	address := asicdCommonDefs.PUB_SOCKET_ADDR
@@ -209,6 +370,50 @@ func (p *AsicPlugin) listenAsicdUpdates() {
 			} else {
 				api.SendPortStateChange(l2IntfStateNotifyMsg.IfIndex, "DOWN")
 			}
+		case asicdCommonDefs.NOTIFY_IPV4INTF_CREATE, asicdCommonDefs.NOTIFY_IPV4INTF_DELETE:
+			var ipv4IntfNotifyMsg asicdCommonDefs.IPv4IntfNotifyMsg
+			err = json.Unmarshal(msg.Msg, &ipv4IntfNotifyMsg)
+			if err != nil {
+				debug.Logger.Err("Unable to Unmarshal ipv4 intf notification:", msg.Msg)
+				continue
+			}
+			debug.Logger.Debug("Got IPv4 Intf Notification from Asicd Subscriber socket for ifIndex:",
+				ipv4IntfNotifyMsg.IfIndex, "MsgType:", msg.MsgType)
+			api.SendPortL3StateChange(ipv4IntfNotifyMsg.IfIndex,
+				msg.MsgType == asicdCommonDefs.NOTIFY_IPV4INTF_CREATE)
+		case asicdCommonDefs.NOTIFY_VLAN_CREATE, asicdCommonDefs.NOTIFY_VLAN_UPDATE,
+			asicdCommonDefs.NOTIFY_VLAN_DELETE:
+			var vlanNotifyMsg asicdCommonDefs.VlanNotifyMsg
+			err = json.Unmarshal(msg.Msg, &vlanNotifyMsg)
+			if err != nil {
+				debug.Logger.Err("Unable to Unmarshal vlan notification:", msg.Msg)
+				continue
+			}
+			debug.Logger.Debug("Got Vlan Notification from Asicd Subscriber socket for vlan:",
+				vlanNotifyMsg.VlanId, "MsgType:", msg.MsgType)
+			if msg.MsgType == asicdCommonDefs.NOTIFY_VLAN_DELETE {
+				// vlan with no ports is treated as delete
+				api.SendVlanStateChange(int32(vlanNotifyMsg.VlanId), vlanNotifyMsg.VlanName, nil, nil)
+			} else {
+				api.SendVlanStateChange(int32(vlanNotifyMsg.VlanId), vlanNotifyMsg.VlanName,
+					vlanNotifyMsg.TagPorts, vlanNotifyMsg.UntagPorts)
+			}
+		case asicdCommonDefs.NOTIFY_LAG_CREATE, asicdCommonDefs.NOTIFY_LAG_UPDATE,
+			asicdCommonDefs.NOTIFY_LAG_DELETE:
+			var lagNotifyMsg asicdCommonDefs.LagNotifyMsg
+			err = json.Unmarshal(msg.Msg, &lagNotifyMsg)
+			if err != nil {
+				debug.Logger.Err("Unable to Unmarshal lag notification:", msg.Msg)
+				continue
+			}
+			debug.Logger.Debug("Got Lag Notification from Asicd Subscriber socket for lag:",
+				lagNotifyMsg.IfIndex, "MsgType:", msg.MsgType)
+			if msg.MsgType == asicdCommonDefs.NOTIFY_LAG_DELETE {
+				// lag with no members is treated as delete
+				api.SendLagStateChange(lagNotifyMsg.IfIndex, nil)
+			} else {
+				api.SendLagStateChange(lagNotifyMsg.IfIndex, lagNotifyMsg.IfIndexList)
+			}
 		}
 	}
 
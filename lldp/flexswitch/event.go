@@ -48,7 +48,12 @@ func (p *SystemPlugin) PublishEvent(info config.EventInfo) {
 		txEvt.EventId = events.NeighborUpdated
 	case config.Removed:
 		txEvt.EventId = events.NeighborRemoved
-
+	case config.CablingMismatch:
+		// there is no cabling event in models, neighbor update event carries cabling status
+		txEvt.EventId = events.NeighborUpdated
+		txEvt.AdditionalInfo = fmt.Sprintf("Cabling plan %s, expected: %s %s actual: %s %s",
+			info.After.CablingStatus, info.After.ExpectedPeerHostName, info.After.ExpectedPeerPort,
+			info.After.PeerHostName, info.After.PeerPort)
 	}
 	err = eventUtils.PublishEvents(&txEvt)
 	if err != nil {
@@ -0,0 +1,339 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package linux
+
+import (
+	"github.com/vishvananda/netlink"
+	"l2/lldp/api"
+	"l2/lldp/config"
+	"l2/lldp/packet"
+	"l2/lldp/utils"
+	"net"
+	"strconv"
+	"syscall"
+)
+
+type AsicPlugin struct {
+	cfg *Config
+	// ifIndex to oper state of the lldp ports, used to filter out duplicate netlink notifications
+	portStates map[int32]string
+	// ifIndex to l3 enabled of the lldp ports
+	l3Ports map[int32]bool
+	// lldp ports discovered in GetPortsInfo
+	lldpPorts []int32
+	// ports on which ingress filters dropping lldp frames are installed
+	snoopPorts []int32
+	// closed once lldp ports are discovered
+	portsReady chan struct{}
+	done       chan struct{}
+}
+
+func NewAsicPlugin(cfg *Config) (*AsicPlugin, error) {
+	mgr := &AsicPlugin{
+		cfg:        cfg,
+		portStates: make(map[int32]string),
+		l3Ports:    make(map[int32]bool),
+		portsReady: make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	return mgr, nil
+}
+
+/*  Link is treated as operationally up if kernel says so, virtual interfaces which do not report
+ *  oper state are up if admin state is up
+ */
+func linkOperState(attrs *netlink.LinkAttrs) string {
+	if attrs.OperState == netlink.OperUp ||
+		(attrs.OperState == netlink.OperUnknown && attrs.Flags&net.FlagUp != 0) {
+		return "UP"
+	}
+	return "DOWN"
+}
+
+/*  Helper function to check whether ipv4 address is configured on the link, i.e. routed port
+ */
+func linkL3Enabled(link netlink.Link) bool {
+	addrs, err := netlink.AddrList(link, netlink.FAMILY_V4)
+	if err != nil {
+		return false
+	}
+	return len(addrs) > 0
+}
+
+/*  Helper function to get port information from netlink link, speed & duplex are read from sysfs
+ */
+func convertLinkToPortInfo(link netlink.Link) *config.PortInfo {
+	attrs := link.Attrs()
+	port := &config.PortInfo{
+		IfIndex:     int32(attrs.Index),
+		Name:        attrs.Name,
+		OperState:   linkOperState(attrs),
+		MacAddr:     attrs.HardwareAddr.String(),
+		Description: attrs.Name,
+		Mtu:         int32(attrs.MTU),
+		L3Enabled:   linkL3Enabled(link),
+	}
+	speed, err := strconv.Atoi(readFileLine(SYSFS_NET_PATH + attrs.Name + "/speed"))
+	if err == nil && speed > 0 {
+		port.Speed = int32(speed)
+	}
+	switch readFileLine(SYSFS_NET_PATH + attrs.Name + "/duplex") {
+	case "full":
+		port.Duplex = "Full Duplex"
+	case "half":
+		port.Duplex = "Half Duplex"
+	}
+	return port
+}
+
+/*  Lldp ports are the ethernet interfaces, vlan membership is learned from vlan sub interfaces
+ *  created on top of the port
+ */
+func (p *AsicPlugin) GetPortsInfo() []*config.PortInfo {
+	debug.Logger.Info("Get Port List from netlink")
+	links, err := netlink.LinkList()
+	if err != nil {
+		debug.Logger.Err("Getting link list from netlink failed with reason", err)
+		close(p.portsReady)
+		return nil
+	}
+	portStates := make([]*config.PortInfo, 0)
+	ports := make(map[int]*config.PortInfo)
+	for _, link := range links {
+		attrs := link.Attrs()
+		if !p.cfg.isLLDPPort(attrs.Name, link.Type(), attrs.EncapType) {
+			continue
+		}
+		port := convertLinkToPortInfo(link)
+		p.portStates[port.IfIndex] = port.OperState
+		p.l3Ports[port.IfIndex] = port.L3Enabled
+		p.lldpPorts = append(p.lldpPorts, port.IfIndex)
+		ports[attrs.Index] = port
+		debug.Logger.Debug("Adding port Name, OperState, IfIndex:", port.Name, port.OperState, port.IfIndex,
+			"to portStates")
+		portStates = append(portStates, port)
+	}
+	for _, link := range links {
+		vlan, ok := link.(*netlink.Vlan)
+		if !ok {
+			continue
+		}
+		port, exists := ports[vlan.ParentIndex]
+		if !exists {
+			continue
+		}
+		port.Vlans = append(port.Vlans, config.VlanInfo{
+			VlanId: int32(vlan.VlanId),
+			Name:   vlan.Name,
+		})
+	}
+	debug.Logger.Info("Done with Port list")
+	close(p.portsReady)
+	return portStates
+}
+
+func snoopFilterAttrs(ifIndex int32) netlink.FilterAttrs {
+	return netlink.FilterAttrs{
+		LinkIndex: int(ifIndex),
+		Parent:    netlink.MakeHandle(0xffff, 0),
+		Priority:  LLDP_SNOOP_FILTER_PRIORITY,
+		Protocol:  syscall.ETH_P_ALL,
+	}
+}
+
+/*  Ingress u32 filter dropping frames sent to the given destination mac, offsets are relative to network
+ *  header and hence destination mac is at -14
+ */
+func snoopFilter(ifIndex int32, dstMAC net.HardwareAddr) *netlink.U32 {
+	return &netlink.U32{
+		FilterAttrs: snoopFilterAttrs(ifIndex),
+		Sel: &netlink.TcU32Sel{
+			Flags: netlink.TC_U32_TERMINAL,
+			Keys: []netlink.TcU32Key{
+				{
+					Mask: 0xffffffff,
+					Val: uint32(dstMAC[0])<<24 | uint32(dstMAC[1])<<16 | uint32(dstMAC[2])<<8 |
+						uint32(dstMAC[3]),
+					Off: -14,
+				},
+				{
+					Mask: 0xffff0000,
+					Val:  uint32(dstMAC[4])<<24 | uint32(dstMAC[5])<<16,
+					Off:  -10,
+				},
+			},
+		},
+		Actions: []netlink.Action{
+			&netlink.GenericAction{ActionAttrs: netlink.ActionAttrs{Action: netlink.TC_ACT_SHOT}},
+		},
+	}
+}
+
+/*  Lldp frames sent to nearest bridge, nearest non-TPMR and nearest customer bridge addresses are dropped
+ *  by tc ingress filters on all the lldp ports. Packet sockets get the frame before tc ingress and hence
+ *  lldp still receives the frame but it is not forwarded by the bridge
+ */
+func (p *AsicPlugin) SetSnoopAndDrop(enable bool) error {
+	if !enable {
+		for _, ifIndex := range p.snoopPorts {
+			// deleting filters by priority deletes all the lldp filters of the port
+			err := netlink.FilterDel(&netlink.U32{FilterAttrs: snoopFilterAttrs(ifIndex)})
+			if err != nil {
+				debug.Logger.Err("Deleting lldp snoop filters for ifIndex", ifIndex, "failed with reason", err)
+			}
+		}
+		p.snoopPorts = nil
+		return nil
+	}
+	if len(p.snoopPorts) > 0 {
+		return nil
+	}
+	dstMACs := []string{packet.LLDP_PROTO_DST_MAC, packet.LLDP_NEAREST_NON_TPMR_DST_MAC,
+		packet.LLDP_NEAREST_CUSTOMER_DST_MAC}
+	for _, ifIndex := range p.lldpPorts {
+		qdisc := &netlink.GenericQdisc{
+			QdiscAttrs: netlink.QdiscAttrs{
+				LinkIndex: int(ifIndex),
+				Handle:    netlink.MakeHandle(0xffff, 0),
+				Parent:    netlink.HANDLE_INGRESS,
+			},
+			QdiscType: "ingress",
+		}
+		err := netlink.QdiscAdd(qdisc)
+		if err != nil && err != syscall.EEXIST {
+			p.SetSnoopAndDrop(false)
+			return err
+		}
+		p.snoopPorts = append(p.snoopPorts, ifIndex)
+		for _, mac := range dstMACs {
+			dstMAC, _ := net.ParseMAC(mac)
+			err = netlink.FilterAdd(snoopFilter(ifIndex, dstMAC))
+			if err != nil {
+				p.SetSnoopAndDrop(false)
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+/*  Lag membership is learned from bond master of the lldp ports
+ */
+func (p *AsicPlugin) sendLagMembership() {
+	links, err := netlink.LinkList()
+	if err != nil {
+		return
+	}
+	lags := make(map[int32][]int32)
+	for _, link := range links {
+		attrs := link.Attrs()
+		if _, exists := p.portStates[int32(attrs.Index)]; !exists || attrs.MasterIndex == 0 {
+			continue
+		}
+		master, err := netlink.LinkByIndex(attrs.MasterIndex)
+		if err != nil || master.Type() != "bond" {
+			continue
+		}
+		lags[int32(attrs.MasterIndex)] = append(lags[int32(attrs.MasterIndex)], int32(attrs.Index))
+	}
+	for lagIfIndex, members := range lags {
+		api.SendLagStateChange(lagIfIndex, members)
+	}
+}
+
+func (p *AsicPlugin) handleLinkUpdate(update netlink.LinkUpdate) {
+	attrs := update.Attrs()
+	ifIndex := int32(attrs.Index)
+	prevState, exists := p.portStates[ifIndex]
+	if !exists {
+		// not a lldp port
+		return
+	}
+	state := linkOperState(attrs)
+	if state == prevState {
+		return
+	}
+	debug.Logger.Debug("Got netlink link notification for ifIndex:", ifIndex, "State:", state)
+	p.portStates[ifIndex] = state
+	api.SendPortStateChange(ifIndex, state)
+}
+
+func (p *AsicPlugin) handleAddrUpdate(update netlink.AddrUpdate) {
+	ifIndex := int32(update.LinkIndex)
+	prevL3, exists := p.l3Ports[ifIndex]
+	if !exists || update.LinkAddress.IP.To4() == nil {
+		return
+	}
+	link, err := netlink.LinkByIndex(update.LinkIndex)
+	if err != nil {
+		return
+	}
+	l3Enabled := linkL3Enabled(link)
+	if l3Enabled == prevL3 {
+		return
+	}
+	debug.Logger.Debug("Got netlink ipv4 address notification for ifIndex:", ifIndex, "l3 enabled:", l3Enabled)
+	p.l3Ports[ifIndex] = l3Enabled
+	api.SendPortL3StateChange(ifIndex, l3Enabled)
+}
+
+func (p *AsicPlugin) listenNetlinkUpdates(linkCh chan netlink.LinkUpdate, addrCh chan netlink.AddrUpdate) {
+	// notifications are handled only after lldp ports are discovered
+	<-p.portsReady
+	p.sendLagMembership()
+	for {
+		select {
+		case update, ok := <-linkCh:
+			if !ok {
+				return
+			}
+			p.handleLinkUpdate(update)
+		case update, ok := <-addrCh:
+			if !ok {
+				return
+			}
+			p.handleAddrUpdate(update)
+		}
+	}
+}
+
+/*  Subscribe for netlink link & address notifications, notifications received before lldp ports
+ *  are discovered in GetPortsInfo are queued
+ */
+func (p *AsicPlugin) Start() {
+	linkCh := make(chan netlink.LinkUpdate, NETLINK_UPDATE_CHANNEL_SIZE)
+	err := netlink.LinkSubscribe(linkCh, p.done)
+	if err != nil {
+		debug.Logger.Err("Failed to subscribe for netlink link updates, error:", err)
+		return
+	}
+	addrCh := make(chan netlink.AddrUpdate, NETLINK_UPDATE_CHANNEL_SIZE)
+	err = netlink.AddrSubscribe(addrCh, p.done)
+	if err != nil {
+		debug.Logger.Err("Failed to subscribe for netlink address updates, error:", err)
+		return
+	}
+	debug.Logger.Info("netlink update listener is set")
+	go p.listenNetlinkUpdates(linkCh, addrCh)
+}
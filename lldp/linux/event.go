@@ -0,0 +1,49 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package linux
+
+import (
+	"l2/lldp/config"
+	"l2/lldp/utils"
+)
+
+/*  There is no event bus on plain linux host, events are logged and are available to http api
+ *  subscribers
+ */
+func (p *SystemPlugin) PublishEvent(info config.EventInfo) {
+	switch info.EventType {
+	case config.Learned:
+		debug.Logger.Info("Neighbor learned on ifIndex:", info.IfIndex, "remIndex:", info.RemIndex,
+			"chassis:", info.After.PeerMac, "port:", info.After.PeerPort)
+	case config.Updated:
+		debug.Logger.Info("Neighbor updated on ifIndex:", info.IfIndex, "remIndex:", info.RemIndex,
+			"changes:", info.Changes)
+	case config.Removed:
+		debug.Logger.Info("Neighbor removed on ifIndex:", info.IfIndex, "remIndex:", info.RemIndex)
+	case config.CablingMismatch:
+		debug.Logger.Alert("Cabling plan", info.After.CablingStatus, "on port:", info.After.IntfRef,
+			"expected:", info.After.ExpectedPeerHostName, info.After.ExpectedPeerPort,
+			"actual:", info.After.PeerHostName, info.After.PeerPort)
+	}
+}
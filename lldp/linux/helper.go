@@ -0,0 +1,137 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package linux
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"l2/lldp/config"
+	"l2/lldp/utils"
+	"strings"
+)
+
+const (
+	SYSFS_NET_PATH       = "/sys/class/net/"
+	OS_RELEASE_FILE_NAME = "/etc/os-release"
+	DEFAULT_VRF          = "default"
+
+	NETLINK_UPDATE_CHANNEL_SIZE = 100
+
+	// tc priority of ingress filters dropping lldp frames when snoop and drop is enabled, all the
+	// filters of the port are deleted together using the priority
+	LLDP_SNOOP_FILTER_PRIORITY = 0x88cc
+)
+
+/*  Local configuration used by linux plugins, read from json file. All the fields are optional
+ */
+type Config struct {
+	// interfaces on which lldp runs, all ethernet interfaces are used if empty
+	Ports []string
+	// interface used for system mac & management address, first port is used if empty
+	MgmtIntf string
+	// http api listen address e.g. 127.0.0.1:8090, no http api if empty
+	HttpAddr string
+	// lldpNotificationInterval in seconds
+	NotificationInterval int32
+	// chassis id subtype SystemMac, Hostname or Local, local chassis id is only used with Local
+	ChassisIdSubtype string
+	ChassisId        string
+	Global           *GlobalConfig
+	Intfs            []config.IntfUserConfig
+}
+
+type GlobalConfig struct {
+	Vrf             string
+	Enable          bool
+	TxRxMode        string
+	SnoopAndDrop    bool
+	TranmitInterval int32
+}
+
+/*  Read linux plugin configuration file, empty file name means default configuration, i.e. lldp
+ *  enabled on all the ethernet interfaces
+ */
+func ReadConfig(fileName string) (*Config, error) {
+	cfg := &Config{}
+	if fileName != "" {
+		data, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(data, cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Global == nil {
+		cfg.Global = &GlobalConfig{
+			Enable: true,
+		}
+	}
+	if cfg.Global.Vrf == "" {
+		cfg.Global.Vrf = DEFAULT_VRF
+	}
+	if cfg.Global.TxRxMode == "" {
+		cfg.Global.TxRxMode = config.TX_RX_MODE_TxRx
+	}
+	for idx := range cfg.Intfs {
+		if cfg.Intfs[idx].TxRxMode == "" {
+			cfg.Intfs[idx].TxRxMode = config.TX_RX_MODE_TxRx
+		}
+	}
+	debug.Logger.Info("Linux plugin configuration:", *cfg)
+	return cfg, nil
+}
+
+/*  Helper function to read single line attribute from sysfs/procfs
+ */
+func readFileLine(fileName string) string {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+/*  Port is part of the lldp ports if it is configured or if no ports are configured then
+ *  all the ethernet interfaces are lldp ports
+ */
+func (cfg *Config) isLLDPPort(name string, linkType string, encapType string) bool {
+	if len(cfg.Ports) > 0 {
+		for _, port := range cfg.Ports {
+			if port == name {
+				return true
+			}
+		}
+		return false
+	}
+	if encapType != "ether" {
+		return false
+	}
+	switch linkType {
+	case "device", "veth":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,322 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package linux
+
+import (
+	"encoding/json"
+	"errors"
+	"l2/lldp/api"
+	"l2/lldp/config"
+	"l2/lldp/utils"
+	"net/http"
+)
+
+const (
+	HTTP_GLOBAL_URL      = "/lldp/global"
+	HTTP_INTF_URL        = "/lldp/intf"
+	HTTP_NEIGHBORS_URL   = "/lldp/neighbors"
+	HTTP_CLEAR_STATS_URL = "/lldp/clearstats"
+	HTTP_EVENTS_URL      = "/lldp/events"
+	HTTP_CHASSIS_ID_URL  = "/lldp/chassisid"
+	HTTP_CABLING_URL     = "/lldp/cablingplan"
+	HTTP_DCBX_URL        = "/lldp/dcbx"
+
+	HTTP_BULK_GET_COUNT = 1024
+)
+
+/*  Config plugin which takes configuration from local json file and http api
+ */
+type NBPlugin struct {
+	cfg *Config
+}
+
+func NewNBPlugin(cfg *Config) *NBPlugin {
+	return &NBPlugin{cfg}
+}
+
+/*  Apply configuration from file, global object is always created here so http api only does update
+ */
+func (p *NBPlugin) applyFileConfig() error {
+	if p.cfg.NotificationInterval != 0 {
+		_, err := api.SetNotificationInterval(p.cfg.NotificationInterval)
+		if err != nil {
+			return err
+		}
+	}
+	if p.cfg.ChassisIdSubtype != "" {
+		_, err := api.UpdateChassisId(p.cfg.ChassisIdSubtype, p.cfg.ChassisId)
+		if err != nil {
+			return err
+		}
+	}
+	gbl := p.cfg.Global
+	_, err := api.SendGlobalConfig(gbl.Vrf, gbl.TxRxMode, gbl.Enable, gbl.SnoopAndDrop, gbl.TranmitInterval)
+	if err != nil {
+		return err
+	}
+	for _, intf := range p.cfg.Intfs {
+		_, err = api.UpdateIntfFullConfig(intf)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *NBPlugin) Start() error {
+	err := p.applyFileConfig()
+	if err != nil {
+		debug.Logger.Err("Applying configuration from file failed, err:", err)
+		return err
+	}
+	if p.cfg.HttpAddr == "" {
+		debug.Logger.Info("No http api address configured, running with file configuration")
+		select {}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(HTTP_GLOBAL_URL, p.handleGlobal)
+	mux.HandleFunc(HTTP_INTF_URL, p.handleIntf)
+	mux.HandleFunc(HTTP_NEIGHBORS_URL, p.handleNeighbors)
+	mux.HandleFunc(HTTP_CLEAR_STATS_URL, p.handleClearStats)
+	mux.HandleFunc(HTTP_EVENTS_URL, p.handleEvents)
+	mux.HandleFunc(HTTP_CHASSIS_ID_URL, p.handleChassisId)
+	mux.HandleFunc(HTTP_CABLING_URL, p.handleCablingPlan)
+	mux.HandleFunc(HTTP_DCBX_URL, p.handleDcbx)
+	debug.Logger.Info("Starting LLDP http listener on", p.cfg.HttpAddr)
+	err = http.ListenAndServe(p.cfg.HttpAddr, mux)
+	if err != nil {
+		debug.Logger.Err("Failed to start the listener, err:", err)
+		return err
+	}
+	return nil
+}
+
+/*  Helper function to send response, err is send as bad request
+ */
+func writeResponse(w http.ResponseWriter, resp interface{}, err error) {
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+/*  GET returns global state, PUT updates global config
+ */
+func (p *NBPlugin) handleGlobal(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		vrf := r.URL.Query().Get("Vrf")
+		if vrf == "" {
+			vrf = DEFAULT_VRF
+		}
+		gblState, err := api.GetLLDPGlobalState(vrf)
+		writeResponse(w, gblState, err)
+	case http.MethodPut:
+		gbl := GlobalConfig{
+			Vrf:      DEFAULT_VRF,
+			TxRxMode: config.TX_RX_MODE_TxRx,
+		}
+		err := json.NewDecoder(r.Body).Decode(&gbl)
+		if err == nil {
+			_, err = api.UpdateGlobalConfig(gbl.Vrf, gbl.TxRxMode, gbl.Enable, gbl.SnoopAndDrop,
+				gbl.TranmitInterval)
+		}
+		writeResponse(w, gbl, err)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+/*  PUT updates chassis id advertised on all the ports, chassis id in use is part of global state
+ */
+func (p *NBPlugin) handleChassisId(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	chassisId := struct {
+		ChassisIdSubtype string
+		ChassisId        string
+	}{}
+	err := json.NewDecoder(r.Body).Decode(&chassisId)
+	if err == nil {
+		_, err = api.UpdateChassisId(chassisId.ChassisIdSubtype, chassisId.ChassisId)
+	}
+	writeResponse(w, chassisId, err)
+}
+
+/*  GET returns cabling plan, PUT replaces it. Cabling status is part of interface state
+ */
+func (p *NBPlugin) handleCablingPlan(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeResponse(w, api.GetCablingPlan(), nil)
+	case http.MethodPut:
+		var plan []config.ExpectedNeighbor
+		err := json.NewDecoder(r.Body).Decode(&plan)
+		if err == nil {
+			_, err = api.UpdateCablingPlan(plan)
+		}
+		writeResponse(w, plan, err)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// DCBX configuration of the interface in http request
+type dcbxIntfConfig struct {
+	IntfRef string
+	config.DcbxConfig
+}
+
+/*  GET returns DCB operational parameters of given IntfRef or of all the interfaces with DCBX enabled,
+ *  PUT updates DCBX configuration of the interface
+ */
+func (p *NBPlugin) handleDcbx(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		intfRef := r.URL.Query().Get("IntfRef")
+		if intfRef == "" {
+			writeResponse(w, api.GetDcbxStates(), nil)
+			return
+		}
+		dcbxState := api.GetIntfDcbxState(intfRef)
+		if dcbxState == nil {
+			writeResponse(w, nil, errors.New("No LLDP interface found for "+intfRef))
+			return
+		}
+		writeResponse(w, dcbxState, nil)
+	case http.MethodPut:
+		var dcbxCfg dcbxIntfConfig
+		err := json.NewDecoder(r.Body).Decode(&dcbxCfg)
+		if err == nil {
+			_, err = api.UpdateIntfDcbxConfig(dcbxCfg.IntfRef, dcbxCfg.DcbxConfig)
+		}
+		writeResponse(w, dcbxCfg, err)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+/*  GET returns interface state of given IntfRef or of all the interfaces, PUT updates interface config
+ */
+func (p *NBPlugin) handleIntf(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		intfRef := r.URL.Query().Get("IntfRef")
+		if intfRef == "" {
+			_, _, intfStates := api.GetIntfStates(0, HTTP_BULK_GET_COUNT)
+			writeResponse(w, intfStates, nil)
+			return
+		}
+		intfState := api.GetIntfState(intfRef)
+		if intfState == nil {
+			writeResponse(w, nil, errors.New("No LLDP interface found for "+intfRef))
+			return
+		}
+		writeResponse(w, intfState, nil)
+	case http.MethodPut:
+		intf := config.IntfUserConfig{
+			TxRxMode: config.TX_RX_MODE_TxRx,
+		}
+		err := json.NewDecoder(r.Body).Decode(&intf)
+		if err == nil {
+			_, err = api.UpdateIntfFullConfig(intf)
+		}
+		writeResponse(w, intf, err)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+/*  GET returns neighbors learned on given IntfRef or on all the interfaces
+ */
+func (p *NBPlugin) handleNeighbors(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	intfRef := r.URL.Query().Get("IntfRef")
+	if intfRef != "" {
+		writeResponse(w, api.GetIntfNeighbors(intfRef), nil)
+		return
+	}
+	var nbrs []config.IntfState
+	idx := 0
+	for {
+		nextIdx, _, result := api.GetNeighbors(idx, HTTP_BULK_GET_COUNT)
+		nbrs = append(nbrs, result...)
+		if nextIdx == 0 {
+			break
+		}
+		idx = nextIdx
+	}
+	writeResponse(w, nbrs, nil)
+}
+
+/*  POST clears statistics of given IntfRef or of all the interfaces
+ */
+func (p *NBPlugin) handleClearStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	rv, err := api.ClearStatistics(r.URL.Query().Get("IntfRef"))
+	writeResponse(w, rv, err)
+}
+
+/*  GET streams neighbor events as json objects, one per line, till client closes the connection
+ */
+func (p *NBPlugin) handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	id, eventCh := api.SubscribeEvents()
+	defer api.UnsubscribeEvents(id)
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	for {
+		select {
+		case eventInfo, ok := <-eventCh:
+			if !ok {
+				return
+			}
+			err := encoder.Encode(eventInfo)
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,172 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package linux
+
+import (
+	"bufio"
+	"l2/lldp/api"
+	"l2/lldp/config"
+	"l2/lldp/utils"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+	"utils/dbutils"
+)
+
+const (
+	SYSTEM_INFO_POLL_INTERVAL = 30 // in seconds
+	KERNEL_RELEASE_FILE_NAME  = "/proc/sys/kernel/osrelease"
+)
+
+type SystemPlugin struct {
+	cfg     *Config
+	sysInfo *config.SystemInfo
+	// closed once initial system information is read
+	sysInfoReady chan struct{}
+}
+
+func NewSystemPlugin(cfg *Config) (*SystemPlugin, error) {
+	mgr := &SystemPlugin{
+		cfg:          cfg,
+		sysInfoReady: make(chan struct{}),
+	}
+	return mgr, nil
+}
+
+/*  Management interface is the configured one, otherwise first non loopback interface which is up
+ *  and has global unicast address
+ */
+func (p *SystemPlugin) getMgmtIntf() *net.Interface {
+	if p.cfg.MgmtIntf != "" {
+		intf, err := net.InterfaceByName(p.cfg.MgmtIntf)
+		if err != nil {
+			debug.Logger.Err("Management interface", p.cfg.MgmtIntf, "not found, error:", err)
+			return nil
+		}
+		return intf
+	}
+	intfs, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	for idx, intf := range intfs {
+		if intf.Flags&net.FlagLoopback != 0 || intf.Flags&net.FlagUp == 0 {
+			continue
+		}
+		if len(getGlobalAddrs(&intfs[idx])) > 0 {
+			return &intfs[idx]
+		}
+	}
+	return nil
+}
+
+/*  Helper function to get all the global unicast ipv4/ipv6 addresses of the interface
+ */
+func getGlobalAddrs(intf *net.Interface) []net.IP {
+	var ips []net.IP
+	addrs, err := intf.Addrs()
+	if err != nil {
+		return ips
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || !ipNet.IP.IsGlobalUnicast() {
+			continue
+		}
+		ips = append(ips, ipNet.IP)
+	}
+	return ips
+}
+
+/*  Helper function to get os name from os-release file
+ */
+func getOsDescription() string {
+	file, err := os.Open(OS_RELEASE_FILE_NAME)
+	if err != nil {
+		return "Linux"
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "PRETTY_NAME=") {
+			return strings.Trim(strings.TrimPrefix(line, "PRETTY_NAME="), "\"")
+		}
+	}
+	return "Linux"
+}
+
+/*  System information is read from the host os
+ */
+func (p *SystemPlugin) readSystemInfo() *config.SystemInfo {
+	sysInfo := &config.SystemInfo{
+		Vrf:         DEFAULT_VRF,
+		SwVersion:   readFileLine(KERNEL_RELEASE_FILE_NAME),
+		Description: getOsDescription(),
+	}
+	sysInfo.Hostname, _ = os.Hostname()
+	mgmtIntf := p.getMgmtIntf()
+	if mgmtIntf == nil {
+		debug.Logger.Info("No management interface found")
+		return sysInfo
+	}
+	sysInfo.SwitchMac = mgmtIntf.HardwareAddr.String()
+	for _, ip := range getGlobalAddrs(mgmtIntf) {
+		if sysInfo.MgmtIp == "" && ip.To4() != nil {
+			sysInfo.MgmtIp = ip.String()
+		}
+		sysInfo.MgmtAddrs = append(sysInfo.MgmtAddrs, config.MgmtAddr{Addr: ip.String()})
+	}
+	return sysInfo
+}
+
+func (p *SystemPlugin) GetSystemInfo(dbHdl *dbutils.DBUtil) *config.SystemInfo {
+	p.sysInfo = p.readSystemInfo()
+	debug.Logger.Info("System information from os:", *p.sysInfo)
+	close(p.sysInfoReady)
+	return p.sysInfo
+}
+
+/*  Host os doesn't notify hostname/address changes, so system information is polled and cache is
+ *  updated on change
+ */
+func (p *SystemPlugin) listenSystemUpdates() {
+	<-p.sysInfoReady
+	ticker := time.NewTicker(SYSTEM_INFO_POLL_INTERVAL * time.Second)
+	for _ = range ticker.C {
+		sysInfo := p.readSystemInfo()
+		if reflect.DeepEqual(*p.sysInfo, *sysInfo) {
+			continue
+		}
+		debug.Logger.Debug("LLDP received system update:", *sysInfo)
+		p.sysInfo = sysInfo
+		api.UpdateCache(sysInfo)
+	}
+}
+
+func (p *SystemPlugin) Start() {
+	go p.listenSystemUpdates()
+}
@@ -28,6 +28,7 @@ import (
 	"fmt"
 	"l2/lldp/api"
 	"l2/lldp/flexswitch"
+	"l2/lldp/linux"
 	"l2/lldp/server"
 	"l2/lldp/utils"
 	"utils/dbutils"
@@ -38,6 +39,13 @@ import (
 func main() {
 	fmt.Println("Starting lldp daemon")
 	paramsDir := flag.String("params", "./params", "Params directory")
+	pluginName := flag.String("plugin", "flexswitch", "Plugin set to use: flexswitch or linux")
+	cfgFile := flag.String("config", "", "Configuration file for linux plugin, lldp runs on all "+
+		"ethernet interfaces if not provided")
+	httpAddr := flag.String("http", "", "Http api listen address for linux plugin, overrides config file")
+	cablingFile := flag.String("cabling", "", "Cabling plan file with expected neighbor per port")
+	pktIOName := flag.String("pktio", "afpacket", "Packet rx/tx path: afpacket (one shared socket) or pcap "+
+		"(one handle per port)")
 	flag.Parse()
 	fileName := *paramsDir
 	if fileName[len(fileName)-1] != '/' {
@@ -53,10 +61,41 @@ func main() {
 	debug.Logger.Info("Started the logger successfully.")
 
 	debug.Logger.Info("Starting LLDP server....")
-	name := ""
-	switch name {
+	pktIO, err := server.NewPktIO(*pktIOName)
+	if err != nil {
+		debug.Logger.Err("Creating packet io", *pktIOName, "failed, error:", err)
+		return
+	}
+	switch *pluginName {
 	case "ovsdb":
 
+	case "linux":
+		// standalone plugins for plain linux hosts, no asicd/sysd/db is needed
+		cfg, err := linux.ReadConfig(*cfgFile)
+		if err != nil {
+			debug.Logger.Err("Reading config file", *cfgFile, "failed, error:", err)
+			return
+		}
+		if *httpAddr != "" {
+			cfg.HttpAddr = *httpAddr
+		}
+		aPlugin, _ := linux.NewAsicPlugin(cfg)
+		sPlugin, _ := linux.NewSystemPlugin(cfg)
+		lPlugin := linux.NewNBPlugin(cfg)
+
+		lldpSvr := server.LLDPNewServer(aPlugin, lPlugin, sPlugin, nil)
+		lldpSvr.SetPktIO(pktIO)
+		api.Init(lldpSvr)
+		lldpSvr.LLDPStartServer(*paramsDir)
+		loadCablingPlan(*cablingFile)
+
+		debug.Logger.Info("Starting LLDP linux config listener....")
+		err = lldpSvr.CfgPlugin.Start()
+		if err != nil {
+			debug.Logger.Err(fmt.Sprintln("Cannot start lldp server", err))
+			return
+		}
+
 	default:
 		lldpDbHdl := dbutils.NewDBUtil(debug.Logger)
 		aPlugin, err := flexswitch.NewAsicPlugin(fileName)
@@ -73,11 +112,13 @@ func main() {
 
 		// Create lldp server handler
 		lldpSvr := server.LLDPNewServer(aPlugin, lPlugin, sPlugin, lldpDbHdl)
+		lldpSvr.SetPktIO(pktIO)
 		// Start Api Layer
 		api.Init(lldpSvr)
 
 		// Until Server is connected to clients do not start with RPC
 		lldpSvr.LLDPStartServer(*paramsDir)
+		loadCablingPlan(*cablingFile)
 
 		// Start keepalive routine
 		go keepalive.InitKeepAlive("lldpd", fileName)
@@ -89,3 +130,15 @@ func main() {
 		}
 	}
 }
+
+/*  Cabling plan is optional, lldp runs without verification if it cannot be loaded
+ */
+func loadCablingPlan(fileName string) {
+	if fileName == "" {
+		return
+	}
+	_, err := api.LoadCablingPlan(fileName)
+	if err != nil {
+		debug.Logger.Err("Loading cabling plan", fileName, "failed, error:", err)
+	}
+}
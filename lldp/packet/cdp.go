@@ -0,0 +1,396 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"l2/lldp/config"
+	"l2/lldp/utils"
+	"net"
+)
+
+const (
+	LLDP_CDP_DST_MAC = "01:00:0c:cc:cc:cc"
+	LLDP_CDP_VERSION = 2
+	// CDP holdtime is 1 byte
+	LLDP_CDP_MAX_TTL = 255
+	// CDP tlv header is type (2) & length (2)
+	LLDP_CDP_TLV_HDR_LEN = 4
+	// CDP address protocol type & protocol
+	LLDP_CDP_PROTO_NLPID = 1
+	LLDP_CDP_PROTO_802_2 = 2
+	LLDP_CDP_NLPID_IPV4  = 0xcc
+	// LLC/SNAP header of CDP frame
+	LLDP_CDP_LLC_SAP       = 0xaa
+	LLDP_CDP_LLC_CONTROL   = 0x03
+	LLDP_CDP_SNAP_PROTOCOL = 0x2000
+)
+
+var (
+	// Cisco OUI of SNAP header
+	cdpOUI = []byte{0x00, 0x00, 0x0c}
+	// 802.2 protocol of ipv6 address, SNAP header with ipv6 ethertype
+	cdpProtoIPv6 = []byte{0xaa, 0xaa, 0x03, 0x00, 0x00, 0x00, 0x86, 0xdd}
+)
+
+/*  CDP agent runs the same rx & tx state machines as lldp agent, only frame format is different
+ */
+func CdpRxInit() *RX {
+	rxInfo := RxInit(LLDP_CDP_DST_MAC)
+	rxInfo.cdp = true
+	return rxInfo
+}
+
+func CdpTxInit(interval, hold int) *TX {
+	txInfo := TxInit(interval, hold, LLDP_CDP_DST_MAC)
+	txInfo.cdp = true
+	return txInfo
+}
+
+/*  CDP checksum is internet checksum except for odd length pdu. Cisco puts the last byte in low order
+ *  byte of last word and adds it as signed value, which is compensated for in the last word
+ */
+func CdpChecksum(pdu []byte) uint16 {
+	var sum uint32
+	length := len(pdu)
+	if length%2 != 0 {
+		last := [2]byte{0, pdu[length-1]}
+		if last[1]&0x80 != 0 {
+			last[0]--
+			last[1]--
+		}
+		sum += uint32(binary.BigEndian.Uint16(last[:]))
+		length--
+	}
+	for idx := 0; idx < length; idx += 2 {
+		sum += uint32(binary.BigEndian.Uint16(pdu[idx : idx+2]))
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
+
+func encodeCdpTLV(tlvType layers.CDPTLVType, value []byte) []byte {
+	tlv := make([]byte, LLDP_CDP_TLV_HDR_LEN+len(value))
+	binary.BigEndian.PutUint16(tlv[0:2], uint16(tlvType))
+	binary.BigEndian.PutUint16(tlv[2:4], uint16(len(tlv)))
+	copy(tlv[LLDP_CDP_TLV_HDR_LEN:], value)
+	return tlv
+}
+
+/*  CDP Addresses TLV value
+ *     Number of addresses is 4 bytes
+ *     Every address is protocol type (1), protocol length (1), protocol (N), address length (2) & address
+ */
+func EncodeCdpAddresses(mgmtAddrs []config.MgmtAddr) []byte {
+	value := make([]byte, 4)
+	var count uint32
+	for _, mgmtAddr := range mgmtAddrs {
+		ip := net.ParseIP(mgmtAddr.Addr)
+		if ip == nil {
+			continue
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			value = append(value, LLDP_CDP_PROTO_NLPID, 1, LLDP_CDP_NLPID_IPV4, 0, net.IPv4len)
+			value = append(value, ip4...)
+		} else {
+			value = append(value, LLDP_CDP_PROTO_802_2, byte(len(cdpProtoIPv6)))
+			value = append(value, cdpProtoIPv6...)
+			value = append(value, 0, net.IPv6len)
+			value = append(value, ip.To16()...)
+		}
+		count++
+	}
+	binary.BigEndian.PutUint32(value[0:4], count)
+	return value
+}
+
+/*  Decode ipv4 & ipv6 addresses from CDP Addresses TLV value, other protocols are skipped
+ */
+func DecodeCdpAddresses(value []byte) []net.IP {
+	var addrs []net.IP
+	if len(value) < 4 {
+		return addrs
+	}
+	count := binary.BigEndian.Uint32(value[0:4])
+	value = value[4:]
+	for ; count > 0 && len(value) >= 2; count-- {
+		protoLen := int(value[1])
+		if len(value) < 2+protoLen+2 {
+			break
+		}
+		proto := value[2 : 2+protoLen]
+		addrLen := int(binary.BigEndian.Uint16(value[2+protoLen : 4+protoLen]))
+		if len(value) < 4+protoLen+addrLen {
+			break
+		}
+		addr := value[4+protoLen : 4+protoLen+addrLen]
+		switch {
+		case value[0] == LLDP_CDP_PROTO_NLPID && protoLen == 1 && proto[0] == LLDP_CDP_NLPID_IPV4 &&
+			addrLen == net.IPv4len:
+			addrs = append(addrs, net.IP(append([]byte(nil), addr...)))
+		case value[0] == LLDP_CDP_PROTO_802_2 && protoLen == len(cdpProtoIPv6) &&
+			string(proto) == string(cdpProtoIPv6) && addrLen == net.IPv6len:
+			addrs = append(addrs, net.IP(append([]byte(nil), addr...)))
+		}
+		value = value[4+protoLen+addrLen:]
+	}
+	return addrs
+}
+
+/*  Convert lldp enabled capabilities into CDP capabilities, bridge is advertised as switch
+ */
+func cdpCapabilities(c layers.LLDPCapabilities) uint32 {
+	var bits layers.CDPCapability
+	if c.Router {
+		bits |= layers.CDPCapMaskRouter
+	}
+	if c.Bridge {
+		bits |= layers.CDPCapMaskSwitch
+	}
+	if c.Repeater {
+		bits |= layers.CDPCapMaskRepeater
+	}
+	if c.Phone {
+		bits |= layers.CDPCapMaskPhone
+	}
+	if c.StationOnly {
+		bits |= layers.CDPCapMaskHost
+	}
+	return uint32(bits)
+}
+
+/*  Convert CDP capabilities into lldp capabilities, CDP only advertises enabled capabilities
+ */
+func lldpCapabilities(bits layers.CDPCapability) layers.LLDPCapabilities {
+	return layers.LLDPCapabilities{
+		Router:      bits&layers.CDPCapMaskRouter != 0,
+		Bridge:      bits&(layers.CDPCapMaskTBBridge|layers.CDPCapMaskSPBridge|layers.CDPCapMaskSwitch) != 0,
+		Repeater:    bits&layers.CDPCapMaskRepeater != 0,
+		Phone:       bits&layers.CDPCapMaskPhone != 0,
+		StationOnly: bits&layers.CDPCapMaskHost != 0,
+	}
+}
+
+/*  helper function to construct CDP frame, shutdown frame with ttl 0 only has device id & port id
+ *     Device ID is hostname, or port mac if hostname is not known
+ *     Port ID is port name
+ *     Addresses are management addresses
+ *     Version is software version & Platform is system description
+ */
+func (t *TX) createCdpFrame(srcmac net.HardwareAddr, port config.PortInfo, sysInfo *config.SystemInfo, ttl int) []byte {
+	deviceId := srcmac.String()
+	if sysInfo != nil && sysInfo.Hostname != "" {
+		deviceId = sysInfo.Hostname
+	}
+	// version, ttl & checksum which is filled in once all the tlv's are added
+	pdu := []byte{LLDP_CDP_VERSION, byte(Min(ttl, LLDP_CDP_MAX_TTL)), 0, 0}
+	pdu = append(pdu, encodeCdpTLV(layers.CDPTLVDevID, []byte(deviceId))...)
+	pdu = append(pdu, encodeCdpTLV(layers.CDPTLVPortID, []byte(port.Name))...)
+	if sysInfo != nil && ttl != 0 {
+		if mgmtAddrs := GetMgmtAddrs(sysInfo); len(mgmtAddrs) > 0 {
+			pdu = append(pdu, encodeCdpTLV(layers.CDPTLVAddress, EncodeCdpAddresses(mgmtAddrs))...)
+		}
+		caps := make([]byte, 4)
+		binary.BigEndian.PutUint32(caps, cdpCapabilities(GetLocalCapabilities(port, sysInfo).EnabledCap))
+		pdu = append(pdu, encodeCdpTLV(layers.CDPTLVCapabilities, caps)...)
+		pdu = append(pdu, encodeCdpTLV(layers.CDPTLVVersion, []byte(sysInfo.SwVersion))...)
+		pdu = append(pdu, encodeCdpTLV(layers.CDPTLVPlatform, []byte(sysInfo.Description))...)
+		if pvid := GetPortVlanId(port); pvid != LLDP_NO_PVID {
+			vlan := make([]byte, 2)
+			binary.BigEndian.PutUint16(vlan, uint16(pvid))
+			pdu = append(pdu, encodeCdpTLV(layers.CDPTLVNativeVLAN, vlan)...)
+		}
+		duplex := []byte{0}
+		if isFullDuplex(port) {
+			duplex[0] = 1
+		}
+		pdu = append(pdu, encodeCdpTLV(layers.CDPTLVFullDuplex, duplex)...)
+	}
+	binary.BigEndian.PutUint16(pdu[2:4], CdpChecksum(pdu))
+
+	// 802.3 frame with LLC/SNAP header, length is fixed by serialization
+	eth := &layers.Ethernet{
+		SrcMAC:       srcmac,
+		DstMAC:       t.DstMAC,
+		EthernetType: layers.EthernetTypeLLC,
+	}
+	llc := &layers.LLC{
+		DSAP:    LLDP_CDP_LLC_SAP,
+		SSAP:    LLDP_CDP_LLC_SAP,
+		Control: LLDP_CDP_LLC_CONTROL,
+	}
+	snap := &layers.SNAP{
+		OrganizationalCode: cdpOUI,
+		Type:               LLDP_CDP_SNAP_PROTOCOL,
+	}
+	buffer := gopacket.NewSerializeBuffer()
+	options := gopacket.SerializeOptions{
+		FixLengths: true,
+	}
+	err := gopacket.SerializeLayers(buffer, options, eth, llc, snap, gopacket.Payload(pdu))
+	if err != nil {
+		debug.Logger.Err("Creating CDP frame failed for port", port.Name, "error:", err)
+		return nil
+	}
+	frame := buffer.Bytes()
+	debug.Logger.Debug("CDP frame for port", port.Name, frame)
+	return frame
+}
+
+/*  Get CDP layer of received frame
+ */
+func getCdpLayer(pkt gopacket.Packet) *layers.CiscoDiscovery {
+	cdpLayer := pkt.Layer(layers.LayerTypeCiscoDiscovery)
+	if cdpLayer == nil {
+		return nil
+	}
+	return cdpLayer.(*layers.CiscoDiscovery)
+}
+
+/*  Device ID & Port ID of CDP neighbor as lldp chassis id (local) & port id (interface name)
+ */
+func cdpNeighborId(cdp *layers.CiscoDiscovery) *layers.LinkLayerDiscovery {
+	lldpInfo := &layers.LinkLayerDiscovery{
+		ChassisID: layers.LLDPChassisID{Subtype: layers.LLDPChassisIDSubTypeLocal},
+		PortID:    layers.LLDPPortID{Subtype: layers.LLDPPortIDSubtypeIfaceName},
+		TTL:       uint16(cdp.TTL),
+	}
+	for _, tlv := range cdp.Values {
+		switch tlv.Type {
+		case layers.CDPTLVDevID:
+			lldpInfo.ChassisID.ID = tlv.Value
+		case layers.CDPTLVPortID:
+			lldpInfo.PortID.ID = tlv.Value
+		}
+	}
+	return lldpInfo
+}
+
+/*  Remote table key information of CDP neighbor which sent the frame, nil for invalid frame
+ */
+func CdpNeighborId(pkt gopacket.Packet) *layers.LinkLayerDiscovery {
+	cdp := getCdpLayer(pkt)
+	if cdp == nil {
+		return nil
+	}
+	return cdpNeighborId(cdp)
+}
+
+/*  Decode CDP frame into lldp neighbor information:
+ *     Device ID & Port ID are chassis id & port id, Device ID is also system name unless System Name
+ *     TLV is received
+ *     Version is system description, Platform is kept as is
+ *     Capabilities are system & enabled capabilities
+ *     Addresses & Management Addresses are management address tlv's
+ *     Native VLAN is IEEE 802.1 Port VLAN ID
+ */
+func (rxInfo *RX) decodeCdp(pkt gopacket.Packet) (*Neighbor, error) {
+	cdp := getCdpLayer(pkt)
+	if cdp == nil {
+		rxInfo.Stats.FramesInErrors++
+		rxInfo.Stats.FramesDiscarded++
+		return nil, errors.New("Invalid CDP frame")
+	}
+	pdu := make([]byte, 0, len(cdp.Contents)+len(cdp.Payload))
+	pdu = append(pdu, cdp.Contents...)
+	pdu = append(pdu, cdp.Payload...)
+	if CdpChecksum(pdu) != 0 {
+		rxInfo.Stats.FramesInErrors++
+		rxInfo.Stats.FramesDiscarded++
+		return nil, errors.New("Invalid CDP checksum")
+	}
+	lldpInfo := cdpNeighborId(cdp)
+	if len(lldpInfo.ChassisID.ID) == 0 || len(lldpInfo.PortID.ID) == 0 {
+		rxInfo.Stats.FramesInErrors++
+		rxInfo.Stats.FramesDiscarded++
+		return nil, errors.New("CDP frame without device id or port id")
+	}
+	linkInfo := &layers.LinkLayerDiscoveryInfo{
+		SysName: string(lldpInfo.ChassisID.ID),
+	}
+	nbr := &Neighbor{
+		RxFrame:    lldpInfo,
+		RxLinkInfo: linkInfo,
+	}
+	var addrs, mgmtAddrs []net.IP
+	for _, tlv := range cdp.Values {
+		switch tlv.Type {
+		case layers.CDPTLVSysName:
+			linkInfo.SysName = string(tlv.Value)
+		case layers.CDPTLVVersion:
+			linkInfo.SysDescription = string(tlv.Value)
+		case layers.CDPTLVPlatform:
+			nbr.Platform = string(tlv.Value)
+		case layers.CDPTLVCapabilities:
+			if len(tlv.Value) < 4 {
+				rxInfo.Stats.TLVsDiscarded++
+				continue
+			}
+			caps := lldpCapabilities(layers.CDPCapability(binary.BigEndian.Uint32(tlv.Value[0:4])))
+			linkInfo.SysCapabilities.SystemCap = caps
+			linkInfo.SysCapabilities.EnabledCap = caps
+		case layers.CDPTLVNativeVLAN:
+			if len(tlv.Value) < 2 {
+				rxInfo.Stats.TLVsDiscarded++
+				continue
+			}
+			linkInfo.OrgTLVs = append(linkInfo.OrgTLVs, layers.LLDPOrgSpecificTLV{
+				OUI:     layers.IEEEOUI8021,
+				SubType: layers.LLDP8021SubtypePortVLANID,
+				Info:    tlv.Value[0:2],
+			})
+		case layers.CDPTLVAddress:
+			addrs = DecodeCdpAddresses(tlv.Value)
+		case layers.CDPTLVMgmtAddresses:
+			mgmtAddrs = DecodeCdpAddresses(tlv.Value)
+		}
+	}
+	// management addresses are preferred over interface addresses
+	if len(mgmtAddrs) == 0 {
+		mgmtAddrs = addrs
+	}
+	for _, addr := range mgmtAddrs {
+		mgmtInfo := &layers.LLDPMgmtAddress{
+			Subtype:          layers.IANAAddressFamilyIPV6,
+			Address:          addr,
+			InterfaceSubtype: layers.LLDPInterfaceSubtypeUnknown,
+		}
+		if ip4 := addr.To4(); ip4 != nil {
+			mgmtInfo.Subtype = layers.IANAAddressFamilyIPV4
+			mgmtInfo.Address = ip4
+		}
+		value := EncodeMgmtTLV(mgmtInfo)
+		lldpInfo.Values = append(lldpInfo.Values, layers.LinkLayerDiscoveryValue{
+			Type:   layers.LLDPTLVMgmtAddress,
+			Length: uint16(len(value)),
+			Value:  value,
+		})
+	}
+	return nbr, nil
+}
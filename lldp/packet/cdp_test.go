@@ -0,0 +1,369 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package packet
+
+import (
+	"encoding/binary"
+	"github.com/google/gopacket/layers"
+	"l2/lldp/config"
+	"net"
+	"reflect"
+	"testing"
+)
+
+/*  Encode CDP frame of the port with the given ttl
+ */
+func testCdpTxFrame(port config.PortInfo, sysInfo *config.SystemInfo, ttl int) []byte {
+	srcmac, _ := net.ParseMAC(port.MacAddr)
+	return CdpTxInit(30, 4).encodeFrame(srcmac, port, sysInfo, ttl)
+}
+
+// CDPv2 reference frame in the layout sent by a Catalyst 2960 running IOS 12.2(55)SE5 on FastEthernet0/1:
+// Device ID, Software Version, Platform, Addresses (192.168.1.2), Port ID, Capabilities (switch & IGMP),
+// Protocol Hello, VTP Domain, Native VLAN 10, Duplex full, Trust Bitmap, Untrusted CoS and Management
+// Addresses (10.1.1.2), holdtime 180
+var testCdpIOSFrame = []byte{
+	0x01, 0x00, 0x0c, 0xcc, 0xcc, 0xcc, 0x00, 0x1b, 0x54, 0xc2, 0x5b, 0x01, 0x01, 0xb2, 0xaa, 0xaa,
+	0x03, 0x00, 0x00, 0x0c, 0x20, 0x00, 0x02, 0xb4, 0xfc, 0x82, 0x00, 0x01, 0x00, 0x16, 0x53, 0x77,
+	0x69, 0x74, 0x63, 0x68, 0x2e, 0x65, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x2e, 0x63, 0x6f, 0x6d,
+	0x00, 0x05, 0x00, 0xf8, 0x43, 0x69, 0x73, 0x63, 0x6f, 0x20, 0x49, 0x4f, 0x53, 0x20, 0x53, 0x6f,
+	0x66, 0x74, 0x77, 0x61, 0x72, 0x65, 0x2c, 0x20, 0x43, 0x32, 0x39, 0x36, 0x30, 0x20, 0x53, 0x6f,
+	0x66, 0x74, 0x77, 0x61, 0x72, 0x65, 0x20, 0x28, 0x43, 0x32, 0x39, 0x36, 0x30, 0x2d, 0x4c, 0x41,
+	0x4e, 0x42, 0x41, 0x53, 0x45, 0x4b, 0x39, 0x2d, 0x4d, 0x29, 0x2c, 0x20, 0x56, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x20, 0x31, 0x32, 0x2e, 0x32, 0x28, 0x35, 0x35, 0x29, 0x53, 0x45, 0x35, 0x2c,
+	0x20, 0x52, 0x45, 0x4c, 0x45, 0x41, 0x53, 0x45, 0x20, 0x53, 0x4f, 0x46, 0x54, 0x57, 0x41, 0x52,
+	0x45, 0x20, 0x28, 0x66, 0x63, 0x31, 0x29, 0x0a, 0x54, 0x65, 0x63, 0x68, 0x6e, 0x69, 0x63, 0x61,
+	0x6c, 0x20, 0x53, 0x75, 0x70, 0x70, 0x6f, 0x72, 0x74, 0x3a, 0x20, 0x68, 0x74, 0x74, 0x70, 0x3a,
+	0x2f, 0x2f, 0x77, 0x77, 0x77, 0x2e, 0x63, 0x69, 0x73, 0x63, 0x6f, 0x2e, 0x63, 0x6f, 0x6d, 0x2f,
+	0x74, 0x65, 0x63, 0x68, 0x73, 0x75, 0x70, 0x70, 0x6f, 0x72, 0x74, 0x0a, 0x43, 0x6f, 0x70, 0x79,
+	0x72, 0x69, 0x67, 0x68, 0x74, 0x20, 0x28, 0x63, 0x29, 0x20, 0x31, 0x39, 0x38, 0x36, 0x2d, 0x32,
+	0x30, 0x31, 0x32, 0x20, 0x62, 0x79, 0x20, 0x43, 0x69, 0x73, 0x63, 0x6f, 0x20, 0x53, 0x79, 0x73,
+	0x74, 0x65, 0x6d, 0x73, 0x2c, 0x20, 0x49, 0x6e, 0x63, 0x2e, 0x0a, 0x43, 0x6f, 0x6d, 0x70, 0x69,
+	0x6c, 0x65, 0x64, 0x20, 0x54, 0x68, 0x75, 0x20, 0x30, 0x39, 0x2d, 0x46, 0x65, 0x62, 0x2d, 0x31,
+	0x32, 0x20, 0x31, 0x38, 0x3a, 0x31, 0x34, 0x20, 0x62, 0x79, 0x20, 0x70, 0x72, 0x6f, 0x64, 0x5f,
+	0x72, 0x65, 0x6c, 0x5f, 0x74, 0x65, 0x61, 0x6d, 0x00, 0x06, 0x00, 0x19, 0x63, 0x69, 0x73, 0x63,
+	0x6f, 0x20, 0x57, 0x53, 0x2d, 0x43, 0x32, 0x39, 0x36, 0x30, 0x2d, 0x32, 0x34, 0x54, 0x54, 0x2d,
+	0x4c, 0x00, 0x02, 0x00, 0x11, 0x00, 0x00, 0x00, 0x01, 0x01, 0x01, 0xcc, 0x00, 0x04, 0xc0, 0xa8,
+	0x01, 0x02, 0x00, 0x03, 0x00, 0x13, 0x46, 0x61, 0x73, 0x74, 0x45, 0x74, 0x68, 0x65, 0x72, 0x6e,
+	0x65, 0x74, 0x30, 0x2f, 0x31, 0x00, 0x04, 0x00, 0x08, 0x00, 0x00, 0x00, 0x28, 0x00, 0x08, 0x00,
+	0x26, 0x00, 0x00, 0x0c, 0x01, 0x12, 0x00, 0x00, 0x00, 0x00, 0xff, 0xff, 0xff, 0xff, 0x01, 0x02,
+	0x21, 0xff, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x1b, 0x54, 0xc2, 0x5b, 0x00,
+	0xff, 0x00, 0x00, 0x00, 0x09, 0x00, 0x07, 0x6c, 0x61, 0x62, 0x00, 0x0a, 0x00, 0x06, 0x00, 0x0a,
+	0x00, 0x0b, 0x00, 0x05, 0x01, 0x00, 0x12, 0x00, 0x05, 0x00, 0x00, 0x13, 0x00, 0x05, 0x00, 0x00,
+	0x16, 0x00, 0x11, 0x00, 0x00, 0x00, 0x01, 0x01, 0x01, 0xcc, 0x00, 0x04, 0x0a, 0x01, 0x01, 0x02,
+}
+
+var testCdpIOSVersion = "Cisco IOS Software, C2960 Software (C2960-LANBASEK9-M), Version 12.2(55)SE5, " +
+	"RELEASE SOFTWARE (fc1)\nTechnical Support: http://www.cisco.com/techsupport\n" +
+	"Copyright (c) 1986-2012 by Cisco Systems, Inc.\nCompiled Thu 09-Feb-12 18:14 by prod_rel_team"
+
+/*  Build CDP frame from the given tlv's with valid checksum
+ */
+func testCdpFrame(ttl byte, tlvs ...[]byte) []byte {
+	pdu := []byte{LLDP_CDP_VERSION, ttl, 0, 0}
+	for _, tlv := range tlvs {
+		pdu = append(pdu, tlv...)
+	}
+	binary.BigEndian.PutUint16(pdu[2:4], CdpChecksum(pdu))
+	frame := []byte{0x01, 0x00, 0x0c, 0xcc, 0xcc, 0xcc, 0x00, 0x1b, 0x54, 0xc2, 0x5b, 0x01, 0, 0,
+		0xaa, 0xaa, 0x03, 0x00, 0x00, 0x0c, 0x20, 0x00}
+	binary.BigEndian.PutUint16(frame[12:14], uint16(8+len(pdu)))
+	return append(frame, pdu...)
+}
+
+func TestCdpDecode(t *testing.T) {
+	testSetup()
+	devId := encodeCdpTLV(layers.CDPTLVDevID, []byte("Switch.example.com"))
+	portId := encodeCdpTLV(layers.CDPTLVPortID, []byte("FastEthernet0/1"))
+	badChecksum := append([]byte(nil), testCdpIOSFrame...)
+	badChecksum[len(badChecksum)-1] ^= 0xff
+
+	tests := []struct {
+		name         string
+		frame        []byte
+		err          bool
+		sysName      string
+		sysDesc      string
+		platform     string
+		caps         layers.LLDPCapabilities
+		pvid         []byte
+		mgmtAddrs    []config.MgmtAddr
+		tlvDiscarded int32
+	}{
+		{
+			name:      "IOS reference frame",
+			frame:     testCdpIOSFrame,
+			sysName:   "Switch.example.com",
+			sysDesc:   testCdpIOSVersion,
+			platform:  "cisco WS-C2960-24TT-L",
+			caps:      layers.LLDPCapabilities{Bridge: true},
+			pvid:      []byte{0x00, 0x0a},
+			mgmtAddrs: []config.MgmtAddr{{Addr: "10.1.1.2"}},
+		},
+		{
+			name:  "invalid checksum",
+			frame: badChecksum,
+			err:   true,
+		},
+		{
+			name: "interface addresses are used without management addresses",
+			frame: testCdpFrame(180, devId, portId,
+				encodeCdpTLV(layers.CDPTLVAddress, EncodeCdpAddresses([]config.MgmtAddr{{Addr: "192.168.1.2"},
+					{Addr: "2001:db8::2"}})),
+				encodeCdpTLV(layers.CDPTLVSysName, []byte("Switch"))),
+			sysName:   "Switch",
+			mgmtAddrs: []config.MgmtAddr{{Addr: "192.168.1.2"}, {Addr: "2001:db8::2"}},
+		},
+		{
+			name: "short capabilities & native vlan are discarded",
+			frame: testCdpFrame(180, devId, portId,
+				encodeCdpTLV(layers.CDPTLVCapabilities, []byte{0x00, 0x28}),
+				encodeCdpTLV(layers.CDPTLVNativeVLAN, []byte{0x0a})),
+			sysName:      "Switch.example.com",
+			tlvDiscarded: 2,
+		},
+		{
+			name:  "device id is mandatory",
+			frame: testCdpFrame(180, portId),
+			err:   true,
+		},
+		{
+			name:  "port id is mandatory",
+			frame: testCdpFrame(180, devId),
+			err:   true,
+		},
+	}
+	for _, tc := range tests {
+		rxInfo := CdpRxInit()
+		nbr, err := testProcess(rxInfo, tc.frame)
+		if tc.err {
+			if err == nil || rxInfo.Stats.FramesInErrors != 1 {
+				t.Error(tc.name, "expected error & frame in error actual", err, rxInfo.Stats)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error(tc.name, "failed with error", err)
+			continue
+		}
+		if nbr.RxFrame.ChassisID.Subtype != layers.LLDPChassisIDSubTypeLocal ||
+			DecodeChassisId(nbr.RxFrame.ChassisID) != "Switch.example.com" ||
+			nbr.RxFrame.PortID.Subtype != layers.LLDPPortIDSubtypeIfaceName ||
+			DecodePortId(nbr.RxFrame.PortID) != "FastEthernet0/1" || nbr.RxFrame.TTL != 180 {
+			t.Error(tc.name, "expected device id & port id as chassis id & port id actual",
+				nbr.RxFrame.ChassisID, nbr.RxFrame.PortID, nbr.RxFrame.TTL)
+		}
+		info := nbr.RxLinkInfo
+		if info.SysName != tc.sysName || info.SysDescription != tc.sysDesc || nbr.Platform != tc.platform {
+			t.Error(tc.name, "expected system name, description & platform", tc.sysName, tc.sysDesc,
+				tc.platform, "actual", info.SysName, info.SysDescription, nbr.Platform)
+		}
+		if info.SysCapabilities.SystemCap != tc.caps || info.SysCapabilities.EnabledCap != tc.caps {
+			t.Error(tc.name, "expected capabilities", tc.caps, "actual", info.SysCapabilities)
+		}
+		var pvid []byte
+		for _, o := range info.OrgTLVs {
+			if o.OUI == layers.IEEEOUI8021 && o.SubType == layers.LLDP8021SubtypePortVLANID {
+				pvid = o.Info
+			}
+		}
+		if !reflect.DeepEqual(pvid, tc.pvid) {
+			t.Error(tc.name, "expected port vlan id", tc.pvid, "actual", pvid)
+		}
+		if mgmtAddrs := DecodeMgmtAddrs(nbr.RxFrame); !reflect.DeepEqual(mgmtAddrs, tc.mgmtAddrs) {
+			t.Error(tc.name, "expected management addresses", tc.mgmtAddrs, "actual", mgmtAddrs)
+		}
+		if rxInfo.Stats.TLVsDiscarded != tc.tlvDiscarded {
+			t.Error(tc.name, "expected tlv's discarded", tc.tlvDiscarded, "actual", rxInfo.Stats.TLVsDiscarded)
+		}
+	}
+}
+
+func TestCdpEncode(t *testing.T) {
+	testSetup()
+	l3Port := testPort
+	l3Port.L3Enabled = true
+	l3Port.Vlans = nil
+	l3Port.Duplex = "Half Duplex"
+	noHostname := *testSysInfo
+	noHostname.Hostname = ""
+	v6SysInfo := *testSysInfo
+	v6SysInfo.MgmtAddrs = []config.MgmtAddr{{Addr: "2001:db8::1"}, {Addr: "10.0.0.1/24"}}
+
+	tests := []struct {
+		name      string
+		port      config.PortInfo
+		sysInfo   *config.SystemInfo
+		ttl       int
+		holdtime  uint16
+		devId     string
+		caps      layers.LLDPCapabilities
+		pvid      []byte
+		mgmtAddrs []config.MgmtAddr
+		duplex    []byte
+	}{
+		{
+			name:      "bridged port",
+			port:      testPort,
+			sysInfo:   testSysInfo,
+			ttl:       121,
+			holdtime:  121,
+			devId:     "leaf1",
+			caps:      layers.LLDPCapabilities{Bridge: true},
+			pvid:      []byte{0x00, 0x0a},
+			mgmtAddrs: []config.MgmtAddr{{Addr: "10.0.0.1"}},
+			duplex:    []byte{1},
+		},
+		{
+			name:      "routed port with ipv6 management address",
+			port:      l3Port,
+			sysInfo:   &v6SysInfo,
+			ttl:       121,
+			holdtime:  121,
+			devId:     "leaf1",
+			caps:      layers.LLDPCapabilities{Router: true},
+			mgmtAddrs: []config.MgmtAddr{{Addr: "2001:db8::1"}, {Addr: "10.0.0.1"}},
+			duplex:    []byte{0},
+		},
+		{
+			name:      "port mac is device id without hostname & holdtime is limited to 255",
+			port:      testPort,
+			sysInfo:   &noHostname,
+			ttl:       3600,
+			holdtime:  LLDP_CDP_MAX_TTL,
+			devId:     "00:11:22:33:44:01",
+			caps:      layers.LLDPCapabilities{Bridge: true},
+			pvid:      []byte{0x00, 0x0a},
+			mgmtAddrs: []config.MgmtAddr{{Addr: "10.0.0.1"}},
+			duplex:    []byte{1},
+		},
+	}
+	for _, tc := range tests {
+		frame := testCdpTxFrame(tc.port, tc.sysInfo, tc.ttl)
+		cdp := getCdpLayer(testPacket(frame))
+		if cdp == nil {
+			t.Error(tc.name, "CDP frame is not decoded", frame)
+			continue
+		}
+		if cdp.Version != LLDP_CDP_VERSION || uint16(cdp.TTL) != tc.holdtime {
+			t.Error(tc.name, "expected version 2 & holdtime", tc.holdtime, "actual", cdp.Version, cdp.TTL)
+		}
+		values := make(map[layers.CDPTLVType][]byte)
+		for _, tlv := range cdp.Values {
+			values[tlv.Type] = tlv.Value
+		}
+		if !reflect.DeepEqual(values[layers.CDPTLVFullDuplex], tc.duplex) {
+			t.Error(tc.name, "expected duplex", tc.duplex, "actual", values[layers.CDPTLVFullDuplex])
+		}
+
+		nbr, err := testProcess(CdpRxInit(), frame)
+		if err != nil {
+			t.Error(tc.name, "decoding encoded frame failed with error", err)
+			continue
+		}
+		info := nbr.RxLinkInfo
+		if DecodeChassisId(nbr.RxFrame.ChassisID) != tc.devId || DecodePortId(nbr.RxFrame.PortID) != tc.port.Name {
+			t.Error(tc.name, "expected device id", tc.devId, "port id", tc.port.Name, "actual",
+				nbr.RxFrame.ChassisID, nbr.RxFrame.PortID)
+		}
+		if info.SysDescription != tc.sysInfo.SwVersion || nbr.Platform != tc.sysInfo.Description {
+			t.Error(tc.name, "expected version & platform", tc.sysInfo.SwVersion, tc.sysInfo.Description,
+				"actual", info.SysDescription, nbr.Platform)
+		}
+		if info.SysCapabilities.EnabledCap != tc.caps {
+			t.Error(tc.name, "expected capabilities", tc.caps, "actual", info.SysCapabilities.EnabledCap)
+		}
+		var pvid []byte
+		for _, o := range info.OrgTLVs {
+			if o.OUI == layers.IEEEOUI8021 && o.SubType == layers.LLDP8021SubtypePortVLANID {
+				pvid = o.Info
+			}
+		}
+		if !reflect.DeepEqual(pvid, tc.pvid) {
+			t.Error(tc.name, "expected native vlan", tc.pvid, "actual", pvid)
+		}
+		if mgmtAddrs := DecodeMgmtAddrs(nbr.RxFrame); !reflect.DeepEqual(mgmtAddrs, tc.mgmtAddrs) {
+			t.Error(tc.name, "expected addresses", tc.mgmtAddrs, "actual", mgmtAddrs)
+		}
+	}
+}
+
+func TestCdpShutdownFrame(t *testing.T) {
+	testSetup()
+	// shutdown frame only has device id & port id
+	expected := []byte{0x02, 0x00, 0x9c, 0xcf, 0x00, 0x01, 0x00, 0x09, 0x6c, 0x65, 0x61, 0x66, 0x31,
+		0x00, 0x03, 0x00, 0x0b, 0x66, 0x70, 0x50, 0x6f, 0x72, 0x74, 0x31}
+	frame := CdpTxInit(30, 4).ShutdownFrame(testPort, testSysInfo)
+	cdp := getCdpLayer(testPacket(frame))
+	if cdp == nil {
+		t.Fatal("CDP shutdown frame is not decoded", frame)
+	}
+	pdu := append(append([]byte(nil), cdp.Contents...), cdp.Payload...)
+	if !reflect.DeepEqual(pdu, expected) {
+		t.Errorf("expected shutdown pdu\n%x\nactual\n%x", expected, pdu)
+	}
+}
+
+func TestCdpAddresses(t *testing.T) {
+	tests := []struct {
+		name  string
+		value []byte
+		addrs []net.IP
+	}{
+		{
+			name: "ipv4 & ipv6",
+			value: []byte{0x00, 0x00, 0x00, 0x02,
+				0x01, 0x01, 0xcc, 0x00, 0x04, 0xc0, 0xa8, 0x01, 0x02,
+				0x02, 0x08, 0xaa, 0xaa, 0x03, 0x00, 0x00, 0x00, 0x86, 0xdd, 0x00, 0x10,
+				0x20, 0x01, 0x0d, 0xb8, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02},
+			addrs: []net.IP{net.ParseIP("192.168.1.2").To4(), net.ParseIP("2001:db8::2")},
+		},
+		{
+			name: "other protocols are skipped",
+			value: []byte{0x00, 0x00, 0x00, 0x02,
+				0x01, 0x01, 0x81, 0x00, 0x02, 0x12, 0x34,
+				0x01, 0x01, 0xcc, 0x00, 0x04, 0x0a, 0x01, 0x01, 0x02},
+			addrs: []net.IP{net.ParseIP("10.1.1.2").To4()},
+		},
+		{
+			name:  "truncated address",
+			value: []byte{0x00, 0x00, 0x00, 0x01, 0x01, 0x01, 0xcc, 0x00, 0x04, 0x0a, 0x01},
+		},
+		{
+			name:  "short value",
+			value: []byte{0x00, 0x00},
+		},
+	}
+	for _, tc := range tests {
+		if addrs := DecodeCdpAddresses(tc.value); !reflect.DeepEqual(addrs, tc.addrs) {
+			t.Error(tc.name, "expected addresses", tc.addrs, "actual", addrs)
+		}
+	}
+
+	// encoded addresses are the same as the reference encoding
+	mgmtAddrs := []config.MgmtAddr{{Addr: "192.168.1.2"}, {Addr: "2001:db8::2"}, {Addr: "invalid"}}
+	if value := EncodeCdpAddresses(mgmtAddrs); !reflect.DeepEqual(value, tests[0].value) {
+		t.Errorf("expected encoded addresses\n%x\nactual\n%x", tests[0].value, value)
+	}
+}
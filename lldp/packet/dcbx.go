@@ -0,0 +1,220 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package packet
+
+import (
+	"github.com/google/gopacket/layers"
+	"l2/lldp/config"
+	"l2/lldp/utils"
+	"reflect"
+)
+
+const (
+	// IEEE 802.1Qaz org specific tlv subtypes, gopacket does not know about them
+	LLDP_8021_SUBTYPE_ETS_CONFIG = 0x09
+	LLDP_8021_SUBTYPE_ETS_RECO   = 0x0a
+	LLDP_8021_SUBTYPE_PFC_CONFIG = 0x0b
+	LLDP_8021_SUBTYPE_APP_PRIO   = 0x0c
+	// ETS parameters are 4 bytes priority assignment, 8 bytes bandwidth & 8 bytes TSA
+	LLDP_DCBX_ETS_PARAMS_LEN = 20
+	LLDP_DCBX_APP_ENTRY_LEN  = 3
+	LLDP_DCBX_WILLING        = 0x80
+	LLDP_DCBX_CBS            = 0x40 // ETS credit based shaper supported
+	LLDP_DCBX_MBC            = 0x40 // PFC MACsec bypass capability
+)
+
+/*  Update DCBX configuration, frame is send right away if configuration is changed
+ */
+func (t *TX) SetDcbxConfig(dcbxCfg config.DcbxConfig) {
+	if reflect.DeepEqual(t.dcbxConfig, dcbxCfg) {
+		return
+	}
+	t.dcbxConfig = dcbxCfg
+	t.SomethingChangedLocal()
+}
+
+/*  helper function to create all DCBX tlv's, application priority tlv is only added if there are
+ *  application priority entries configured
+ */
+func (t *TX) createDcbxPayload() []byte {
+	var payload []byte
+	payload = append(payload, EncodeEtsConfigTLV(&t.dcbxConfig)...)
+	payload = append(payload, EncodeEtsRecommendationTLV(&t.dcbxConfig.EtsRecommendation)...)
+	payload = append(payload, EncodePfcConfigTLV(&t.dcbxConfig)...)
+	if len(t.dcbxConfig.Apps) > 0 {
+		payload = append(payload, EncodeAppPriorityTLV(t.dcbxConfig.Apps)...)
+	}
+	debug.Logger.Debug("DCBX tlv's", payload)
+	return payload
+}
+
+/*  ETS parameters common to ETS configuration & recommendation tlv's
+ *     Priority Assignment Table is 4 bytes, 4 bits traffic class per priority starting with priority 0
+ *     TC Bandwidth Table is 8 bytes, bandwidth percentage per traffic class
+ *     TSA Assignment Table is 8 bytes, transmission selection algorithm per traffic class
+ */
+func encodeEtsParams(ets *config.EtsConfig) []byte {
+	params := make([]byte, LLDP_DCBX_ETS_PARAMS_LEN)
+	for prio, tc := range ets.PriorityTC {
+		params[prio/2] |= (tc & 0xf) << uint(4*(1-prio%2))
+	}
+	copy(params[4:12], ets.TCBandwidth[:])
+	copy(params[12:20], ets.TSA[:])
+	return params
+}
+
+func decodeEtsParams(params []byte) *config.EtsConfig {
+	ets := &config.EtsConfig{}
+	for prio := range ets.PriorityTC {
+		ets.PriorityTC[prio] = (params[prio/2] >> uint(4*(1-prio%2))) & 0xf
+	}
+	copy(ets.TCBandwidth[:], params[4:12])
+	copy(ets.TSA[:], params[12:20])
+	return ets
+}
+
+/*  IEEE 802.1Qaz ETS Configuration TLV, subtype 9
+ *     Willing 1 bit, CBS 1 bit, Reserved 3 bits, Max TCs 3 bits (0 means 8)
+ *     ETS parameters are 20 bytes
+ */
+func EncodeEtsConfigTLV(dcbxCfg *config.DcbxConfig) []byte {
+	info := make([]byte, 1, 1+LLDP_DCBX_ETS_PARAMS_LEN)
+	if dcbxCfg.Willing {
+		info[0] |= LLDP_DCBX_WILLING
+	}
+	for _, tsa := range dcbxCfg.Ets.TSA {
+		if tsa == config.TSA_CBS {
+			info[0] |= LLDP_DCBX_CBS
+			break
+		}
+	}
+	info[0] |= dcbxCfg.MaxTCs & 0x7
+	info = append(info, encodeEtsParams(&dcbxCfg.Ets)...)
+	return EncodeOrgTLV(layers.IEEEOUI8021, LLDP_8021_SUBTYPE_ETS_CONFIG, info)
+}
+
+/*  IEEE 802.1Qaz ETS Recommendation TLV, subtype 10
+ *     Reserved is 1 byte
+ *     ETS parameters are 20 bytes
+ */
+func EncodeEtsRecommendationTLV(ets *config.EtsConfig) []byte {
+	info := make([]byte, 1, 1+LLDP_DCBX_ETS_PARAMS_LEN)
+	info = append(info, encodeEtsParams(ets)...)
+	return EncodeOrgTLV(layers.IEEEOUI8021, LLDP_8021_SUBTYPE_ETS_RECO, info)
+}
+
+/*  IEEE 802.1Qaz PFC Configuration TLV, subtype 11
+ *     Willing 1 bit, MBC 1 bit, Reserved 2 bits, PFC Cap 4 bits
+ *     PFC Enable is 1 byte, bit n is priority n
+ */
+func EncodePfcConfigTLV(dcbxCfg *config.DcbxConfig) []byte {
+	info := make([]byte, 2)
+	if dcbxCfg.Willing {
+		info[0] |= LLDP_DCBX_WILLING
+	}
+	info[0] |= dcbxCfg.PfcCap & 0xf
+	info[1] = dcbxCfg.PfcEnable
+	return EncodeOrgTLV(layers.IEEEOUI8021, LLDP_8021_SUBTYPE_PFC_CONFIG, info)
+}
+
+/*  IEEE 802.1Qaz Application Priority TLV, subtype 12
+ *     Reserved is 1 byte
+ *     Application Priority Table is 3 bytes per entry:
+ *        Priority 3 bits, Reserved 2 bits, Selector 3 bits
+ *        Protocol ID is 2 bytes
+ */
+func EncodeAppPriorityTLV(apps []config.AppPriority) []byte {
+	if len(apps) > config.DCBX_MAX_APP_ENTRIES {
+		apps = apps[:config.DCBX_MAX_APP_ENTRIES]
+	}
+	info := make([]byte, 1+LLDP_DCBX_APP_ENTRY_LEN*len(apps))
+	for idx, app := range apps {
+		entry := info[1+LLDP_DCBX_APP_ENTRY_LEN*idx:]
+		entry[0] = (app.Priority&0x7)<<5 | app.Selector&0x7
+		entry[1] = byte(app.Protocol >> 8)
+		entry[2] = byte(app.Protocol)
+	}
+	return EncodeOrgTLV(layers.IEEEOUI8021, LLDP_8021_SUBTYPE_APP_PRIO, info)
+}
+
+/*  Decode DCBX tlv's received from peer, nil is returned if peer did not advertise any of them
+ */
+func DecodeDcbx(info *layers.LinkLayerDiscoveryInfo) *config.DcbxPeerInfo {
+	if info == nil {
+		return nil
+	}
+	var peer *config.DcbxPeerInfo
+	for _, o := range info.OrgTLVs {
+		if o.OUI != layers.IEEEOUI8021 {
+			continue
+		}
+		switch o.SubType {
+		case LLDP_8021_SUBTYPE_ETS_CONFIG, LLDP_8021_SUBTYPE_ETS_RECO:
+			if len(o.Info) < 1+LLDP_DCBX_ETS_PARAMS_LEN {
+				continue
+			}
+			if peer == nil {
+				peer = &config.DcbxPeerInfo{}
+			}
+			ets := decodeEtsParams(o.Info[1:])
+			if o.SubType == LLDP_8021_SUBTYPE_ETS_RECO {
+				peer.EtsRecommendation = ets
+				continue
+			}
+			peer.Ets = ets
+			peer.EtsWilling = o.Info[0]&LLDP_DCBX_WILLING != 0
+			peer.MaxTCs = o.Info[0] & 0x7
+			if peer.MaxTCs == 0 {
+				peer.MaxTCs = config.DCBX_MAX_TCS
+			}
+		case LLDP_8021_SUBTYPE_PFC_CONFIG:
+			if len(o.Info) < 2 {
+				continue
+			}
+			if peer == nil {
+				peer = &config.DcbxPeerInfo{}
+			}
+			peer.PfcValid = true
+			peer.PfcWilling = o.Info[0]&LLDP_DCBX_WILLING != 0
+			peer.PfcCap = o.Info[0] & 0xf
+			peer.PfcEnable = o.Info[1]
+		case LLDP_8021_SUBTYPE_APP_PRIO:
+			if len(o.Info) < 1 {
+				continue
+			}
+			if peer == nil {
+				peer = &config.DcbxPeerInfo{}
+			}
+			peer.Apps = make([]config.AppPriority, 0, (len(o.Info)-1)/LLDP_DCBX_APP_ENTRY_LEN)
+			for entry := o.Info[1:]; len(entry) >= LLDP_DCBX_APP_ENTRY_LEN; entry = entry[LLDP_DCBX_APP_ENTRY_LEN:] {
+				peer.Apps = append(peer.Apps, config.AppPriority{
+					Priority: entry[0] >> 5,
+					Selector: entry[0] & 0x7,
+					Protocol: uint16(entry[1])<<8 | uint16(entry[2]),
+				})
+			}
+		}
+	}
+	return peer
+}
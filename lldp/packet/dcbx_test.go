@@ -0,0 +1,187 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package packet
+
+import (
+	"github.com/google/gopacket/layers"
+	"l2/lldp/config"
+	"reflect"
+	"testing"
+)
+
+// DCBX reference frame in the layout sent by lldpad on a converged network adapter: ETS configuration
+// & recommendation with priorities 0-2 on TC 0, 3-4 on TC 1 and 5-7 on TC 2 at 30/30/40 percent, willing
+// PFC with 8 traffic classes and priority 3 enabled, application priority for FCoE (ethertype 0x8906) on
+// priority 3 and iSCSI (tcp port 3260) on priority 4
+var testDcbxFrame = []byte{
+	0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e, 0x00, 0x02, 0xc9, 0x12, 0x34, 0x56, 0x88, 0xcc, 0x02, 0x07,
+	0x04, 0x00, 0x02, 0xc9, 0x12, 0x34, 0x56, 0x04, 0x07, 0x03, 0x00, 0x02, 0xc9, 0x12, 0x34, 0x56,
+	0x06, 0x02, 0x00, 0x78, 0xfe, 0x19, 0x00, 0x80, 0xc2, 0x09, 0x08, 0x00, 0x01, 0x12, 0x22, 0x1e,
+	0x1e, 0x28, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x02, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0xfe,
+	0x19, 0x00, 0x80, 0xc2, 0x0a, 0x00, 0x00, 0x01, 0x12, 0x22, 0x1e, 0x1e, 0x28, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x02, 0x02, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0xfe, 0x06, 0x00, 0x80, 0xc2, 0x0b,
+	0x88, 0x08, 0xfe, 0x0b, 0x00, 0x80, 0xc2, 0x0c, 0x00, 0x61, 0x89, 0x06, 0x84, 0x0c, 0xbc, 0x00,
+	0x00,
+}
+
+var testDcbxConfig = config.DcbxConfig{
+	Enable:  true,
+	Willing: true,
+	MaxTCs:  4,
+	Ets: config.EtsConfig{
+		PriorityTC:  [config.DCBX_MAX_PRIORITIES]uint8{0, 0, 0, 1, 1, 2, 2, 3},
+		TCBandwidth: [config.DCBX_MAX_TCS]uint8{25, 25, 40, 10},
+		TSA:         [config.DCBX_MAX_TCS]uint8{config.TSA_ETS, config.TSA_ETS, config.TSA_ETS, config.TSA_STRICT},
+	},
+	EtsRecommendation: config.EtsConfig{
+		PriorityTC:  [config.DCBX_MAX_PRIORITIES]uint8{0, 0, 0, 1, 1, 2, 2, 3},
+		TCBandwidth: [config.DCBX_MAX_TCS]uint8{50, 50},
+		TSA:         [config.DCBX_MAX_TCS]uint8{config.TSA_ETS, config.TSA_CBS},
+	},
+	PfcCap:    8,
+	PfcEnable: 0x08,
+	Apps: []config.AppPriority{
+		{Selector: 1, Protocol: 0x8906, Priority: 3},
+		{Selector: 4, Protocol: 3260, Priority: 4},
+	},
+}
+
+func TestDcbxEncode(t *testing.T) {
+	cbs := testDcbxConfig
+	cbs.Willing = false
+	cbs.MaxTCs = 0
+	cbs.Ets = testDcbxConfig.EtsRecommendation
+
+	tests := []struct {
+		name     string
+		tlv      []byte
+		expected []byte
+	}{
+		{
+			name: "ets configuration",
+			tlv:  EncodeEtsConfigTLV(&testDcbxConfig),
+			expected: []byte{0xfe, 0x19, 0x00, 0x80, 0xc2, 0x09, 0x84, 0x00, 0x01, 0x12, 0x23,
+				0x19, 0x19, 0x28, 0x0a, 0x00, 0x00, 0x00, 0x00, 0x02, 0x02, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			name: "ets configuration with credit based shaper",
+			tlv:  EncodeEtsConfigTLV(&cbs),
+			expected: []byte{0xfe, 0x19, 0x00, 0x80, 0xc2, 0x09, 0x40, 0x00, 0x01, 0x12, 0x23,
+				0x32, 0x32, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			name: "ets recommendation",
+			tlv:  EncodeEtsRecommendationTLV(&testDcbxConfig.EtsRecommendation),
+			expected: []byte{0xfe, 0x19, 0x00, 0x80, 0xc2, 0x0a, 0x00, 0x00, 0x01, 0x12, 0x23,
+				0x32, 0x32, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			name:     "pfc configuration",
+			tlv:      EncodePfcConfigTLV(&testDcbxConfig),
+			expected: []byte{0xfe, 0x06, 0x00, 0x80, 0xc2, 0x0b, 0x88, 0x08},
+		},
+		{
+			name:     "application priority",
+			tlv:      EncodeAppPriorityTLV(testDcbxConfig.Apps),
+			expected: []byte{0xfe, 0x0b, 0x00, 0x80, 0xc2, 0x0c, 0x00, 0x61, 0x89, 0x06, 0x84, 0x0c, 0xbc},
+		},
+	}
+	for _, tc := range tests {
+		if !reflect.DeepEqual(tc.tlv, tc.expected) {
+			t.Errorf("%s expected\n%x\nactual\n%x", tc.name, tc.expected, tc.tlv)
+		}
+	}
+}
+
+func TestDcbxDecode(t *testing.T) {
+	testSetup()
+	referenceEts := &config.EtsConfig{
+		PriorityTC:  [config.DCBX_MAX_PRIORITIES]uint8{0, 0, 0, 1, 1, 2, 2, 2},
+		TCBandwidth: [config.DCBX_MAX_TCS]uint8{30, 30, 40},
+		TSA:         [config.DCBX_MAX_TCS]uint8{config.TSA_ETS, config.TSA_ETS, config.TSA_ETS},
+	}
+	tx := TxInit(30, 4, LLDP_PROTO_DST_MAC)
+	tx.SetDcbxConfig(testDcbxConfig)
+	etsConfig := EncodeEtsConfigTLV(&testDcbxConfig)
+
+	tests := []struct {
+		name  string
+		frame []byte
+		peer  *config.DcbxPeerInfo
+	}{
+		{
+			name:  "lldpad reference frame",
+			frame: testDcbxFrame,
+			peer: &config.DcbxPeerInfo{
+				MaxTCs:            config.DCBX_MAX_TCS,
+				Ets:               referenceEts,
+				EtsRecommendation: referenceEts,
+				PfcValid:          true,
+				PfcWilling:        true,
+				PfcCap:            8,
+				PfcEnable:         0x08,
+				Apps:              testDcbxConfig.Apps,
+			},
+		},
+		{
+			name:  "dcbx tlv's advertised by us",
+			frame: testFrame(tx, testPort, testSysInfo),
+			peer: &config.DcbxPeerInfo{
+				EtsWilling:        true,
+				MaxTCs:            4,
+				Ets:               &testDcbxConfig.Ets,
+				EtsRecommendation: &testDcbxConfig.EtsRecommendation,
+				PfcValid:          true,
+				PfcWilling:        true,
+				PfcCap:            8,
+				PfcEnable:         0x08,
+				Apps:              testDcbxConfig.Apps,
+			},
+		},
+		{
+			name: "truncated ets configuration is ignored",
+			frame: testLldpFrame(EncodeOrgTLV(layers.IEEEOUI8021, LLDP_8021_SUBTYPE_ETS_CONFIG,
+				etsConfig[6:len(etsConfig)-1])),
+		},
+		{
+			name: "ets configuration with other oui is ignored",
+			frame: testLldpFrame(EncodeOrgTLV(layers.IEEEOUI(0x001b21), LLDP_8021_SUBTYPE_ETS_CONFIG,
+				etsConfig[6:])),
+		},
+		{
+			name:  "switch reference frame without dcbx",
+			frame: testSwitchFrame,
+		},
+	}
+	for _, tc := range tests {
+		nbr, err := testProcess(RxInit(LLDP_PROTO_DST_MAC), tc.frame)
+		if err != nil {
+			t.Error(tc.name, "failed with error", err)
+			continue
+		}
+		if peer := DecodeDcbx(nbr.RxLinkInfo); !reflect.DeepEqual(peer, tc.peer) {
+			t.Errorf("%s expected dcbx peer %+v actual %+v", tc.name, tc.peer, peer)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package packet
+
+import (
+	"encoding/hex"
+	"github.com/google/gopacket/layers"
+	"l2/lldp/config"
+	"net"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*  Set chassis id selection, frame is send right away if chassis id is changed
+ */
+func (t *TX) SetChassisId(chassisId config.ChassisIdConfig) {
+	if t.chassisId == chassisId {
+		return
+	}
+	t.chassisId = chassisId
+	t.SomethingChangedLocal()
+}
+
+/*  Set port id selection, frame is send right away if port id is changed
+ */
+func (t *TX) SetPortIdSubtype(subtype uint8) {
+	if t.portIdSubtype == subtype {
+		return
+	}
+	t.portIdSubtype = subtype
+	t.SomethingChangedLocal()
+}
+
+/*  Chassis ID TLV value as per chassis id selection, if the information for selected chassis id is not
+ *  available, e.g. system mac is not known, then port mac is used
+ */
+func (t *TX) encodeChassisIdTLV(srcmac []byte, sysInfo *config.SystemInfo) []byte {
+	switch t.chassisId.Subtype {
+	case config.CHASSIS_ID_SYSTEM_MAC:
+		if sysInfo != nil {
+			mac, err := net.ParseMAC(sysInfo.SwitchMac)
+			if err == nil {
+				return EncodeMandatoryTLV(byte(layers.LLDPChassisIDSubTypeMACAddr), mac)
+			}
+		}
+	case config.CHASSIS_ID_HOSTNAME:
+		if sysInfo != nil && sysInfo.Hostname != "" {
+			return EncodeMandatoryTLV(byte(layers.LLDPChassisIDSubTypeLocal), []byte(sysInfo.Hostname))
+		}
+	case config.CHASSIS_ID_LOCAL:
+		if t.chassisId.Local != "" {
+			return EncodeMandatoryTLV(byte(layers.LLDPChassisIDSubTypeLocal), []byte(t.chassisId.Local))
+		}
+	}
+	return EncodeMandatoryTLV(byte(layers.LLDPChassisIDSubTypeMACAddr), srcmac)
+}
+
+/*  Port ID TLV value as per port id selection, port name is used if port description is not set
+ */
+func (t *TX) encodePortIdTLV(srcmac []byte, port config.PortInfo) []byte {
+	switch t.portIdSubtype {
+	case config.PORT_ID_IF_ALIAS:
+		if port.Description != "" {
+			return EncodeMandatoryTLV(byte(layers.LLDPPortIDSubtypeIfaceAlias), []byte(port.Description))
+		}
+	case config.PORT_ID_MAC_ADDR:
+		return EncodeMandatoryTLV(byte(layers.LLDPPortIDSubtypeMACAddr), srcmac)
+	case config.PORT_ID_LOCAL:
+		return EncodeMandatoryTLV(byte(layers.LLDPPortIDSubtypeLocal),
+			[]byte(strconv.Itoa(int(port.IfIndex))))
+	}
+	return EncodeMandatoryTLV(byte(layers.LLDPPortIDSubtypeIfaceName), []byte(port.Name))
+}
+
+/*  Decode chassis id received from peer based on the subtype
+ */
+func DecodeChassisId(chassisId layers.LLDPChassisID) string {
+	switch chassisId.Subtype {
+	case layers.LLDPChassisIDSubTypeMACAddr:
+		return decodeMacAddr(chassisId.ID)
+	case layers.LLDPChassisIDSubTypeNetworkAddr:
+		return decodeNetworkAddr(chassisId.ID)
+	case layers.LLDPChassisIDSubTypeChassisComp, layers.LLDPChassisIDSubtypeIfaceAlias,
+		layers.LLDPChassisIDSubTypePortComp, layers.LLDPChassisIDSubtypeIfaceName,
+		layers.LLDPChassisIDSubTypeLocal:
+		return decodeString(chassisId.ID)
+	}
+	// reserved subtypes
+	return hex.EncodeToString(chassisId.ID)
+}
+
+/*  Decode port id received from peer based on the subtype
+ */
+func DecodePortId(portId layers.LLDPPortID) string {
+	switch portId.Subtype {
+	case layers.LLDPPortIDSubtypeMACAddr:
+		return decodeMacAddr(portId.ID)
+	case layers.LLDPPortIDSubtypeNetworkAddr:
+		return decodeNetworkAddr(portId.ID)
+	case layers.LLDPPortIDSubtypeIfaceAlias, layers.LLDPPortIDSubtypePortComp,
+		layers.LLDPPortIDSubtypeIfaceName, layers.LLDPPortIDSubtypeAgentCircuitID,
+		layers.LLDPPortIDSubtypeLocal:
+		return decodeString(portId.ID)
+	}
+	// reserved subtypes
+	return hex.EncodeToString(portId.ID)
+}
+
+/*  Mac address is expected to be 6 bytes, anything else is shown as hex string
+ */
+func decodeMacAddr(id []byte) string {
+	if len(id) != 6 {
+		return hex.EncodeToString(id)
+	}
+	return net.HardwareAddr(id).String()
+}
+
+/*  Network address is IANA address family followed by the address
+ */
+func decodeNetworkAddr(id []byte) string {
+	if len(id) < 2 {
+		return hex.EncodeToString(id)
+	}
+	family := layers.IANAAddressFamily(id[0])
+	addr := id[1:]
+	switch {
+	case family == layers.IANAAddressFamilyIPV4 && len(addr) == net.IPv4len,
+		family == layers.IANAAddressFamilyIPV6 && len(addr) == net.IPv6len:
+		return net.IP(addr).String()
+	case family == layers.IANAAddressFamily802 && len(addr) == 6:
+		return net.HardwareAddr(addr).String()
+	}
+	return family.String() + ":" + hex.EncodeToString(addr)
+}
+
+/*  Alphanumeric ids are shown as is, ids with non printable characters are shown as hex string
+ */
+func decodeString(id []byte) string {
+	if !utf8.Valid(id) {
+		return hex.EncodeToString(id)
+	}
+	for _, r := range string(id) {
+		if !unicode.IsPrint(r) {
+			return hex.EncodeToString(id)
+		}
+	}
+	return string(id)
+}
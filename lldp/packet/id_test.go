@@ -0,0 +1,209 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package packet
+
+import (
+	"github.com/google/gopacket/layers"
+	"l2/lldp/config"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestChassisIdEncode(t *testing.T) {
+	srcmac, _ := net.ParseMAC(testPort.MacAddr)
+	portMac := []byte{0x04, 0x00, 0x11, 0x22, 0x33, 0x44, 0x01}
+	noSwitchMac := *testSysInfo
+	noSwitchMac.SwitchMac = ""
+	noHostname := *testSysInfo
+	noHostname.Hostname = ""
+
+	tests := []struct {
+		name      string
+		chassisId config.ChassisIdConfig
+		sysInfo   *config.SystemInfo
+		expected  []byte
+	}{
+		{
+			name:      "system mac",
+			chassisId: config.ChassisIdConfig{Subtype: config.CHASSIS_ID_SYSTEM_MAC},
+			sysInfo:   testSysInfo,
+			expected:  []byte{0x04, 0x00, 0x11, 0x22, 0x33, 0x44, 0x00},
+		},
+		{
+			name:      "port mac without system information",
+			chassisId: config.ChassisIdConfig{Subtype: config.CHASSIS_ID_SYSTEM_MAC},
+			expected:  portMac,
+		},
+		{
+			name:      "port mac without system mac",
+			chassisId: config.ChassisIdConfig{Subtype: config.CHASSIS_ID_SYSTEM_MAC},
+			sysInfo:   &noSwitchMac,
+			expected:  portMac,
+		},
+		{
+			name:      "hostname",
+			chassisId: config.ChassisIdConfig{Subtype: config.CHASSIS_ID_HOSTNAME},
+			sysInfo:   testSysInfo,
+			expected:  []byte{0x07, 'l', 'e', 'a', 'f', '1'},
+		},
+		{
+			name:      "port mac without hostname",
+			chassisId: config.ChassisIdConfig{Subtype: config.CHASSIS_ID_HOSTNAME},
+			sysInfo:   &noHostname,
+			expected:  portMac,
+		},
+		{
+			name:      "local",
+			chassisId: config.ChassisIdConfig{Subtype: config.CHASSIS_ID_LOCAL, Local: "rack1"},
+			sysInfo:   testSysInfo,
+			expected:  []byte{0x07, 'r', 'a', 'c', 'k', '1'},
+		},
+		{
+			name:      "port mac without local string",
+			chassisId: config.ChassisIdConfig{Subtype: config.CHASSIS_ID_LOCAL},
+			sysInfo:   testSysInfo,
+			expected:  portMac,
+		},
+	}
+	for _, tc := range tests {
+		tx := &TX{chassisId: tc.chassisId}
+		if tlv := tx.encodeChassisIdTLV(srcmac, tc.sysInfo); !reflect.DeepEqual(tlv, tc.expected) {
+			t.Errorf("%s expected\n%x\nactual\n%x", tc.name, tc.expected, tlv)
+		}
+	}
+}
+
+func TestPortIdEncode(t *testing.T) {
+	srcmac, _ := net.ParseMAC(testPort.MacAddr)
+	noDescription := testPort
+	noDescription.Description = ""
+
+	tests := []struct {
+		name     string
+		subtype  uint8
+		port     config.PortInfo
+		expected []byte
+	}{
+		{
+			name:     "interface name",
+			subtype:  config.PORT_ID_IF_NAME,
+			port:     testPort,
+			expected: []byte{0x05, 'f', 'p', 'P', 'o', 'r', 't', '1'},
+		},
+		{
+			name:    "interface alias",
+			subtype: config.PORT_ID_IF_ALIAS,
+			port:    testPort,
+			expected: []byte{0x01, 'u', 'p', 'l', 'i', 'n', 'k', ' ', 't', 'o', ' ',
+				'c', 'o', 'r', 'e'},
+		},
+		{
+			name:     "interface name without description",
+			subtype:  config.PORT_ID_IF_ALIAS,
+			port:     noDescription,
+			expected: []byte{0x05, 'f', 'p', 'P', 'o', 'r', 't', '1'},
+		},
+		{
+			name:     "mac address",
+			subtype:  config.PORT_ID_MAC_ADDR,
+			port:     testPort,
+			expected: []byte{0x03, 0x00, 0x11, 0x22, 0x33, 0x44, 0x01},
+		},
+		{
+			name:     "local",
+			subtype:  config.PORT_ID_LOCAL,
+			port:     testPort,
+			expected: []byte{0x07, '1'},
+		},
+	}
+	for _, tc := range tests {
+		tx := &TX{portIdSubtype: tc.subtype}
+		if tlv := tx.encodePortIdTLV(srcmac, tc.port); !reflect.DeepEqual(tlv, tc.expected) {
+			t.Errorf("%s expected\n%x\nactual\n%x", tc.name, tc.expected, tlv)
+		}
+	}
+}
+
+func TestIdDecode(t *testing.T) {
+	testSetup()
+	frameTests := []struct {
+		name      string
+		frame     []byte
+		chassisId string
+		portId    string
+	}{
+		{"switch reference frame", testSwitchFrame, "switch1", "port-001"},
+		{"LLDP-MED phone reference frame", testMedPhoneFrame, "192.168.1.100", "001122334455:P1"},
+		{"lldpad reference frame", testDcbxFrame, "00:02:c9:12:34:56", "00:02:c9:12:34:56"},
+		{"encoded frame", testFrame(TxInit(30, 4, LLDP_PROTO_DST_MAC), testPort, testSysInfo),
+			"00:11:22:33:44:00", "fpPort1"},
+	}
+	for _, tc := range frameTests {
+		nbr, err := testProcess(RxInit(LLDP_PROTO_DST_MAC), tc.frame)
+		if err != nil {
+			t.Error(tc.name, "failed with error", err)
+			continue
+		}
+		chassisId := DecodeChassisId(nbr.RxFrame.ChassisID)
+		portId := DecodePortId(nbr.RxFrame.PortID)
+		if chassisId != tc.chassisId || portId != tc.portId {
+			t.Error(tc.name, "expected chassis id", tc.chassisId, "port id", tc.portId, "actual", chassisId, portId)
+		}
+	}
+
+	ipv6 := append([]byte{byte(layers.IANAAddressFamilyIPV6)}, net.ParseIP("2001:db8::1")...)
+	tests := []struct {
+		name           string
+		chassisSubtype layers.LLDPChassisIDSubType
+		portSubtype    layers.LLDPPortIDSubType
+		id             []byte
+		expected       string
+	}{
+		{"mac address", layers.LLDPChassisIDSubTypeMACAddr, layers.LLDPPortIDSubtypeMACAddr,
+			[]byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}, "00:11:22:33:44:55"},
+		{"short mac address", layers.LLDPChassisIDSubTypeMACAddr, layers.LLDPPortIDSubtypeMACAddr,
+			[]byte{0x00, 0x11, 0x22}, "001122"},
+		{"ipv6 network address", layers.LLDPChassisIDSubTypeNetworkAddr, layers.LLDPPortIDSubtypeNetworkAddr,
+			ipv6, "2001:db8::1"},
+		{"802 network address", layers.LLDPChassisIDSubTypeNetworkAddr, layers.LLDPPortIDSubtypeNetworkAddr,
+			[]byte{0x06, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55}, "00:11:22:33:44:55"},
+		{"network address with invalid length", layers.LLDPChassisIDSubTypeNetworkAddr,
+			layers.LLDPPortIDSubtypeNetworkAddr, []byte{0x01, 0x0a, 0x00, 0x00}, "IPv4:0a0000"},
+		{"non printable string", layers.LLDPChassisIDSubTypeLocal, layers.LLDPPortIDSubtypeLocal,
+			[]byte{'e', 't', 'h', 0x00}, "65746800"},
+		{"interface alias", layers.LLDPChassisIDSubtypeIfaceAlias, layers.LLDPPortIDSubtypeIfaceAlias,
+			[]byte("uplink to core"), "uplink to core"},
+		{"reserved subtype", layers.LLDPChassisIDSubType(8), layers.LLDPPortIDSubType(8), []byte("id"), "6964"},
+	}
+	for _, tc := range tests {
+		chassisId := DecodeChassisId(layers.LLDPChassisID{Subtype: tc.chassisSubtype, ID: tc.id})
+		if chassisId != tc.expected {
+			t.Error(tc.name, "expected chassis id", tc.expected, "actual", chassisId)
+		}
+		if portId := DecodePortId(layers.LLDPPortID{Subtype: tc.portSubtype, ID: tc.id}); portId != tc.expected {
+			t.Error(tc.name, "expected port id", tc.expected, "actual", portId)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package packet
+
+import (
+	"encoding/binary"
+	"github.com/google/gopacket/layers"
+	"l2/lldp/config"
+	"l2/lldp/utils"
+)
+
+const (
+	// TIA-1057 fast start repeat count, number of frames send at 1 second interval
+	// once MED endpoint is detected
+	LLDP_MED_FAST_START_REPEAT_COUNT = 4
+	// TIA-1057 Power type/source for PSE device with primary power source
+	LLDP_MED_POWER_TYPE_PSE       = 0
+	LLDP_MED_POWER_SOURCE_PRIMARY = 1
+)
+
+/*  MED tlv's are advertised if MED is enabled or if MED is in auto mode and MED endpoint is
+ *  detected on the port
+ */
+func (t *TX) medActive() bool {
+	switch t.MedConfig.Mode {
+	case config.MED_ENABLED:
+		return true
+	case config.MED_AUTO:
+		return t.medEndpoint
+	}
+	return false
+}
+
+/*  Update MED configuration, next frame needs to be constructed again
+ */
+func (t *TX) SetMedConfig(medCfg config.MedConfig) {
+	t.MedConfig = medCfg
+	t.useCacheFrame = false
+}
+
+/*  Update MED endpoint detected on the port. When endpoint is detected for the first time we
+ *  start fast start transmission
+ */
+func (t *TX) SetMedEndpoint(detected bool) bool {
+	if t.medEndpoint == detected {
+		return false
+	}
+	t.medEndpoint = detected
+	t.useCacheFrame = false
+	if detected && t.MedConfig.Mode != config.MED_DISABLED {
+		t.startFastTx(LLDP_MED_FAST_START_REPEAT_COUNT)
+	}
+	return true
+}
+
+func (t *TX) MedEndpoint() bool {
+	return t.medEndpoint
+}
+
+/*  helper function to create all LLDP-MED tlv's advertised by network connectivity device
+ */
+func (t *TX) createMedPayload() []byte {
+	var payload []byte
+	payload = append(payload, EncodeMedCapabilitiesTLV(&t.MedConfig)...)
+	for _, policy := range t.MedConfig.Policies {
+		payload = append(payload, EncodeMedNetworkPolicyTLV(policy)...)
+	}
+	payload = append(payload, EncodeMedLocationTLV(t.MedConfig.Location)...)
+	if t.MedConfig.PowerValue > 0 {
+		payload = append(payload, EncodeMedPowerTLV(&t.MedConfig)...)
+	}
+	debug.Logger.Debug("MED tlv's", payload)
+	return payload
+}
+
+/*  LLDP-MED Capabilities TLV, subtype 1
+ *     Capabilities is 2 bytes
+ *     Device Type is 1 byte, we are always network connectivity device
+ */
+func EncodeMedCapabilitiesTLV(medCfg *config.MedConfig) []byte {
+	caps := layers.LLDPMediaCapsLLDP | layers.LLDPMediaCapsNetwork | layers.LLDPMediaCapsLocation
+	if medCfg.PowerValue > 0 {
+		caps |= layers.LLDPMediaCapsPowerPSE
+	}
+	info := make([]byte, 3)
+	binary.BigEndian.PutUint16(info[0:2], caps)
+	info[2] = byte(layers.LLDPMediaClassNetwork)
+	return EncodeOrgTLV(layers.IEEEOUIMedia, uint8(layers.LLDPMediaTypeCapabilities), info)
+}
+
+/*  LLDP-MED Network Policy TLV, subtype 2
+ *     Application Type is 1 byte
+ *     Unknown Policy Flag 1 bit, Tagged Flag 1 bit, Reserved 1 bit
+ *     VLAN ID 12 bits, L2 Priority 3 bits, DSCP Value 6 bits
+ */
+func EncodeMedNetworkPolicyTLV(policy config.MedNetworkPolicy) []byte {
+	var flags uint32
+	if policy.Tagged {
+		flags |= 1 << 22
+	}
+	flags |= (uint32(policy.VlanId) & 0xfff) << 9
+	flags |= (uint32(policy.Priority) & 0x7) << 6
+	flags |= uint32(policy.Dscp) & 0x3f
+	info := make([]byte, 4)
+	info[0] = policy.AppType
+	info[1] = byte(flags >> 16)
+	info[2] = byte(flags >> 8)
+	info[3] = byte(flags)
+	return EncodeOrgTLV(layers.IEEEOUIMedia, uint8(layers.LLDPMediaTypeNetwork), info)
+}
+
+/*  LLDP-MED Location Identification TLV, subtype 3
+ *     Location Data Format is 1 byte
+ *     Location ID is N bytes
+ *  ELIN takes precedence over civic address, if none is configured then nothing is advertised
+ */
+func EncodeMedLocationTLV(location config.MedLocation) []byte {
+	var info []byte
+	if location.Elin != "" {
+		info = append(info, byte(layers.LLDPLocationFormatECS))
+		info = append(info, []byte(location.Elin)...)
+	} else if location.CountryCode != "" {
+		// LCI length covers what, country code and all civic address elements
+		lci := []byte{byte(layers.LLDPLocationAddressWhatClient)}
+		countryCode := make([]byte, 2)
+		copy(countryCode, location.CountryCode)
+		lci = append(lci, countryCode...)
+		for _, ca := range location.CivicAddr {
+			lci = append(lci, ca.CAType, byte(len(ca.Value)))
+			lci = append(lci, []byte(ca.Value)...)
+		}
+		info = append(info, byte(layers.LLDPLocationFormatAddress), byte(len(lci)))
+		info = append(info, lci...)
+	} else {
+		return nil
+	}
+	return EncodeOrgTLV(layers.IEEEOUIMedia, uint8(layers.LLDPMediaTypeLocation), info)
+}
+
+/*  LLDP-MED Extended Power-via-MDI TLV, subtype 4
+ *     Power Type 2 bits, Power Source 2 bits, Power Priority 4 bits
+ *     Power Value is 2 bytes in 0.1 Watt
+ */
+func EncodeMedPowerTLV(medCfg *config.MedConfig) []byte {
+	info := make([]byte, 3)
+	info[0] = LLDP_MED_POWER_TYPE_PSE<<6 | LLDP_MED_POWER_SOURCE_PRIMARY<<4 | (medCfg.PowerPriority & 0xf)
+	binary.BigEndian.PutUint16(info[1:3], uint16(medCfg.PowerValue))
+	return EncodeOrgTLV(layers.IEEEOUIMedia, uint8(layers.LLDPMediaTypePower), info)
+}
+
+/*  Check whether peer is MED endpoint, i.e. it advertised MED Capabilities TLV with endpoint
+ *  device class
+ */
+func IsMedEndpoint(info *layers.LinkLayerDiscoveryInfo) bool {
+	if info == nil {
+		return false
+	}
+	for _, o := range info.OrgTLVs {
+		if o.OUI != layers.IEEEOUIMedia || layers.LLDPMediaSubtype(o.SubType) != layers.LLDPMediaTypeCapabilities {
+			continue
+		}
+		if len(o.Info) < 3 {
+			return false
+		}
+		class := layers.LLDPMediaClass(o.Info[2])
+		return class >= layers.LLDPMediaClassEndpointI && class <= layers.LLDPMediaClassEndpointIII
+	}
+	return false
+}
+
+/*  Decode all LLDP-MED Network Policy TLV's, gopacket only keeps the last one
+ */
+func DecodeMedNetworkPolicies(info *layers.LinkLayerDiscoveryInfo) []config.MedNetworkPolicy {
+	var policies []config.MedNetworkPolicy
+	for _, o := range info.OrgTLVs {
+		if o.OUI != layers.IEEEOUIMedia || layers.LLDPMediaSubtype(o.SubType) != layers.LLDPMediaTypeNetwork {
+			continue
+		}
+		if len(o.Info) < 4 {
+			continue
+		}
+		flags := uint32(o.Info[1])<<16 | uint32(o.Info[2])<<8 | uint32(o.Info[3])
+		if flags&(1<<23) != 0 {
+			// unknown policy
+			continue
+		}
+		policies = append(policies, config.MedNetworkPolicy{
+			AppType:  o.Info[0],
+			Tagged:   flags&(1<<22) != 0,
+			VlanId:   int32((flags >> 9) & 0xfff),
+			Priority: int32((flags >> 6) & 0x7),
+			Dscp:     int32(flags & 0x3f),
+		})
+	}
+	return policies
+}
+
+/*  MED endpoint is detected on the port if any of the neighbors is MED endpoint
+ */
+func (rxInfo *RX) MedEndpointDetected() bool {
+	for _, nbr := range rxInfo.Neighbors {
+		if IsMedEndpoint(nbr.RxLinkInfo) {
+			return true
+		}
+	}
+	return false
+}
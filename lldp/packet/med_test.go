@@ -0,0 +1,164 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package packet
+
+import (
+	"l2/lldp/config"
+	"reflect"
+	"testing"
+)
+
+// LLDP-MED reference frame in the layout sent by a Cisco 7962G IP phone: chassis id network address
+// 192.168.1.100, port id "001122334455:P1", MED capabilities as endpoint class III, voice policy on
+// tagged vlan 100 with priority 5 & dscp 46, voice signaling policy with unknown flag set, PD power
+// request of 6.5W and inventory tlv's
+var testMedPhoneFrame = []byte{
+	0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e, 0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x88, 0xcc, 0x02, 0x06,
+	0x05, 0x01, 0xc0, 0xa8, 0x01, 0x64, 0x04, 0x10, 0x07, 0x30, 0x30, 0x31, 0x31, 0x32, 0x32, 0x33,
+	0x33, 0x34, 0x34, 0x35, 0x35, 0x3a, 0x50, 0x31, 0x06, 0x02, 0x00, 0xb4, 0x0a, 0x0f, 0x53, 0x45,
+	0x50, 0x30, 0x30, 0x31, 0x31, 0x32, 0x32, 0x33, 0x33, 0x34, 0x34, 0x35, 0x35, 0x0c, 0x2f, 0x43,
+	0x69, 0x73, 0x63, 0x6f, 0x20, 0x49, 0x50, 0x20, 0x50, 0x68, 0x6f, 0x6e, 0x65, 0x20, 0x43, 0x50,
+	0x2d, 0x37, 0x39, 0x36, 0x32, 0x47, 0x2c, 0x56, 0x31, 0x32, 0x2c, 0x20, 0x53, 0x43, 0x43, 0x50,
+	0x34, 0x32, 0x2e, 0x39, 0x2d, 0x33, 0x2d, 0x31, 0x53, 0x52, 0x32, 0x2d, 0x31, 0x53, 0x08, 0x07,
+	0x53, 0x57, 0x20, 0x50, 0x4f, 0x52, 0x54, 0x0e, 0x04, 0x00, 0x24, 0x00, 0x24, 0x10, 0x0c, 0x05,
+	0x01, 0xc0, 0xa8, 0x01, 0x64, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0xfe, 0x09, 0x00, 0x12, 0x0f,
+	0x01, 0x03, 0x6c, 0x00, 0x00, 0x10, 0xfe, 0x07, 0x00, 0x12, 0xbb, 0x01, 0x00, 0x33, 0x03, 0xfe,
+	0x08, 0x00, 0x12, 0xbb, 0x02, 0x01, 0x40, 0xc9, 0x6e, 0xfe, 0x08, 0x00, 0x12, 0xbb, 0x02, 0x02,
+	0x80, 0x00, 0x00, 0xfe, 0x07, 0x00, 0x12, 0xbb, 0x04, 0x51, 0x00, 0x41, 0xfe, 0x05, 0x00, 0x12,
+	0xbb, 0x05, 0x36, 0xfe, 0x17, 0x00, 0x12, 0xbb, 0x06, 0x74, 0x6e, 0x70, 0x36, 0x32, 0x2e, 0x38,
+	0x2d, 0x33, 0x2d, 0x31, 0x2d, 0x32, 0x31, 0x61, 0x2e, 0x62, 0x69, 0x6e, 0xfe, 0x16, 0x00, 0x12,
+	0xbb, 0x07, 0x53, 0x43, 0x43, 0x50, 0x34, 0x32, 0x2e, 0x39, 0x2d, 0x33, 0x2d, 0x31, 0x53, 0x52,
+	0x32, 0x2d, 0x31, 0x53, 0xfe, 0x0f, 0x00, 0x12, 0xbb, 0x08, 0x46, 0x43, 0x48, 0x31, 0x32, 0x33,
+	0x34, 0x41, 0x42, 0x43, 0x44, 0xfe, 0x17, 0x00, 0x12, 0xbb, 0x09, 0x43, 0x69, 0x73, 0x63, 0x6f,
+	0x20, 0x53, 0x79, 0x73, 0x74, 0x65, 0x6d, 0x73, 0x2c, 0x20, 0x49, 0x6e, 0x63, 0x2e, 0xfe, 0x0c,
+	0x00, 0x12, 0xbb, 0x0a, 0x43, 0x50, 0x2d, 0x37, 0x39, 0x36, 0x32, 0x47, 0x00, 0x00,
+}
+
+func TestMedEncode(t *testing.T) {
+	voice := config.MedNetworkPolicy{AppType: 1, VlanId: 100, Tagged: true, Priority: 5, Dscp: 46}
+	tests := []struct {
+		name     string
+		tlv      []byte
+		expected []byte
+	}{
+		{
+			name:     "capabilities",
+			tlv:      EncodeMedCapabilitiesTLV(&config.MedConfig{}),
+			expected: []byte{0xfe, 0x07, 0x00, 0x12, 0xbb, 0x01, 0x00, 0x07, 0x04},
+		},
+		{
+			name:     "capabilities with power",
+			tlv:      EncodeMedCapabilitiesTLV(&config.MedConfig{PowerValue: 150}),
+			expected: []byte{0xfe, 0x07, 0x00, 0x12, 0xbb, 0x01, 0x00, 0x0f, 0x04},
+		},
+		{
+			name:     "voice network policy",
+			tlv:      EncodeMedNetworkPolicyTLV(voice),
+			expected: []byte{0xfe, 0x08, 0x00, 0x12, 0xbb, 0x02, 0x01, 0x40, 0xc9, 0x6e},
+		},
+		{
+			name:     "untagged network policy",
+			tlv:      EncodeMedNetworkPolicyTLV(config.MedNetworkPolicy{AppType: 2, Dscp: 24}),
+			expected: []byte{0xfe, 0x08, 0x00, 0x12, 0xbb, 0x02, 0x02, 0x00, 0x00, 0x18},
+		},
+		{
+			name: "elin location",
+			tlv:  EncodeMedLocationTLV(config.MedLocation{Elin: "5125551234", CountryCode: "US"}),
+			expected: []byte{0xfe, 0x0f, 0x00, 0x12, 0xbb, 0x03, 0x03,
+				'5', '1', '2', '5', '5', '5', '1', '2', '3', '4'},
+		},
+		{
+			name: "civic address location",
+			tlv: EncodeMedLocationTLV(config.MedLocation{CountryCode: "US",
+				CivicAddr: []config.MedCivicAddr{{CAType: 3, Value: "Austin"}}}),
+			expected: []byte{0xfe, 0x11, 0x00, 0x12, 0xbb, 0x03, 0x02, 0x0b, 0x02, 'U', 'S', 0x03, 0x06,
+				'A', 'u', 's', 't', 'i', 'n'},
+		},
+		{
+			name: "no location",
+			tlv:  EncodeMedLocationTLV(config.MedLocation{}),
+		},
+		{
+			name:     "extended power",
+			tlv:      EncodeMedPowerTLV(&config.MedConfig{PowerPriority: 2, PowerValue: 150}),
+			expected: []byte{0xfe, 0x07, 0x00, 0x12, 0xbb, 0x04, 0x12, 0x00, 0x96},
+		},
+	}
+	for _, tc := range tests {
+		if !reflect.DeepEqual(tc.tlv, tc.expected) {
+			t.Errorf("%s expected\n%x\nactual\n%x", tc.name, tc.expected, tc.tlv)
+		}
+	}
+}
+
+func TestMedDecode(t *testing.T) {
+	testSetup()
+	policies := []config.MedNetworkPolicy{
+		{AppType: 1, VlanId: 100, Tagged: true, Priority: 5, Dscp: 46},
+		{AppType: 2, VlanId: 100, Tagged: true, Priority: 3, Dscp: 24},
+	}
+	tx := TxInit(30, 4, LLDP_PROTO_DST_MAC)
+	tx.SetMedConfig(config.MedConfig{Mode: config.MED_ENABLED, Policies: policies, PowerValue: 150})
+
+	tests := []struct {
+		name     string
+		frame    []byte
+		endpoint bool
+		policies []config.MedNetworkPolicy
+	}{
+		{
+			name:     "LLDP-MED phone reference frame",
+			frame:    testMedPhoneFrame,
+			endpoint: true,
+			policies: policies[:1],
+		},
+		{
+			name:  "switch reference frame without MED",
+			frame: testSwitchFrame,
+		},
+		{
+			name:     "MED tlv's advertised by us",
+			frame:    testFrame(tx, testPort, testSysInfo),
+			policies: policies,
+		},
+		{
+			name:  "MED tlv's are not advertised in auto mode without MED endpoint",
+			frame: testFrame(TxInit(30, 4, LLDP_PROTO_DST_MAC), testPort, testSysInfo),
+		},
+	}
+	for _, tc := range tests {
+		rxInfo := RxInit(LLDP_PROTO_DST_MAC)
+		nbr, err := testProcess(rxInfo, tc.frame)
+		if err != nil {
+			t.Error(tc.name, "failed with error", err)
+			continue
+		}
+		if IsMedEndpoint(nbr.RxLinkInfo) != tc.endpoint {
+			t.Error(tc.name, "expected MED endpoint", tc.endpoint)
+		}
+		if p := DecodeMedNetworkPolicies(nbr.RxLinkInfo); !reflect.DeepEqual(p, tc.policies) {
+			t.Error(tc.name, "expected network policies", tc.policies, "actual", p)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package packet
+
+import (
+	"encoding/binary"
+	"github.com/google/gopacket/layers"
+	"l2/lldp/config"
+	"l2/lldp/utils"
+	"strings"
+)
+
+const (
+	// Ethernet header (14 bytes) + FCS (4 bytes), used to derive max frame size from port mtu
+	LLDP_ETH_FRAME_OVERHEAD = 18
+	// IEEE 802.1Q-2014 Table D-1, VLAN name is limited to 32 octets
+	LLDP_VLAN_NAME_MAX_LEN = 32
+	// Port with no untagged vlan advertise PVID 0
+	LLDP_NO_PVID = 0
+)
+
+/*  Org Specific TLV:
+ *  TLV Type = 127, 7 bits
+ *  TLV Length = 9 bits
+ *  Value: N bytes
+ *     OUI is 3 bytes
+ *     Subtype is 1 byte
+ *     Info is []byte
+ */
+func EncodeOrgTLV(oui layers.IEEEOUI, subtype uint8, info []byte) []byte {
+	tlv := &layers.LinkLayerDiscoveryValue{}
+	tlv.Type = layers.LLDPTLVOrgSpecific
+	tlv.Value = make([]byte, 4+len(info))
+	tlv.Value[0] = byte(oui >> 16)
+	tlv.Value[1] = byte(oui >> 8)
+	tlv.Value[2] = byte(oui)
+	tlv.Value[3] = subtype
+	copy(tlv.Value[4:], info)
+	tlv.Length = uint16(len(tlv.Value))
+	return EncodeTLV(tlv)
+}
+
+/*  helper function to create all IEEE 802.1 & IEEE 802.3 org specific tlv's for the port
+ *  VLAN Name tlv's are added at the end and only till the lldpdu fits in available bytes
+ */
+func (t *TX) createOrgPayload(port config.PortInfo, available int) []byte {
+	var payload []byte
+	var vlans []config.VlanInfo
+	// IEEE 802.1 TLV's
+	if t.tlvs&config.TLV_DOT1 != 0 {
+		payload = append(payload, EncodePortVlanIdTLV(port)...)
+		payload = append(payload, EncodePortProtocolVlanIdTLV()...)
+		payload = append(payload, EncodeLinkAggregationTLV(port)...)
+		vlans = port.Vlans
+	}
+	// IEEE 802.3 TLV's
+	if t.tlvs&config.TLV_DOT3 != 0 {
+		payload = append(payload, EncodeMacPhyConfigStatusTLV(port)...)
+		payload = append(payload, EncodeMaxFrameSizeTLV(port)...)
+	}
+	for _, vlan := range vlans {
+		vlanTlv := EncodeVlanNameTLV(vlan)
+		if len(payload)+len(vlanTlv) > available {
+			debug.Logger.Info("LLDPDU is full, not adding vlan name tlv for vlan", vlan.VlanId,
+				"on port", port.Name)
+			break
+		}
+		payload = append(payload, vlanTlv...)
+	}
+	if len(payload) > available {
+		debug.Logger.Err("No space left in LLDPDU for org specific tlv's on port", port.Name)
+		return nil
+	}
+	debug.Logger.Debug("Org specific tlv's for port", port.Name, payload)
+	return payload
+}
+
+/*  Get Port Vlan Id, which is the untagged vlan for the port
+ */
+func GetPortVlanId(port config.PortInfo) int32 {
+	for _, vlan := range port.Vlans {
+		if vlan.Untagged {
+			return vlan.VlanId
+		}
+	}
+	return LLDP_NO_PVID
+}
+
+/*  IEEE 802.1 Port VLAN ID TLV, subtype 1
+ *     PVID is 2 bytes
+ */
+func EncodePortVlanIdTLV(port config.PortInfo) []byte {
+	info := make([]byte, 2)
+	binary.BigEndian.PutUint16(info, uint16(GetPortVlanId(port)))
+	return EncodeOrgTLV(layers.IEEEOUI8021, layers.LLDP8021SubtypePortVLANID, info)
+}
+
+/*  IEEE 802.1 Port And Protocol VLAN ID TLV, subtype 2
+ *     Flags is 1 byte
+ *     PPVID is 2 bytes
+ *  Protocol based vlan's are not supported and hence flags are not set and PPVID is 0
+ */
+func EncodePortProtocolVlanIdTLV() []byte {
+	info := make([]byte, 3)
+	return EncodeOrgTLV(layers.IEEEOUI8021, layers.LLDP8021SubtypeProtocolVLANID, info)
+}
+
+/*  IEEE 802.1 VLAN Name TLV, subtype 3
+ *     VLAN ID is 2 bytes
+ *     VLAN Name length is 1 byte
+ *     VLAN Name is N bytes
+ */
+func EncodeVlanNameTLV(vlan config.VlanInfo) []byte {
+	name := vlan.Name
+	if len(name) > LLDP_VLAN_NAME_MAX_LEN {
+		name = name[:LLDP_VLAN_NAME_MAX_LEN]
+	}
+	info := make([]byte, 3+len(name))
+	binary.BigEndian.PutUint16(info[0:2], uint16(vlan.VlanId))
+	info[2] = byte(len(name))
+	copy(info[3:], name)
+	return EncodeOrgTLV(layers.IEEEOUI8021, layers.LLDP8021SubtypeVLANName, info)
+}
+
+/*  IEEE 802.1 Link Aggregation TLV, subtype 7
+ *     Aggregation Status is 1 byte
+ *     Aggregated port id is 4 bytes, which is lag IfIndex in our case
+ */
+func EncodeLinkAggregationTLV(port config.PortInfo) []byte {
+	info := make([]byte, 5)
+	info[0] = layers.LLDPAggregationCapability
+	if port.AggIfIndex != 0 {
+		info[0] |= layers.LLDPAggregationStatus
+		binary.BigEndian.PutUint32(info[1:5], uint32(port.AggIfIndex))
+	}
+	return EncodeOrgTLV(layers.IEEEOUI8021, layers.LLDP8021SubtypeLinkAggregation, info)
+}
+
+/*  IEEE 802.3 MAC/PHY Configuration/Status TLV, subtype 1
+ *     Auto-negotiation support/status is 1 byte
+ *     PMD auto-negotiation advertised capability is 2 bytes
+ *     Operational MAU type is 2 bytes
+ */
+func EncodeMacPhyConfigStatusTLV(port config.PortInfo) []byte {
+	info := make([]byte, 5)
+	info[0] = layers.LLDPMACPHYCapability
+	if port.Autoneg {
+		info[0] |= layers.LLDPMACPHYStatus
+	}
+	binary.BigEndian.PutUint16(info[1:3], GetAutonegAdvertisedCap(port))
+	binary.BigEndian.PutUint16(info[3:5], GetMAUType(port))
+	return EncodeOrgTLV(layers.IEEEOUI8023, layers.LLDP8023SubtypeMACPHY, info)
+}
+
+/*  IEEE 802.3 Maximum Frame Size TLV, subtype 4
+ *     Maximum Frame Size is 2 bytes
+ */
+func EncodeMaxFrameSizeTLV(port config.PortInfo) []byte {
+	info := make([]byte, 2)
+	binary.BigEndian.PutUint16(info, uint16(GetMaxFrameSize(port)))
+	return EncodeOrgTLV(layers.IEEEOUI8023, layers.LLDP8023SubtypeMTU, info)
+}
+
+/*  Max Frame size is port mtu + ethernet overhead, if mtu is not known then we advertise 0
+ */
+func GetMaxFrameSize(port config.PortInfo) int32 {
+	if port.Mtu == 0 {
+		return 0
+	}
+	return port.Mtu + LLDP_ETH_FRAME_OVERHEAD
+}
+
+func isFullDuplex(port config.PortInfo) bool {
+	return !strings.Contains(strings.ToLower(port.Duplex), "half")
+}
+
+/*  Get operational MAU type (RFC 4836 dot3MauType) based on port speed (Mbps) and duplex
+ */
+func GetMAUType(port config.PortInfo) uint16 {
+	fullDuplex := isFullDuplex(port)
+	switch port.Speed {
+	case 10:
+		if fullDuplex {
+			return layers.LLDPMAUType10BaseT_FD
+		}
+		return layers.LLDPMAUType10BaseT_HD
+	case 100:
+		if fullDuplex {
+			return layers.LLDPMAUType100BaseTX_FD
+		}
+		return layers.LLDPMAUType100BaseTX_HD
+	case 1000:
+		if fullDuplex {
+			return layers.LLDPMAUType1000BaseT_FD
+		}
+		return layers.LLDPMAUType1000BaseT_HD
+	case 10000:
+		return layers.LLDPMAUType10GBaseR
+	}
+	return layers.LLDPMAUTypeUnknown
+}
+
+/*  Get PMD auto-negotiation advertised capability (RFC 3636 ifMauAutoNegCapAdvertisedBits)
+ *  based on port speed (Mbps) and duplex. Speeds which are not part of RFC 3636 are advertised
+ *  as other
+ */
+func GetAutonegAdvertisedCap(port config.PortInfo) uint16 {
+	if !port.Autoneg {
+		return 0
+	}
+	fullDuplex := isFullDuplex(port)
+	switch port.Speed {
+	case 10:
+		if fullDuplex {
+			return layers.LLDPMAUPMD10BaseT_FD
+		}
+		return layers.LLDPMAUPMD10BaseT
+	case 100:
+		if fullDuplex {
+			return layers.LLDPMAUPMD100BaseTX_FD
+		}
+		return layers.LLDPMAUPMD100BaseTX
+	case 1000:
+		if fullDuplex {
+			return layers.LLDPMAUPMD1000BaseT_FD
+		}
+		return layers.LLDPMAUPMD1000BaseT
+	}
+	return layers.LLDPMAUPMDOther
+}
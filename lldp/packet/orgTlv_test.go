@@ -0,0 +1,199 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package packet
+
+import (
+	"github.com/google/gopacket/layers"
+	"l2/lldp/config"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOrgTLVEncode(t *testing.T) {
+	longName := strings.Repeat("v", 40)
+	tests := []struct {
+		name     string
+		tlv      []byte
+		expected []byte
+	}{
+		{
+			name:     "port vlan id",
+			tlv:      EncodePortVlanIdTLV(testPort),
+			expected: []byte{0xfe, 0x06, 0x00, 0x80, 0xc2, 0x01, 0x00, 0x0a},
+		},
+		{
+			name:     "port vlan id without untagged vlan",
+			tlv:      EncodePortVlanIdTLV(config.PortInfo{Vlans: []config.VlanInfo{{VlanId: 100}}}),
+			expected: []byte{0xfe, 0x06, 0x00, 0x80, 0xc2, 0x01, 0x00, 0x00},
+		},
+		{
+			name:     "port and protocol vlan id",
+			tlv:      EncodePortProtocolVlanIdTLV(),
+			expected: []byte{0xfe, 0x07, 0x00, 0x80, 0xc2, 0x02, 0x00, 0x00, 0x00},
+		},
+		{
+			name: "vlan name",
+			tlv:  EncodeVlanNameTLV(config.VlanInfo{VlanId: 100, Name: "voice"}),
+			expected: []byte{0xfe, 0x0c, 0x00, 0x80, 0xc2, 0x03, 0x00, 0x64, 0x05,
+				'v', 'o', 'i', 'c', 'e'},
+		},
+		{
+			name: "vlan name is truncated to 32 octets",
+			tlv:  EncodeVlanNameTLV(config.VlanInfo{VlanId: 4094, Name: longName}),
+			expected: append([]byte{0xfe, 0x27, 0x00, 0x80, 0xc2, 0x03, 0x0f, 0xfe, 0x20},
+				longName[:LLDP_VLAN_NAME_MAX_LEN]...),
+		},
+		{
+			name:     "link aggregation for port not in lag",
+			tlv:      EncodeLinkAggregationTLV(testPort),
+			expected: []byte{0xfe, 0x09, 0x00, 0x80, 0xc2, 0x07, 0x01, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			name:     "link aggregation for lag member",
+			tlv:      EncodeLinkAggregationTLV(config.PortInfo{AggIfIndex: 0x2000001}),
+			expected: []byte{0xfe, 0x09, 0x00, 0x80, 0xc2, 0x07, 0x03, 0x02, 0x00, 0x00, 0x01},
+		},
+		{
+			name:     "mac/phy configuration status",
+			tlv:      EncodeMacPhyConfigStatusTLV(testPort),
+			expected: []byte{0xfe, 0x09, 0x00, 0x12, 0x0f, 0x01, 0x03, 0x00, 0x01, 0x00, 0x1e},
+		},
+		{
+			name:     "mac/phy configuration status without autoneg",
+			tlv:      EncodeMacPhyConfigStatusTLV(config.PortInfo{Speed: 10000, Duplex: "Full Duplex"}),
+			expected: []byte{0xfe, 0x09, 0x00, 0x12, 0x0f, 0x01, 0x01, 0x00, 0x00, 0x00, 0x21},
+		},
+		{
+			name:     "max frame size",
+			tlv:      EncodeMaxFrameSizeTLV(testPort),
+			expected: []byte{0xfe, 0x06, 0x00, 0x12, 0x0f, 0x04, 0x05, 0xee},
+		},
+		{
+			name:     "max frame size with unknown mtu",
+			tlv:      EncodeMaxFrameSizeTLV(config.PortInfo{}),
+			expected: []byte{0xfe, 0x06, 0x00, 0x12, 0x0f, 0x04, 0x00, 0x00},
+		},
+	}
+	for _, tc := range tests {
+		if !reflect.DeepEqual(tc.tlv, tc.expected) {
+			t.Errorf("%s expected\n%x\nactual\n%x", tc.name, tc.expected, tc.tlv)
+		}
+	}
+}
+
+func TestMAUType(t *testing.T) {
+	tests := []struct {
+		speed   int32
+		duplex  string
+		autoneg bool
+		mau     uint16
+		cap     uint16
+	}{
+		{10, "Half Duplex", true, layers.LLDPMAUType10BaseT_HD, layers.LLDPMAUPMD10BaseT},
+		{10, "Full Duplex", true, layers.LLDPMAUType10BaseT_FD, layers.LLDPMAUPMD10BaseT_FD},
+		{100, "half", true, layers.LLDPMAUType100BaseTX_HD, layers.LLDPMAUPMD100BaseTX},
+		{100, "Full Duplex", true, layers.LLDPMAUType100BaseTX_FD, layers.LLDPMAUPMD100BaseTX_FD},
+		{1000, "Half Duplex", true, layers.LLDPMAUType1000BaseT_HD, layers.LLDPMAUPMD1000BaseT},
+		{1000, "", true, layers.LLDPMAUType1000BaseT_FD, layers.LLDPMAUPMD1000BaseT_FD},
+		{1000, "Full Duplex", false, layers.LLDPMAUType1000BaseT_FD, 0},
+		{10000, "Full Duplex", true, layers.LLDPMAUType10GBaseR, layers.LLDPMAUPMDOther},
+		{40000, "Full Duplex", true, layers.LLDPMAUTypeUnknown, layers.LLDPMAUPMDOther},
+	}
+	for _, tc := range tests {
+		port := config.PortInfo{Speed: tc.speed, Duplex: tc.duplex, Autoneg: tc.autoneg}
+		if mau := GetMAUType(port); mau != tc.mau {
+			t.Error("speed", tc.speed, tc.duplex, "expected mau type", tc.mau, "actual", mau)
+		}
+		if cap := GetAutonegAdvertisedCap(port); cap != tc.cap {
+			t.Error("speed", tc.speed, tc.duplex, "autoneg", tc.autoneg, "expected advertised capability",
+				tc.cap, "actual", cap)
+		}
+	}
+}
+
+func TestOrgTLVDecode(t *testing.T) {
+	testSetup()
+	lagPort := testPort
+	lagPort.AggIfIndex = 0x2000001
+	lagPort.Vlans = append(lagPort.Vlans, config.VlanInfo{VlanId: 200, Name: strings.Repeat("v", 40)})
+
+	tests := []struct {
+		name  string
+		frame []byte
+		dot1  *layers.LLDPInfo8021
+		dot3  layers.LLDPInfo8023
+	}{
+		{
+			name:  "switch reference frame",
+			frame: testSwitchFrame,
+			dot3: layers.LLDPInfo8023{
+				MACPHYConfigStatus: layers.LLDPMACPHYConfigStatus{
+					AutoNegSupported:  true,
+					AutoNegEnabled:    true,
+					AutoNegCapability: 0x6c00,
+					MAUType:           layers.LLDPMAUType100BaseTX_FD,
+				},
+			},
+		},
+		{
+			name:  "lag member port",
+			frame: testFrame(TxInit(30, 4, LLDP_PROTO_DST_MAC), lagPort, testSysInfo),
+			dot1: &layers.LLDPInfo8021{
+				PVID:   10,
+				PPVIDs: []layers.PortProtocolVLANID{{}},
+				VLANNames: []layers.VLANName{{ID: 10, Name: "data"}, {ID: 100, Name: "voice"},
+					{ID: 200, Name: strings.Repeat("v", LLDP_VLAN_NAME_MAX_LEN)}},
+				LinkAggregation: layers.LLDPLinkAggregation{Supported: true, Enabled: true, PortID: 0x2000001},
+			},
+			dot3: layers.LLDPInfo8023{
+				MACPHYConfigStatus: layers.LLDPMACPHYConfigStatus{
+					AutoNegSupported:  true,
+					AutoNegEnabled:    true,
+					AutoNegCapability: layers.LLDPMAUPMD1000BaseT_FD,
+					MAUType:           layers.LLDPMAUType1000BaseT_FD,
+				},
+				MTU: 1518,
+			},
+		},
+	}
+	for _, tc := range tests {
+		rxInfo := RxInit(LLDP_PROTO_DST_MAC)
+		nbr, err := testProcess(rxInfo, tc.frame)
+		if err != nil {
+			t.Error(tc.name, "failed with error", err)
+			continue
+		}
+		if tc.dot1 != nil {
+			dot1, err := nbr.RxLinkInfo.Decode8021()
+			if err != nil || !reflect.DeepEqual(dot1, *tc.dot1) {
+				t.Error(tc.name, "expected 802.1 info", *tc.dot1, "actual", dot1, err)
+			}
+		}
+		dot3, err := nbr.RxLinkInfo.Decode8023()
+		if err != nil || !reflect.DeepEqual(dot3, tc.dot3) {
+			t.Error(tc.name, "expected 802.3 info", tc.dot3, "actual", dot3, err)
+		}
+	}
+}
@@ -25,6 +25,7 @@ package packet
 
 import (
 	"github.com/google/gopacket/layers"
+	"l2/lldp/config"
 	"net"
 	"time"
 )
@@ -33,14 +34,54 @@ const (
 	LLDP_MAX_TTL             = 65535
 	LLDP_PROTO_DST_MAC       = "01:80:c2:00:00:0e"
 	LLDP_TOTAL_TLV_SUPPORTED = 8
+	LLDP_MAX_PDU_SIZE        = 1500 // lldpdu needs to fit in ethernet payload
+	LLDP_MAX_OID_LEN         = 128
+	// default limit for number of neighbors learned per port
+	LLDP_DEFAULT_MAX_NEIGHBORS = 16
+	// chassis id, port id & ttl tlv's with minimum length
+	LLDP_MIN_PDU_SIZE = 12
+)
+
+// nearest non-TPMR bridge & nearest customer bridge agent destination mac, LLDP_PROTO_DST_MAC is
+// used by nearest bridge agent
+const (
+	LLDP_NEAREST_NON_TPMR_DST_MAC = "01:80:c2:00:00:03"
+	LLDP_NEAREST_CUSTOMER_DST_MAC = "01:80:c2:00:00:00"
 )
 
 type RX struct {
-	RxRunning bool
 	// ethernet frame Info (used for rx/tx)
-	SrcMAC net.HardwareAddr // NOTE: Please be informed this is Peer Mac Addr
 	DstMAC net.HardwareAddr
 
+	// remote systems information, one entry per neighbor keyed by chassis id & port id
+	Neighbors    map[string]*Neighbor
+	MaxNeighbors int
+	nextRemIndex int32
+	// set when frame from new neighbor is discarded as remote table for the port is full
+	tooManyNeighbors       bool
+	tooManyNeighborsExpiry time.Time
+	// IEEE 802.1AB rx statistics
+	Stats RxStats
+	// CDP agent, CDP frames are decoded into lldp neighbor information
+	cdp bool
+}
+
+type RxStats struct {
+	FramesDiscarded    int32
+	FramesInErrors     int32
+	TLVsDiscarded      int32
+	TLVsUnrecognized   int32
+	Ageouts            int32
+	LldpduLengthErrors int32
+}
+
+/*  Remote systems MIB entry for a neighbor learned on the port
+ */
+type Neighbor struct {
+	// remote index assigned when neighbor is learned
+	RemIndex int32
+	SrcMAC   net.HardwareAddr // NOTE: Please be informed this is Peer Mac Addr
+
 	// lldp rx information
 	RxFrame         *layers.LinkLayerDiscovery
 	RxLinkInfo      *layers.LinkLayerDiscoveryInfo
@@ -48,6 +89,10 @@ type RX struct {
 
 	// cache last packet and see if we need to update current information or not
 	LastPkt []byte
+	// last received packet time
+	RcvdTime time.Time
+	// platform of CDP neighbor, lldp has no such tlv
+	Platform string
 }
 
 type TX struct {
@@ -58,5 +103,29 @@ type TX struct {
 	MessageTxHoldMultiplier int
 	useCacheFrame           bool
 	cacheFrame              []byte
-	TxTimer                 *time.Timer
+	// IEEE 802.1AB-2016 transmit & timer state machine information
+	txEnabled    bool
+	txNow        bool
+	localChange  bool
+	newNeighbor  bool
+	txTTR        int // time to next regular transmission in seconds
+	txFast       int
+	txFastInit   int
+	msgFastTx    int
+	txCredit     int
+	txCreditMax  int
+	reinitDelay  int
+	shutdownTime time.Time
+	// LLDP-MED information
+	MedConfig   config.MedConfig
+	medEndpoint bool
+	// IEEE 802.1Qaz DCBX information
+	dcbxConfig config.DcbxConfig
+	// chassis id & port id selection
+	chassisId     config.ChassisIdConfig
+	portIdSubtype uint8
+	// optional tlv's bitmap
+	tlvs uint8
+	// CDP agent, CDP frames are send instead of lldp frames
+	cdp bool
 }
@@ -0,0 +1,117 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package packet
+
+import (
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"l2/lldp/config"
+	"l2/lldp/utils"
+	"sync"
+	"utils/logging"
+)
+
+var loggerOnce sync.Once
+
+func testSetup() {
+	loggerOnce.Do(func() {
+		logger, _ := logging.NewLogger("lldpd", "TEST", false)
+		debug.SetLogger(logger)
+	})
+}
+
+/*  Decode frame the same way as frames received by the server
+ */
+func testPacket(frame []byte) gopacket.Packet {
+	return gopacket.NewPacket(frame, layers.LayerTypeEthernet, gopacket.Default)
+}
+
+/*  Process frame on the agent, received information is stored in the remote table entry of the
+ *  neighbor
+ */
+func testProcess(rxInfo *RX, frame []byte) (*Neighbor, error) {
+	_, nbr, err := rxInfo.Process(rxInfo, testPacket(frame))
+	return nbr, err
+}
+
+/*  Encode frame with the tx information of the agent, the same way as it is send out on the port
+ */
+func testFrame(t *TX, port config.PortInfo, sysInfo *config.SystemInfo) []byte {
+	return t.Frame(port, sysInfo)
+}
+
+// Captured LLDP frame of a Siemens SCALANCE X212-2 switch (the same capture is used by gopacket's lldp
+// tests): chassis id local "switch1", port id local "port-001", PROFINET & 802.3 MAC/PHY tlv's
+var testSwitchFrame = []byte{
+	0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e, 0x00, 0x1b, 0x1b, 0x02, 0xe6, 0x1f, 0x88, 0xcc, 0x02, 0x08,
+	0x07, 0x73, 0x77, 0x69, 0x74, 0x63, 0x68, 0x31, 0x04, 0x09, 0x07, 0x70, 0x6f, 0x72, 0x74, 0x2d,
+	0x30, 0x30, 0x31, 0x06, 0x02, 0x00, 0x14, 0x08, 0x2d, 0x53, 0x69, 0x65, 0x6d, 0x65, 0x6e, 0x73,
+	0x2c, 0x20, 0x53, 0x49, 0x4d, 0x41, 0x54, 0x49, 0x43, 0x20, 0x4e, 0x45, 0x54, 0x2c, 0x20, 0x45,
+	0x74, 0x68, 0x65, 0x72, 0x6e, 0x65, 0x74, 0x20, 0x53, 0x77, 0x69, 0x74, 0x63, 0x68, 0x20, 0x50,
+	0x6f, 0x72, 0x74, 0x20, 0x30, 0x31, 0x0a, 0x07, 0x53, 0x77, 0x69, 0x74, 0x63, 0x68, 0x31, 0x0c,
+	0x4c, 0x53, 0x69, 0x65, 0x6d, 0x65, 0x6e, 0x73, 0x2c, 0x20, 0x53, 0x49, 0x4d, 0x41, 0x54, 0x49,
+	0x43, 0x20, 0x4e, 0x45, 0x54, 0x2c, 0x20, 0x53, 0x43, 0x41, 0x4c, 0x41, 0x4e, 0x43, 0x45, 0x20,
+	0x58, 0x32, 0x31, 0x32, 0x2d, 0x32, 0x2c, 0x20, 0x36, 0x47, 0x4b, 0x35, 0x20, 0x32, 0x31, 0x32,
+	0x2d, 0x32, 0x42, 0x42, 0x30, 0x30, 0x2d, 0x32, 0x41, 0x41, 0x33, 0x2c, 0x20, 0x48, 0x57, 0x3a,
+	0x20, 0x37, 0x2c, 0x20, 0x46, 0x57, 0x3a, 0x20, 0x56, 0x34, 0x2e, 0x30, 0x32, 0x0e, 0x04, 0x00,
+	0x80, 0x00, 0x80, 0x10, 0x14, 0x05, 0x01, 0x8d, 0x51, 0x00, 0xbe, 0x02, 0x00, 0x00, 0x00, 0x01,
+	0x08, 0x2b, 0x06, 0x01, 0x04, 0x01, 0x81, 0xc0, 0x6e, 0xfe, 0x08, 0x00, 0x0e, 0xcf, 0x02, 0x00,
+	0x00, 0x00, 0x00, 0xfe, 0x0a, 0x00, 0x0e, 0xcf, 0x05, 0x00, 0x1b, 0x1b, 0x02, 0xe6, 0x1e, 0xfe,
+	0x09, 0x00, 0x12, 0x0f, 0x01, 0x03, 0x6c, 0x00, 0x00, 0x10, 0x00, 0x00,
+}
+
+var testPort = config.PortInfo{
+	IfIndex:     1,
+	Name:        "fpPort1",
+	OperState:   "UP",
+	MacAddr:     "00:11:22:33:44:01",
+	Description: "uplink to core",
+	Mtu:         1500,
+	Speed:       1000,
+	Duplex:      "Full Duplex",
+	Autoneg:     true,
+	Vlans:       []config.VlanInfo{{VlanId: 10, Name: "data", Untagged: true}, {VlanId: 100, Name: "voice"}},
+}
+
+var testSysInfo = &config.SystemInfo{
+	Vrf:         "default",
+	MgmtIp:      "10.0.0.1",
+	Hostname:    "leaf1",
+	SwitchMac:   "00:11:22:33:44:00",
+	SwVersion:   "2.0.1",
+	Description: "test switch",
+}
+
+/*  Build LLDP frame from the given tlv's with mac chassis id & port id of 00:02:c9:12:34:56 and ttl 120
+ */
+func testLldpFrame(tlvs ...[]byte) []byte {
+	frame := []byte{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e, 0x00, 0x02, 0xc9, 0x12, 0x34, 0x56, 0x88, 0xcc,
+		0x02, 0x07, 0x04, 0x00, 0x02, 0xc9, 0x12, 0x34, 0x56,
+		0x04, 0x07, 0x03, 0x00, 0x02, 0xc9, 0x12, 0x34, 0x56,
+		0x06, 0x02, 0x00, 0x78}
+	for _, tlv := range tlvs {
+		frame = append(frame, tlv...)
+	}
+	return append(frame, 0x00, 0x00)
+}
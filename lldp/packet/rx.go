@@ -25,6 +25,8 @@ package packet
 
 import (
 	"bytes"
+	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/google/gopacket"
@@ -33,13 +35,21 @@ import (
 	"l2/lldp/config"
 	"l2/lldp/utils"
 	"net"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
-func RxInit() *RX {
+var ErrTooManyNeighbors = errors.New("Remote table is full, discarding frame from new neighbor")
+
+func RxInit(dstMAC string) *RX {
 	var err error
-	rxInfo := &RX{}
-	rxInfo.DstMAC, err = net.ParseMAC(LLDP_PROTO_DST_MAC)
+	rxInfo := &RX{
+		Neighbors:    make(map[string]*Neighbor),
+		MaxNeighbors: LLDP_DEFAULT_MAX_NEIGHBORS,
+	}
+	rxInfo.DstMAC, err = net.ParseMAC(dstMAC)
 	if err != nil {
 		debug.Logger.Err(fmt.Sprintln("parsing lldp protocol Mac failed",
 			err))
@@ -48,6 +58,95 @@ func RxInit() *RX {
 	return rxInfo
 }
 
+/*  Get destination mac address of received frame, which is used to find the agent for the frame
+ */
+func GetDstMAC(pkt gopacket.Packet) string {
+	ethernetLayer := pkt.Layer(layers.LayerTypeEthernet)
+	if ethernetLayer == nil {
+		return ""
+	}
+	return ethernetLayer.(*layers.Ethernet).DstMAC.String()
+}
+
+/*  Walk through all the tlv headers and verify that tlv lengths do not exceed lldpdu and that lldpdu
+ *  is terminated by end tlv. Ethernet padding after end tlv is ignored
+ */
+func VerifyTLVLengths(lldpdu []byte) error {
+	if len(lldpdu) < LLDP_MIN_PDU_SIZE || len(lldpdu) > LLDP_MAX_PDU_SIZE {
+		return errors.New("Invalid LLDPDU length")
+	}
+	for offset := 0; offset+2 <= len(lldpdu); {
+		tlvType := layers.LLDPTLVType(lldpdu[offset] >> 1)
+		tlvLength := int(binary.BigEndian.Uint16(lldpdu[offset:offset+2]) & 0x1ff)
+		offset += 2
+		if offset+tlvLength > len(lldpdu) {
+			return errors.New("TLV length exceeds LLDPDU length")
+		}
+		if tlvType == layers.LLDPTLVEnd {
+			return nil
+		}
+		offset += tlvLength
+	}
+	return errors.New("LLDPDU is not terminated by end tlv")
+}
+
+/*  Count optional tlv's that are discarded (duplicate tlv's which can appear only once in lldpdu) and
+ *  tlv's that are not recognized (reserved tlv types & org specific tlv's with unknown OUI or unknown
+ *  subtype of known OUI)
+ */
+func CheckOptionalTLVs(lldpInfo *layers.LinkLayerDiscovery) (int32, int32) {
+	var discarded, unrecognized int32
+	seen := make(map[layers.LLDPTLVType]bool)
+	for _, tlv := range lldpInfo.Values {
+		switch tlv.Type {
+		case layers.LLDPTLVPortDescription, layers.LLDPTLVSysName, layers.LLDPTLVSysDescription,
+			layers.LLDPTLVSysCapabilities:
+			if seen[tlv.Type] {
+				discarded++
+			}
+			seen[tlv.Type] = true
+		case layers.LLDPTLVMgmtAddress:
+			// multiple management address tlv's are allowed
+		case layers.LLDPTLVOrgSpecific:
+			if len(tlv.Value) < 4 {
+				discarded++
+				continue
+			}
+			oui := layers.IEEEOUI(binary.BigEndian.Uint32(append([]byte{0}, tlv.Value[0:3]...)))
+			if !knownOrgTLV(oui, tlv.Value[3]) {
+				unrecognized++
+			}
+		default:
+			unrecognized++
+		}
+	}
+	return discarded, unrecognized
+}
+
+/*  Org specific tlv's which are recognized by the agent
+ */
+func knownOrgTLV(oui layers.IEEEOUI, subtype uint8) bool {
+	switch oui {
+	case layers.IEEEOUI8021:
+		if subtype >= LLDP_8021_SUBTYPE_ETS_CONFIG && subtype <= LLDP_8021_SUBTYPE_APP_PRIO {
+			return true
+		}
+		return subtype >= layers.LLDP8021SubtypePortVLANID && subtype <= layers.LLDP8021SubtypeLinkAggregation
+	case layers.IEEEOUI8023:
+		return subtype >= layers.LLDP8023SubtypeMACPHY && subtype <= layers.LLDP8023SubtypeMTU
+	case layers.IEEEOUIMedia:
+		return layers.LLDPMediaSubtype(subtype) >= layers.LLDPMediaTypeCapabilities &&
+			layers.LLDPMediaSubtype(subtype) <= layers.LLDPMediaTypeAssetID
+	}
+	return false
+}
+
+/*  Clear IEEE 802.1AB rx statistics for the port
+ */
+func (rxInfo *RX) ClearStats() {
+	rxInfo.Stats = RxStats{}
+}
+
 /*  Upon receiving incoming packet check whether all the madatory layer info is
  *  correct or not.. If not then treat the packet as corrupted and move on
  */
@@ -67,82 +166,344 @@ func (p *RX) VerifyFrame(lldpInfo *layers.LinkLayerDiscovery) error {
 	return nil
 }
 
-func (p *RX) Process(rxInfo *RX, pkt gopacket.Packet) (int, error) {
+/*  Remote table key for the neighbor, which is chassis id & port id (including subtypes)
+ */
+func NeighborKey(lldpInfo *layers.LinkLayerDiscovery) string {
+	return fmt.Sprintf("%d:%s:%d:%s", lldpInfo.ChassisID.Subtype, hex.EncodeToString(lldpInfo.ChassisID.ID),
+		lldpInfo.PortID.Subtype, hex.EncodeToString(lldpInfo.PortID.ID))
+}
+
+/*  Process received frame and update remote table entry for the neighbor, CDP agent decodes CDP frame
+ *  into the same neighbor information. Shutdown frame (TTL 0) deletes the neighbor right away. If remote
+ *  table is full then frame from new neighbor is discarded and tooManyNeighbors is set till the TTL of
+ *  discarded frame
+ */
+func (p *RX) Process(rxInfo *RX, pkt gopacket.Packet) (int, *Neighbor, error) {
 	event := config.NoOp
 	ethernetLayer := pkt.Layer(layers.LayerTypeEthernet)
 	if ethernetLayer == nil {
-		return event, errors.New("Invalid eth layer")
+		rxInfo.Stats.FramesDiscarded++
+		return event, nil, errors.New("Invalid eth layer")
 	}
 	eth := ethernetLayer.(*layers.Ethernet)
-	// copy src mac and dst mac
-	rxInfo.SrcMAC = eth.SrcMAC
 	if rxInfo.DstMAC.String() != eth.DstMAC.String() {
-		return event, errors.New("Invalid DST MAC in rx frame")
+		rxInfo.Stats.FramesDiscarded++
+		return event, nil, errors.New("Invalid DST MAC in rx frame")
 	}
-	// Get lldp manadatory layer and optional info
-	lldpLayer := pkt.Layer(layers.LayerTypeLinkLayerDiscovery)
-	lldpLayerInfo := pkt.Layer(layers.LayerTypeLinkLayerDiscoveryInfo)
-	// Verify that the information is not nil
-	if lldpLayer == nil || lldpLayerInfo == nil {
-		return event, errors.New("Invalid Frame")
+	var rcvd *Neighbor
+	var err error
+	if rxInfo.cdp {
+		rcvd, err = rxInfo.decodeCdp(pkt)
+	} else {
+		rcvd, err = rxInfo.decodeLldp(eth, pkt)
 	}
-
-	// Verify that the mandatory layer info is indeed correct
-	err := p.VerifyFrame(lldpLayer.(*layers.LinkLayerDiscovery))
 	if err != nil {
-		return event, err
+		return event, nil, err
+	}
+	lldpInfo := rcvd.RxFrame
+	key := NeighborKey(lldpInfo)
+	nbr, exists := rxInfo.Neighbors[key]
+	if lldpInfo.TTL == 0 {
+		// shutdown frame, delete the neighbor right away
+		if !exists {
+			return event, nil, nil
+		}
+		rxInfo.deleteNeighbor(key)
+		return config.Removed, nbr, nil
+	}
+	if !exists {
+		if len(rxInfo.Neighbors) >= rxInfo.MaxNeighbors {
+			expiry := time.Now().Add(time.Duration(lldpInfo.TTL) * time.Second)
+			if expiry.After(rxInfo.tooManyNeighborsExpiry) {
+				rxInfo.tooManyNeighborsExpiry = expiry
+			}
+			rxInfo.tooManyNeighbors = true
+			rxInfo.Stats.FramesDiscarded++
+			return event, nil, ErrTooManyNeighbors
+		}
+		rxInfo.nextRemIndex++
+		if rxInfo.nextRemIndex <= 0 {
+			// remote index wraps around after 2147483647
+			rxInfo.nextRemIndex = 1
+		}
+		nbr = &Neighbor{
+			RemIndex: rxInfo.nextRemIndex,
+		}
+		rxInfo.Neighbors[key] = nbr
 	}
+	// copy src mac
+	nbr.SrcMAC = eth.SrcMAC
 	// Update last packet byte for cacheing...
-	if len(rxInfo.LastPkt) == 0 || rxInfo.LastPkt == nil {
+	if len(nbr.LastPkt) == 0 {
 		//this is new cache set event state to be learned
-		rxInfo.LastPkt = pkt.Data()
 		event = config.Learned
-	} else {
+	} else if bytes.Compare(nbr.LastPkt, pkt.Data()) != 0 {
 		// if incoming packet has difference then it means that we need to publish event
-		if bytes.Compare(rxInfo.LastPkt, pkt.Data()) != 0 {
-			event = config.Updated
-		}
+		event = config.Updated
 	}
+	nbr.LastPkt = pkt.Data()
+	nbr.RcvdTime = time.Now()
 
-	if rxInfo.RxFrame == nil {
-		rxInfo.RxFrame = new(layers.LinkLayerDiscovery)
+	// Store lldp frame & link layer optional tlv information received from direct connection
+	nbr.RxFrame = rcvd.RxFrame
+	nbr.RxLinkInfo = rcvd.RxLinkInfo
+	nbr.Platform = rcvd.Platform
+
+	return event, nbr, nil
+}
+
+/*  Decode lldpdu of received frame. New copy of lldp information is created every time so that
+ *  previous information of the neighbor is still valid for the caller who is holding it
+ */
+func (rxInfo *RX) decodeLldp(eth *layers.Ethernet, pkt gopacket.Packet) (*Neighbor, error) {
+	// Verify that all tlv's fit in the lldpdu before decoding it
+	err := VerifyTLVLengths(eth.Payload)
+	if err != nil {
+		rxInfo.Stats.LldpduLengthErrors++
+		rxInfo.Stats.FramesInErrors++
+		rxInfo.Stats.FramesDiscarded++
+		return nil, err
+	}
+	// Get lldp manadatory layer and optional info
+	lldpLayer := pkt.Layer(layers.LayerTypeLinkLayerDiscovery)
+	lldpLayerInfo := pkt.Layer(layers.LayerTypeLinkLayerDiscoveryInfo)
+	// Verify that the information is not nil
+	if lldpLayer == nil || lldpLayerInfo == nil {
+		rxInfo.Stats.FramesInErrors++
+		rxInfo.Stats.FramesDiscarded++
+		return nil, errors.New("Invalid Frame")
 	}
-	// Store lldp frame information received from direct connection
-	*rxInfo.RxFrame = *lldpLayer.(*layers.LinkLayerDiscovery)
 
-	if rxInfo.RxLinkInfo == nil {
-		rxInfo.RxLinkInfo = new(layers.LinkLayerDiscoveryInfo)
+	// Verify that the mandatory layer info is indeed correct
+	lldpInfo := lldpLayer.(*layers.LinkLayerDiscovery)
+	err = rxInfo.VerifyFrame(lldpInfo)
+	if err != nil {
+		rxInfo.Stats.FramesInErrors++
+		rxInfo.Stats.FramesDiscarded++
+		return nil, err
 	}
-	// Store lldp link layer optional tlv information
-	*rxInfo.RxLinkInfo = *lldpLayerInfo.(*layers.LinkLayerDiscoveryInfo)
+	discarded, unrecognized := CheckOptionalTLVs(lldpInfo)
+	rxInfo.Stats.TLVsDiscarded += discarded
+	rxInfo.Stats.TLVsUnrecognized += unrecognized
+	rxFrame := *lldpInfo
+	rxLinkInfo := *lldpLayerInfo.(*layers.LinkLayerDiscoveryInfo)
+	return &Neighbor{
+		RxFrame:    &rxFrame,
+		RxLinkInfo: &rxLinkInfo,
+	}, nil
+}
 
-	return event, nil
+/*  Get copy of remote table entry for the neighbor which sent the frame, nil if neighbor is not known.
+ *  This is used to find out what changed once the frame is processed
+ */
+func (rxInfo *RX) GetNeighbor(pkt gopacket.Packet) *Neighbor {
+	var lldpInfo *layers.LinkLayerDiscovery
+	if rxInfo.cdp {
+		lldpInfo = CdpNeighborId(pkt)
+	} else if lldpLayer := pkt.Layer(layers.LayerTypeLinkLayerDiscovery); lldpLayer != nil {
+		lldpInfo = lldpLayer.(*layers.LinkLayerDiscovery)
+	}
+	if lldpInfo == nil {
+		return nil
+	}
+	nbr, exists := rxInfo.Neighbors[NeighborKey(lldpInfo)]
+	if !exists {
+		return nil
+	}
+	prev := *nbr
+	return &prev
 }
 
 /*
- *  Handle TTL timer. Once the timer expires, we will delete the remote entry
+ *  Handle TTL timer. Once the timer expires, we will inform server to delete the remote entry
  *  if timer is running then reset the value
  */
-func (rxInfo *RX) CheckPeerEntry(port string, eCh chan config.EventInfo, ifIndex int32) {
-	if rxInfo.ClearCacheTimer != nil {
+func (nbr *Neighbor) CheckPeerEntry(port string, eCh chan config.EventInfo, ifIndex int32, agent uint8) {
+	if nbr.ClearCacheTimer != nil {
 		// timer is running reset the time so that it doesn't expire
-		rxInfo.ClearCacheTimer.Reset(time.Duration(rxInfo.RxFrame.TTL) * time.Second)
+		nbr.ClearCacheTimer.Reset(nbr.RemainingTTL())
 	} else {
+		remIndex := nbr.RemIndex
 		var clearPeerInfo_func func()
-		// On timer expiration we will delete peer info and set it to nil
+		// On timer expiration we will ask server to delete peer info
 		clearPeerInfo_func = func() {
 			debug.Logger.Info("Recipient info delete timer expired for " + "peer connected to port " +
 				port + " and hence deleting peer information from runtime")
-			rxInfo.RxFrame = nil
-			rxInfo.RxLinkInfo = nil
-			rxInfo.LastPkt = nil
 			eCh <- config.EventInfo{
 				EventType: config.Removed,
 				IfIndex:   ifIndex,
+				Agent:     agent,
+				RemIndex:  remIndex,
 			}
 		}
 		// First time start function
-		rxInfo.ClearCacheTimer = time.AfterFunc(time.Duration(rxInfo.RxFrame.TTL)*time.Second,
-			clearPeerInfo_func)
+		nbr.ClearCacheTimer = time.AfterFunc(nbr.RemainingTTL(), clearPeerInfo_func)
+	}
+}
+
+/*  Time left till the neighbor information expires, ttl of last frame is counted from its receive time
+ */
+func (nbr *Neighbor) RemainingTTL() time.Duration {
+	return time.Duration(nbr.RxFrame.TTL)*time.Second - time.Since(nbr.RcvdTime)
+}
+
+/*  Restore remote table entry from the frame which was received before restart. Receive time of the
+ *  frame is restored as well so that the neighbor still ages out as per ttl of the frame, entry is not
+ *  restored if the ttl has already expired. Restored frame is not counted in rx statistics
+ */
+func (rxInfo *RX) Restore(pkt gopacket.Packet, rcvdTime time.Time) (*Neighbor, error) {
+	stats := rxInfo.Stats
+	_, nbr, err := rxInfo.Process(rxInfo, pkt)
+	rxInfo.Stats = stats
+	if err != nil {
+		return nil, err
+	}
+	if nbr == nil || nbr.RxFrame.TTL == 0 {
+		return nil, errors.New("Shutdown frame can't be restored")
+	}
+	nbr.RcvdTime = rcvdTime
+	if nbr.RemainingTTL() <= 0 {
+		rxInfo.deleteNeighbor(NeighborKey(nbr.RxFrame))
+		return nil, errors.New("TTL of restored frame expired")
+	}
+	return nbr, nil
+}
+
+/*  Remote entry for the neighbor has expired (rxInfoAge), delete it from remote table. Entry is not
+ *  deleted if frame from the neighbor was received after timer expiry
+ */
+func (rxInfo *RX) AgeOutNeighbor(remIndex int32) *Neighbor {
+	for key, nbr := range rxInfo.Neighbors {
+		if nbr.RemIndex != remIndex {
+			continue
+		}
+		if nbr.RemainingTTL() > 0 {
+			return nil
+		}
+		rxInfo.deleteNeighbor(key)
+		rxInfo.Stats.Ageouts++
+		return nbr
+	}
+	return nil
+}
+
+/*  Delete neighbor from the remote table and stop its ttl timer
+ */
+func (rxInfo *RX) deleteNeighbor(key string) {
+	nbr, exists := rxInfo.Neighbors[key]
+	if !exists {
+		return
+	}
+	if nbr.ClearCacheTimer != nil {
+		nbr.ClearCacheTimer.Stop()
+		nbr.ClearCacheTimer = nil
+	}
+	delete(rxInfo.Neighbors, key)
+}
+
+/*  Delete all the neighbors learned on the port
+ */
+func (rxInfo *RX) DeleteAllNeighbors() {
+	for key, _ := range rxInfo.Neighbors {
+		rxInfo.deleteNeighbor(key)
+	}
+	rxInfo.tooManyNeighbors = false
+}
+
+/*  Get all the neighbors learned on the port sorted by remote index
+ */
+func (rxInfo *RX) SortedNeighbors() []*Neighbor {
+	nbrs := make([]*Neighbor, 0, len(rxInfo.Neighbors))
+	for _, nbr := range rxInfo.Neighbors {
+		nbrs = append(nbrs, nbr)
+	}
+	sort.Sort(NeighborsByRemIndex(nbrs))
+	return nbrs
+}
+
+/*  tooManyNeighbors is set till the TTL of last discarded frame expires
+ */
+func (rxInfo *RX) TooManyNeighbors() bool {
+	if rxInfo.tooManyNeighbors && time.Now().After(rxInfo.tooManyNeighborsExpiry) {
+		rxInfo.tooManyNeighbors = false
+	}
+	return rxInfo.tooManyNeighbors
+}
+
+type NeighborsByRemIndex []*Neighbor
+
+func (n NeighborsByRemIndex) Len() int {
+	return len(n)
+}
+
+func (n NeighborsByRemIndex) Swap(i, j int) {
+	n[i], n[j] = n[j], n[i]
+}
+
+func (n NeighborsByRemIndex) Less(i, j int) bool {
+	return n[i].RemIndex < n[j].RemIndex
+}
+
+/*  Decode all the management address tlv's received from peer, gopacket only keeps the last one
+ */
+func DecodeMgmtAddrs(rxFrame *layers.LinkLayerDiscovery) []config.MgmtAddr {
+	var mgmtAddrs []config.MgmtAddr
+	for _, tlv := range rxFrame.Values {
+		if tlv.Type != layers.LLDPTLVMgmtAddress {
+			continue
+		}
+		// address string length (1) + subtype (1) + interface subtype (1) +
+		// interface number (4) + oid string length (1)
+		if len(tlv.Value) < 8 {
+			continue
+		}
+		addrLen := int(tlv.Value[0])
+		if addrLen < 1 || len(tlv.Value) < addrLen+7 {
+			continue
+		}
+		subtype := layers.IANAAddressFamily(tlv.Value[1])
+		addr := tlv.Value[2 : addrLen+1]
+		mgmtAddr := config.MgmtAddr{
+			IfNumber: int32(binary.BigEndian.Uint32(tlv.Value[addrLen+2 : addrLen+6])),
+		}
+		switch subtype {
+		case layers.IANAAddressFamilyIPV4, layers.IANAAddressFamilyIPV6:
+			mgmtAddr.Addr = net.IP(addr).String()
+		case layers.IANAAddressFamily802:
+			mgmtAddr.Addr = net.HardwareAddr(addr).String()
+		default:
+			mgmtAddr.Addr = subtype.String() + ":" + hex.EncodeToString(addr)
+		}
+		oidLen := int(tlv.Value[addrLen+6])
+		if oidLen > 0 && len(tlv.Value) >= addrLen+7+oidLen {
+			mgmtAddr.OID = DecodeOID(tlv.Value[addrLen+7 : addrLen+7+oidLen])
+		}
+		mgmtAddrs = append(mgmtAddrs, mgmtAddr)
+	}
+	return mgmtAddrs
+}
+
+/*  Decode BER encoded object identifier into dotted notation
+ */
+func DecodeOID(b []byte) string {
+	var arcs []string
+	var value uint64
+	for idx, octet := range b {
+		value = value<<7 | uint64(octet&0x7f)
+		if octet&0x80 != 0 && idx != len(b)-1 {
+			continue
+		}
+		if len(arcs) == 0 {
+			// first sub identifier carries first two arcs
+			first := value / 40
+			if first > 2 {
+				first = 2
+			}
+			arcs = append(arcs, strconv.FormatUint(first, 10), strconv.FormatUint(value-first*40, 10))
+		} else {
+			arcs = append(arcs, strconv.FormatUint(value, 10))
+		}
+		value = 0
 	}
+	return strings.Join(arcs, ".")
 }
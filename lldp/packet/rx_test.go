@@ -0,0 +1,275 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package packet
+
+import (
+	"github.com/google/gopacket/layers"
+	"testing"
+	"time"
+)
+
+/*  Frame send by the agent on the given port of the peer, chassis id is port mac and port id is
+ *  port name, so every port is a different neighbor
+ */
+func testPeerFrame(name, mac string, shutdown bool) []byte {
+	port := testPort
+	port.Name = name
+	port.MacAddr = mac
+	tx := TxInit(30, 4, LLDP_PROTO_DST_MAC)
+	if shutdown {
+		return tx.ShutdownFrame(port, testSysInfo)
+	}
+	return testFrame(tx, port, testSysInfo)
+}
+
+func TestNeighbors(t *testing.T) {
+	testSetup()
+	peer1 := testPeerFrame("fpPort1", "00:11:22:33:44:01", false)
+	peer2 := testPeerFrame("fpPort2", "00:11:22:33:44:02", false)
+	peer2Shutdown := testPeerFrame("fpPort2", "00:11:22:33:44:02", true)
+	tests := []struct {
+		name         string
+		maxNeighbors int
+		frames       [][]byte
+		errs         []error
+		remIndexes   []int32
+		tooMany      bool
+	}{
+		{
+			name:         "two neighbors on one port",
+			maxNeighbors: LLDP_DEFAULT_MAX_NEIGHBORS,
+			frames:       [][]byte{peer1, testSwitchFrame, peer1},
+			errs:         []error{nil, nil, nil},
+			remIndexes:   []int32{1, 2},
+		},
+		{
+			name:         "shutdown frame deletes only that neighbor",
+			maxNeighbors: LLDP_DEFAULT_MAX_NEIGHBORS,
+			frames:       [][]byte{peer1, peer2, testSwitchFrame, peer2Shutdown},
+			errs:         []error{nil, nil, nil, nil},
+			remIndexes:   []int32{1, 3},
+		},
+		{
+			name:         "full table without new neighbor",
+			maxNeighbors: 2,
+			frames:       [][]byte{peer1, peer2, peer1, peer2},
+			errs:         []error{nil, nil, nil, nil},
+			remIndexes:   []int32{1, 2},
+		},
+		{
+			name:         "full table discards new neighbor",
+			maxNeighbors: 2,
+			frames:       [][]byte{peer1, peer2, testSwitchFrame, peer1},
+			errs:         []error{nil, nil, ErrTooManyNeighbors, nil},
+			remIndexes:   []int32{1, 2},
+			tooMany:      true,
+		},
+		{
+			name:         "new neighbor is learned once there is space in the table",
+			maxNeighbors: 2,
+			frames:       [][]byte{peer1, peer2, testSwitchFrame, peer2Shutdown, testSwitchFrame},
+			errs:         []error{nil, nil, ErrTooManyNeighbors, nil, nil},
+			remIndexes:   []int32{1, 3},
+			tooMany:      true,
+		},
+	}
+	for _, tc := range tests {
+		rxInfo := RxInit(LLDP_PROTO_DST_MAC)
+		rxInfo.MaxNeighbors = tc.maxNeighbors
+		for idx, frame := range tc.frames {
+			if _, err := testProcess(rxInfo, frame); err != tc.errs[idx] {
+				t.Error(tc.name, "frame", idx, "expected error", tc.errs[idx], "actual", err)
+			}
+		}
+		nbrs := rxInfo.SortedNeighbors()
+		remIndexes := make([]int32, 0, len(nbrs))
+		for _, nbr := range nbrs {
+			remIndexes = append(remIndexes, nbr.RemIndex)
+		}
+		if len(remIndexes) != len(tc.remIndexes) {
+			t.Error(tc.name, "expected neighbors", tc.remIndexes, "actual", remIndexes)
+		} else {
+			for idx := range remIndexes {
+				if remIndexes[idx] != tc.remIndexes[idx] {
+					t.Error(tc.name, "expected neighbors", tc.remIndexes, "actual", remIndexes)
+					break
+				}
+			}
+		}
+		if rxInfo.TooManyNeighbors() != tc.tooMany {
+			t.Error(tc.name, "expected tooManyNeighbors", tc.tooMany)
+		}
+		if tc.tooMany {
+			// tooManyNeighbors is cleared once the TTL of discarded frame is expired
+			rxInfo.tooManyNeighborsExpiry = time.Now().Add(-time.Second)
+			if rxInfo.TooManyNeighbors() {
+				t.Error(tc.name, "tooManyNeighbors is expected to be cleared after TTL")
+			}
+		}
+		rxInfo.DeleteAllNeighbors()
+	}
+}
+
+func TestAgeOutNeighbor(t *testing.T) {
+	testSetup()
+	tests := []struct {
+		name     string
+		expired  []bool
+		ageOut   int32
+		deleted  bool
+		remained []int32
+	}{
+		{"expired neighbor is deleted", []bool{true, false}, 1, true, []int32{2}},
+		{"other neighbor is not touched", []bool{false, true}, 2, true, []int32{1}},
+		{"frame received after timer expiry", []bool{false, false}, 1, false, []int32{1, 2}},
+		{"unknown remote index", []bool{true, true}, 3, false, []int32{1, 2}},
+	}
+	for _, tc := range tests {
+		rxInfo := RxInit(LLDP_PROTO_DST_MAC)
+		testProcess(rxInfo, testPeerFrame("fpPort1", "00:11:22:33:44:01", false))
+		testProcess(rxInfo, testSwitchFrame)
+		for idx, nbr := range rxInfo.SortedNeighbors() {
+			if tc.expired[idx] {
+				nbr.RcvdTime = nbr.RcvdTime.Add(-time.Duration(nbr.RxFrame.TTL) * time.Second)
+			}
+		}
+		nbr := rxInfo.AgeOutNeighbor(tc.ageOut)
+		if (nbr != nil) != tc.deleted || (nbr != nil && nbr.RemIndex != tc.ageOut) {
+			t.Error(tc.name, "expected neighbor", tc.ageOut, "deleted", tc.deleted, "actual", nbr)
+		}
+		nbrs := rxInfo.SortedNeighbors()
+		if len(nbrs) != len(tc.remained) {
+			t.Error(tc.name, "expected neighbors", tc.remained, "actual", len(nbrs))
+			continue
+		}
+		for idx, nbr := range nbrs {
+			if nbr.RemIndex != tc.remained[idx] {
+				t.Error(tc.name, "expected neighbors", tc.remained, "actual", nbr.RemIndex)
+			}
+		}
+	}
+}
+
+func TestRxStats(t *testing.T) {
+	testSetup()
+	wrongDstMAC := testLldpFrame()
+	wrongDstMAC[5] = 0x03
+	badChassisSubtype := testLldpFrame()
+	badChassisSubtype[16] = 0x09
+	noTTL := append(testLldpFrame()[:32], 0x00, 0x00)
+	sysName := EncodeTLV(&layers.LinkLayerDiscoveryValue{Type: layers.LLDPTLVSysName, Value: []byte("peer1"),
+		Length: 5})
+	peer2 := testPeerFrame("fpPort2", "00:11:22:33:44:02", false)
+	tests := []struct {
+		name         string
+		maxNeighbors int
+		frames       [][]byte
+		ageOut       bool
+		stats        RxStats
+	}{
+		{
+			name:   "valid frames",
+			frames: [][]byte{testLldpFrame(), testLldpFrame(sysName), peer2},
+		},
+		{
+			// PROFINET org specific tlv's are not known to us
+			name:   "unrecognized tlv's from the switch",
+			frames: [][]byte{testSwitchFrame},
+			stats:  RxStats{TLVsUnrecognized: 2},
+		},
+		{
+			name:   "dcbx tlv's are recognized",
+			frames: [][]byte{testDcbxFrame},
+		},
+		{
+			name:   "frame to other group mac is discarded",
+			frames: [][]byte{wrongDstMAC},
+			stats:  RxStats{FramesDiscarded: 1},
+		},
+		{
+			name: "tlv length exceeds lldpdu",
+			frames: [][]byte{testLldpFrame([]byte{0x08, 0x20, 0x61}), testLldpFrame()[:len(testLldpFrame())-2],
+				testLldpFrame()},
+			stats: RxStats{FramesDiscarded: 2, FramesInErrors: 2, LldpduLengthErrors: 2},
+		},
+		{
+			name:   "invalid mandatory tlv's",
+			frames: [][]byte{badChassisSubtype, noTTL, testLldpFrame()},
+			stats:  RxStats{FramesDiscarded: 2, FramesInErrors: 2},
+		},
+		{
+			name:   "duplicate tlv's are discarded",
+			frames: [][]byte{testLldpFrame(sysName, sysName), testLldpFrame(sysName)},
+			stats:  RxStats{TLVsDiscarded: 1},
+		},
+		{
+			name: "unrecognized tlv's",
+			frames: [][]byte{testLldpFrame(
+				EncodeTLV(&layers.LinkLayerDiscoveryValue{Type: 9, Value: []byte{0x01}, Length: 1}),
+				EncodeOrgTLV(layers.IEEEOUI(0x001b21), 1, []byte{0x01}),
+				EncodeOrgTLV(layers.IEEEOUI8021, 0x7f, []byte{0x01}),
+				EncodeOrgTLV(layers.IEEEOUI8023, 0x7f, []byte{0x01}),
+				EncodeOrgTLV(layers.IEEEOUIMedia, 0x7f, []byte{0x01}),
+				EncodeOrgTLV(layers.IEEEOUI8021, layers.LLDP8021SubtypePortVLANID, []byte{0x00, 0x0a}),
+				EncodeOrgTLV(layers.IEEEOUI8023, layers.LLDP8023SubtypeMTU, []byte{0x05, 0xee}))},
+			stats: RxStats{TLVsUnrecognized: 5},
+		},
+		{
+			name:         "frame from new neighbor is discarded when table is full",
+			maxNeighbors: 1,
+			frames:       [][]byte{testLldpFrame(), peer2, peer2, testLldpFrame()},
+			stats:        RxStats{FramesDiscarded: 2},
+		},
+		{
+			name:   "neighbor ageout",
+			frames: [][]byte{testLldpFrame(), peer2},
+			ageOut: true,
+			stats:  RxStats{Ageouts: 1},
+		},
+	}
+	for _, tc := range tests {
+		rxInfo := RxInit(LLDP_PROTO_DST_MAC)
+		if tc.maxNeighbors > 0 {
+			rxInfo.MaxNeighbors = tc.maxNeighbors
+		}
+		for _, frame := range tc.frames {
+			testProcess(rxInfo, frame)
+		}
+		if tc.ageOut {
+			nbr := rxInfo.SortedNeighbors()[0]
+			nbr.RcvdTime = nbr.RcvdTime.Add(-time.Duration(nbr.RxFrame.TTL) * time.Second)
+			if rxInfo.AgeOutNeighbor(nbr.RemIndex) == nil {
+				t.Error(tc.name, "neighbor", nbr.RemIndex, "is expected to be aged out")
+			}
+		}
+		if rxInfo.Stats != tc.stats {
+			t.Error(tc.name, "expected stats", tc.stats, "actual", rxInfo.Stats)
+		}
+		rxInfo.ClearStats()
+		if rxInfo.Stats != (RxStats{}) {
+			t.Error(tc.name, "stats are expected to be cleared, actual", rxInfo.Stats)
+		}
+		rxInfo.DeleteAllNeighbors()
+	}
+}
@@ -33,6 +33,8 @@ import (
 	"l2/lldp/config"
 	"l2/lldp/utils"
 	"net"
+	"strconv"
+	"strings"
 )
 
 func Min(x, y int) int {
@@ -42,7 +44,7 @@ func Min(x, y int) int {
 	return y
 }
 
-func TxInit(interval, hold int) *TX {
+func TxInit(interval, hold int, dstMAC string) *TX {
 	var err error
 	/*  Set tx interval during init or update
 	 *  default value is 30
@@ -53,13 +55,18 @@ func TxInit(interval, hold int) *TX {
 		MessageTxInterval:       interval,
 		MessageTxHoldMultiplier: hold,
 		useCacheFrame:           false,
+		txFastInit:              LLDP_DEFAULT_TX_FAST_INIT,
+		msgFastTx:               LLDP_DEFAULT_MSG_FAST_TX,
+		txCreditMax:             LLDP_DEFAULT_TX_CREDIT_MAX,
+		reinitDelay:             LLDP_DEFAULT_REINIT_DELAY,
+		tlvs:                    config.TLV_ALL,
 	}
 	/*  Set TTL Value at the time of init or update of lldp config
-	 *  default value comes out to be 120
+	 *  default value comes out to be 121 (msgTxInterval * msgTxHold + 1)
 	 */
 	txInfo.ttl = Min(LLDP_MAX_TTL, txInfo.MessageTxInterval*
-		txInfo.MessageTxHoldMultiplier)
-	txInfo.DstMAC, err = net.ParseMAC(LLDP_PROTO_DST_MAC)
+		txInfo.MessageTxHoldMultiplier+1)
+	txInfo.DstMAC, err = net.ParseMAC(dstMAC)
 	if err != nil {
 		debug.Logger.Err("parsing lldp protocol Mac failed", err)
 	}
@@ -67,6 +74,16 @@ func TxInit(interval, hold int) *TX {
 	return txInfo
 }
 
+/*  Set optional tlv's advertised on the port, frame is send right away if tlv's are changed
+ */
+func (t *TX) SetTLVs(tlvs uint8) {
+	if t.tlvs == tlvs {
+		return
+	}
+	t.tlvs = tlvs
+	t.SomethingChangedLocal()
+}
+
 /*  Function to send out lldp frame to peer on timer expiry.
  *  if a cache entry is present then use that otherwise create a new lldp frame
  *  A new frame will be constructed:
@@ -82,34 +99,14 @@ func (t *TX) Frame(port config.PortInfo, sysInfo *config.SystemInfo) []byte {
 		srcmac, _ := net.ParseMAC(port.MacAddr)
 		// we need to construct new lldp frame based of the information that we
 		// have collected locally
-		// Chassis ID: Mac Address of Port
-		// Port ID: Port Name
-		// TTL: calculated during port init default is 30 * 4 = 120
-		payload := t.createPayload(srcmac, port, sysInfo)
-		if payload == nil {
+		// Chassis ID & Port ID as per configured selection
+		// TTL: calculated during port init default is 30 * 4 + 1 = 121
+		pkt := t.encodeFrame(srcmac, port, sysInfo, t.ttl)
+		if pkt == nil {
 			debug.Logger.Err("Creating payload failed for port", port)
 			t.useCacheFrame = false
 			return temp
 		}
-		// Additional TLV's... @TODO: get it done later on
-		// System information... like "show version" command at Cisco
-		// System Capabilites...
-
-		// Construct ethernet information
-		eth := &layers.Ethernet{
-			SrcMAC:       srcmac,
-			DstMAC:       t.DstMAC,
-			EthernetType: layers.EthernetTypeLinkLayerDiscovery,
-		}
-
-		// construct new buffer
-		buffer := gopacket.NewSerializeBuffer()
-		options := gopacket.SerializeOptions{
-			FixLengths:       true,
-			ComputeChecksums: true,
-		}
-		gopacket.SerializeLayers(buffer, options, eth, gopacket.Payload(payload))
-		pkt := buffer.Bytes()
 		t.cacheFrame = make([]byte, len(pkt))
 		copied := copy(t.cacheFrame, pkt)
 		if copied < len(pkt) {
@@ -125,9 +122,43 @@ func (t *TX) Frame(port config.PortInfo, sysInfo *config.SystemInfo) []byte {
 	}
 }
 
-/*  helper function to create payload from lldp frame struct
+/*  helper function to construct complete frame, CDP agent sends CDP frame instead of lldp frame
+ */
+func (t *TX) encodeFrame(srcmac net.HardwareAddr, port config.PortInfo, sysInfo *config.SystemInfo, ttl int) []byte {
+	if t.cdp {
+		return t.createCdpFrame(srcmac, port, sysInfo, ttl)
+	}
+	payload := t.createPayload(srcmac, port, sysInfo, ttl)
+	if payload == nil {
+		return nil
+	}
+	return t.createFrame(srcmac, payload)
+}
+
+/*  helper function to construct ethernet frame for lldp payload
  */
-func (t *TX) createPayload(srcmac []byte, port config.PortInfo, sysInfo *config.SystemInfo) []byte {
+func (t *TX) createFrame(srcmac net.HardwareAddr, payload []byte) []byte {
+	// Construct ethernet information
+	eth := &layers.Ethernet{
+		SrcMAC:       srcmac,
+		DstMAC:       t.DstMAC,
+		EthernetType: layers.EthernetTypeLinkLayerDiscovery,
+	}
+
+	// construct new buffer
+	buffer := gopacket.NewSerializeBuffer()
+	options := gopacket.SerializeOptions{
+		FixLengths:       true,
+		ComputeChecksums: true,
+	}
+	gopacket.SerializeLayers(buffer, options, eth, gopacket.Payload(payload))
+	return buffer.Bytes()
+}
+
+/*  helper function to create payload from lldp frame struct, shutdown lldpdu is created with ttl 0
+ *  and only mandatory tlv's
+ */
+func (t *TX) createPayload(srcmac []byte, port config.PortInfo, sysInfo *config.SystemInfo, ttl int) []byte {
 	var payload []byte
 	var err error
 	tlvType := layers.LLDPTLVChassisID // start with chassis id always
@@ -138,23 +169,30 @@ func (t *TX) createPayload(srcmac []byte, port config.PortInfo, sysInfo *config.
 			debug.Logger.Debug("Reading System Information from DB failed and hence sending out only " +
 				"Mandatory TLV's")
 			break
+		} else if tlvType > layers.LLDPTLVTTL && ttl == 0 {
+			// shutdown lldpdu
+			break
+		}
+		if !t.tlvEnabled(tlvType) {
+			tlvType++
+			continue
 		}
 		tlv := &layers.LinkLayerDiscoveryValue{}
 		switch tlvType {
 		case layers.LLDPTLVChassisID: // Chassis ID
 			tlv.Type = layers.LLDPTLVChassisID
-			tlv.Value = EncodeMandatoryTLV(byte(layers.LLDPChassisIDSubTypeMACAddr), srcmac)
+			tlv.Value = t.encodeChassisIdTLV(srcmac, sysInfo)
 			debug.Logger.Debug("Chassis id tlv", *tlv)
 
 		case layers.LLDPTLVPortID: // Port ID
 			tlv.Type = layers.LLDPTLVPortID
-			tlv.Value = EncodeMandatoryTLV(byte(layers.LLDPPortIDSubtypeIfaceName), []byte(port.Name))
+			tlv.Value = t.encodePortIdTLV(srcmac, port)
 			debug.Logger.Debug("Port id tlv", *tlv)
 
 		case layers.LLDPTLVTTL: // TTL
 			tlv.Type = layers.LLDPTLVTTL
 			tb := []byte{0, 0}
-			binary.BigEndian.PutUint16(tb, uint16(t.ttl))
+			binary.BigEndian.PutUint16(tb, uint16(ttl))
 			tlv.Value = append(tlv.Value, tb...)
 			debug.Logger.Debug("TTL tlv", *tlv)
 
@@ -174,26 +212,16 @@ func (t *TX) createPayload(srcmac []byte, port config.PortInfo, sysInfo *config.
 			debug.Logger.Debug("System Name", *tlv)
 
 		case layers.LLDPTLVSysCapabilities:
-			err = errors.New("Tlv not supported")
+			tlv.Type = layers.LLDPTLVSysCapabilities
+			sysCap := GetLocalCapabilities(port, sysInfo)
+			tlv.Value = EncodeSysCapTLV(&sysCap)
+			debug.Logger.Debug("System Capabilities", *tlv)
 
 		case layers.LLDPTLVMgmtAddress:
-			/*
-			 *  Value: N bytes
-			 *     Subtype is 1 byte
-			 *     Address is []byte
-			 *     IntefaceSubtype is 1 byte
-			 *     IntefaceNumber uint32 <<< this is system interface number which is IfIndex in out case
-			 *     OID string
-
-			 */
-			tlv.Type = layers.LLDPTLVMgmtAddress
-			mgmtInfo := &layers.LLDPMgmtAddress{
-				Subtype:          layers.IANAAddressFamilyIPV4,
-				Address:          net.ParseIP(sysInfo.MgmtIp).To4(),
-				InterfaceSubtype: layers.LLDPInterfaceSubtypeifIndex,
-				InterfaceNumber:  uint32(port.IfIndex),
-			}
-			tlv.Value = EncodeMgmtTLV(mgmtInfo)
+			// one tlv per management address and hence all of them are added right here
+			payload = append(payload, t.createMgmtPayload(srcmac, port, sysInfo)...)
+			tlvType++
+			continue
 		}
 		if err == nil {
 			tlv.Length = uint16(len(tlv.Value))
@@ -203,6 +231,19 @@ func (t *TX) createPayload(srcmac []byte, port config.PortInfo, sysInfo *config.
 		tlvType++
 	}
 
+	// Org specific TLV's are added only if system information is available and not for shutdown lldpdu
+	if sysInfo != nil && ttl != 0 {
+		if t.medActive() {
+			payload = append(payload, t.createMedPayload()...)
+		}
+		if t.dcbxConfig.Enable {
+			payload = append(payload, t.createDcbxPayload()...)
+		}
+		// reserve 2 bytes for end tlv
+		available := LLDP_MAX_PDU_SIZE - len(payload) - 2
+		payload = append(payload, t.createOrgPayload(port, available)...)
+	}
+
 	// After all TLV's are added we need to go ahead and Add LLDPTLVEnd
 	tlv := &layers.LinkLayerDiscoveryValue{}
 	tlv.Type = layers.LLDPTLVEnd
@@ -211,6 +252,103 @@ func (t *TX) createPayload(srcmac []byte, port config.PortInfo, sysInfo *config.
 	return payload
 }
 
+/*  Mandatory tlv's are always send, optional tlv's only if enabled on the port
+ */
+func (t *TX) tlvEnabled(tlvType layers.LLDPTLVType) bool {
+	switch tlvType {
+	case layers.LLDPTLVPortDescription:
+		return t.tlvs&config.TLV_PORT_DESCRIPTION != 0
+	case layers.LLDPTLVSysName:
+		return t.tlvs&config.TLV_SYSTEM_NAME != 0
+	case layers.LLDPTLVSysDescription:
+		return t.tlvs&config.TLV_SYSTEM_DESCRIPTION != 0
+	case layers.LLDPTLVSysCapabilities:
+		return t.tlvs&config.TLV_SYSTEM_CAP != 0
+	case layers.LLDPTLVMgmtAddress:
+		return t.tlvs&config.TLV_MGMT_ADDRESS != 0
+	}
+	return true
+}
+
+/*  helper function to create management address tlv for all the ipv4/ipv6 addresses of the system
+ *  If there is no valid management address then port mac address is used as per IEEE 802.1AB
+ */
+func (t *TX) createMgmtPayload(srcmac []byte, port config.PortInfo, sysInfo *config.SystemInfo) []byte {
+	var payload []byte
+	for _, mgmtAddr := range GetMgmtAddrs(sysInfo) {
+		/*
+		 *  Value: N bytes
+		 *     Subtype is 1 byte
+		 *     Address is []byte
+		 *     IntefaceSubtype is 1 byte
+		 *     IntefaceNumber uint32 <<< this is system interface number which is IfIndex in out case
+		 *     OID string
+		 */
+		ip := net.ParseIP(mgmtAddr.Addr)
+		mgmtInfo := &layers.LLDPMgmtAddress{
+			InterfaceSubtype: layers.LLDPInterfaceSubtypeifIndex,
+			InterfaceNumber:  uint32(port.IfIndex),
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			mgmtInfo.Subtype = layers.IANAAddressFamilyIPV4
+			mgmtInfo.Address = ip4
+		} else {
+			mgmtInfo.Subtype = layers.IANAAddressFamilyIPV6
+			mgmtInfo.Address = ip.To16()
+		}
+		oid, err := EncodeOID(mgmtAddr.OID)
+		if err != nil {
+			debug.Logger.Err("Invalid OID", mgmtAddr.OID, "for management address", mgmtAddr.Addr,
+				"error:", err)
+		}
+		mgmtInfo.OID = string(oid)
+		tlv := &layers.LinkLayerDiscoveryValue{}
+		tlv.Type = layers.LLDPTLVMgmtAddress
+		tlv.Value = EncodeMgmtTLV(mgmtInfo)
+		tlv.Length = uint16(len(tlv.Value))
+		payload = append(payload, EncodeTLV(tlv)...)
+	}
+	if len(payload) == 0 {
+		mgmtInfo := &layers.LLDPMgmtAddress{
+			Subtype:          layers.IANAAddressFamily802,
+			Address:          srcmac,
+			InterfaceSubtype: layers.LLDPInterfaceSubtypeifIndex,
+			InterfaceNumber:  uint32(port.IfIndex),
+		}
+		tlv := &layers.LinkLayerDiscoveryValue{}
+		tlv.Type = layers.LLDPTLVMgmtAddress
+		tlv.Value = EncodeMgmtTLV(mgmtInfo)
+		tlv.Length = uint16(len(tlv.Value))
+		payload = append(payload, EncodeTLV(tlv)...)
+	}
+	debug.Logger.Debug("Management Address tlv's", payload)
+	return payload
+}
+
+/*  Get all valid ipv4/ipv6 management addresses of the system. If list of management addresses is
+ *  not provided then MgmtIp is used, which can be in ip or ip/prefix format
+ */
+func GetMgmtAddrs(sysInfo *config.SystemInfo) []config.MgmtAddr {
+	var mgmtAddrs []config.MgmtAddr
+	addrs := sysInfo.MgmtAddrs
+	if len(addrs) == 0 && sysInfo.MgmtIp != "" {
+		addrs = []config.MgmtAddr{config.MgmtAddr{Addr: sysInfo.MgmtIp}}
+	}
+	for _, mgmtAddr := range addrs {
+		ip := net.ParseIP(mgmtAddr.Addr)
+		if ip == nil {
+			ip, _, _ = net.ParseCIDR(mgmtAddr.Addr)
+		}
+		if ip == nil {
+			debug.Logger.Err("Invalid management address", mgmtAddr.Addr)
+			continue
+		}
+		mgmtAddr.Addr = ip.String()
+		mgmtAddrs = append(mgmtAddrs, mgmtAddr)
+	}
+	return mgmtAddrs
+}
+
 /*  Encode Mandatory tlv, chassis id and port id
  */
 func EncodeMandatoryTLV(Subtype byte, ID []byte) []byte {
@@ -257,12 +395,130 @@ func EncodeMgmtTLV(tlv *layers.LLDPMgmtAddress) []byte {
 	b = append(b, byte(tlv.InterfaceSubtype))
 	temp := make([]byte, 4 /*uint32*/ +1 /*Length of OID String*/)
 	binary.BigEndian.PutUint32(temp[0:4], tlv.InterfaceNumber)
-	temp[4] = 0
+	temp[4] = byte(len(tlv.OID))
 	b = append(b, temp...)
+	b = append(b, []byte(tlv.OID)...)
 	debug.Logger.Debug("byte returned", b)
 	return b
 }
 
+/*  TLV Type = 7, 7 bits
+ *  TLV Length = 9 bits, always 4
+ *  Value: 4 bytes
+ *     System Capabilities is 2 bytes
+ *     Enabled Capabilities is 2 bytes
+ */
+func EncodeSysCapTLV(sysCap *layers.LLDPSysCapabilities) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint16(b[0:2], capabilitiesToBits(sysCap.SystemCap))
+	binary.BigEndian.PutUint16(b[2:4], capabilitiesToBits(sysCap.EnabledCap))
+	return b
+}
+
+/*  Convert capabilities booleans into bit map as per IEEE 802.1AB Table 8-4
+ */
+func capabilitiesToBits(c layers.LLDPCapabilities) uint16 {
+	var bits uint16
+	if c.Other {
+		bits |= layers.LLDPCapsOther
+	}
+	if c.Repeater {
+		bits |= layers.LLDPCapsRepeater
+	}
+	if c.Bridge {
+		bits |= layers.LLDPCapsBridge
+	}
+	if c.WLANAP {
+		bits |= layers.LLDPCapsWLANAP
+	}
+	if c.Router {
+		bits |= layers.LLDPCapsRouter
+	}
+	if c.Phone {
+		bits |= layers.LLDPCapsPhone
+	}
+	if c.DocSis {
+		bits |= layers.LLDPCapsDocSis
+	}
+	if c.StationOnly {
+		bits |= layers.LLDPCapsStationOnly
+	}
+	if c.CVLAN {
+		bits |= layers.LLDPCapsCVLAN
+	}
+	if c.SVLAN {
+		bits |= layers.LLDPCapsSVLAN
+	}
+	if c.TMPR {
+		bits |= layers.LLDPCapsTmpr
+	}
+	return bits
+}
+
+/*  Derive local capabilities from system information and port state.
+ *  A switch is capable of bridging and routing, what is enabled depends on the port:
+ *	1) l2 port is bridge
+ *	2) l3 port is router
+ *  If system information doesn't have switch mac then we are not running on a switch and
+ *  hence we are station only
+ */
+func GetLocalCapabilities(port config.PortInfo, sysInfo *config.SystemInfo) layers.LLDPSysCapabilities {
+	sysCap := layers.LLDPSysCapabilities{}
+	if sysInfo == nil || sysInfo.SwitchMac == "" {
+		sysCap.SystemCap.StationOnly = true
+		sysCap.EnabledCap.StationOnly = true
+		return sysCap
+	}
+	sysCap.SystemCap.Bridge = true
+	sysCap.SystemCap.Router = true
+	if port.L3Enabled {
+		sysCap.EnabledCap.Router = true
+	} else {
+		sysCap.EnabledCap.Bridge = true
+	}
+	return sysCap
+}
+
+/*  Encode object identifier from dotted notation into BER format, as OID in management address tlv
+ *  is ASN.1 BER encoded. Empty string means no OID
+ */
+func EncodeOID(oid string) ([]byte, error) {
+	var b []byte
+	if oid == "" {
+		return b, nil
+	}
+	arcs := strings.Split(strings.TrimPrefix(oid, "."), ".")
+	if len(arcs) < 2 {
+		return nil, errors.New("OID should have atleast two arcs")
+	}
+	values := make([]uint64, len(arcs))
+	for idx, arc := range arcs {
+		value, err := strconv.ParseUint(arc, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		values[idx] = value
+	}
+	if values[0] > 2 || (values[0] < 2 && values[1] > 39) {
+		return nil, errors.New("Invalid first two arcs of OID")
+	}
+	// first two arcs are encoded as single sub identifier
+	values = append([]uint64{values[0]*40 + values[1]}, values[2:]...)
+	for _, value := range values {
+		// base 128 with msb set for all but last byte
+		var subId []byte
+		subId = append(subId, byte(value&0x7f))
+		for value >>= 7; value > 0; value >>= 7 {
+			subId = append([]byte{byte(value&0x7f) | 0x80}, subId...)
+		}
+		b = append(b, subId...)
+	}
+	if len(b) > LLDP_MAX_OID_LEN {
+		return nil, errors.New("OID is too long")
+	}
+	return b, nil
+}
+
 func (t *TX) UseCache() bool {
 	return t.useCacheFrame
 }
@@ -277,12 +533,3 @@ func (t *TX) DeleteCacheFrame() {
 	t.useCacheFrame = false
 	t.cacheFrame = nil
 }
-
-/*  Stop Send Tx timer... as we have already delete the pcap handle
- */
-func (t *TX) StopTxTimer() {
-	if t.TxTimer != nil {
-		t.TxTimer.Stop()
-		t.TxTimer = nil
-	}
-}
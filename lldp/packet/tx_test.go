@@ -0,0 +1,115 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package packet
+
+import (
+	"l2/lldp/config"
+	"reflect"
+	"testing"
+)
+
+func TestMgmtAddrs(t *testing.T) {
+	testSetup()
+	ifMib := "1.3.6.1.2.1.2.2.1.1"
+	tests := []struct {
+		name      string
+		frame     []byte
+		mgmtAddrs []config.MgmtAddr
+	}{
+		{
+			name: "ipv4 & ipv6 addresses",
+			frame: testFrame(TxInit(30, 4, LLDP_PROTO_DST_MAC), testPort, &config.SystemInfo{
+				MgmtAddrs: []config.MgmtAddr{{Addr: "10.0.0.1/24", OID: ifMib}, {Addr: "2001:db8::1"}},
+			}),
+			mgmtAddrs: []config.MgmtAddr{{Addr: "10.0.0.1", OID: ifMib, IfNumber: 1},
+				{Addr: "2001:db8::1", IfNumber: 1}},
+		},
+		{
+			name: "multiple ipv6 addresses & invalid address is skipped",
+			frame: testFrame(TxInit(30, 4, LLDP_PROTO_DST_MAC), testPort, &config.SystemInfo{
+				MgmtAddrs: []config.MgmtAddr{{Addr: "invalid"}, {Addr: "fe80::1"}, {Addr: "2001:db8::2/64"}},
+			}),
+			mgmtAddrs: []config.MgmtAddr{{Addr: "fe80::1", IfNumber: 1}, {Addr: "2001:db8::2", IfNumber: 1}},
+		},
+		{
+			name:      "management ip is used without management addresses",
+			frame:     testFrame(TxInit(30, 4, LLDP_PROTO_DST_MAC), testPort, testSysInfo),
+			mgmtAddrs: []config.MgmtAddr{{Addr: "10.0.0.1", IfNumber: 1}},
+		},
+		{
+			name: "port mac is used without valid management address",
+			frame: testFrame(TxInit(30, 4, LLDP_PROTO_DST_MAC), testPort, &config.SystemInfo{
+				MgmtAddrs: []config.MgmtAddr{{Addr: "invalid"}},
+			}),
+			mgmtAddrs: []config.MgmtAddr{{Addr: "00:11:22:33:44:01", IfNumber: 1}},
+		},
+		{
+			name:      "switch reference frame",
+			frame:     testSwitchFrame,
+			mgmtAddrs: []config.MgmtAddr{{Addr: "141.81.0.190", OID: "1.3.6.1.4.1.24686", IfNumber: 1}},
+		},
+	}
+	for _, tc := range tests {
+		rxInfo := RxInit(LLDP_PROTO_DST_MAC)
+		nbr, err := testProcess(rxInfo, tc.frame)
+		if err != nil {
+			t.Error(tc.name, "failed with error", err)
+			continue
+		}
+		if mgmtAddrs := DecodeMgmtAddrs(nbr.RxFrame); !reflect.DeepEqual(mgmtAddrs, tc.mgmtAddrs) {
+			t.Error(tc.name, "expected management addresses", tc.mgmtAddrs, "actual", mgmtAddrs)
+		}
+	}
+}
+
+func TestOID(t *testing.T) {
+	tests := []struct {
+		oid     string
+		encoded []byte
+		err     bool
+	}{
+		{oid: ""},
+		{oid: "1.3.6.1.2.1.2.2.1.1", encoded: []byte{0x2b, 0x06, 0x01, 0x02, 0x01, 0x02, 0x02, 0x01, 0x01}},
+		{oid: ".1.3.6.1.4.1.24686", encoded: []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0x81, 0xc0, 0x6e}},
+		{oid: "2.999.3", encoded: []byte{0x88, 0x37, 0x03}},
+		{oid: "1", err: true},
+		{oid: "1.40", err: true},
+		{oid: "3.1", err: true},
+		{oid: "1.3.x", err: true},
+	}
+	for _, tc := range tests {
+		encoded, err := EncodeOID(tc.oid)
+		if (err != nil) != tc.err || !reflect.DeepEqual(encoded, tc.encoded) {
+			t.Error("oid", tc.oid, "expected", tc.encoded, "error", tc.err, "actual", encoded, err)
+			continue
+		}
+		if tc.err || tc.oid == "" {
+			continue
+		}
+		// decoded oid is always without leading dot
+		if oid := DecodeOID(encoded); "."+oid != tc.oid && oid != tc.oid {
+			t.Error("expected decoded oid", tc.oid, "actual", oid)
+		}
+	}
+}
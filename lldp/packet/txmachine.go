@@ -0,0 +1,191 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package packet
+
+import (
+	"l2/lldp/config"
+	"l2/lldp/utils"
+	"net"
+	"time"
+)
+
+/*  IEEE 802.1AB-2016 default values for transmit state machine & timer state machine
+ */
+const (
+	LLDP_DEFAULT_MSG_FAST_TX   = 1 // in seconds
+	LLDP_DEFAULT_TX_FAST_INIT  = 4
+	LLDP_DEFAULT_TX_CREDIT_MAX = 5
+	LLDP_DEFAULT_REINIT_DELAY  = 2 // in seconds
+)
+
+/*  Start transmit state machine for the port, this is TX_LLDP_INITIALIZE & TX_TIMER_INITIALIZE
+ *  state. txTTR is set to 0 so that first frame is send out on next run of timer state machine
+ */
+func (t *TX) StartTx() {
+	t.txEnabled = true
+	t.txNow = false
+	t.localChange = false
+	t.newNeighbor = false
+	t.txTTR = 0
+	t.txFast = 0
+	t.txCredit = t.txCreditMax
+}
+
+/*  Stop transmit state machine for the port. Caller is informed whether shutdown frame needs to be
+ *  send out or not, which is only when transmit state machine was running
+ */
+func (t *TX) StopTx() bool {
+	if !t.txEnabled {
+		return false
+	}
+	t.txEnabled = false
+	t.txNow = false
+	t.txFast = 0
+	t.shutdownTime = time.Now()
+	return true
+}
+
+/*  Transmit state machine is running or not
+ */
+func (t *TX) TxEnabled() bool {
+	return t.txEnabled
+}
+
+/*  somethingChangedLocal: local information is changed, next frame needs to be constructed again
+ *  and send out right away (subject to txCredit)
+ */
+func (t *TX) SomethingChangedLocal() {
+	t.useCacheFrame = false
+	if t.txEnabled {
+		t.localChange = true
+	}
+}
+
+/*  Update msgTxInterval, msgTxHold & reinitDelay. TTL is part of the frame and hence frame is
+ *  constructed again and send out right away, which also restarts the tx timer with new interval
+ */
+func (t *TX) SetTimers(interval, hold, reinitDelay int) {
+	if t.MessageTxInterval == interval && t.MessageTxHoldMultiplier == hold && t.reinitDelay == reinitDelay {
+		return
+	}
+	t.MessageTxInterval = interval
+	t.MessageTxHoldMultiplier = hold
+	t.reinitDelay = reinitDelay
+	t.ttl = Min(LLDP_MAX_TTL, t.MessageTxInterval*t.MessageTxHoldMultiplier+1)
+	if t.txTTR > t.MessageTxInterval {
+		t.txTTR = t.MessageTxInterval
+	}
+	t.SomethingChangedLocal()
+}
+
+/*  newNeighbor: new neighbor is learned on the port and hence start fast transmission
+ */
+func (t *TX) NewNeighbor() {
+	if t.txEnabled {
+		t.newNeighbor = true
+	}
+}
+
+/*  Run timer state machine & transmit state machine for the port. tick is set when the api is called
+ *  on every txTick (1 second), otherwise only pending signals (newNeighbor, localChange) are handled.
+ *  Returns true if lldp frame needs to be send out, txCredit is consumed for that frame
+ */
+func (t *TX) Run(tick bool) bool {
+	if !t.txEnabled {
+		return false
+	}
+	// wait for reinitDelay before we start sending frames after shutdown
+	if time.Since(t.shutdownTime) < time.Duration(t.reinitDelay)*time.Second {
+		return false
+	}
+	// TX_TICK
+	if tick {
+		if t.txCredit < t.txCreditMax {
+			t.txCredit++
+		}
+		if t.txTTR > 0 {
+			t.txTTR--
+		}
+	}
+	// TX_FAST_START
+	if t.newNeighbor {
+		t.newNeighbor = false
+		if t.txFast == 0 {
+			t.txFast = t.txFastInit
+		}
+		t.txTTR = 0
+	}
+	if t.txTTR == 0 {
+		// TX_TIMER_EXPIRES
+		if t.txFast > 0 {
+			t.txFast--
+		}
+		t.signalTx()
+	} else if t.localChange {
+		t.signalTx()
+	}
+	// TX_INFO_FRAME
+	if t.txNow && t.txCredit > 0 {
+		t.txCredit--
+		t.txNow = false
+		return true
+	}
+	return false
+}
+
+/*  SIGNAL_TX: request transmission and restart time to next regular transmission based on fast
+ *  transmission is in progress or not
+ */
+func (t *TX) signalTx() {
+	t.txNow = true
+	t.localChange = false
+	if t.txFast > 0 {
+		t.txTTR = t.msgFastTx
+	} else {
+		t.txTTR = t.MessageTxInterval
+	}
+}
+
+/*  Start fast transmission with given repeat count, used by LLDP-MED fast start
+ */
+func (t *TX) startFastTx(count int) {
+	if !t.txEnabled {
+		return
+	}
+	if t.txFast < count {
+		t.txFast = count
+	}
+	t.txTTR = 0
+}
+
+/*  Shutdown LLDPDU: chassis id, port id and TTL with value 0, so that peer can age us out right away
+ */
+func (t *TX) ShutdownFrame(port config.PortInfo, sysInfo *config.SystemInfo) []byte {
+	srcmac, _ := net.ParseMAC(port.MacAddr)
+	pkt := t.encodeFrame(srcmac, port, sysInfo, 0)
+	if pkt == nil {
+		debug.Logger.Err("Creating shutdown payload failed for port", port)
+	}
+	return pkt
+}
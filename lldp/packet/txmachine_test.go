@@ -0,0 +1,147 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package packet
+
+import (
+	"testing"
+	"time"
+)
+
+// transmit state machine events applied on every step of the test
+const (
+	txEventNone        = iota // run with pending signals only
+	txEventTick               // txTick
+	txEventLocal              // somethingChangedLocal
+	txEventNeighbor           // newNeighbor
+	txEventFastStart          // LLDP-MED fast start
+	txEventRestart            // port is disabled & enabled again
+	txEventReinitDelay        // reinitDelay is expired
+)
+
+type txStep struct {
+	event int
+	send  bool
+}
+
+func TestTxMachine(t *testing.T) {
+	testSetup()
+	tests := []struct {
+		name      string
+		interval  int
+		creditMax int
+		steps     []txStep
+		credit    int
+		fast      int
+	}{
+		{
+			name:     "first frame right away and then every msgTxInterval",
+			interval: 3,
+			steps: []txStep{{txEventNone, true}, {txEventTick, false}, {txEventTick, false},
+				{txEventTick, true}, {txEventTick, false}, {txEventTick, false}, {txEventTick, true}},
+			credit: LLDP_DEFAULT_TX_CREDIT_MAX - 1,
+		},
+		{
+			name:     "local changes are limited by txCredit",
+			interval: 30,
+			steps: []txStep{{txEventNone, true}, {txEventLocal, true}, {txEventLocal, true},
+				{txEventLocal, true}, {txEventLocal, true}, {txEventLocal, false}, {txEventNone, false},
+				// pending frame is send once txCredit is given back by txTick
+				{txEventTick, true}, {txEventLocal, false}, {txEventTick, true}, {txEventTick, false}},
+			credit: 1,
+		},
+		{
+			name:      "txCreditMax",
+			interval:  30,
+			creditMax: 2,
+			steps: []txStep{{txEventNone, true}, {txEventLocal, true}, {txEventLocal, false},
+				{txEventTick, true}, {txEventTick, false}, {txEventTick, false}, {txEventTick, false}},
+			credit: 2,
+		},
+		{
+			name:     "new neighbor starts txFastInit frames every msgFastTx",
+			interval: 30,
+			steps: []txStep{{txEventNone, true}, {txEventTick, false}, {txEventNeighbor, true},
+				{txEventTick, true}, {txEventTick, true}, {txEventTick, true}, {txEventTick, false},
+				{txEventTick, false}},
+			credit: LLDP_DEFAULT_TX_CREDIT_MAX,
+		},
+		{
+			name:     "new neighbor during fast transmission does not restart it",
+			interval: 30,
+			steps: []txStep{{txEventNone, true}, {txEventNeighbor, true}, {txEventTick, true},
+				{txEventNeighbor, true}, {txEventTick, true}, {txEventTick, false}},
+			credit: 3,
+		},
+		{
+			name:     "MED fast start",
+			interval: 30,
+			steps: []txStep{{txEventNone, true}, {txEventFastStart, true}, {txEventTick, true},
+				{txEventTick, true}, {txEventTick, true}, {txEventTick, false}},
+			credit: 4,
+		},
+		{
+			name:     "no frame is send till reinitDelay after port is enabled again",
+			interval: 30,
+			steps: []txStep{{txEventNone, true}, {txEventRestart, false}, {txEventTick, false},
+				{txEventLocal, false}, {txEventReinitDelay, true}, {txEventLocal, true}},
+			credit: LLDP_DEFAULT_TX_CREDIT_MAX - 2,
+		},
+	}
+	for _, tc := range tests {
+		tx := TxInit(tc.interval, 4, LLDP_PROTO_DST_MAC)
+		if tc.creditMax > 0 {
+			tx.txCreditMax = tc.creditMax
+		}
+		if tx.Run(true) {
+			t.Error(tc.name, "frame send before transmit state machine is started")
+		}
+		tx.StartTx()
+		for idx, step := range tc.steps {
+			tick := false
+			switch step.event {
+			case txEventTick:
+				tick = true
+			case txEventLocal:
+				tx.SomethingChangedLocal()
+			case txEventNeighbor:
+				tx.NewNeighbor()
+			case txEventFastStart:
+				tx.startFastTx(LLDP_MED_FAST_START_REPEAT_COUNT)
+			case txEventRestart:
+				if !tx.StopTx() || tx.StopTx() {
+					t.Error(tc.name, "step", idx, "shutdown frame is expected only once")
+				}
+				tx.StartTx()
+			case txEventReinitDelay:
+				tx.shutdownTime = tx.shutdownTime.Add(-time.Duration(tx.reinitDelay) * time.Second)
+			}
+			if send := tx.Run(tick); send != step.send {
+				t.Error(tc.name, "step", idx, "expected send", step.send, "actual", send)
+			}
+		}
+		if tx.txCredit != tc.credit || tx.txFast != tc.fast {
+			t.Error(tc.name, "expected txCredit", tc.credit, "txFast", tc.fast, "actual", tx.txCredit, tx.txFast)
+		}
+	}
+}
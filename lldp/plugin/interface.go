@@ -33,6 +33,14 @@ type AsicIntf interface {
 	Start()
 }
 
+/*  Optional interface of the asic plugin, implemented by plugins which are able to trap the lldp frames sent to
+ *  the nearest bridge, nearest non-TPMR and nearest customer bridge addresses and drop them instead of
+ *  forwarding them. Snoop and drop is rejected when the asic plugin does not implement it
+ */
+type SnoopAndDropIntf interface {
+	SetSnoopAndDrop(enable bool) error
+}
+
 type ConfigIntf interface {
 	Start() error
 }
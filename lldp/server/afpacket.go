@@ -0,0 +1,288 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"l2/lldp/config"
+	"l2/lldp/utils"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	LLDP_ETH_PROTOCOL = 0x88cc
+	// CDP frames are 802.3 frames with LLC/SNAP header, socket is bound to all protocols
+	ETH_P_ALL       = 0x0003
+	ETH_P_802_2     = 0x0004
+	ETH_P_802_3_MIN = 0x0600
+	CDP_DST_MAC_HI  = 0x01000ccc
+	CDP_DST_MAC_LO  = 0xcccc
+)
+
+/*  Shared AF_PACKET packet I/O, one raw socket for all the ports. Socket filters lldp ethertype & CDP
+ *  destination mac on all the interfaces and frames are demultiplexed by the ifindex on which they are received.
+ *  Socket is registered with go runtime poller so there are no wakeups unless a frame is received
+ */
+type afPacketIO struct {
+	sync.Mutex
+	fd          int
+	file        *os.File
+	conn        syscall.RawConn
+	snapshotLen int
+	promiscuous bool
+	// open ports keyed by kernel ifindex
+	ports map[int]*afPacketHandle
+}
+
+type afPacketHandle struct {
+	pio     *afPacketIO
+	name    string
+	ifIndex int32
+	// kernel ifindex of the port, lldp ifIndex is allocated by asic plugin and might not match it
+	osIfIndex int
+	// rx channel, nil when rx is not running
+	rxCh chan InPktChannel
+}
+
+// struct packet_mreq
+type packetMreq struct {
+	Ifindex int32
+	Type    uint16
+	Alen    uint16
+	Address [8]byte
+}
+
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
+
+/*  Create raw socket with bpf filter for lldp ethertype & CDP frames and start the shared rx go routine
+ */
+func NewAfPacketIO(snapshotLen int, promiscuous bool) (PktIO, error) {
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW|syscall.SOCK_NONBLOCK|syscall.SOCK_CLOEXEC,
+		int(htons(ETH_P_ALL)))
+	if err != nil {
+		debug.Logger.Err("Creating AF_PACKET socket failed, Error:", err)
+		return nil, err
+	}
+	// ether proto 0x88cc or ether dst 01:00:0c:cc:cc:cc
+	filter := []syscall.SockFilter{
+		*syscall.LsfStmt(syscall.BPF_LD|syscall.BPF_H|syscall.BPF_ABS, 12),
+		*syscall.LsfJump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, LLDP_ETH_PROTOCOL, 4, 0),
+		*syscall.LsfStmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, 0),
+		*syscall.LsfJump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, CDP_DST_MAC_HI, 0, 3),
+		*syscall.LsfStmt(syscall.BPF_LD|syscall.BPF_H|syscall.BPF_ABS, 4),
+		*syscall.LsfJump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, CDP_DST_MAC_LO, 0, 1),
+		*syscall.LsfStmt(syscall.BPF_RET|syscall.BPF_K, snapshotLen),
+		*syscall.LsfStmt(syscall.BPF_RET|syscall.BPF_K, 0),
+	}
+	err = syscall.AttachLsf(fd, filter)
+	if err != nil {
+		debug.Logger.Err("setting filter:", LLDP_BPF_FILTER, "failed with error:", err)
+		syscall.Close(fd)
+		return nil, err
+	}
+	pio := &afPacketIO{
+		fd:          fd,
+		file:        os.NewFile(uintptr(fd), "lldp"),
+		snapshotLen: snapshotLen,
+		promiscuous: promiscuous,
+		ports:       make(map[int]*afPacketHandle),
+	}
+	pio.conn, err = pio.file.SyscallConn()
+	if err != nil {
+		pio.file.Close()
+		return nil, err
+	}
+	go pio.receiveFrames()
+	return pio, nil
+}
+
+/*  Join the port to the socket, in promiscuous mode port is put in promiscuous mode or else lldp
+ *  multicast addresses of all the agents are added to the port
+ */
+func (pio *afPacketIO) Open(port *config.PortInfo) (PktHandle, error) {
+	netIntf, err := net.InterfaceByName(port.Name)
+	if err != nil {
+		debug.Logger.Err("Getting kernel ifindex failed for", port.Name, "Error:", err)
+		return nil, err
+	}
+	pio.Lock()
+	defer pio.Unlock()
+	if _, exists := pio.ports[netIntf.Index]; exists {
+		return nil, errors.New("Packet handle already open for " + port.Name)
+	}
+	err = pio.membership(netIntf.Index, syscall.PACKET_ADD_MEMBERSHIP)
+	if err != nil {
+		debug.Logger.Err(fmt.Sprintln("Adding membership for", port.Name, "failed with error:", err))
+		pio.membership(netIntf.Index, syscall.PACKET_DROP_MEMBERSHIP)
+		return nil, err
+	}
+	ph := &afPacketHandle{
+		pio:       pio,
+		name:      port.Name,
+		ifIndex:   port.IfIndex,
+		osIfIndex: netIntf.Index,
+	}
+	pio.ports[netIntf.Index] = ph
+	debug.Logger.Debug("AF_PACKET handle created for port:", port.Name, "ifIndex:", port.IfIndex,
+		"kernel ifIndex:", netIntf.Index)
+	return ph, nil
+}
+
+/*  Add or drop packet socket membership for the port
+ */
+func (pio *afPacketIO) membership(osIfIndex int, op int) error {
+	var mreqs []packetMreq
+	if pio.promiscuous {
+		mreqs = append(mreqs, packetMreq{Ifindex: int32(osIfIndex), Type: syscall.PACKET_MR_PROMISC})
+	} else {
+		for _, agentType := range agentTypes {
+			mac, _ := net.ParseMAC(AgentDstMAC(agentType))
+			mreq := packetMreq{Ifindex: int32(osIfIndex), Type: syscall.PACKET_MR_MULTICAST, Alen: uint16(len(mac))}
+			copy(mreq.Address[:], mac)
+			mreqs = append(mreqs, mreq)
+		}
+	}
+	var err error
+	for _, mreq := range mreqs {
+		_, _, errno := syscall.Syscall6(syscall.SYS_SETSOCKOPT, uintptr(pio.fd), syscall.SOL_PACKET,
+			uintptr(op), uintptr(unsafe.Pointer(&mreq)), unsafe.Sizeof(mreq), 0)
+		if errno != 0 {
+			err = errno
+		}
+	}
+	return err
+}
+
+/*  Shared rx go routine, frames are handed over to the port only when rx is running on it. Rx channel is
+ *  never blocked on while holding the lock, server might be stopping rx on the same port
+ */
+func (pio *afPacketIO) receiveFrames() {
+	buf := make([]byte, pio.snapshotLen)
+	for {
+		var n int
+		var from syscall.Sockaddr
+		var rerr error
+		err := pio.conn.Read(func(fd uintptr) bool {
+			n, from, rerr = syscall.Recvfrom(int(fd), buf, 0)
+			return rerr != syscall.EAGAIN
+		})
+		if err != nil {
+			debug.Logger.Info("AF_PACKET socket closed, rx exiting go routine")
+			return
+		}
+		if rerr != nil {
+			debug.Logger.Err("Receiving frame failed, Error:", rerr)
+			continue
+		}
+		sll, ok := from.(*syscall.SockaddrLinklayer)
+		if !ok || sll.Pkttype == syscall.PACKET_OUTGOING || n < LLDP_MIN_FRAME_LENGTH {
+			continue
+		}
+		data := make([]byte, n)
+		copy(data, buf[:n])
+		pio.Lock()
+		ph, exists := pio.ports[sll.Ifindex]
+		if exists && ph.rxCh != nil {
+			select {
+			case ph.rxCh <- InPktChannel{gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default),
+				ph.ifIndex}:
+			default:
+				debug.Logger.Debug("Rx channel full, dropping frame received on", ph.name)
+			}
+		}
+		pio.Unlock()
+	}
+}
+
+/*  Close the socket, rx go routine exits as the socket is removed from poller
+ */
+func (pio *afPacketIO) Close() {
+	pio.Lock()
+	pio.ports = make(map[int]*afPacketHandle)
+	pio.Unlock()
+	pio.file.Close()
+}
+
+func (ph *afPacketHandle) StartRx(lldpRxPktCh chan InPktChannel) {
+	ph.pio.Lock()
+	ph.rxCh = lldpRxPktCh
+	ph.pio.Unlock()
+}
+
+func (ph *afPacketHandle) StopRx() {
+	ph.pio.Lock()
+	ph.rxCh = nil
+	ph.pio.Unlock()
+	debug.Logger.Info("quit for ifIndex", ph.name, "rx stopped")
+}
+
+/*  Send frame out of the port, frame already has ethernet header
+ */
+func (ph *afPacketHandle) WritePacket(pkt []byte) error {
+	if len(pkt) < LLDP_MIN_FRAME_LENGTH {
+		return errors.New("Invalid frame length for " + ph.name)
+	}
+	sll := &syscall.SockaddrLinklayer{
+		Protocol: htons(LLDP_ETH_PROTOCOL),
+		Ifindex:  ph.osIfIndex,
+		Halen:    6,
+	}
+	// 802.3 length instead of ethertype, CDP frame
+	if binary.BigEndian.Uint16(pkt[12:14]) < ETH_P_802_3_MIN {
+		sll.Protocol = htons(ETH_P_802_2)
+	}
+	copy(sll.Addr[:], pkt[0:6])
+	var werr error
+	err := ph.pio.conn.Write(func(fd uintptr) bool {
+		werr = syscall.Sendto(int(fd), pkt, 0, sll)
+		return werr != syscall.EAGAIN
+	})
+	if err != nil {
+		return err
+	}
+	return werr
+}
+
+/*  Stop rx and drop port membership from the socket
+ */
+func (ph *afPacketHandle) Close() {
+	ph.pio.Lock()
+	defer ph.pio.Unlock()
+	ph.rxCh = nil
+	if cur, exists := ph.pio.ports[ph.osIfIndex]; !exists || cur != ph {
+		return
+	}
+	delete(ph.pio.ports, ph.osIfIndex)
+	ph.pio.membership(ph.osIfIndex, syscall.PACKET_DROP_MEMBERSHIP)
+}
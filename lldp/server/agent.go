@@ -0,0 +1,261 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"l2/lldp/config"
+	"l2/lldp/packet"
+	"l2/lldp/utils"
+)
+
+// LLDP agent running on the port, one agent per destination mac address
+type LLDPAgent struct {
+	AgentType uint8
+	// rx information
+	RxInfo *packet.RX
+	// tx information
+	TxInfo *packet.TX
+	// agent is running only for snooping frames as snoop and drop is enabled and agent is not
+	// configured on the port, no frames are send out by such agent
+	snoop bool
+}
+
+// order in which agents are created on the port, nearest bridge agent is always first
+var agentTypes = []uint8{
+	config.AGENT_NEAREST_BRIDGE,
+	config.AGENT_NEAREST_NON_TPMR,
+	config.AGENT_NEAREST_CUSTOMER,
+	config.AGENT_CDP,
+}
+
+func AgentDstMAC(agentType uint8) string {
+	switch agentType {
+	case config.AGENT_NEAREST_NON_TPMR:
+		return packet.LLDP_NEAREST_NON_TPMR_DST_MAC
+	case config.AGENT_NEAREST_CUSTOMER:
+		return packet.LLDP_NEAREST_CUSTOMER_DST_MAC
+	case config.AGENT_CDP:
+		return packet.LLDP_CDP_DST_MAC
+	}
+	return packet.LLDP_PROTO_DST_MAC
+}
+
+func AgentTypeToString(agentType uint8) string {
+	switch agentType {
+	case config.AGENT_NEAREST_NON_TPMR:
+		return config.AGENT_NearestNonTpmrBridge
+	case config.AGENT_NEAREST_CUSTOMER:
+		return config.AGENT_NearestCustomerBridge
+	case config.AGENT_CDP:
+		return config.AGENT_Cdp
+	}
+	return config.AGENT_NearestBridge
+}
+
+/*  Get agent running on the port for agent type
+ */
+func (intf *LLDPGlobalInfo) GetAgent(agentType uint8) *LLDPAgent {
+	for _, agent := range intf.Agents {
+		if agent.AgentType == agentType {
+			return agent
+		}
+	}
+	return nil
+}
+
+/*  Get agent running on the port for destination mac address of received frame
+ */
+func (intf *LLDPGlobalInfo) GetAgentByDstMAC(dstMAC string) *LLDPAgent {
+	for _, agent := range intf.Agents {
+		if agent.RxInfo.DstMAC.String() == dstMAC {
+			return agent
+		}
+	}
+	return nil
+}
+
+/*  Create/Delete agents on the port based on agent configuration and snoop and drop mode. Agents which
+ *  are deleted are returned back so that caller can send shutdown frame and delete the neighbors
+ */
+func (intf *LLDPGlobalInfo) SetAgents(snoop bool) []*LLDPAgent {
+	var removed []*LLDPAgent
+	agents := make([]*LLDPAgent, 0, len(agentTypes))
+	for _, agentType := range agentTypes {
+		configured := intf.agentTypes&agentType != 0
+		agent := intf.GetAgent(agentType)
+		// CDP agent is never created for snooping, snoop and drop is only for lldp frames
+		if !configured && (!snoop || agentType == config.AGENT_CDP) {
+			if agent != nil {
+				removed = append(removed, agent)
+			}
+			continue
+		}
+		if agent == nil {
+			agent = intf.newAgent(agentType)
+		}
+		agent.snoop = !configured
+		agents = append(agents, agent)
+	}
+	intf.Agents = agents
+	return removed
+}
+
+/*  Create agent for the port with port level configuration
+ */
+func (intf *LLDPGlobalInfo) newAgent(agentType uint8) *LLDPAgent {
+	agent := &LLDPAgent{
+		AgentType: agentType,
+		RxInfo:    packet.RxInit(AgentDstMAC(agentType)),
+		TxInfo:    packet.TxInit(intf.txInterval, intf.txHold, AgentDstMAC(agentType)),
+	}
+	if agentType == config.AGENT_CDP {
+		agent.RxInfo = packet.CdpRxInit()
+		agent.TxInfo = packet.CdpTxInit(intf.txInterval, intf.txHold)
+	}
+	agent.RxInfo.MaxNeighbors = intf.maxNeighbors
+	agent.TxInfo.SetTimers(intf.txInterval, intf.txHold, intf.reinitDelay)
+	agent.TxInfo.SetTLVs(intf.tlvs)
+	agent.TxInfo.SetChassisId(intf.chassisId)
+	agent.TxInfo.SetPortIdSubtype(intf.portIdSubtype)
+	if agentType == config.AGENT_NEAREST_BRIDGE {
+		// LLDP-MED & DCBX are only supported by nearest bridge agent
+		agent.TxInfo.SetMedConfig(intf.medConfig)
+		agent.TxInfo.SetDcbxConfig(intf.dcbxConfig)
+	}
+	return agent
+}
+
+/*  Apply chassis id selection to all the agents on the port
+ */
+func (intf *LLDPGlobalInfo) SetChassisId(chassisId config.ChassisIdConfig) {
+	intf.chassisId = chassisId
+	for _, agent := range intf.Agents {
+		agent.TxInfo.SetChassisId(chassisId)
+	}
+}
+
+/*  Apply port id selection to all the agents on the port
+ */
+func (intf *LLDPGlobalInfo) SetPortIdSubtype(subtype uint8) {
+	intf.portIdSubtype = subtype
+	for _, agent := range intf.Agents {
+		agent.TxInfo.SetPortIdSubtype(subtype)
+	}
+}
+
+/*  Apply optional tlv's selection to all the agents on the port
+ */
+func (intf *LLDPGlobalInfo) SetTLVs(tlvs uint8) {
+	intf.tlvs = tlvs
+	for _, agent := range intf.Agents {
+		agent.TxInfo.SetTLVs(tlvs)
+	}
+}
+
+/*  Apply transmit timers to all the agents on the port
+ */
+func (intf *LLDPGlobalInfo) SetTimers(interval, hold, reinitDelay int) {
+	intf.txInterval = interval
+	intf.txHold = hold
+	intf.reinitDelay = reinitDelay
+	for _, agent := range intf.Agents {
+		agent.TxInfo.SetTimers(interval, hold, reinitDelay)
+	}
+}
+
+/*  Agent can transmit frames only if it is configured on the port and tx is enabled
+ */
+func (agent *LLDPAgent) txAllowed(rxtxMode uint8) bool {
+	return !agent.snoop && rxtxMode != config.RX_ONLY
+}
+
+/*  Apply agent configuration for the port. Agents that are deleted send out shutdown frame and all the
+ *  neighbors learned by them are removed. Transmission is started/stopped for remaining agents if lldp
+ *  is running on the port
+ */
+func (svr *LLDPServer) UpdateAgents(ifIndex int32) {
+	intf, exists := svr.lldpGblInfo[ifIndex]
+	if !exists {
+		return
+	}
+	snoop := svr.Global != nil && svr.Global.SnoopAndDrop
+	intf.RxLock.Lock()
+	removed := intf.SetAgents(snoop)
+	intf.RxLock.Unlock()
+	svr.lldpGblInfo[ifIndex] = intf
+	for _, agent := range removed {
+		debug.Logger.Info("Deleting agent", AgentTypeToString(agent.AgentType), "on port", intf.Port.Name)
+		svr.sendAgentShutdownFrame(ifIndex, agent)
+		svr.deleteAgentNeighbors(ifIndex, agent)
+	}
+	if intf.PktHdl != nil {
+		svr.startAgentsTx(ifIndex, intf.rxtxMode)
+	}
+	svr.UpdateMedEndpoint(ifIndex)
+}
+
+/*  Start transmit state machine for all the agents which are allowed to send frames and stop it for
+ *  others
+ */
+func (svr *LLDPServer) startAgentsTx(ifIndex int32, rxtxMode uint8) {
+	intf, exists := svr.lldpGblInfo[ifIndex]
+	if !exists {
+		return
+	}
+	for _, agent := range intf.Agents {
+		if !agent.txAllowed(rxtxMode) {
+			//TX state machine could have been started due to earlier txrx or tx only modes
+			svr.sendAgentShutdownFrame(ifIndex, agent)
+			continue
+		}
+		if !agent.TxInfo.TxEnabled() {
+			agent.TxInfo.StartTx()
+		}
+	}
+	svr.RunTxMachine(ifIndex, false)
+}
+
+/*  Delete all the neighbors learned by the agent and publish removed event for each of them
+ */
+func (svr *LLDPServer) deleteAgentNeighbors(ifIndex int32, agent *LLDPAgent) {
+	intf, exists := svr.lldpGblInfo[ifIndex]
+	if !exists {
+		return
+	}
+	intf.RxLock.Lock()
+	nbrs := agent.RxInfo.SortedNeighbors()
+	agent.RxInfo.DeleteAllNeighbors()
+	intf.RxLock.Unlock()
+	for _, nbr := range nbrs {
+		svr.UpdateRemTablesStats(config.Removed)
+		svr.checkpointNeighbor(&intf, agent, nbr, config.Removed)
+		svr.NotifyEvent(config.EventInfo{
+			IfIndex:   ifIndex,
+			Agent:     agent.AgentType,
+			RemIndex:  nbr.RemIndex,
+			EventType: config.Removed,
+			Before:    svr.neighborRecord(&intf, agent, nbr),
+		})
+	}
+}
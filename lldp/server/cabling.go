@@ -0,0 +1,158 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"l2/lldp/config"
+	"l2/lldp/packet"
+	"l2/lldp/utils"
+	"sort"
+)
+
+/*  Replace cabling plan and check all the ports against the new plan, entries for unknown ports
+ *  are ignored
+ */
+func (svr *LLDPServer) handleCablingPlan(plan []config.ExpectedNeighbor) {
+	svr.cablingPlan = make(map[int32]config.ExpectedNeighbor, len(plan))
+	for _, expected := range plan {
+		ifIndex, exists := svr.EntryExist(expected.IntfRef)
+		if !exists {
+			debug.Logger.Err("Ignoring cabling plan entry for unknown port", expected.IntfRef)
+			continue
+		}
+		svr.cablingPlan[ifIndex] = expected
+	}
+	for _, ifIndex := range svr.lldpIntfStateSlice {
+		svr.CheckCabling(ifIndex)
+	}
+}
+
+/*  Get cabling plan, one entry per port sorted by ifIndex
+ */
+func (svr *LLDPServer) GetCablingPlan() []config.ExpectedNeighbor {
+	ifIndexes := make([]int, 0, len(svr.cablingPlan))
+	for ifIndex := range svr.cablingPlan {
+		ifIndexes = append(ifIndexes, int(ifIndex))
+	}
+	sort.Ints(ifIndexes)
+	plan := make([]config.ExpectedNeighbor, 0, len(ifIndexes))
+	for _, ifIndex := range ifIndexes {
+		expected := svr.cablingPlan[int32(ifIndex)]
+		if intf, exists := svr.lldpGblInfo[int32(ifIndex)]; exists {
+			expected.IntfRef = intf.Port.Name
+		}
+		plan = append(plan, expected)
+	}
+	return plan
+}
+
+/*  Port is up & running lldp or not
+ */
+func (svr *LLDPServer) isPortUp(ifIndex int32) bool {
+	for _, upIfIndex := range svr.lldpUpIntfStateSlice {
+		if upIfIndex == ifIndex {
+			return true
+		}
+	}
+	return false
+}
+
+/*  Compare neighbors learned by nearest bridge agent with expected neighbor, port matches if any of
+ *  the neighbors is the expected one. First neighbor is returned in case of mismatch
+ */
+func (svr *LLDPServer) cablingStatus(ifIndex int32, expected config.ExpectedNeighbor) (string, *packet.Neighbor) {
+	intf, exists := svr.lldpGblInfo[ifIndex]
+	if !exists || !svr.isPortUp(ifIndex) {
+		return config.CABLING_Missing, nil
+	}
+	agent := intf.GetAgent(config.AGENT_NEAREST_BRIDGE)
+	if agent == nil {
+		return config.CABLING_Missing, nil
+	}
+	intf.RxLock.RLock()
+	defer intf.RxLock.RUnlock()
+	nbrs := agent.RxInfo.SortedNeighbors()
+	if len(nbrs) == 0 {
+		return config.CABLING_Missing, nil
+	}
+	for _, nbr := range nbrs {
+		if intf.GetPeerHostName(nbr) != expected.SystemName {
+			continue
+		}
+		if expected.PortId == "" || intf.GetPortIdInfo(nbr) == expected.PortId {
+			return config.CABLING_Match, nbr
+		}
+	}
+	return config.CABLING_Mismatch, nbrs[0]
+}
+
+/*  Check the port against cabling plan and publish event when port goes out of compliance, i.e
+ *  unexpected neighbor is learned or expected neighbor is lost. Ports which were never in compliance
+ *  are not reported when neighbor is missing as that is the state till neighbors are learned
+ */
+func (svr *LLDPServer) CheckCabling(ifIndex int32) {
+	expected, planned := svr.cablingPlan[ifIndex]
+	prev := svr.cablingStatusInfo[ifIndex]
+	if !planned {
+		delete(svr.cablingStatusInfo, ifIndex)
+		return
+	}
+	status, nbr := svr.cablingStatus(ifIndex, expected)
+	if status == prev {
+		return
+	}
+	svr.cablingStatusInfo[ifIndex] = status
+	intf, _ := svr.lldpGblInfo[ifIndex]
+	debug.Logger.Info("Cabling status of port", intf.Port.Name, "changed from", prev, "to", status)
+	if status == config.CABLING_Match || (status == config.CABLING_Missing && prev != config.CABLING_Match) {
+		return
+	}
+	eventInfo := config.EventInfo{
+		IfIndex:   ifIndex,
+		Agent:     config.AGENT_NEAREST_BRIDGE,
+		EventType: config.CablingMismatch,
+		After:     &config.IntfState{},
+	}
+	svr.populateLocalInfo(&intf, eventInfo.After)
+	if nbr != nil {
+		eventInfo.RemIndex = nbr.RemIndex
+		intf.RxLock.RLock()
+		intf.populatePeerInfo(intf.GetAgent(config.AGENT_NEAREST_BRIDGE), nbr, eventInfo.After)
+		intf.RxLock.RUnlock()
+	}
+	svr.SysPlugin.PublishEvent(eventInfo)
+	svr.publishToSubscribers(eventInfo)
+}
+
+/*  helper function to populate cabling plan information of the port
+ */
+func (svr *LLDPServer) populateCablingInfo(ifIndex int32, entry *config.IntfState) {
+	expected, planned := svr.cablingPlan[ifIndex]
+	if !planned {
+		return
+	}
+	entry.CablingStatus = svr.cablingStatusInfo[ifIndex]
+	entry.ExpectedPeerHostName = expected.SystemName
+	entry.ExpectedPeerPort = expected.PortId
+}
@@ -0,0 +1,149 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"l2/lldp/config"
+	"testing"
+)
+
+func checkCablingStatus(t *testing.T, svr *LLDPServer, intfRef string, status string) {
+	state := svr.GetIntfState(intfRef)
+	if state.CablingStatus != status {
+		t.Error("expected cabling status", status, "on", intfRef, "actual", state.CablingStatus)
+	}
+}
+
+func cablingEvents(svr *LLDPServer) []config.EventInfo {
+	var events []config.EventInfo
+	for _, event := range svr.SysPlugin.(*testSysPlugin).events {
+		if event.EventType == config.CablingMismatch {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestLLDPCablingMatch(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	a.handleCablingPlan([]config.ExpectedNeighbor{{IntfRef: "eth1", SystemName: "B", PortId: "eth2"}})
+	checkCablingStatus(t, a, "eth1", config.CABLING_Missing)
+
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	pump(a, b)
+	checkCablingStatus(t, a, "eth1", config.CABLING_Match)
+	// port not in plan
+	checkCablingStatus(t, b, "eth2", "")
+	state := a.GetIntfState("eth1")
+	if state.ExpectedPeerHostName != "B" || state.ExpectedPeerPort != "eth2" {
+		t.Error("expected neighbor B eth2 actual", state.ExpectedPeerHostName, state.ExpectedPeerPort)
+	}
+	if len(cablingEvents(a)) != 0 {
+		t.Error("expected no cabling event actual", cablingEvents(a))
+	}
+
+	// expected neighbor lost
+	updateIntfConfig(b, "eth2", func(intfCfg *config.IntfConfig) {
+		intfCfg.Enable = false
+	})
+	pump(a, b)
+	checkCablingStatus(t, a, "eth1", config.CABLING_Missing)
+	events := cablingEvents(a)
+	if len(events) != 1 || events[0].After.CablingStatus != config.CABLING_Missing {
+		t.Fatal("expected one cabling missing event actual", events)
+	}
+}
+
+func TestLLDPCablingMismatch(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	pump(a, b)
+
+	// any peer port matches if port id is not part of the plan
+	a.handleCablingPlan([]config.ExpectedNeighbor{{IntfRef: "eth1", SystemName: "B"}})
+	checkCablingStatus(t, a, "eth1", config.CABLING_Match)
+
+	a.handleCablingPlan([]config.ExpectedNeighbor{{IntfRef: "eth1", SystemName: "B", PortId: "eth9"}})
+	checkCablingStatus(t, a, "eth1", config.CABLING_Mismatch)
+	events := cablingEvents(a)
+	if len(events) != 1 {
+		t.Fatal("expected one cabling event actual", events)
+	}
+	after := events[0].After
+	if after.CablingStatus != config.CABLING_Mismatch || after.PeerHostName != "B" || after.PeerPort != "eth2" ||
+		after.ExpectedPeerPort != "eth9" {
+		t.Error("expected mismatch with peer B eth2 instead of B eth9 actual", after.CablingStatus,
+			after.PeerHostName, after.PeerPort, after.ExpectedPeerPort)
+	}
+
+	// neighbor renamed as per plan
+	a.handleCablingPlan([]config.ExpectedNeighbor{{IntfRef: "eth1", SystemName: "B-new", PortId: "eth2"}})
+	checkCablingStatus(t, a, "eth1", config.CABLING_Mismatch)
+	sysInfo := *b.SysInfo
+	sysInfo.Hostname = "B-new"
+	b.UpdateCache(&sysInfo)
+	tick(1, a, b)
+	checkCablingStatus(t, a, "eth1", config.CABLING_Match)
+	if len(cablingEvents(a)) != 1 {
+		t.Error("expected no new cabling event actual", cablingEvents(a))
+	}
+
+	// plan removed
+	a.handleCablingPlan(nil)
+	checkCablingStatus(t, a, "eth1", "")
+}
+
+func TestLLDPCablingWrongPeer(t *testing.T) {
+	OnlyForTestSetup()
+	mn := NewMemNetwork()
+	a := newTestServer(mn, 1, "A", "eth1")
+	b := newTestServer(mn, 2, "B", "eth1")
+	c := newTestServer(mn, 3, "C", "eth1")
+	defer OnlyForTestTeardown(a, b, c)
+	mn.Connect(a.pktIO, "eth1", b.pktIO, "eth1")
+	a.handleCablingPlan([]config.ExpectedNeighbor{{IntfRef: "eth1", SystemName: "B", PortId: "eth1"}})
+	for _, svr := range []*LLDPServer{a, b, c} {
+		startTestServer(svr, config.TXRX)
+	}
+	pump(a, b, c)
+	checkCablingStatus(t, a, "eth1", config.CABLING_Match)
+
+	// cable moved to c, b is removed by shutdown frame and c is learned
+	updateIntfConfig(b, "eth1", func(intfCfg *config.IntfConfig) {
+		intfCfg.Enable = false
+	})
+	mn.Connect(a.pktIO, "eth1", c.pktIO, "eth1")
+	pump(a, b, c)
+	tick(LLDP_DEFAULT_TX_INTERVAL, a, b, c)
+	checkCablingStatus(t, a, "eth1", config.CABLING_Mismatch)
+	events := cablingEvents(a)
+	if len(events) != 2 || events[0].After.CablingStatus != config.CABLING_Missing ||
+		events[1].After.PeerHostName != "C" {
+		t.Error("expected missing & mismatch with C cabling events actual", events)
+	}
+}
@@ -0,0 +1,152 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"l2/lldp/config"
+	"l2/lldp/packet"
+	"testing"
+)
+
+/*  Neighbor learned by given agent on the port
+ */
+func agentNeighbor(svr *LLDPServer, intfRef string, agent string) *config.IntfState {
+	for _, nbr := range svr.GetIntfNeighbors(intfRef) {
+		if nbr.Agent == agent {
+			return &nbr
+		}
+	}
+	return nil
+}
+
+func TestLLDPCdpLearnNeighbor(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	intf := b.lldpGblInfo[1]
+	intf.Port.Vlans = []config.VlanInfo{{VlanId: 100, Untagged: true}, {VlanId: 200}}
+	b.lldpGblInfo[1] = intf
+	b.SysInfo.SwVersion = "1.0.1"
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	for _, svr := range []*LLDPServer{a, b} {
+		updateIntfConfig(svr, svr.asicPlugin.GetPortsInfo()[0].Name, func(intfCfg *config.IntfConfig) {
+			intfCfg.Agents = config.AGENT_NEAREST_BRIDGE | config.AGENT_CDP
+		})
+	}
+	tick(1, a, b)
+
+	// lldp & CDP neighbors are learned side by side
+	if nbrs := a.GetIntfNeighbors("eth1"); len(nbrs) != 2 {
+		t.Fatal("expected lldp & CDP neighbor on eth1 actual", len(nbrs), nbrs)
+	}
+	if agentNeighbor(a, "eth1", config.AGENT_NearestBridge) == nil {
+		t.Error("expected lldp neighbor on eth1 actual", a.GetIntfNeighbors("eth1"))
+	}
+	nbr := agentNeighbor(a, "eth1", config.AGENT_Cdp)
+	if nbr == nil {
+		t.Fatal("expected CDP neighbor on eth1 actual", a.GetIntfNeighbors("eth1"))
+	}
+	if nbr.PeerHostName != "B" || nbr.PeerMac != "B" || nbr.PeerPort != "eth2" {
+		t.Error("expected CDP device id B & port id eth2 actual", nbr.PeerHostName, nbr.PeerMac, nbr.PeerPort)
+	}
+	if nbr.PeerPlatform != "B test system" || nbr.SystemDescription != "1.0.1" {
+		t.Error("expected platform 'B test system' & version 1.0.1 actual", nbr.PeerPlatform,
+			nbr.SystemDescription)
+	}
+	if nbr.PeerPortVlanId != 100 {
+		t.Error("expected native vlan 100 actual", nbr.PeerPortVlanId)
+	}
+	if len(nbr.PeerMgmtAddrs) != 1 || nbr.PeerMgmtAddrs[0].Addr != "10.0.0.2" {
+		t.Error("expected CDP address 10.0.0.2 actual", nbr.PeerMgmtAddrs)
+	}
+	if nbr.SystemCapabilities != nbr.EnabledCapabilities {
+		t.Error("expected CDP capabilities as system & enabled capabilities actual", nbr.SystemCapabilities,
+			nbr.EnabledCapabilities)
+	}
+	event := lastEvent(a)
+	if event == nil || event.EventType != config.Learned || event.After.Agent != config.AGENT_Cdp {
+		t.Error("expected learned event for CDP neighbor actual", event)
+	}
+
+	// platform change updates the neighbor
+	sysInfo := *b.SysInfo
+	sysInfo.Description = "B new platform"
+	b.UpdateCache(&sysInfo)
+	tick(1, a, b)
+	event = lastEvent(a)
+	if event == nil || event.EventType != config.Updated || event.After.Agent != config.AGENT_Cdp ||
+		event.After.PeerPlatform != "B new platform" {
+		t.Fatal("expected updated event for CDP neighbor actual", event)
+	}
+
+	// CDP agent is removed, shutdown frame removes CDP neighbor only
+	updateIntfConfig(b, "eth2", func(intfCfg *config.IntfConfig) {
+		intfCfg.Agents = config.AGENT_NEAREST_BRIDGE
+	})
+	pump(a, b)
+	if agentNeighbor(a, "eth1", config.AGENT_Cdp) != nil {
+		t.Error("expected CDP neighbor to be removed actual", a.GetIntfNeighbors("eth1"))
+	}
+	if agentNeighbor(a, "eth1", config.AGENT_NearestBridge) == nil {
+		t.Error("expected lldp neighbor to stay actual", a.GetIntfNeighbors("eth1"))
+	}
+	event = lastEvent(a)
+	if event == nil || event.EventType != config.Removed || event.Before.Agent != config.AGENT_Cdp {
+		t.Error("expected removed event for CDP neighbor actual", event)
+	}
+}
+
+func TestLLDPCdpNotConfigured(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	a.Global.SnoopAndDrop = true
+	updateIntfConfig(b, "eth2", func(intfCfg *config.IntfConfig) {
+		intfCfg.Agents = config.AGENT_CDP
+	})
+	tick(1, a, b)
+
+	// CDP frames are neither received nor snooped unless CDP agent is configured
+	checkNoNeighbor(t, a, "eth1")
+	if intf := a.lldpGblInfo[1]; intf.GetAgent(config.AGENT_CDP) != nil {
+		t.Error("expected no CDP agent on eth1")
+	}
+	updateIntfConfig(a, "eth1", func(intfCfg *config.IntfConfig) {
+		intfCfg.Agents = config.AGENT_CDP
+	})
+	tick(1, a, b)
+	checkNeighbor(t, a, "eth1", "B", "eth2")
+}
+
+func TestLLDPCdpChecksum(t *testing.T) {
+	// odd length pdu with last byte having high bit set
+	pdu := []byte{0x02, 0xb4, 0x00, 0x00, 0x00, 0x01, 0x00, 0x06, 0x41, 0x90}
+	pdu = append(pdu, 0x81)
+	sum := packet.CdpChecksum(pdu)
+	pdu[2], pdu[3] = byte(sum>>8), byte(sum)
+	if packet.CdpChecksum(pdu) != 0 {
+		t.Error("expected checksum of pdu with checksum to be 0 actual", packet.CdpChecksum(pdu))
+	}
+}
@@ -0,0 +1,187 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"l2/lldp/config"
+	"testing"
+)
+
+func TestLLDPPortIdSubtypeChange(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	pump(a, b)
+
+	// neighbor with old port id is removed by shutdown frame and new one is learned
+	updateIntfConfig(b, "eth2", func(intfCfg *config.IntfConfig) {
+		intfCfg.PortIdSubtype = config.PORT_ID_MAC_ADDR
+	})
+	pump(a, b)
+	checkNeighbor(t, a, "eth1", "B", "00:00:00:00:02:01")
+	if a.remTables.Inserts != 2 || a.remTables.Deletes != 1 {
+		t.Error("expected remote table inserts/deletes 2/1 actual", a.remTables.Inserts, a.remTables.Deletes)
+	}
+	if nbr := a.GetIntfNeighbors("eth1")[0]; nbr.PeerPortIdSubtype != "MAC Address" {
+		t.Error("expected peer port id subtype 'MAC Address' actual", nbr.PeerPortIdSubtype)
+	}
+}
+
+func TestLLDPChassisIdChange(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	pump(a, b)
+
+	b.handleChassisIdConfig(&config.ChassisIdConfig{
+		Subtype: config.CHASSIS_ID_LOCAL,
+		Local:   "chassis-b",
+	})
+	tick(1, a, b)
+	nbrs := a.GetIntfNeighbors("eth1")
+	if len(nbrs) != 1 || nbrs[0].PeerMac != "chassis-b" {
+		t.Fatal("expected one neighbor with chassis id chassis-b actual", nbrs)
+	}
+	if a.remTables.Deletes != 1 {
+		t.Error("expected remote table deletes 1 actual", a.remTables.Deletes)
+	}
+}
+
+func TestLLDPTLVSelection(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	pump(a, b)
+
+	updateIntfConfig(b, "eth2", func(intfCfg *config.IntfConfig) {
+		intfCfg.TLVs = config.TLV_SYSTEM_NAME
+	})
+	tick(1, a, b)
+	checkNeighbor(t, a, "eth1", "B", "eth2")
+	nbr := a.GetIntfNeighbors("eth1")[0]
+	if nbr.SystemDescription != "" || len(nbr.PeerMgmtAddrs) != 0 || nbr.PeerMaxFrameSize != 0 {
+		t.Error("expected only system name tlv from peer actual", nbr.SystemDescription, nbr.PeerMgmtAddrs,
+			nbr.PeerMaxFrameSize)
+	}
+	event := lastEvent(a)
+	if event == nil || event.EventType != config.Updated {
+		t.Error("expected updated event actual", event)
+	}
+}
+
+func TestLLDPAgents(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	pump(a, b)
+
+	agents := uint8(config.AGENT_NEAREST_BRIDGE | config.AGENT_NEAREST_CUSTOMER)
+	for _, svr := range []*LLDPServer{a, b} {
+		for intfRef := range svr.lldpIntfRef2IfIndexMap {
+			updateIntfConfig(svr, intfRef, func(intfCfg *config.IntfConfig) {
+				intfCfg.Agents = agents
+			})
+		}
+	}
+	tick(1, a, b)
+	nbrs := a.GetIntfNeighbors("eth1")
+	if len(nbrs) != 2 {
+		t.Fatal("expected one neighbor per agent actual", len(nbrs))
+	}
+	if nbrs[0].Agent != config.AGENT_NearestBridge || nbrs[1].Agent != config.AGENT_NearestCustomerBridge {
+		t.Error("expected neighbors learned by nearest bridge & nearest customer agents actual",
+			nbrs[0].Agent, nbrs[1].Agent)
+	}
+
+	// neighbors of deleted agent are removed on both ends
+	updateIntfConfig(b, "eth2", func(intfCfg *config.IntfConfig) {
+		intfCfg.Agents = config.AGENT_NEAREST_BRIDGE
+	})
+	pump(a, b)
+	checkNeighbor(t, a, "eth1", "B", "eth2")
+	checkNeighbor(t, b, "eth2", "A", "eth1")
+}
+
+func TestLLDPGlobalDisable(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	pump(a, b)
+
+	a.Global.Enable = false
+	a.handleGlobalConfig()
+	pump(a, b)
+	checkNoNeighbor(t, b, "eth2")
+	if a.counter.Send != 0 || a.counter.Rcvd != 0 {
+		t.Error("expected global counters to be reset actual", a.counter.Send, a.counter.Rcvd)
+	}
+}
+
+func TestLLDPMultipleServers(t *testing.T) {
+	OnlyForTestSetup()
+	mn := NewMemNetwork()
+	a := newTestServer(mn, 1, "A", "eth1", "eth2")
+	b := newTestServer(mn, 2, "B", "eth1")
+	c := newTestServer(mn, 3, "C", "eth1")
+	defer OnlyForTestTeardown(a, b, c)
+	mn.Connect(a.pktIO, "eth1", b.pktIO, "eth1")
+	mn.Connect(a.pktIO, "eth2", c.pktIO, "eth1")
+	for _, svr := range []*LLDPServer{a, b, c} {
+		startTestServer(svr, config.TXRX)
+	}
+	pump(a, b, c)
+	checkNeighbor(t, a, "eth1", "B", "eth1")
+	checkNeighbor(t, a, "eth2", "C", "eth1")
+	checkNeighbor(t, b, "eth1", "A", "eth1")
+	checkNeighbor(t, c, "eth1", "A", "eth2")
+
+	// lldp disabled on the port, shutdown frame is send to the peer
+	updateIntfConfig(a, "eth2", func(intfCfg *config.IntfConfig) {
+		intfCfg.Enable = false
+	})
+	pump(a, b, c)
+	checkNoNeighbor(t, c, "eth1")
+	checkNeighbor(t, b, "eth1", "A", "eth1")
+
+	// cable moved from b to c, b's entry stays till its ttl expires
+	mn.Connect(a.pktIO, "eth1", c.pktIO, "eth1")
+	tick(LLDP_DEFAULT_TX_INTERVAL, a, b, c)
+	checkNeighbor(t, c, "eth1", "A", "eth1")
+	nbrs := a.GetIntfNeighbors("eth1")
+	if len(nbrs) != 2 || nbrs[0].PeerHostName != "B" || nbrs[1].PeerHostName != "C" {
+		t.Error("expected neighbors B & C on eth1 actual", nbrs)
+	}
+
+	// port down, neighbors are reported only for up ports
+	a.UpdateL2IntfStateChange(a.lldpIntfRef2IfIndexMap["eth1"], LLDP_PORT_STATE_DOWN)
+	_, _, nbrs = a.GetNeighbors(0, 10)
+	if len(nbrs) != 0 {
+		t.Error("expected no neighbors as no port is up actual", len(nbrs))
+	}
+}
@@ -24,15 +24,48 @@
 package server
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
 	"l2/lldp/config"
+	"l2/lldp/packet"
 	"l2/lldp/utils"
 	"models/objects"
+	"time"
 )
 
+const (
+	// redis hash holding remote table checkpoint, one field per neighbor
+	LLDP_NEIGHBOR_DB_KEY = "LLDPRemoteTable"
+)
+
+/*  Redis commands used for remote table checkpoint, dbutils.DBUtil provides them
+ */
+type NeighborDB interface {
+	Do(cmd string, args ...interface{}) (interface{}, error)
+}
+
+/*  Checkpoint of remote table entry, last frame received from the neighbor and its receive time. TTL
+ *  is part of the frame
+ */
+type neighborCheckpoint struct {
+	IntfRef  string
+	Agent    string
+	Pkt      []byte
+	RcvdTime time.Time
+	// db field of the checkpoint
+	field string
+}
+
 func (svr *LLDPServer) InitDB() error {
 	var err error
 	debug.Logger.Info("Initializing DB")
+	if svr.lldpDbHdl == nil {
+		// plugins which are not using db, like linux, do not provide db handle
+		return errors.New("No DB handle provided")
+	}
 	err = svr.lldpDbHdl.Connect()
 	if err != nil {
 		debug.Logger.Err(fmt.Sprintln("Failed to Create DB Handle", err))
@@ -40,12 +73,15 @@ func (svr *LLDPServer) InitDB() error {
 		return err
 	}
 	debug.Logger.Info("DB connection is established, error:", err)
+	svr.nbrDB = svr.lldpDbHdl
 	return nil
 }
 
 func (svr *LLDPServer) CloseDB() {
 	debug.Logger.Info("Closed lldp db")
-	svr.lldpDbHdl.Disconnect()
+	if svr.lldpDbHdl != nil {
+		svr.lldpDbHdl.Disconnect()
+	}
 }
 
 func (svr *LLDPServer) readLLDPIntfConfig() {
@@ -97,6 +133,7 @@ func (svr *LLDPServer) readLLDPGlobalConfig() {
 		svr.Global.Vrf = dbEntry.Vrf
 		svr.Global.Enable = dbEntry.Enable
 		svr.Global.TranmitInterval = dbEntry.TranmitInterval
+		svr.Global.SnoopAndDrop = dbEntry.SnoopAndDrop
 	}
 	debug.Logger.Info("Done with LLDPGlobal")
 }
@@ -108,5 +145,125 @@ func (svr *LLDPServer) ReadDB() error {
 	}
 	svr.readLLDPGlobalConfig()
 	svr.readLLDPIntfConfig()
+	svr.readNeighbors()
 	return nil
 }
+
+func neighborField(intfRef string, agent *LLDPAgent, nbr *packet.Neighbor) string {
+	return intfRef + "#" + AgentTypeToString(agent.AgentType) + "#" + packet.NeighborKey(nbr.RxFrame)
+}
+
+/*  Read remote table checkpoint, neighbors are restored per port once rx is started on the port.
+ *  Neighbors whose ttl has expired while lldp was not running are deleted from db
+ */
+func (svr *LLDPServer) readNeighbors() {
+	if svr.nbrDB == nil {
+		return
+	}
+	debug.Logger.Info("Reading remote table checkpoint from db")
+	reply, err := svr.nbrDB.Do("HGETALL", LLDP_NEIGHBOR_DB_KEY)
+	if err != nil {
+		debug.Logger.Err("DB query failed for remote table checkpoint", err)
+		return
+	}
+	values, _ := reply.([]interface{})
+	for idx := 0; idx+1 < len(values); idx += 2 {
+		field, _ := values[idx].([]byte)
+		data, _ := values[idx+1].([]byte)
+		var entry neighborCheckpoint
+		err = json.Unmarshal(data, &entry)
+		if err != nil {
+			debug.Logger.Err("Invalid remote table checkpoint", string(field), "error:", err)
+			svr.deleteNeighborCheckpoint(string(field))
+			continue
+		}
+		entry.field = string(field)
+		svr.restoredNbrs[entry.IntfRef] = append(svr.restoredNbrs[entry.IntfRef], entry)
+	}
+	debug.Logger.Info("Done with remote table checkpoint, neighbors read:", len(values)/2)
+}
+
+/*  Restore neighbors of the port from remote table checkpoint. Neighbors are restored only once, right
+ *  after restart, with remaining ttl and without any event as they were known before restart
+ */
+func (svr *LLDPServer) restoreNeighbors(ifIndex int32) {
+	intf, exists := svr.lldpGblInfo[ifIndex]
+	if !exists {
+		return
+	}
+	entries, exists := svr.restoredNbrs[intf.Port.Name]
+	if !exists {
+		return
+	}
+	delete(svr.restoredNbrs, intf.Port.Name)
+	restored := 0
+	for _, entry := range entries {
+		var agent *LLDPAgent
+		for _, agentType := range agentTypes {
+			if AgentTypeToString(agentType) == entry.Agent {
+				agent = intf.GetAgent(agentType)
+			}
+		}
+		err := errors.New("No agent receiving frames for " + entry.Agent)
+		if agent != nil && intf.RxRunning {
+			pkt := gopacket.NewPacket(entry.Pkt, layers.LayerTypeEthernet, gopacket.Default)
+			intf.RxLock.Lock()
+			var nbr *packet.Neighbor
+			nbr, err = agent.RxInfo.Restore(pkt, entry.RcvdTime)
+			if err == nil {
+				// ttl timer runs for the remaining ttl only
+				nbr.CheckPeerEntry(intf.Port.Name, svr.EventCh, ifIndex, agent.AgentType)
+			}
+			intf.RxLock.Unlock()
+		}
+		if err != nil {
+			debug.Logger.Info("Neighbor", entry.field, "is not restored, error:", err)
+			svr.deleteNeighborCheckpoint(entry.field)
+			continue
+		}
+		svr.UpdateRemTablesStats(config.Learned)
+		restored++
+	}
+	svr.lldpGblInfo[ifIndex] = intf
+	if restored > 0 {
+		svr.UpdateMedEndpoint(ifIndex)
+	}
+	debug.Logger.Info("Restored", restored, "neighbors of port", intf.Port.Name)
+}
+
+/*  Checkpoint remote table entry after the frame from the neighbor is processed, neighbor which is
+ *  removed is deleted from db
+ */
+func (svr *LLDPServer) checkpointNeighbor(intf *LLDPGlobalInfo, agent *LLDPAgent, nbr *packet.Neighbor,
+	eventType int) {
+	if svr.nbrDB == nil || nbr == nil {
+		return
+	}
+	field := neighborField(intf.Port.Name, agent, nbr)
+	if eventType == config.Removed {
+		svr.deleteNeighborCheckpoint(field)
+		return
+	}
+	data, err := json.Marshal(neighborCheckpoint{
+		IntfRef:  intf.Port.Name,
+		Agent:    AgentTypeToString(agent.AgentType),
+		Pkt:      nbr.LastPkt,
+		RcvdTime: nbr.RcvdTime,
+	})
+	if err == nil {
+		_, err = svr.nbrDB.Do("HSET", LLDP_NEIGHBOR_DB_KEY, field, data)
+	}
+	if err != nil {
+		debug.Logger.Err("Checkpoint of neighbor", field, "failed, error:", err)
+	}
+}
+
+func (svr *LLDPServer) deleteNeighborCheckpoint(field string) {
+	if svr.nbrDB == nil {
+		return
+	}
+	_, err := svr.nbrDB.Do("HDEL", LLDP_NEIGHBOR_DB_KEY, field)
+	if err != nil {
+		debug.Logger.Err("Deleting checkpoint of neighbor", field, "failed, error:", err)
+	}
+}
@@ -0,0 +1,194 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"l2/lldp/config"
+	"sync"
+	"testing"
+	"time"
+)
+
+/*  In memory redis hash commands used by remote table checkpoint
+ */
+type testNeighborDB struct {
+	sync.Mutex
+	hashes map[string]map[string][]byte
+}
+
+func newTestNeighborDB() *testNeighborDB {
+	return &testNeighborDB{hashes: make(map[string]map[string][]byte)}
+}
+
+func (db *testNeighborDB) Do(cmd string, args ...interface{}) (interface{}, error) {
+	db.Lock()
+	defer db.Unlock()
+	key := args[0].(string)
+	switch cmd {
+	case "HSET":
+		if db.hashes[key] == nil {
+			db.hashes[key] = make(map[string][]byte)
+		}
+		db.hashes[key][args[1].(string)] = args[2].([]byte)
+		return int64(1), nil
+	case "HDEL":
+		delete(db.hashes[key], args[1].(string))
+		return int64(1), nil
+	case "HGETALL":
+		var reply []interface{}
+		for field, value := range db.hashes[key] {
+			reply = append(reply, []byte(field), value)
+		}
+		return reply, nil
+	}
+	return nil, errors.New("unsupported command " + cmd)
+}
+
+func (db *testNeighborDB) checkpoints() map[string]neighborCheckpoint {
+	db.Lock()
+	defer db.Unlock()
+	entries := make(map[string]neighborCheckpoint)
+	for field, value := range db.hashes[LLDP_NEIGHBOR_DB_KEY] {
+		var entry neighborCheckpoint
+		json.Unmarshal(value, &entry)
+		entries[field] = entry
+	}
+	return entries
+}
+
+/*  Move receive time of all the checkpoints back by given duration
+ */
+func (db *testNeighborDB) age(elapsed time.Duration) {
+	for field, entry := range db.checkpoints() {
+		entry.RcvdTime = entry.RcvdTime.Add(-elapsed)
+		data, _ := json.Marshal(entry)
+		db.Do("HSET", LLDP_NEIGHBOR_DB_KEY, field, data)
+	}
+}
+
+/*  Restart server A of the pair, new server is not connected to any peer and hence knows neighbors only
+ *  from checkpoint
+ */
+func restartTestServer(db *testNeighborDB) *LLDPServer {
+	svr := newTestServer(NewMemNetwork(), 1, "A", "eth1")
+	svr.nbrDB = db
+	svr.readNeighbors()
+	startTestServer(svr, config.TXRX)
+	return svr
+}
+
+func TestLLDPCheckpointRestore(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	db := newTestNeighborDB()
+	a.nbrDB = db
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	pump(a, b)
+	entries := db.checkpoints()
+	if len(entries) != 1 {
+		t.Fatal("expected one checkpoint actual", entries)
+	}
+	for _, entry := range entries {
+		if entry.IntfRef != "eth1" || entry.Agent != config.AGENT_NearestBridge || len(entry.Pkt) == 0 {
+			t.Error("expected checkpoint of nearest bridge neighbor on eth1 actual", entry)
+		}
+	}
+
+	// neighbor is known right after restart with remaining ttl and no event is published
+	db.age(time.Minute)
+	restarted := restartTestServer(db)
+	defer OnlyForTestTeardown(restarted)
+	checkNeighbor(t, restarted, "eth1", "B", "eth2")
+	nbr := restarted.GetIntfNeighbors("eth1")[0]
+	holdTime, err := time.ParseDuration(nbr.HoldTime)
+	if err != nil || holdTime > 61*time.Second || holdTime < 59*time.Second {
+		t.Error("expected remaining hold time of 61 seconds actual", nbr.HoldTime)
+	}
+	if nbr.SystemDescription != "B test system" {
+		t.Error("expected peer system description 'B test system' actual", nbr.SystemDescription)
+	}
+	if event := lastEvent(restarted); event != nil {
+		t.Error("expected no event for restored neighbor actual", event)
+	}
+	if restarted.remTables.Inserts != 1 {
+		t.Error("expected remote table inserts 1 actual", restarted.remTables.Inserts)
+	}
+
+	// peer shutdown deletes the checkpoint
+	updateIntfConfig(b, "eth2", func(intfCfg *config.IntfConfig) {
+		intfCfg.Enable = false
+	})
+	pump(a, b)
+	if entries := db.checkpoints(); len(entries) != 0 {
+		t.Error("expected checkpoint to be deleted actual", entries)
+	}
+}
+
+func TestLLDPCheckpointExpired(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	db := newTestNeighborDB()
+	a.nbrDB = db
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	pump(a, b)
+
+	// ttl of 121 seconds expired while lldp was not running
+	db.age(2*time.Minute + 2*time.Second)
+	restarted := restartTestServer(db)
+	defer OnlyForTestTeardown(restarted)
+	checkNoNeighbor(t, restarted, "eth1")
+	if entries := db.checkpoints(); len(entries) != 0 {
+		t.Error("expected expired checkpoint to be deleted actual", entries)
+	}
+}
+
+func TestLLDPCheckpointAgeOut(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	db := newTestNeighborDB()
+	a.nbrDB = db
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	pump(a, b)
+
+	// restored neighbor ages out once remaining ttl of 1 second is over
+	db.age(120 * time.Second)
+	restarted := restartTestServer(db)
+	defer OnlyForTestTeardown(restarted)
+	checkNeighbor(t, restarted, "eth1", "B", "eth2")
+	start := time.Now()
+	eventInfo := waitAgeOut(t, restarted, 5*time.Second)
+	if time.Since(start) > 3*time.Second {
+		t.Error("ttl timer expired after full ttl instead of remaining ttl, after", time.Since(start))
+	}
+	restarted.AgeOutNeighbor(eventInfo)
+	checkNoNeighbor(t, restarted, "eth1")
+	if entries := db.checkpoints(); len(entries) != 0 {
+		t.Error("expected aged out checkpoint to be deleted actual", entries)
+	}
+}
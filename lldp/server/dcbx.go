@@ -0,0 +1,155 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"bytes"
+	"l2/lldp/config"
+	"l2/lldp/packet"
+	"l2/lldp/utils"
+	"net"
+	"reflect"
+	"sort"
+)
+
+/*  IEEE 802.1Qaz willing negotiation, local configuration is used unless local port is willing:
+ *     ETS: peer recommendation is accepted if advertised
+ *     PFC: peer configuration is accepted if peer is not willing or, when both are willing, if local
+ *          port has numerically lower mac address
+ *     Application Priority: same as PFC using willing bit of peer ETS configuration
+ */
+func negotiateDcbx(local *config.DcbxConfig, peer *config.DcbxPeerInfo, localMac, peerMac net.HardwareAddr,
+	state *config.DcbxState) {
+	state.Ets = local.Ets
+	state.EtsSource = config.DCBX_SOURCE_Local
+	state.PfcEnable = local.PfcEnable
+	state.PfcSource = config.DCBX_SOURCE_Local
+	state.Apps = local.Apps
+	state.AppsSource = config.DCBX_SOURCE_Local
+	if !local.Willing || peer == nil {
+		return
+	}
+	lowerMac := bytes.Compare(localMac, peerMac) < 0
+	if peer.EtsRecommendation != nil {
+		state.Ets = *peer.EtsRecommendation
+		state.EtsSource = config.DCBX_SOURCE_Peer
+	}
+	if peer.PfcValid && (!peer.PfcWilling || lowerMac) {
+		state.PfcEnable = peer.PfcEnable
+		state.PfcSource = config.DCBX_SOURCE_Peer
+	}
+	if peer.Apps != nil && (!peer.EtsWilling || lowerMac) {
+		state.Apps = peer.Apps
+		state.AppsSource = config.DCBX_SOURCE_Peer
+	}
+}
+
+/*  DCB operational parameters of the port, peer is only considered if port is up and exactly one
+ *  neighbor of nearest bridge agent advertised DCBX tlv's
+ */
+func (svr *LLDPServer) dcbxState(ifIndex int32) *config.DcbxState {
+	intf, exists := svr.lldpGblInfo[ifIndex]
+	if !exists {
+		return nil
+	}
+	state := &config.DcbxState{
+		IntfRef: intf.Port.Name,
+		IfIndex: ifIndex,
+		Enable:  intf.dcbxConfig.Enable,
+		Willing: intf.dcbxConfig.Willing,
+	}
+	var peerMac net.HardwareAddr
+	agent := intf.GetAgent(config.AGENT_NEAREST_BRIDGE)
+	if state.Enable && agent != nil && svr.isPortUp(ifIndex) {
+		intf.RxLock.RLock()
+		for _, nbr := range agent.RxInfo.SortedNeighbors() {
+			peer := packet.DecodeDcbx(nbr.RxLinkInfo)
+			if peer == nil {
+				continue
+			}
+			if state.Peer != nil {
+				state.MultiplePeers = true
+				break
+			}
+			state.Peer = peer
+			peerMac = nbr.SrcMAC
+		}
+		intf.RxLock.RUnlock()
+	}
+	var peer *config.DcbxPeerInfo
+	state.PeerDetected = state.Peer != nil && !state.MultiplePeers
+	if state.PeerDetected {
+		peer = state.Peer
+	}
+	localMac, _ := net.ParseMAC(intf.Port.MacAddr)
+	negotiateDcbx(&intf.dcbxConfig, peer, localMac, peerMac, state)
+	return state
+}
+
+/*  Recompute DCB operational parameters of the port and notify subscribers if they changed. Ports
+ *  with DCBX disabled are not notified till DCBX is enabled on them
+ */
+func (svr *LLDPServer) UpdateDcbx(ifIndex int32) {
+	state := svr.dcbxState(ifIndex)
+	if state == nil {
+		delete(svr.dcbxStateInfo, ifIndex)
+		return
+	}
+	prev, exists := svr.dcbxStateInfo[ifIndex]
+	if reflect.DeepEqual(&prev, state) || (!exists && !state.Enable) {
+		return
+	}
+	svr.dcbxStateInfo[ifIndex] = *state
+	debug.Logger.Info("DCB operational parameters of port", state.IntfRef, "changed, ETS from",
+		state.EtsSource, "PFC from", state.PfcSource, "application priority from", state.AppsSource)
+	svr.publishToSubscribers(config.EventInfo{
+		IfIndex:   ifIndex,
+		Agent:     config.AGENT_NEAREST_BRIDGE,
+		EventType: config.DcbxChanged,
+		Dcbx:      state,
+	})
+}
+
+/*  Get DCB operational parameters of the port
+ */
+func (svr *LLDPServer) GetDcbxState(ifIndex int32) *config.DcbxState {
+	return svr.dcbxState(ifIndex)
+}
+
+/*  Get DCB operational parameters of all the ports with DCBX enabled, sorted by ifIndex
+ */
+func (svr *LLDPServer) GetDcbxStates() []config.DcbxState {
+	ifIndexes := make([]int, 0, len(svr.lldpGblInfo))
+	for ifIndex, intf := range svr.lldpGblInfo {
+		if intf.dcbxConfig.Enable {
+			ifIndexes = append(ifIndexes, int(ifIndex))
+		}
+	}
+	sort.Ints(ifIndexes)
+	states := make([]config.DcbxState, 0, len(ifIndexes))
+	for _, ifIndex := range ifIndexes {
+		states = append(states, *svr.dcbxState(int32(ifIndex)))
+	}
+	return states
+}
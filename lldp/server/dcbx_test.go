@@ -0,0 +1,213 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"l2/lldp/config"
+	"reflect"
+	"testing"
+)
+
+func testDcbxConfig(willing bool, pfcEnable uint8, app config.AppPriority) config.DcbxConfig {
+	return config.DcbxConfig{
+		Enable:  true,
+		Willing: willing,
+		MaxTCs:  4,
+		Ets: config.EtsConfig{
+			PriorityTC:  [8]uint8{0, 0, 0, 1, 2, 2, 3, 3},
+			TCBandwidth: [8]uint8{40, 60, 0, 0},
+			TSA:         [8]uint8{config.TSA_ETS, config.TSA_ETS, config.TSA_STRICT, config.TSA_STRICT},
+		},
+		EtsRecommendation: config.EtsConfig{
+			PriorityTC:  [8]uint8{0, 0, 0, 1, 1, 1, 1, 1},
+			TCBandwidth: [8]uint8{pfcEnable, 100 - pfcEnable},
+			TSA:         [8]uint8{config.TSA_ETS, config.TSA_ETS},
+		},
+		PfcCap:    4,
+		PfcEnable: pfcEnable,
+		Apps:      []config.AppPriority{app},
+	}
+}
+
+/*  Drain subscriber channel and return DCBX events
+ */
+func dcbxEvents(eventCh <-chan config.EventInfo) []config.EventInfo {
+	var events []config.EventInfo
+	for {
+		select {
+		case event := <-eventCh:
+			if event.EventType == config.DcbxChanged {
+				events = append(events, event)
+			}
+		default:
+			return events
+		}
+	}
+}
+
+func checkDcbxSources(t *testing.T, state *config.DcbxState, ets, pfc, apps string) {
+	if state.EtsSource != ets || state.PfcSource != pfc || state.AppsSource != apps {
+		t.Error("expected ETS, PFC & application priority from", ets, pfc, apps, "on", state.IntfRef,
+			"actual", state.EtsSource, state.PfcSource, state.AppsSource)
+	}
+}
+
+func TestLLDPDcbxWilling(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	_, eventCh := a.Subscribe()
+	iscsi := config.AppPriority{Selector: config.APP_SEL_TCP, Protocol: 3260, Priority: 4}
+	fcoe := config.AppPriority{Selector: config.APP_SEL_ETHERTYPE, Protocol: 0x8906, Priority: 3}
+	aCfg := testDcbxConfig(true, 0x10, iscsi)
+	bCfg := testDcbxConfig(false, 0x08, fcoe)
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	updateIntfConfig(a, "eth1", func(intfCfg *config.IntfConfig) {
+		intfCfg.Dcbx = aCfg
+	})
+	updateIntfConfig(b, "eth2", func(intfCfg *config.IntfConfig) {
+		intfCfg.Dcbx = bCfg
+	})
+	tick(1, a, b)
+
+	// willing port accepts parameters of not willing peer
+	state := a.GetDcbxState(a.lldpIntfRef2IfIndexMap["eth1"])
+	if !state.PeerDetected || state.MultiplePeers {
+		t.Fatal("expected DCBX peer on eth1 actual", state.PeerDetected, state.MultiplePeers)
+	}
+	checkDcbxSources(t, state, config.DCBX_SOURCE_Peer, config.DCBX_SOURCE_Peer, config.DCBX_SOURCE_Peer)
+	if state.Ets != bCfg.EtsRecommendation || state.PfcEnable != bCfg.PfcEnable ||
+		!reflect.DeepEqual(state.Apps, bCfg.Apps) {
+		t.Error("expected peer recommendation", bCfg.EtsRecommendation, bCfg.PfcEnable, bCfg.Apps,
+			"actual", state.Ets, state.PfcEnable, state.Apps)
+	}
+	peer := state.Peer
+	if peer.EtsWilling || peer.MaxTCs != 4 || peer.Ets == nil || *peer.Ets != bCfg.Ets || !peer.PfcValid ||
+		peer.PfcWilling || peer.PfcCap != 4 {
+		t.Error("expected peer DCBX tlv's as per", bCfg, "actual", peer, peer.Ets)
+	}
+	events := dcbxEvents(eventCh)
+	if len(events) == 0 || !reflect.DeepEqual(events[len(events)-1].Dcbx, state) {
+		t.Error("expected DCBX event with", state, "actual", events)
+	}
+
+	// not willing port keeps local configuration
+	state = b.GetDcbxState(b.lldpIntfRef2IfIndexMap["eth2"])
+	checkDcbxSources(t, state, config.DCBX_SOURCE_Local, config.DCBX_SOURCE_Local, config.DCBX_SOURCE_Local)
+	if state.Ets != bCfg.Ets || state.PfcEnable != bCfg.PfcEnable || !reflect.DeepEqual(state.Apps, bCfg.Apps) {
+		t.Error("expected local configuration", bCfg, "actual", state)
+	}
+
+	// peer stops advertising DCBX, local configuration is used again
+	bCfg.Enable = false
+	updateIntfConfig(b, "eth2", func(intfCfg *config.IntfConfig) {
+		intfCfg.Dcbx = bCfg
+	})
+	tick(1, a, b)
+	state = a.GetDcbxState(a.lldpIntfRef2IfIndexMap["eth1"])
+	if state.PeerDetected {
+		t.Error("expected no DCBX peer actual", state.Peer)
+	}
+	checkDcbxSources(t, state, config.DCBX_SOURCE_Local, config.DCBX_SOURCE_Local, config.DCBX_SOURCE_Local)
+	events = dcbxEvents(eventCh)
+	if len(events) != 1 || events[0].Dcbx.Ets != aCfg.Ets {
+		t.Error("expected one DCBX event with local ETS actual", events)
+	}
+	if states := b.GetDcbxStates(); len(states) != 0 {
+		t.Error("expected no DCBX enabled port actual", states)
+	}
+}
+
+func TestLLDPDcbxBothWilling(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	iscsi := config.AppPriority{Selector: config.APP_SEL_TCP, Protocol: 3260, Priority: 4}
+	fcoe := config.AppPriority{Selector: config.APP_SEL_ETHERTYPE, Protocol: 0x8906, Priority: 3}
+	aCfg := testDcbxConfig(true, 0x10, iscsi)
+	bCfg := testDcbxConfig(true, 0x08, fcoe)
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	updateIntfConfig(a, "eth1", func(intfCfg *config.IntfConfig) {
+		intfCfg.Dcbx = aCfg
+	})
+	updateIntfConfig(b, "eth2", func(intfCfg *config.IntfConfig) {
+		intfCfg.Dcbx = bCfg
+	})
+	tick(1, a, b)
+
+	// both accept ETS recommendation, port with lower mac (A) accepts PFC & application priority
+	aState := a.GetDcbxState(a.lldpIntfRef2IfIndexMap["eth1"])
+	checkDcbxSources(t, aState, config.DCBX_SOURCE_Peer, config.DCBX_SOURCE_Peer, config.DCBX_SOURCE_Peer)
+	bState := b.GetDcbxState(b.lldpIntfRef2IfIndexMap["eth2"])
+	checkDcbxSources(t, bState, config.DCBX_SOURCE_Peer, config.DCBX_SOURCE_Local, config.DCBX_SOURCE_Local)
+	if aState.PfcEnable != bState.PfcEnable || !reflect.DeepEqual(aState.Apps, bState.Apps) {
+		t.Error("expected same PFC & application priority on both ends actual", aState.PfcEnable,
+			aState.Apps, bState.PfcEnable, bState.Apps)
+	}
+	if aState.Ets != bCfg.EtsRecommendation || bState.Ets != aCfg.EtsRecommendation {
+		t.Error("expected ETS recommendation of peer actual", aState.Ets, bState.Ets)
+	}
+
+	// DCBX disabled locally, peer is ignored
+	aCfg.Enable = false
+	updateIntfConfig(a, "eth1", func(intfCfg *config.IntfConfig) {
+		intfCfg.Dcbx = aCfg
+	})
+	aState = a.GetDcbxState(a.lldpIntfRef2IfIndexMap["eth1"])
+	if aState.Enable || aState.PeerDetected {
+		t.Error("expected DCBX disabled without peer actual", aState)
+	}
+	checkDcbxSources(t, aState, config.DCBX_SOURCE_Local, config.DCBX_SOURCE_Local, config.DCBX_SOURCE_Local)
+}
+
+func TestLLDPDcbxMultiplePeers(t *testing.T) {
+	OnlyForTestSetup()
+	mn := NewMemNetwork()
+	a := newTestServer(mn, 1, "A", "eth1")
+	b := newTestServer(mn, 2, "B", "eth1")
+	c := newTestServer(mn, 3, "C", "eth1")
+	defer OnlyForTestTeardown(a, b, c)
+	mn.Connect(a.pktIO, "eth1", b.pktIO, "eth1")
+	app := config.AppPriority{Selector: config.APP_SEL_UDP, Protocol: 4791, Priority: 5}
+	for _, svr := range []*LLDPServer{a, b, c} {
+		startTestServer(svr, config.TXRX)
+		updateIntfConfig(svr, "eth1", func(intfCfg *config.IntfConfig) {
+			intfCfg.Dcbx = testDcbxConfig(svr == a, 0x20, app)
+		})
+	}
+	tick(1, a, b, c)
+	state := a.GetDcbxState(1)
+	if !state.PeerDetected {
+		t.Fatal("expected DCBX peer B")
+	}
+
+	// second neighbor with DCBX tlv's, local configuration is used
+	mn.Connect(a.pktIO, "eth1", c.pktIO, "eth1")
+	tick(LLDP_DEFAULT_TX_INTERVAL, a, b, c)
+	state = a.GetDcbxState(1)
+	if state.PeerDetected || !state.MultiplePeers {
+		t.Error("expected multiple DCBX peers actual", state.PeerDetected, state.MultiplePeers)
+	}
+	checkDcbxSources(t, state, config.DCBX_SOURCE_Local, config.DCBX_SOURCE_Local, config.DCBX_SOURCE_Local)
+}
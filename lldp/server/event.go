@@ -0,0 +1,214 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"l2/lldp/config"
+	"l2/lldp/packet"
+	"l2/lldp/utils"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	// IEEE 802.1AB lldpNotificationInterval, atmost one notification is send in the interval
+	LLDP_DEFAULT_NOTIFICATION_INTERVAL = 5    // in seconds
+	LLDP_MAX_NOTIFICATION_INTERVAL     = 3600 // in seconds
+	LLDP_EVENT_SUBSCRIBER_CHANNEL_SIZE = 100
+)
+
+// neighbor events are coalesced per neighbor till next notification is send
+type eventKey struct {
+	ifIndex  int32
+	agent    uint8
+	remIndex int32
+}
+
+/*  Create neighbor record which is used as before/after information in the event
+ */
+func (svr *LLDPServer) neighborRecord(intf *LLDPGlobalInfo, agent *LLDPAgent, nbr *packet.Neighbor) *config.IntfState {
+	if nbr == nil || nbr.RxFrame == nil {
+		return nil
+	}
+	entry := &config.IntfState{}
+	svr.populateLocalInfo(intf, entry)
+	intf.populatePeerInfo(agent, nbr, entry)
+	return entry
+}
+
+/*  Neighbor information fields are the one's received from peer, i.e Peer* fields and system
+ *  description/capabilities
+ */
+func isNeighborField(name string) bool {
+	switch name {
+	case "SystemDescription", "SystemCapabilities", "EnabledCapabilities":
+		return true
+	}
+	return strings.HasPrefix(name, "Peer")
+}
+
+/*  Find out neighbor information fields that are different between before & after record
+ */
+func DiffNeighbor(before, after *config.IntfState) []string {
+	var changes []string
+	if before == nil || after == nil {
+		return changes
+	}
+	beforeVal := reflect.ValueOf(*before)
+	afterVal := reflect.ValueOf(*after)
+	for idx := 0; idx < beforeVal.NumField(); idx++ {
+		name := beforeVal.Type().Field(idx).Name
+		if !isNeighborField(name) {
+			continue
+		}
+		if !reflect.DeepEqual(beforeVal.Field(idx).Interface(), afterVal.Field(idx).Interface()) {
+			changes = append(changes, name)
+		}
+	}
+	return changes
+}
+
+/*  Queue neighbor event, events for the same neighbor are coalesced so that first before information
+ *  and last after information is notified. Notification is send right away if notification interval
+ *  has passed since last notification, otherwise it is delayed till the interval expires
+ */
+func (svr *LLDPServer) NotifyEvent(eventInfo config.EventInfo) {
+	// neighbors of the port changed, cabling plan compliance & DCBX negotiation are checked right away
+	svr.CheckCabling(eventInfo.IfIndex)
+	svr.UpdateDcbx(eventInfo.IfIndex)
+	key := eventKey{eventInfo.IfIndex, eventInfo.Agent, eventInfo.RemIndex}
+	pending, exists := svr.pendingEvents[key]
+	if !exists {
+		pending = &config.EventInfo{
+			IfIndex:  eventInfo.IfIndex,
+			Agent:    eventInfo.Agent,
+			RemIndex: eventInfo.RemIndex,
+			Before:   eventInfo.Before,
+		}
+		svr.pendingEvents[key] = pending
+		svr.pendingOrder = append(svr.pendingOrder, key)
+	}
+	pending.After = eventInfo.After
+
+	if svr.notifyTimer != nil {
+		// notification is already scheduled
+		return
+	}
+	svr.scheduleNotification()
+}
+
+/*  Send pending notifications right away if notification interval has passed since last notification,
+ *  otherwise start the timer for remaining interval
+ */
+func (svr *LLDPServer) scheduleNotification() {
+	wait := svr.lastNotification.Add(time.Duration(svr.notificationInterval) * time.Second).Sub(time.Now())
+	if wait <= 0 {
+		svr.SendNotifications()
+		return
+	}
+	notifyCh := svr.notifyCh
+	svr.notifyTimer = time.AfterFunc(wait, func() {
+		notifyCh <- true
+	})
+}
+
+/*  Send all the pending neighbor events to system plugin and to all the subscribers
+ */
+func (svr *LLDPServer) SendNotifications() {
+	svr.notifyTimer = nil
+	svr.lastNotification = time.Now()
+	for _, key := range svr.pendingOrder {
+		eventInfo := svr.pendingEvents[key]
+		switch {
+		case eventInfo.Before == nil && eventInfo.After == nil:
+			// neighbor learned and removed within notification interval
+			continue
+		case eventInfo.Before == nil:
+			eventInfo.EventType = config.Learned
+		case eventInfo.After == nil:
+			eventInfo.EventType = config.Removed
+		default:
+			eventInfo.EventType = config.Updated
+			eventInfo.Changes = DiffNeighbor(eventInfo.Before, eventInfo.After)
+			if len(eventInfo.Changes) == 0 {
+				continue
+			}
+		}
+		svr.SysPlugin.PublishEvent(*eventInfo)
+		svr.publishToSubscribers(*eventInfo)
+	}
+	svr.pendingEvents = make(map[eventKey]*config.EventInfo)
+	svr.pendingOrder = nil
+}
+
+/*  Update notification interval, timer for pending notification if any is restarted as per new interval
+ *  from the last notification
+ */
+func (svr *LLDPServer) UpdateNotificationInterval(interval int32) {
+	svr.notificationInterval = interval
+	if svr.notifyTimer != nil && svr.notifyTimer.Stop() {
+		svr.notifyTimer = nil
+		svr.scheduleNotification()
+	}
+}
+
+/*  Subscribe for neighbor events, events are send on the returned channel till the subscriber
+ *  unsubscribes. Events are dropped if subscriber is not reading them fast enough
+ */
+func (svr *LLDPServer) Subscribe() (int32, <-chan config.EventInfo) {
+	svr.subscriberLock.Lock()
+	defer svr.subscriberLock.Unlock()
+	svr.nextSubscriberId++
+	eventCh := make(chan config.EventInfo, LLDP_EVENT_SUBSCRIBER_CHANNEL_SIZE)
+	svr.subscribers[svr.nextSubscriberId] = eventCh
+	return svr.nextSubscriberId, eventCh
+}
+
+/*  Unsubscribe neighbor events, event channel for the subscriber is closed
+ */
+func (svr *LLDPServer) Unsubscribe(id int32) bool {
+	svr.subscriberLock.Lock()
+	defer svr.subscriberLock.Unlock()
+	eventCh, exists := svr.subscribers[id]
+	if !exists {
+		return false
+	}
+	delete(svr.subscribers, id)
+	close(eventCh)
+	return true
+}
+
+func (svr *LLDPServer) publishToSubscribers(eventInfo config.EventInfo) {
+	svr.subscriberLock.Lock()
+	defer svr.subscriberLock.Unlock()
+	for id, eventCh := range svr.subscribers {
+		select {
+		case eventCh <- eventInfo:
+		default:
+			debug.Logger.Err("Subscriber", id, "is not reading events, dropping event for ifIndex",
+				eventInfo.IfIndex)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"l2/lldp/config"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testNeighborRecord(localPort, peerHostName string) *config.IntfState {
+	return &config.IntfState{
+		LocalPort:    localPort,
+		PeerMac:      "00:00:00:00:02:01",
+		PeerPort:     "fpPort1",
+		PeerHostName: peerHostName,
+	}
+}
+
+func TestNotifyEvent(t *testing.T) {
+	OnlyForTestSetup()
+	peer := testNeighborRecord("fpPort1", "leaf2")
+	renamed := testNeighborRecord("fpPort1", "spine2")
+	// only local information is changed
+	local := testNeighborRecord("fpPort2", "leaf2")
+	tests := []struct {
+		name    string
+		events  []config.EventInfo
+		publish []config.EventInfo
+	}{
+		{
+			name:    "learned",
+			events:  []config.EventInfo{{IfIndex: 1, RemIndex: 1, After: peer}},
+			publish: []config.EventInfo{{IfIndex: 1, RemIndex: 1, EventType: config.Learned, After: peer}},
+		},
+		{
+			name:    "learned & updated are coalesced",
+			events:  []config.EventInfo{{IfIndex: 1, RemIndex: 1, After: peer}, {IfIndex: 1, RemIndex: 1, Before: peer, After: renamed}},
+			publish: []config.EventInfo{{IfIndex: 1, RemIndex: 1, EventType: config.Learned, After: renamed}},
+		},
+		{
+			name:   "learned & removed within notification interval",
+			events: []config.EventInfo{{IfIndex: 1, RemIndex: 1, After: peer}, {IfIndex: 1, RemIndex: 1, Before: peer}},
+		},
+		{
+			name:   "updated",
+			events: []config.EventInfo{{IfIndex: 1, RemIndex: 1, Before: peer, After: renamed}},
+			publish: []config.EventInfo{{IfIndex: 1, RemIndex: 1, EventType: config.Updated, Before: peer, After: renamed,
+				Changes: []string{"PeerHostName"}}},
+		},
+		{
+			name:   "updated back to before information",
+			events: []config.EventInfo{{IfIndex: 1, RemIndex: 1, Before: peer, After: renamed}, {IfIndex: 1, RemIndex: 1, Before: renamed, After: peer}},
+		},
+		{
+			name:   "only local information updated",
+			events: []config.EventInfo{{IfIndex: 1, RemIndex: 1, Before: peer, After: local}},
+		},
+		{
+			name:    "removed",
+			events:  []config.EventInfo{{IfIndex: 1, RemIndex: 1, Before: peer}},
+			publish: []config.EventInfo{{IfIndex: 1, RemIndex: 1, EventType: config.Removed, Before: peer}},
+		},
+		{
+			name: "events of different neighbors are not coalesced",
+			events: []config.EventInfo{{IfIndex: 2, RemIndex: 1, After: peer}, {IfIndex: 1, RemIndex: 1, Before: peer},
+				{IfIndex: 1, Agent: config.AGENT_NEAREST_CUSTOMER, RemIndex: 1, After: peer}},
+			publish: []config.EventInfo{{IfIndex: 2, RemIndex: 1, EventType: config.Learned, After: peer},
+				{IfIndex: 1, RemIndex: 1, EventType: config.Removed, Before: peer},
+				{IfIndex: 1, Agent: config.AGENT_NEAREST_CUSTOMER, RemIndex: 1, EventType: config.Learned, After: peer}},
+		},
+	}
+	for _, tc := range tests {
+		svr := newTestServer(NewMemNetwork(), 1, "leaf1", "fpPort1", "fpPort2")
+		svr.notificationInterval = LLDP_DEFAULT_NOTIFICATION_INTERVAL
+		sysPlugin := svr.SysPlugin.(*testSysPlugin)
+		// all the events are received within notification interval
+		svr.lastNotification = time.Now()
+		for _, eventInfo := range tc.events {
+			svr.NotifyEvent(eventInfo)
+		}
+		if len(sysPlugin.events) != 0 || svr.notifyTimer == nil {
+			t.Error(tc.name, "events are expected to be delayed till notification interval expires")
+		}
+		svr.notifyTimer.Stop()
+		svr.SendNotifications()
+		if len(sysPlugin.events) != len(tc.publish) {
+			t.Error(tc.name, "expected events", tc.publish, "actual", sysPlugin.events)
+			continue
+		}
+		for idx := range tc.publish {
+			if !reflect.DeepEqual(sysPlugin.events[idx], tc.publish[idx]) {
+				t.Error(tc.name, "expected event", tc.publish[idx], "actual", sysPlugin.events[idx])
+			}
+		}
+	}
+}
+
+func TestNotificationInterval(t *testing.T) {
+	OnlyForTestSetup()
+	svr := newTestServer(NewMemNetwork(), 1, "leaf1", "fpPort1")
+	svr.notificationInterval = LLDP_DEFAULT_NOTIFICATION_INTERVAL
+	sysPlugin := svr.SysPlugin.(*testSysPlugin)
+	peer := testNeighborRecord("fpPort1", "leaf2")
+
+	// first notification is send right away
+	svr.NotifyEvent(config.EventInfo{IfIndex: 1, RemIndex: 1, After: peer})
+	if len(sysPlugin.events) != 1 || svr.notifyTimer != nil {
+		t.Error("expected first event to be published right away actual", len(sysPlugin.events))
+	}
+	// next one is rate limited by notification interval
+	svr.NotifyEvent(config.EventInfo{IfIndex: 1, RemIndex: 2, After: peer})
+	if len(sysPlugin.events) != 1 || svr.notifyTimer == nil {
+		t.Error("expected event to be delayed by notification interval actual", len(sysPlugin.events))
+	}
+	// timer is restarted as per new interval and pending event is not flushed right away
+	svr.UpdateNotificationInterval(1)
+	if len(sysPlugin.events) != 1 || svr.notifyTimer == nil {
+		t.Error("expected event to be delayed by new notification interval actual", len(sysPlugin.events))
+	}
+	select {
+	case <-svr.notifyCh:
+		svr.SendNotifications()
+	case <-time.After(3 * time.Second):
+		t.Error("notification timer did not expire as per new notification interval")
+	}
+	if len(sysPlugin.events) != 2 || sysPlugin.events[1].RemIndex != 2 {
+		t.Error("expected pending event to be published after interval actual", sysPlugin.events)
+	}
+	// interval has passed already as per new interval, pending event is send right away
+	svr.UpdateNotificationInterval(LLDP_MAX_NOTIFICATION_INTERVAL)
+	svr.NotifyEvent(config.EventInfo{IfIndex: 1, RemIndex: 3, After: peer})
+	svr.UpdateNotificationInterval(0)
+	if len(sysPlugin.events) != 3 || svr.notifyTimer != nil {
+		t.Error("expected pending event to be published for zero interval actual", len(sysPlugin.events))
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	OnlyForTestSetup()
+	svr := newTestServer(NewMemNetwork(), 1, "leaf1", "fpPort1")
+	peer := testNeighborRecord("fpPort1", "leaf2")
+	id1, eventCh1 := svr.Subscribe()
+	id2, eventCh2 := svr.Subscribe()
+	if id1 == id2 {
+		t.Error("expected unique subscriber ids actual", id1, id2)
+	}
+	svr.NotifyEvent(config.EventInfo{IfIndex: 1, RemIndex: 1, After: peer})
+	for _, eventCh := range []<-chan config.EventInfo{eventCh1, eventCh2} {
+		select {
+		case eventInfo := <-eventCh:
+			if eventInfo.EventType != config.Learned || eventInfo.RemIndex != 1 {
+				t.Error("expected learned event for remote index 1 actual", eventInfo)
+			}
+		default:
+			t.Error("expected event to be send to all the subscribers")
+		}
+	}
+	if !svr.Unsubscribe(id1) || svr.Unsubscribe(id1) {
+		t.Error("expected subscriber to be deleted only once")
+	}
+	if _, ok := <-eventCh1; ok {
+		t.Error("expected event channel to be closed on unsubscribe")
+	}
+	// events are dropped for subscriber which is not reading them
+	for remIndex := int32(1); remIndex <= LLDP_EVENT_SUBSCRIBER_CHANNEL_SIZE+1; remIndex++ {
+		svr.NotifyEvent(config.EventInfo{IfIndex: 1, RemIndex: remIndex, Before: peer})
+	}
+	if len(eventCh2) != LLDP_EVENT_SUBSCRIBER_CHANNEL_SIZE {
+		t.Error("expected subscriber channel to be full actual", len(eventCh2))
+	}
+	svr.Unsubscribe(id2)
+}
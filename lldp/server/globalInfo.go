@@ -25,10 +25,9 @@ package server
 
 import (
 	"github.com/google/gopacket"
-	"github.com/google/gopacket/pcap"
 	"l2/lldp/config"
-	"l2/lldp/packet"
 	"l2/lldp/plugin"
+	"os"
 	"sync"
 	"time"
 	"utils/dbutils"
@@ -39,31 +38,42 @@ type InPktChannel struct {
 	ifIndex int32
 }
 
-type SendPktChannel struct {
-	ifIndex int32
-}
-
 type LLDPGlobalInfo struct {
 	// Port information
 	Port config.PortInfo
-	// Pcap Handler for Each Port
-	PcapHandle *pcap.Handle
-	// rx information
-	RxInfo *packet.RX
-	// tx information
-	TxInfo *packet.TX
+	// Packet I/O Handle for Each Port
+	PktHdl PktHandle
+	// lldp agents running on the port, one per destination mac address
+	Agents     []*LLDPAgent
+	agentTypes uint8 // configured agents bitmap
+	// rx go routine is running or not
+	RxRunning bool
 	// State info
 	enable bool
 	// Reading received info & updating received info lock
 	RxLock *sync.RWMutex
 	// Go Routine Killer Channels
-	RxKill chan bool
 	TxDone chan bool
 	// counter for total frames rx/tx
-	counter Frame
-	// last received packet time
-	pktRcvdTime time.Time
-	rxtxMode    uint8
+	counter  Frame
+	rxtxMode uint8
+	// per port configuration applied to all the agents
+	medConfig     config.MedConfig
+	dcbxConfig    config.DcbxConfig
+	maxNeighbors  int
+	portIdSubtype uint8
+	// copy of global chassis id selection
+	chassisId config.ChassisIdConfig
+	// optional tlv's advertised on the port
+	tlvs uint8
+	// per port transmit timers configuration, 0 means global/default value
+	txIntervalCfg  int
+	txHoldCfg      int
+	reinitDelayCfg int
+	// transmit timers in use on the port
+	txInterval  int
+	txHold      int
+	reinitDelay int
 }
 
 type Frame struct {
@@ -71,10 +81,23 @@ type Frame struct {
 	Rcvd int32
 }
 
+// IEEE 802.1AB remote table statistics
+type RemTablesStats struct {
+	Inserts        int32
+	Deletes        int32
+	Drops          int32
+	Ageouts        int32
+	LastChangeTime time.Time
+}
+
 type LLDPServer struct {
 	// Basic server start fields
 	lldpDbHdl *dbutils.DBUtil
 	paramsDir string
+	// remote table checkpoint, db handle once it is connected
+	nbrDB NeighborDB
+	// neighbors read from checkpoint per port which are yet to be restored
+	restoredNbrs map[string][]neighborCheckpoint
 
 	asicPlugin plugin.AsicIntf
 	CfgPlugin  plugin.ConfigIntf
@@ -92,28 +115,63 @@ type LLDPServer struct {
 	lldpUpIntfStateSlice   []int32
 	lldpIntfRef2IfIndexMap map[string]int32
 
-	// lldp pcap handler default config values
-	lldpSnapshotLen int32
-	lldpPromiscuous bool
-	lldpTimeout     time.Duration
+	// lldp packet rx/tx path, pcap or shared AF_PACKET socket
+	pktIO PktIO
 
 	// lldp packet rx channel
 	lldpRxPktCh chan InPktChannel
-	// lldp send packet channel
-	lldpTxPktCh chan SendPktChannel
+	// lldp transmit & timer state machine tick
+	lldpTxTick *time.Ticker
 	// lldp global config channel
 	GblCfgCh chan *config.Global
 	// lldp per port config
 	IntfCfgCh chan *config.IntfConfig
 	// lldp asic notification channel
 	IfStateCh chan *config.PortState
+	// lldp asic l3 interface notification channel
+	IfL3StateCh chan *config.PortL3State
+	// lldp asic vlan membership notification channel
+	VlanStateCh chan *config.VlanState
+	// lldp asic lag membership notification channel
+	LagStateCh chan *config.LagState
 	// Update Cache notification channel
 	UpdateCacheCh chan *config.SystemInfo
 	// Event Publish channel for server
 	EventCh chan config.EventInfo
+	// os signal channel, signal is handled by channel handler as it owns per port information
+	lldpSignalCh chan os.Signal
+	// Clear statistics channel
+	ClearStatsCh chan *config.ClearStats
+	// Notification interval update channel
+	NotificationIntervalCh chan int32
+	// Chassis id selection channel
+	ChassisIdCfgCh chan *config.ChassisIdConfig
+	// Cabling plan channel
+	CablingPlanCh chan []config.ExpectedNeighbor
 
 	// Frames Counter
 	counter Frame
+	// Remote table counters
+	remTables RemTablesStats
+	// Chassis id selection for all the ports
+	chassisId config.ChassisIdConfig
+	// expected neighbor & cabling status per port
+	cablingPlan       map[int32]config.ExpectedNeighbor
+	cablingStatusInfo map[int32]string
+	// last DCB operational parameters notified per port
+	dcbxStateInfo map[int32]config.DcbxState
+
+	// neighbor events waiting for notification interval to expire
+	pendingEvents        map[eventKey]*config.EventInfo
+	pendingOrder         []eventKey
+	notificationInterval int32
+	lastNotification     time.Time
+	notifyTimer          *time.Timer
+	notifyCh             chan bool
+	// neighbor event subscribers
+	subscriberLock   sync.Mutex
+	subscribers      map[int32]chan config.EventInfo
+	nextSubscriberId int32
 }
 
 const (
@@ -123,7 +181,6 @@ const (
 	// Consts Init Size/Capacity
 	LLDP_INITIAL_GLOBAL_INFO_CAPACITY   = 100
 	LLDP_RX_PKT_CHANNEL_SIZE            = 30
-	LLDP_TX_PKT_CHANNEL_SIZE            = 30
 	LLDP_PORT_STATE_CHANGE_CHANNEL_SIZE = 200
 	LLDP_PORT_CONFIG_CHANNEL_SIZE       = 5
 
@@ -132,10 +189,9 @@ const (
 	LLDP_PORT_STATE_UP   = "UP"
 	LLDP_PORT_BROKEN_OUT = "Port broken out"
 
-	LLDP_BPF_FILTER                 = "ether proto 0x88cc"
+	LLDP_BPF_FILTER                 = "ether proto 0x88cc or ether dst 01:00:0c:cc:cc:cc"
 	LLDP_DEFAULT_TX_INTERVAL        = 30
 	LLDP_DEFAULT_TX_HOLD_MULTIPLIER = 4
 	LLDP_MIN_FRAME_LENGTH           = 12 // this is 12 bytes
-	LLDP_FAST_LEARN_TIMER           = 1  // in seconds
-	LLDP_FAST_LEARN_MAX_FRAMES_SEND = 5
+	LLDP_TX_TICK_INTERVAL           = 1  // in seconds
 )
@@ -24,18 +24,13 @@
 package server
 
 import (
-	"errors"
-	"fmt"
 	"github.com/google/gopacket/layers"
-	"github.com/google/gopacket/pcap"
 	"l2/lldp/config"
 	"l2/lldp/packet"
 	"l2/lldp/utils"
-	"net"
 	"strconv"
 	"strings"
 	"sync"
-	"time"
 )
 
 func Min(x, y int) int {
@@ -56,9 +51,13 @@ func Max(x, y int) int {
  */
 func (intf *LLDPGlobalInfo) InitRuntimeInfo(portConf *config.PortInfo) {
 	intf.Port = *portConf
-	intf.RxInfo = packet.RxInit()
-	intf.TxInfo = packet.TxInit(LLDP_DEFAULT_TX_INTERVAL, LLDP_DEFAULT_TX_HOLD_MULTIPLIER)
-	intf.RxKill = make(chan bool)
+	intf.agentTypes = config.AGENT_NEAREST_BRIDGE
+	intf.maxNeighbors = packet.LLDP_DEFAULT_MAX_NEIGHBORS
+	intf.tlvs = config.TLV_ALL
+	intf.txInterval = LLDP_DEFAULT_TX_INTERVAL
+	intf.txHold = LLDP_DEFAULT_TX_HOLD_MULTIPLIER
+	intf.reinitDelay = packet.LLDP_DEFAULT_REINIT_DELAY
+	intf.SetAgents(false)
 	intf.TxDone = make(chan bool)
 	intf.RxLock = &sync.RWMutex{}
 }
@@ -66,23 +65,17 @@ func (intf *LLDPGlobalInfo) InitRuntimeInfo(portConf *config.PortInfo) {
 /*  De-Init l2 port information
  */
 func (intf *LLDPGlobalInfo) DeInitRuntimeInfo() {
-	intf.StopCacheTimer()
-	intf.DeletePcapHandler()
+	intf.ClosePktHandle()
 	intf.FreeDynamicMemory()
 }
 
-/*  Delete l2 port pcap handler
+/*  Close l2 port packet handle
  */
-func (intf *LLDPGlobalInfo) DeletePcapHandler() {
-	if intf.PcapHandle != nil {
-		// Send go routine kill signal right away before even we do anything else
-		if intf.RxInfo.RxRunning {
-			intf.RxKill <- true
-			<-intf.RxKill
-		}
-		// @FIXME: some bug in close handling that causes 5 mins delay
-		intf.PcapHandle.Close()
-		intf.PcapHandle = nil
+func (intf *LLDPGlobalInfo) ClosePktHandle() {
+	if intf.PktHdl != nil {
+		intf.PktHdl.Close()
+		intf.PktHdl = nil
+		intf.RxRunning = false
 	}
 }
 
@@ -110,210 +103,206 @@ func (intf *LLDPGlobalInfo) isEnabled() bool {
 	return intf.enable
 }
 
-/*  Stop RX cache timer
- */
-func (intf *LLDPGlobalInfo) StopCacheTimer() {
-	intf.RxLock.Lock()
-	defer intf.RxLock.Unlock()
-	if intf.RxInfo.ClearCacheTimer == nil {
-		return
-	}
-	intf.RxInfo.ClearCacheTimer.Stop()
-}
-
-/*  Return back all the memory which was allocated using new
+/*  Stop RX cache timer for all the neighbors and return back all the memory which was allocated
+ *  for remote table
  */
 func (intf *LLDPGlobalInfo) FreeDynamicMemory() {
 	intf.RxLock.Lock()
 	defer intf.RxLock.Unlock()
-	intf.RxInfo.RxFrame = nil
-	intf.RxInfo.RxLinkInfo = nil
+	for _, agent := range intf.Agents {
+		agent.RxInfo.DeleteAllNeighbors()
+	}
 }
 
-/*  Create Pcap Handler
+/*  Open packet handle for the port
  */
-func (intf *LLDPGlobalInfo) CreatePcapHandler(lldpSnapshotLen int32, lldpPromiscuous bool, lldpTimeout time.Duration) error {
-	debug.Logger.Debug("Creating Pcap for port:", intf.Port.Name, "ifIndex:", intf.Port.IfIndex)
-	pcapHdl, err := pcap.OpenLive(intf.Port.Name, lldpSnapshotLen, lldpPromiscuous, lldpTimeout)
+func (intf *LLDPGlobalInfo) OpenPktHandle(pktIO PktIO) error {
+	pktHdl, err := pktIO.Open(&intf.Port)
 	if err != nil {
-		debug.Logger.Err(fmt.Sprintln("Creating Pcap Handler failed for", intf.Port.Name, "Error:", err))
-		return errors.New("Creating Pcap Failed")
+		return err
 	}
-	err = pcapHdl.SetBPFFilter(LLDP_BPF_FILTER)
-	if err != nil {
-		debug.Logger.Err(fmt.Sprintln("setting filter:", LLDP_BPF_FILTER, "for", intf.Port.Name,
-			"failed with error:", err))
-		return errors.New("Setting BPF Filter Failed")
-	}
-	intf.PcapHandle = pcapHdl
-	debug.Logger.Debug("Pcap Created for port:", intf.Port.Name, "ifIndex:", intf.Port.IfIndex)
+	intf.PktHdl = pktHdl
 	return nil
 }
 
 /*  Get Chassis Id info
- *	 Based on SubType Return the string, mac address then form string using
- *	 net package
+ *	 Based on SubType Return the string, mac/network address is formatted and
+ *	 alphanumeric id is returned as is
  */
-func (intf *LLDPGlobalInfo) GetChassisIdInfo() string {
-
-	retVal := ""
-	switch intf.RxInfo.RxFrame.ChassisID.Subtype {
-	case layers.LLDPChassisIDSubTypeReserved:
-		debug.Logger.Debug("Need to handle this case")
-	case layers.LLDPChassisIDSubTypeChassisComp:
-		debug.Logger.Debug("Need to handle this case")
-	case layers.LLDPChassisIDSubtypeIfaceAlias:
-		debug.Logger.Debug("Need to handle this case")
-	case layers.LLDPChassisIDSubTypePortComp:
-		debug.Logger.Debug("Need to handle this case")
-	case layers.LLDPChassisIDSubTypeMACAddr:
-		var mac net.HardwareAddr
-		mac = intf.RxInfo.RxFrame.ChassisID.ID
-		return mac.String()
-	case layers.LLDPChassisIDSubTypeNetworkAddr:
-		debug.Logger.Debug("Need to handle this case")
-	case layers.LLDPChassisIDSubtypeIfaceName:
-		debug.Logger.Debug("Need to handle this case")
-	case layers.LLDPChassisIDSubTypeLocal:
-		debug.Logger.Debug("Need to handle this case")
-	default:
-		return retVal
-
-	}
-	return retVal
+func (intf *LLDPGlobalInfo) GetChassisIdInfo(nbr *packet.Neighbor) string {
+	return packet.DecodeChassisId(nbr.RxFrame.ChassisID)
 }
 
 /*  Get Port Id info
- *	 Based on SubType Return the string, mac address then form string using
- *	 net package
+ *	 Based on SubType Return the string, mac/network address is formatted and
+ *	 alphanumeric id is returned as is
  */
-func (intf *LLDPGlobalInfo) GetPortIdInfo() string {
-
-	retVal := ""
-	switch intf.RxInfo.RxFrame.PortID.Subtype {
-	case layers.LLDPPortIDSubtypeReserved:
-		debug.Logger.Debug("Need to handle this case")
-	case layers.LLDPPortIDSubtypeIfaceAlias:
-		debug.Logger.Debug("Need to handle this case")
-	case layers.LLDPPortIDSubtypePortComp:
-		debug.Logger.Debug("Need to handle this case")
-	case layers.LLDPPortIDSubtypeMACAddr:
-		var mac net.HardwareAddr
-		mac = intf.RxInfo.RxFrame.ChassisID.ID
-		return mac.String()
-	case layers.LLDPPortIDSubtypeNetworkAddr:
-		debug.Logger.Debug("Need to handle this case")
-	case layers.LLDPPortIDSubtypeIfaceName:
-		return string(intf.RxInfo.RxFrame.PortID.ID)
-	case layers.LLDPPortIDSubtypeAgentCircuitID:
-		debug.Logger.Debug("Need to handle this case")
-	case layers.LLDPPortIDSubtypeLocal:
-		debug.Logger.Debug("Need to handle this case")
-	default:
-		return retVal
-
-	}
-	return retVal
+func (intf *LLDPGlobalInfo) GetPortIdInfo(nbr *packet.Neighbor) string {
+	return packet.DecodePortId(nbr.RxFrame.PortID)
 }
 
 /*  Get System Capability info
  *	 Based on booleans value Return the string, which states what system capabilities are enabled
  */
-func (intf *LLDPGlobalInfo) GetSystemCap() string {
+func (intf *LLDPGlobalInfo) GetSystemCap(nbr *packet.Neighbor) string {
+	return CapabilitiesToString(nbr.RxLinkInfo.SysCapabilities.SystemCap)
+}
+
+/*  Get Enabled Capability info
+ *	 Based on booleans value Return the string, which states what enabled capabilities are enabled
+ */
+func (intf *LLDPGlobalInfo) GetEnabledCap(nbr *packet.Neighbor) string {
+	return CapabilitiesToString(nbr.RxLinkInfo.SysCapabilities.EnabledCap)
+}
+
+/*  Get Local System Capability & Enabled Capability info which we advertise on this port
+ */
+func (intf *LLDPGlobalInfo) GetLocalCap(sysInfo *config.SystemInfo) (string, string) {
+	sysCap := packet.GetLocalCapabilities(intf.Port, sysInfo)
+	return CapabilitiesToString(sysCap.SystemCap), CapabilitiesToString(sysCap.EnabledCap)
+}
+
+/*  Convert capabilities booleans into comma separated string
+ */
+func CapabilitiesToString(capabilities layers.LLDPCapabilities) string {
 	retVal := ""
-	systemCap := intf.RxInfo.RxLinkInfo.SysCapabilities.SystemCap
-	if systemCap.Other {
+	if capabilities.Other {
 		retVal += "Other, "
 	}
-	if systemCap.Repeater {
+	if capabilities.Repeater {
 		retVal += "Repeater, "
 	}
-	if systemCap.Bridge {
+	if capabilities.Bridge {
 		retVal += "Bridge, "
 	}
-	if systemCap.WLANAP {
+	if capabilities.WLANAP {
 		retVal += "WlanAP, "
 	}
-	if systemCap.Router {
+	if capabilities.Router {
 		retVal += "Router, "
 	}
-	if systemCap.Phone {
+	if capabilities.Phone {
 		retVal += "Phone, "
 	}
-	if systemCap.DocSis {
+	if capabilities.DocSis {
 		retVal += "DocSis, "
 	}
-	if systemCap.StationOnly {
+	if capabilities.StationOnly {
 		retVal += "StationOnly, "
 	}
-	if systemCap.CVLAN {
+	if capabilities.CVLAN {
 		retVal += "CVlan, "
 	}
-	if systemCap.SVLAN {
+	if capabilities.SVLAN {
 		retVal += "SVlan, "
 	}
-	if systemCap.TMPR {
+	if capabilities.TMPR {
 		retVal += "TMPR, "
 	}
 
 	return strings.TrimSuffix(retVal, ", ")
 }
 
-/*  Get Enabled Capability info
- *	 Based on booleans value Return the string, which states what enabled capabilities are enabled
+/*  Get IEEE 802.1 & IEEE 802.3 org specific information received from peer
+ *  Link Aggregation TLV was moved from IEEE 802.3 to IEEE 802.1 in 802.1AB-2009, so if peer
+ *  doesn't send IEEE 802.1 Link Aggregation TLV then we will fallback to IEEE 802.3 one
  */
-func (intf *LLDPGlobalInfo) GetEnabledCap() string {
-	retVal := ""
-	enabledCap := intf.RxInfo.RxLinkInfo.SysCapabilities.EnabledCap
-	if enabledCap.Other {
-		retVal += "Other, "
-	}
-	if enabledCap.Repeater {
-		retVal += "Repeater, "
-	}
-	if enabledCap.Bridge {
-		retVal += "Bridge, "
+func (intf *LLDPGlobalInfo) GetPeerOrgInfo(nbr *packet.Neighbor, entry *config.IntfState) {
+	dot1Info, err := nbr.RxLinkInfo.Decode8021()
+	if err != nil {
+		debug.Logger.Err("Decoding IEEE 802.1 tlv's failed for port", intf.Port.Name, "error:", err)
 	}
-	if enabledCap.WLANAP {
-		retVal += "WlanAP, "
+	dot3Info, err := nbr.RxLinkInfo.Decode8023()
+	if err != nil {
+		debug.Logger.Err("Decoding IEEE 802.3 tlv's failed for port", intf.Port.Name, "error:", err)
 	}
-	if enabledCap.Router {
-		retVal += "Router, "
+	entry.PeerPortVlanId = int32(dot1Info.PVID)
+	entry.PeerProtocolVlanIds = nil
+	for _, ppvid := range dot1Info.PPVIDs {
+		if ppvid.Enabled {
+			entry.PeerProtocolVlanIds = append(entry.PeerProtocolVlanIds, int32(ppvid.ID))
+		}
 	}
-	if enabledCap.Phone {
-		retVal += "Phone, "
+	entry.PeerVlans = nil
+	for _, vlan := range dot1Info.VLANNames {
+		entry.PeerVlans = append(entry.PeerVlans, config.VlanInfo{
+			VlanId:   int32(vlan.ID),
+			Name:     vlan.Name,
+			Untagged: int32(vlan.ID) == entry.PeerPortVlanId,
+		})
+	}
+	linkAgg := dot1Info.LinkAggregation
+	if !linkAgg.Supported && !linkAgg.Enabled {
+		linkAgg = dot3Info.LinkAggregation
+	}
+	entry.PeerAggCapable = linkAgg.Supported
+	entry.PeerAggEnabled = linkAgg.Enabled
+	entry.PeerAggPortId = int32(linkAgg.PortID)
+	entry.PeerAutoNegSupported = dot3Info.MACPHYConfigStatus.AutoNegSupported
+	entry.PeerAutoNegEnabled = dot3Info.MACPHYConfigStatus.AutoNegEnabled
+	entry.PeerAutoNegAdvertisedCap = int32(dot3Info.MACPHYConfigStatus.AutoNegCapability)
+	entry.PeerMAUType = int32(dot3Info.MACPHYConfigStatus.MAUType)
+	entry.PeerMaxFrameSize = int32(dot3Info.MTU)
+}
+
+/*  Get LLDP-MED inventory & policy information received from MED endpoint
+ */
+func (intf *LLDPGlobalInfo) GetPeerMedInfo(nbr *packet.Neighbor, entry *config.IntfState) {
+	medInfo, err := nbr.RxLinkInfo.DecodeMedia()
+	if err != nil {
+		debug.Logger.Err("Decoding LLDP-MED tlv's failed for port", intf.Port.Name, "error:", err)
+	}
+	if medInfo.MediaCapabilities.Class != layers.LLDPMediaClassUndefined {
+		entry.PeerMedDeviceClass = medInfo.MediaCapabilities.Class.String()
+	}
+	entry.PeerMedCapabilities = MedCapabilitiesToString(medInfo.MediaCapabilities)
+	entry.PeerMedNetworkPolicy = packet.DecodeMedNetworkPolicies(nbr.RxLinkInfo)
+	entry.PeerMedHardwareRevision = medInfo.HardwareRevision
+	entry.PeerMedFirmwareRevision = medInfo.FirmwareRevision
+	entry.PeerMedSoftwareRevision = medInfo.SoftwareRevision
+	entry.PeerMedSerialNumber = medInfo.SerialNumber
+	entry.PeerMedManufacturer = medInfo.Manufacturer
+	entry.PeerMedModel = medInfo.Model
+	entry.PeerMedAssetId = medInfo.AssetID
+}
+
+/*  Convert LLDP-MED capabilities booleans into comma separated string
+ */
+func MedCapabilitiesToString(capabilities layers.LLDPMediaCapabilities) string {
+	retVal := ""
+	if capabilities.Capabilities {
+		retVal += "Capabilities, "
 	}
-	if enabledCap.DocSis {
-		retVal += "DocSis, "
+	if capabilities.NetworkPolicy {
+		retVal += "NetworkPolicy, "
 	}
-	if enabledCap.StationOnly {
-		retVal += "StationOnly, "
+	if capabilities.Location {
+		retVal += "Location, "
 	}
-	if enabledCap.CVLAN {
-		retVal += "CVlan, "
+	if capabilities.PowerPSE {
+		retVal += "PowerPSE, "
 	}
-	if enabledCap.SVLAN {
-		retVal += "SVlan, "
+	if capabilities.PowerPD {
+		retVal += "PowerPD, "
 	}
-	if enabledCap.TMPR {
-		retVal += "TMPR, "
+	if capabilities.Inventory {
+		retVal += "Inventory, "
 	}
-
 	return strings.TrimSuffix(retVal, ", ")
 }
 
 /*  Get Peer Host Name information
  *
  */
-func (intf *LLDPGlobalInfo) GetPeerHostName() string {
-	return intf.RxInfo.RxLinkInfo.SysName
+func (intf *LLDPGlobalInfo) GetPeerHostName(nbr *packet.Neighbor) string {
+	return nbr.RxLinkInfo.SysName
 }
 
 /*  Get Peer Host Name information
  *
  */
-func (intf *LLDPGlobalInfo) GetSystemDescription() string {
-	return intf.RxInfo.RxLinkInfo.SysDescription
+func (intf *LLDPGlobalInfo) GetSystemDescription(nbr *packet.Neighbor) string {
+	return nbr.RxLinkInfo.SysDescription
 }
 
 /*  dump received lldp frame and other TX information
@@ -322,11 +311,16 @@ func (intf LLDPGlobalInfo) DumpFrame() {
 	intf.RxLock.RLock()
 	defer intf.RxLock.RUnlock()
 	debug.Logger.Debug("L2 Port:", intf.Port.IfIndex, "Port IfIndex:", intf.Port.IfIndex)
-	debug.Logger.Debug("SrcMAC:", intf.RxInfo.SrcMAC.String(), "DstMAC:", intf.RxInfo.DstMAC.String())
-	debug.Logger.Debug("ChassisID info is", intf.RxInfo.RxFrame.ChassisID)
-	debug.Logger.Debug("PortID info is", intf.RxInfo.RxFrame.PortID)
-	debug.Logger.Debug("TTL info is", intf.RxInfo.RxFrame.TTL)
-	debug.Logger.Debug("Optional Values is", intf.RxInfo.RxLinkInfo)
+	for _, agent := range intf.Agents {
+		for _, nbr := range agent.RxInfo.SortedNeighbors() {
+			debug.Logger.Debug("Agent:", AgentTypeToString(agent.AgentType), "RemIndex:", nbr.RemIndex)
+			debug.Logger.Debug("SrcMAC:", nbr.SrcMAC.String(), "DstMAC:", agent.RxInfo.DstMAC.String())
+			debug.Logger.Debug("ChassisID info is", nbr.RxFrame.ChassisID)
+			debug.Logger.Debug("PortID info is", nbr.RxFrame.PortID)
+			debug.Logger.Debug("TTL info is", nbr.RxFrame.TTL)
+			debug.Logger.Debug("Optional Values is", nbr.RxLinkInfo)
+		}
+	}
 }
 
 /*  Api used to get entry.. This is mainly used by LLDP Server API Layer when it get config from
@@ -359,6 +353,14 @@ func (svr *LLDPServer) UpdateCache(sysInfo *config.SystemInfo) {
 		if !exists {
 			continue
 		}
-		intf.TxInfo.SetCache(false)
+		intf.SomethingChangedLocal()
+	}
+}
+
+/*  Local information is changed, inform all the agents running on the port
+ */
+func (intf *LLDPGlobalInfo) SomethingChangedLocal() {
+	for _, agent := range intf.Agents {
+		agent.TxInfo.SomethingChangedLocal()
 	}
 }
@@ -0,0 +1,200 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"errors"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"l2/lldp/config"
+	"l2/lldp/utils"
+	"sync"
+)
+
+/*  In memory packet I/O network, used to run lldp without real interfaces. Every server gets its own
+ *  packet I/O from the network and ports of different packet I/O's are connected back to back, frame
+ *  written on a port is delivered to the port it is connected to if rx is running on it
+ */
+type MemNetwork struct {
+	sync.Mutex
+	links map[memEndpoint]memEndpoint
+}
+
+type memEndpoint struct {
+	pio  *memPktIO
+	name string
+}
+
+type memPktIO struct {
+	network *MemNetwork
+	// open ports keyed by port name
+	handles map[string]*memPktHandle
+}
+
+type memPktHandle struct {
+	pio     *memPktIO
+	name    string
+	ifIndex int32
+	// rx channel, nil when rx is not running
+	rxCh   chan InPktChannel
+	closed bool
+}
+
+func NewMemNetwork() *MemNetwork {
+	return &MemNetwork{
+		links: make(map[memEndpoint]memEndpoint),
+	}
+}
+
+/*  Create packet I/O for a server attached to the network
+ */
+func (mn *MemNetwork) NewPktIO() PktIO {
+	return &memPktIO{
+		network: mn,
+		handles: make(map[string]*memPktHandle),
+	}
+}
+
+/*  Connect portA of pioA with portB of pioB, existing links of both the ports are removed. Ports do
+ *  not need to be open
+ */
+func (mn *MemNetwork) Connect(pioA PktIO, portA string, pioB PktIO, portB string) error {
+	memA, okA := pioA.(*memPktIO)
+	memB, okB := pioB.(*memPktIO)
+	if !okA || !okB || memA.network != mn || memB.network != mn {
+		return errors.New("Packet io is not part of the network")
+	}
+	a := memEndpoint{memA, portA}
+	b := memEndpoint{memB, portB}
+	if a == b {
+		return errors.New("Port cannot be connected to itself " + portA)
+	}
+	mn.Lock()
+	defer mn.Unlock()
+	mn.disconnect(a)
+	mn.disconnect(b)
+	mn.links[a] = b
+	mn.links[b] = a
+	return nil
+}
+
+/*  Remove link of the port, frames written on both the ends are dropped after that
+ */
+func (mn *MemNetwork) Disconnect(pio PktIO, port string) {
+	mem, ok := pio.(*memPktIO)
+	if !ok {
+		return
+	}
+	mn.Lock()
+	defer mn.Unlock()
+	mn.disconnect(memEndpoint{mem, port})
+}
+
+func (mn *MemNetwork) disconnect(ep memEndpoint) {
+	peer, exists := mn.links[ep]
+	if !exists {
+		return
+	}
+	delete(mn.links, ep)
+	delete(mn.links, peer)
+}
+
+func (pio *memPktIO) Open(port *config.PortInfo) (PktHandle, error) {
+	mn := pio.network
+	mn.Lock()
+	defer mn.Unlock()
+	if _, exists := pio.handles[port.Name]; exists {
+		return nil, errors.New("Packet handle already open for " + port.Name)
+	}
+	ph := &memPktHandle{
+		pio:     pio,
+		name:    port.Name,
+		ifIndex: port.IfIndex,
+	}
+	pio.handles[port.Name] = ph
+	return ph, nil
+}
+
+func (pio *memPktIO) Close() {
+	pio.network.Lock()
+	defer pio.network.Unlock()
+	for _, ph := range pio.handles {
+		ph.closed = true
+		ph.rxCh = nil
+	}
+	pio.handles = make(map[string]*memPktHandle)
+}
+
+func (ph *memPktHandle) StartRx(lldpRxPktCh chan InPktChannel) {
+	ph.pio.network.Lock()
+	ph.rxCh = lldpRxPktCh
+	ph.pio.network.Unlock()
+}
+
+func (ph *memPktHandle) StopRx() {
+	ph.pio.network.Lock()
+	ph.rxCh = nil
+	ph.pio.network.Unlock()
+}
+
+/*  Deliver the frame to the connected port, frame is dropped if port is not connected or rx is not
+ *  running on the other end just like it would be on the wire
+ */
+func (ph *memPktHandle) WritePacket(pkt []byte) error {
+	mn := ph.pio.network
+	mn.Lock()
+	defer mn.Unlock()
+	if ph.closed {
+		return errors.New("Packet handle is closed for " + ph.name)
+	}
+	peer, exists := mn.links[memEndpoint{ph.pio, ph.name}]
+	if !exists {
+		return nil
+	}
+	peerHdl, exists := peer.pio.handles[peer.name]
+	if !exists || peerHdl.rxCh == nil {
+		return nil
+	}
+	data := make([]byte, len(pkt))
+	copy(data, pkt)
+	select {
+	case peerHdl.rxCh <- InPktChannel{gopacket.NewPacket(data, layers.LayerTypeEthernet, gopacket.Default),
+		peerHdl.ifIndex}:
+	default:
+		debug.Logger.Debug("Rx channel full, dropping frame received on", peerHdl.name)
+	}
+	return nil
+}
+
+func (ph *memPktHandle) Close() {
+	mn := ph.pio.network
+	mn.Lock()
+	defer mn.Unlock()
+	ph.rxCh = nil
+	if ph.closed {
+		return
+	}
+	ph.closed = true
+	delete(ph.pio.handles, ph.name)
+}
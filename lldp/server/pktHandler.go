@@ -26,55 +26,27 @@ package server
 import (
 	"errors"
 	_ "fmt"
-	"github.com/google/gopacket"
 	"l2/lldp/utils"
-	"time"
 )
 
-/* Go routine to recieve lldp frames. This go routine is created for all the
- * ports which are in up state.
+/*  Start receiving lldp frames on the port, frames are delivered on lldpRxPktCh
  */
-func (intf *LLDPGlobalInfo) ReceiveFrames(lldpRxPktCh chan InPktChannel) {
-	pktSrc := gopacket.NewPacketSource(intf.PcapHandle, intf.PcapHandle.LinkType())
-	in := pktSrc.Packets()
-	// process packets
-	for {
-		select {
-		case pkt, ok := <-in:
-			if ok {
-				lldpRxPktCh <- InPktChannel{pkt, intf.Port.IfIndex}
-			}
-		case <-intf.RxKill:
-			debug.Logger.Info("quit for ifIndex", intf.Port.Name, "rx exiting go routine")
-			intf.RxKill <- true
-			return
-		}
+func (intf *LLDPGlobalInfo) StartRx(lldpRxPktCh chan InPktChannel) {
+	if intf.PktHdl == nil || intf.RxRunning {
+		return
 	}
+	intf.PktHdl.StartRx(lldpRxPktCh)
+	intf.RxRunning = true
 }
 
-/*  lldp server go routine to handle tx timer... once the timer fires we will
- *  send the ifindex on the channel to handle send info
- *
- *  For fast learning we will send out 5 frames in 5 seconds and then every 30 seconds an update frame will
- *  be send out
+/*  Stop receiving lldp frames on the port
  */
-func (intf *LLDPGlobalInfo) StartTxTimer(lldpTxPktCh chan SendPktChannel) {
-	if intf.TxInfo.TxTimer != nil {
-		if intf.counter.Send > LLDP_FAST_LEARN_MAX_FRAMES_SEND {
-			intf.TxInfo.TxTimer.Reset(time.Duration(intf.TxInfo.MessageTxInterval) * time.Second)
-		} else {
-			intf.TxInfo.TxTimer.Reset(time.Duration(LLDP_FAST_LEARN_TIMER) * time.Second)
-		}
-	} else {
-		var TxTimerHandler_func func()
-		TxTimerHandler_func = func() {
-			lldpTxPktCh <- SendPktChannel{intf.Port.IfIndex}
-		}
-
-		// Create an After Func and go routine for it, so that on timer stop TX is stopped automatically
-		intf.TxInfo.TxTimer = time.AfterFunc(time.Duration(LLDP_FAST_LEARN_TIMER)*time.Second,
-			TxTimerHandler_func)
+func (intf *LLDPGlobalInfo) StopRx() {
+	if intf.PktHdl == nil || !intf.RxRunning {
+		return
 	}
+	intf.PktHdl.StopRx()
+	intf.RxRunning = false
 }
 
 /*  Write packet is helper function to send packet on wire.
@@ -87,10 +59,10 @@ func (intf *LLDPGlobalInfo) WritePacket(pkt []byte) bool {
 	if len(pkt) == 0 {
 		return false
 	}
-	if intf.PcapHandle != nil {
-		err = intf.PcapHandle.WritePacketData(pkt)
+	if intf.PktHdl != nil {
+		err = intf.PktHdl.WritePacket(pkt)
 	} else {
-		err = errors.New("Pcap Handle is invalid for " + intf.Port.Name)
+		err = errors.New("Packet Handle is invalid for " + intf.Port.Name)
 	}
 	if err != nil {
 		debug.Logger.Err("Sending packet failed Error:", err, "for Port:", intf.Port.Name)
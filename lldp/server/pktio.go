@@ -0,0 +1,185 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/pcap"
+	"l2/lldp/config"
+	"l2/lldp/utils"
+	"time"
+)
+
+/*  Packet I/O used by lldp server to receive & send frames. Open is called when rx/tx is started on
+ *  a port and returned handle is used for that port until it is closed. Close is called once on de-init
+ */
+type PktIO interface {
+	Open(port *config.PortInfo) (PktHandle, error)
+	Close()
+}
+
+/*  Per port packet handle, received frames are delivered on rx channel between StartRx & StopRx
+ */
+type PktHandle interface {
+	StartRx(lldpRxPktCh chan InPktChannel)
+	StopRx()
+	WritePacket(pkt []byte) error
+	Close()
+}
+
+const (
+	// lldp pcap handler default config values
+	LLDP_PCAP_SNAPSHOT_LEN = 1024
+	LLDP_PCAP_PROMISCUOUS  = true
+	// LLDP Notifications are atleast 5 seconds apart with default being
+	// 30 seconds. So, we can have the leavrage the pcap timeout (read from
+	// buffer) to be 1 second.
+	LLDP_PCAP_TIMEOUT = 500 * time.Millisecond
+
+	// Packet I/O names
+	LLDP_PKT_IO_PCAP     = "pcap"
+	LLDP_PKT_IO_AFPACKET = "afpacket"
+)
+
+/*  Create packet I/O based on the name, used by the daemon to select rx/tx path
+ */
+func NewPktIO(name string) (PktIO, error) {
+	switch name {
+	case LLDP_PKT_IO_PCAP:
+		return NewPcapIO(LLDP_PCAP_SNAPSHOT_LEN, LLDP_PCAP_PROMISCUOUS, LLDP_PCAP_TIMEOUT), nil
+	case LLDP_PKT_IO_AFPACKET:
+		return NewAfPacketIO(LLDP_PCAP_SNAPSHOT_LEN, LLDP_PCAP_PROMISCUOUS)
+	}
+	return nil, errors.New("Invalid packet io " + name)
+}
+
+/*  Pcap packet I/O, one pcap handle & one rx go routine per port
+ */
+type pcapIO struct {
+	snapshotLen int32
+	promiscuous bool
+	timeout     time.Duration
+}
+
+type pcapHandle struct {
+	hdl     *pcap.Handle
+	name    string
+	ifIndex int32
+	// rx go routine is running or not
+	rxRunning bool
+	// Go Routine Killer Channel
+	rxKill chan bool
+}
+
+func NewPcapIO(snapshotLen int32, promiscuous bool, timeout time.Duration) PktIO {
+	return &pcapIO{
+		snapshotLen: snapshotLen,
+		promiscuous: promiscuous,
+		timeout:     timeout,
+	}
+}
+
+/*  Create Pcap Handler
+ */
+func (pio *pcapIO) Open(port *config.PortInfo) (PktHandle, error) {
+	debug.Logger.Debug("Creating Pcap for port:", port.Name, "ifIndex:", port.IfIndex)
+	pcapHdl, err := pcap.OpenLive(port.Name, pio.snapshotLen, pio.promiscuous, pio.timeout)
+	if err != nil {
+		debug.Logger.Err(fmt.Sprintln("Creating Pcap Handler failed for", port.Name, "Error:", err))
+		return nil, errors.New("Creating Pcap Failed")
+	}
+	err = pcapHdl.SetBPFFilter(LLDP_BPF_FILTER)
+	if err != nil {
+		debug.Logger.Err(fmt.Sprintln("setting filter:", LLDP_BPF_FILTER, "for", port.Name,
+			"failed with error:", err))
+		pcapHdl.Close()
+		return nil, errors.New("Setting BPF Filter Failed")
+	}
+	debug.Logger.Debug("Pcap Created for port:", port.Name, "ifIndex:", port.IfIndex)
+	return &pcapHandle{
+		hdl:     pcapHdl,
+		name:    port.Name,
+		ifIndex: port.IfIndex,
+		rxKill:  make(chan bool),
+	}, nil
+}
+
+func (pio *pcapIO) Close() {
+}
+
+/*  Start go routine to recieve lldp frames
+ */
+func (ph *pcapHandle) StartRx(lldpRxPktCh chan InPktChannel) {
+	if ph.rxRunning {
+		return
+	}
+	go ph.receiveFrames(lldpRxPktCh)
+	ph.rxRunning = true
+}
+
+/*  Send go routine kill signal and wait for it to exit
+ */
+func (ph *pcapHandle) StopRx() {
+	if !ph.rxRunning {
+		return
+	}
+	ph.rxKill <- true
+	<-ph.rxKill
+	ph.rxRunning = false
+}
+
+/* Go routine to recieve lldp frames. This go routine is created for all the
+ * ports which are in up state.
+ */
+func (ph *pcapHandle) receiveFrames(lldpRxPktCh chan InPktChannel) {
+	pktSrc := gopacket.NewPacketSource(ph.hdl, ph.hdl.LinkType())
+	in := pktSrc.Packets()
+	// process packets
+	for {
+		select {
+		case pkt, ok := <-in:
+			if ok {
+				lldpRxPktCh <- InPktChannel{pkt, ph.ifIndex}
+			}
+		case <-ph.rxKill:
+			debug.Logger.Info("quit for ifIndex", ph.name, "rx exiting go routine")
+			ph.rxKill <- true
+			return
+		}
+	}
+}
+
+func (ph *pcapHandle) WritePacket(pkt []byte) error {
+	return ph.hdl.WritePacketData(pkt)
+}
+
+/*  Stop rx go routine right away before even we do anything else and then close the pcap handle
+ */
+func (ph *pcapHandle) Close() {
+	ph.StopRx()
+	// @FIXME: some bug in close handling that causes 5 mins delay
+	ph.hdl.Close()
+}
@@ -26,6 +26,7 @@ package server
 import (
 	_ "fmt"
 	"l2/lldp/config"
+	"l2/lldp/packet"
 	"l2/lldp/plugin"
 	"l2/lldp/utils"
 	_ "models/objects"
@@ -60,24 +61,41 @@ func LLDPNewServer(aPlugin plugin.AsicIntf, lPlugin plugin.ConfigIntf, sPlugin p
 	return lldpServerInfo
 }
 
+/*  Use given packet I/O for lldp frames rx/tx instead of per port pcap handles, should be called
+ *  before server is started
+ */
+func (svr *LLDPServer) SetPktIO(pktIO PktIO) {
+	svr.pktIO = pktIO
+}
+
 /* Allocate memory to all the object which are being used by LLDP server
  */
 func (svr *LLDPServer) InitGlobalDS() {
 	svr.lldpGblInfo = make(map[int32]LLDPGlobalInfo, LLDP_INITIAL_GLOBAL_INFO_CAPACITY)
 	svr.lldpIntfRef2IfIndexMap = make(map[string]int32, LLDP_INITIAL_GLOBAL_INFO_CAPACITY)
+	svr.restoredNbrs = make(map[string][]neighborCheckpoint)
 	svr.lldpRxPktCh = make(chan InPktChannel, LLDP_RX_PKT_CHANNEL_SIZE)
-	svr.lldpTxPktCh = make(chan SendPktChannel, LLDP_TX_PKT_CHANNEL_SIZE)
-	svr.lldpSnapshotLen = 1024
-	svr.lldpPromiscuous = true
-	// LLDP Notifications are atleast 5 seconds apart with default being
-	// 30 seconds. So, we can have the leavrage the pcap timeout (read from
-	// buffer) to be 1 second.
-	svr.lldpTimeout = 500 * time.Millisecond
+	svr.lldpTxTick = time.NewTicker(LLDP_TX_TICK_INTERVAL * time.Second)
+	svr.pktIO = NewPcapIO(LLDP_PCAP_SNAPSHOT_LEN, LLDP_PCAP_PROMISCUOUS, LLDP_PCAP_TIMEOUT)
 	svr.GblCfgCh = make(chan *config.Global, 2)
 	svr.IntfCfgCh = make(chan *config.IntfConfig, LLDP_PORT_CONFIG_CHANNEL_SIZE)
 	svr.IfStateCh = make(chan *config.PortState, LLDP_PORT_STATE_CHANGE_CHANNEL_SIZE)
+	svr.IfL3StateCh = make(chan *config.PortL3State, LLDP_PORT_STATE_CHANGE_CHANNEL_SIZE)
+	svr.VlanStateCh = make(chan *config.VlanState, LLDP_PORT_STATE_CHANGE_CHANNEL_SIZE)
+	svr.LagStateCh = make(chan *config.LagState, LLDP_PORT_STATE_CHANGE_CHANNEL_SIZE)
 	svr.UpdateCacheCh = make(chan *config.SystemInfo, 1)
 	svr.EventCh = make(chan config.EventInfo, 10)
+	svr.ClearStatsCh = make(chan *config.ClearStats, LLDP_PORT_CONFIG_CHANNEL_SIZE)
+	svr.NotificationIntervalCh = make(chan int32, LLDP_PORT_CONFIG_CHANNEL_SIZE)
+	svr.ChassisIdCfgCh = make(chan *config.ChassisIdConfig, LLDP_PORT_CONFIG_CHANNEL_SIZE)
+	svr.CablingPlanCh = make(chan []config.ExpectedNeighbor, 1)
+	svr.cablingPlan = make(map[int32]config.ExpectedNeighbor)
+	svr.cablingStatusInfo = make(map[int32]string)
+	svr.dcbxStateInfo = make(map[int32]config.DcbxState)
+	svr.pendingEvents = make(map[eventKey]*config.EventInfo)
+	svr.notificationInterval = LLDP_DEFAULT_NOTIFICATION_INTERVAL
+	svr.notifyCh = make(chan bool, 1)
+	svr.subscribers = make(map[int32]chan config.EventInfo)
 	svr.counter.Send = 0
 	svr.counter.Rcvd = 0
 	// All Plugin Info
@@ -88,9 +106,11 @@ func (svr *LLDPServer) InitGlobalDS() {
 func (svr *LLDPServer) DeInitGlobalDS() {
 	// close rx packet channel
 	close(svr.lldpRxPktCh)
-	close(svr.lldpTxPktCh)
+	svr.lldpTxTick.Stop()
+	if svr.notifyTimer != nil {
+		svr.notifyTimer.Stop()
+	}
 	svr.lldpRxPktCh = nil
-	svr.lldpTxPktCh = nil
 	svr.lldpGblInfo = nil
 }
 
@@ -100,7 +120,7 @@ func (svr *LLDPServer) DeInitGlobalDS() {
  */
 func (svr *LLDPServer) CloseAllPktHandlers() {
 
-	// close pcap, stop cache timer and free any allocated memory
+	// close packet handles, stop cache timer and free any allocated memory
 	for i := 0; i < len(svr.lldpIntfStateSlice); i++ {
 		key := svr.lldpIntfStateSlice[i]
 		intf, exists := svr.lldpGblInfo[key]
@@ -110,6 +130,7 @@ func (svr *LLDPServer) CloseAllPktHandlers() {
 		intf.DeInitRuntimeInfo()
 		svr.lldpGblInfo[key] = intf
 	}
+	svr.pktIO.Close()
 	debug.Logger.Info("closed everything")
 }
 
@@ -118,6 +139,8 @@ func (svr *LLDPServer) CloseAllPktHandlers() {
 func (svr *LLDPServer) InitL2PortInfo(portInfo *config.PortInfo) {
 	intf, _ := svr.lldpGblInfo[portInfo.IfIndex]
 	intf.InitRuntimeInfo(portInfo)
+	intf.SetChassisId(svr.chassisId)
+	svr.updateTxTimers(&intf)
 	// on fresh start it will not exists but on restart it might
 	// default is set to true but LLDP Object is auto-discover and hence we will enable it manually
 	// we will overwrite the value based on dbReead but default should always be true
@@ -165,24 +188,27 @@ func (svr *LLDPServer) LLDPStartServer(paramsDir string) {
 	go svr.ChannelHandler()
 }
 
-/*  Create os signal handler channel and initiate go routine for that
+/*  Create os signal handler channel, signals are handled by channel handler go routine
  */
 func (svr *LLDPServer) OSSignalHandle() {
-	sigChannel := make(chan os.Signal, 1)
-	signalList := []os.Signal{syscall.SIGHUP}
-	signal.Notify(sigChannel, signalList...)
-	go svr.SignalHandler(sigChannel)
+	svr.lldpSignalCh = make(chan os.Signal, 1)
+	signalList := []os.Signal{syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM}
+	signal.Notify(svr.lldpSignalCh, signalList...)
 }
 
 /* OS signal handler.
- *      If the process get a sighup signal then close all the pcap handlers.
+ *      If the process get a sighup/sigint/sigterm signal then send shutdown frame on all the ports
+ *      so that peer can age us out and close all the pcap handlers.
  *      After that delete all the memory which was used during init process
+ *      This is called from channel handler so that per port information is not accessed concurrently
  */
-func (svr *LLDPServer) SignalHandler(sigChannel <-chan os.Signal) {
-	signal := <-sigChannel
+func (svr *LLDPServer) SignalHandler(signal os.Signal) {
 	switch signal {
-	case syscall.SIGHUP:
-		debug.Logger.Alert("Received SIGHUP Signal")
+	case syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM:
+		debug.Logger.Alert("Received", signal, "Signal")
+		for _, ifIndex := range svr.lldpUpIntfStateSlice {
+			svr.SendShutdownFrame(ifIndex)
+		}
 		svr.CloseAllPktHandlers()
 		svr.DeInitGlobalDS()
 		svr.CloseDB()
@@ -194,10 +220,10 @@ func (svr *LLDPServer) SignalHandler(sigChannel <-chan os.Signal) {
 	}
 }
 
-/* Create l2 port pcap handler and then start rx and tx on that pcap
- *	Filter is LLDP_BPF_FILTER = "ether proto 0x88cc"
+/* Open l2 port packet handle and then start rx and tx on that handle
+ *	Filter is LLDP_BPF_FILTER = "ether proto 0x88cc or ether dst 01:00:0c:cc:cc:cc"
  * Note: API should only and only do
- *  1) pcap create
+ *  1) packet handle open
  *  2) start go routine for Rx/Tx Frames Packet Handler
  *  3) Add the port to UP List
  */
@@ -212,10 +238,10 @@ func (svr *LLDPServer) StartRxTx(ifIndex int32, rxtxMode uint8) {
 		debug.Logger.Info("Port is down and hence not starting pcap handler yet for", intf.Port.Name)
 		return
 	}
-	if intf.PcapHandle == nil {
-		err := intf.CreatePcapHandler(svr.lldpSnapshotLen, svr.lldpPromiscuous, svr.lldpTimeout)
+	if intf.PktHdl == nil {
+		err := intf.OpenPktHandle(svr.pktIO)
 		if err != nil {
-			debug.Logger.Alert("Creating Pcap Handler for", intf.Port.Name,
+			debug.Logger.Alert("Opening Packet Handle for", intf.Port.Name,
 				"failed and hence we will not start LLDP on the port")
 			return
 		}
@@ -225,30 +251,26 @@ func (svr *LLDPServer) StartRxTx(ifIndex int32, rxtxMode uint8) {
 	// Everything set up, so now lets start with receiving frames and transmitting frames go routine...
 	//If RX routine not running start it
 	if rxtxMode != config.TX_ONLY {
-		if !intf.RxInfo.RxRunning {
-			go intf.ReceiveFrames(svr.lldpRxPktCh)
-			intf.RxInfo.RxRunning = true
-		}
+		intf.StartRx(svr.lldpRxPktCh)
 	} else {
-		//RX go routine could have been spawned due to earlier txrx or rx only modes
-		if intf.RxInfo.RxRunning {
-			intf.RxKill <- true
-			intf.RxInfo.RxRunning = false
-			<-intf.RxKill
+		//RX could have been started due to earlier txrx or rx only modes
+		if intf.RxRunning {
+			intf.StopRx()
 			intf.counter.Rcvd = 0
 		}
 	}
-	//If TX routine not running start it
-	if rxtxMode != config.RX_ONLY {
-		if intf.TxInfo.TxTimer == nil {
-			intf.StartTxTimer(svr.lldpTxPktCh)
-		}
-	} else {
-		//TX go routine could have been spawned due to earlier txrx or tx only modes
-		intf.TxInfo.StopTxTimer()
+	svr.lldpGblInfo[ifIndex] = intf
+	// neighbors known before restart are restored before any frame is received
+	svr.restoreNeighbors(ifIndex)
+	//If TX state machine not running start it for all the agents allowed to transmit
+	svr.startAgentsTx(ifIndex, rxtxMode)
+	if rxtxMode == config.RX_ONLY {
+		intf, _ = svr.lldpGblInfo[ifIndex]
 		intf.counter.Send = 0
+		svr.lldpGblInfo[ifIndex] = intf
 	}
-	svr.lldpGblInfo[ifIndex] = intf
+	svr.CheckCabling(ifIndex)
+	svr.UpdateDcbx(ifIndex)
 	return
 }
 
@@ -262,17 +284,25 @@ func (svr *LLDPServer) StopRxTx(ifIndex int32) {
 		return
 	}
 
-	// stop the timer
-	intf.TxInfo.StopTxTimer()
-	// Delete Pcap Handler
-	intf.DeletePcapHandler()
-	// invalid the cache information
-	intf.TxInfo.DeleteCacheFrame()
+	// stop the transmit state machine, shutdown frame can only be send if port is still up
+	if intf.Port.OperState == LLDP_PORT_STATE_UP {
+		svr.SendShutdownFrame(ifIndex)
+		intf, _ = svr.lldpGblInfo[ifIndex]
+	}
+	for _, agent := range intf.Agents {
+		agent.TxInfo.StopTx()
+		// invalid the cache information
+		agent.TxInfo.DeleteCacheFrame()
+	}
+	// Close Packet Handle
+	intf.ClosePktHandle()
 	intf.counter.Rcvd = 0
 	intf.counter.Send = 0
 	svr.lldpGblInfo[ifIndex] = intf
 	debug.Logger.Info("Stop lldp frames rx/tx for port:", intf.Port.Name, "ifIndex:", intf.Port.IfIndex)
 	svr.DeletePortFromUpState(ifIndex)
+	svr.CheckCabling(ifIndex)
+	svr.UpdateDcbx(ifIndex)
 }
 
 /*  helper function to inform whether rx channel is closed or open...
@@ -325,7 +355,7 @@ func (svr *LLDPServer) UpdateL2IntfStateChange(ifIndex int32, state string) {
 		intf.Port.OperState = LLDP_PORT_STATE_UP
 		svr.lldpGblInfo[ifIndex] = intf
 		if intf.isEnabled() {
-			// Create Pcap Handler and start rx/tx packets
+			// Open Packet Handle and start rx/tx packets
 			svr.StartRxTx(ifIndex, intf.rxtxMode)
 		}
 	case "DOWN":
@@ -334,12 +364,103 @@ func (svr *LLDPServer) UpdateL2IntfStateChange(ifIndex int32, state string) {
 		intf.Port.OperState = LLDP_PORT_STATE_DOWN
 		svr.lldpGblInfo[ifIndex] = intf
 		if intf.isEnabled() {
-			// Delete Pcap Handler and stop rx/tx packets
+			// Close Packet Handle and stop rx/tx packets
 			svr.StopRxTx(ifIndex)
 		}
 	}
 }
 
+/*  handle l3 interface create/delete notifications.. local enabled capabilities (bridge/router)
+ *  depends on it and hence next frame needs to be constructed again
+ */
+func (svr *LLDPServer) UpdateL3IntfStateChange(ifIndex int32, l3Enabled bool) {
+	intf, found := svr.lldpGblInfo[ifIndex]
+	if !found {
+		return
+	}
+	if intf.Port.L3Enabled == l3Enabled {
+		return
+	}
+	debug.Logger.Debug("L3 State notification for", intf.Port.Name, "ifIndex:", ifIndex, "l3 enabled:", l3Enabled)
+	intf.Port.L3Enabled = l3Enabled
+	intf.SomethingChangedLocal()
+	svr.lldpGblInfo[ifIndex] = intf
+}
+
+/*  handle vlan create/update/delete notifications.. vlan name and port vlan id tlv's are derived
+ *  from vlan membership and hence next frame needs to be constructed again for ports whose
+ *  membership changed
+ */
+func (svr *LLDPServer) UpdateVlanStateChange(vlanState *config.VlanState) {
+	untagPorts := make(map[int32]bool, len(vlanState.UntagPorts))
+	for _, ifIndex := range vlanState.UntagPorts {
+		untagPorts[ifIndex] = true
+	}
+	tagPorts := make(map[int32]bool, len(vlanState.TagPorts))
+	for _, ifIndex := range vlanState.TagPorts {
+		tagPorts[ifIndex] = true
+	}
+	for _, ifIndex := range svr.lldpIntfStateSlice {
+		intf, found := svr.lldpGblInfo[ifIndex]
+		if !found {
+			continue
+		}
+		changed := false
+		vlans := make([]config.VlanInfo, 0, len(intf.Port.Vlans)+1)
+		for _, vlan := range intf.Port.Vlans {
+			if vlan.VlanId == vlanState.VlanId {
+				changed = true
+				continue
+			}
+			vlans = append(vlans, vlan)
+		}
+		if untagPorts[ifIndex] || tagPorts[ifIndex] {
+			changed = true
+			vlans = append(vlans, config.VlanInfo{
+				VlanId:   vlanState.VlanId,
+				Name:     vlanState.Name,
+				Untagged: untagPorts[ifIndex],
+			})
+		}
+		if !changed {
+			continue
+		}
+		debug.Logger.Debug("Vlan membership changed for", intf.Port.Name, "vlans:", vlans)
+		intf.Port.Vlans = vlans
+		intf.SomethingChangedLocal()
+		svr.lldpGblInfo[ifIndex] = intf
+	}
+}
+
+/*  handle lag create/update/delete notifications.. link aggregation tlv is derived from lag
+ *  membership and hence next frame needs to be constructed again for ports whose membership changed
+ */
+func (svr *LLDPServer) UpdateLagStateChange(lagState *config.LagState) {
+	members := make(map[int32]bool, len(lagState.Members))
+	for _, ifIndex := range lagState.Members {
+		members[ifIndex] = true
+	}
+	for _, ifIndex := range svr.lldpIntfStateSlice {
+		intf, found := svr.lldpGblInfo[ifIndex]
+		if !found {
+			continue
+		}
+		aggIfIndex := intf.Port.AggIfIndex
+		if members[ifIndex] {
+			aggIfIndex = lagState.IfIndex
+		} else if intf.Port.AggIfIndex == lagState.IfIndex {
+			aggIfIndex = 0
+		}
+		if aggIfIndex == intf.Port.AggIfIndex {
+			continue
+		}
+		debug.Logger.Debug("Lag membership changed for", intf.Port.Name, "lag ifIndex:", aggIfIndex)
+		intf.Port.AggIfIndex = aggIfIndex
+		intf.SomethingChangedLocal()
+		svr.lldpGblInfo[ifIndex] = intf
+	}
+}
+
 /*  handle global lldp enable/disable, which will enable/disable lldp for all the ports
  */
 func (svr *LLDPServer) handleGlobalConfig() {
@@ -386,16 +507,79 @@ func (svr *LLDPServer) handleGlobalConfig() {
 	}
 }
 
+/*  Snoop and drop can only be enabled when asic plugin is able to trap and drop the snooped frames
+ */
+func (svr *LLDPServer) SnoopAndDropSupported() bool {
+	_, ok := svr.asicPlugin.(plugin.SnoopAndDropIntf)
+	return ok
+}
+
+/*  Program trap & drop of the snooped frames via asic plugin, snoop and drop is disabled if the asic plugin
+ *  cannot do it, e.g. global config restored from db
+ */
+func (svr *LLDPServer) updateSnoopAndDrop() {
+	snoopPlugin, ok := svr.asicPlugin.(plugin.SnoopAndDropIntf)
+	if !ok {
+		if svr.Global.SnoopAndDrop {
+			debug.Logger.Err("Snoop and drop is not supported by the asic plugin, disabling it")
+			svr.Global.SnoopAndDrop = false
+		}
+		return
+	}
+	err := snoopPlugin.SetSnoopAndDrop(svr.Global.SnoopAndDrop)
+	if err != nil {
+		debug.Logger.Err("Setting snoop and drop to", svr.Global.SnoopAndDrop, "failed with reason", err)
+		svr.Global.SnoopAndDrop = false
+	}
+}
+
 /*  handle configuration coming from user, which will enable/disable lldp per port
  */
-func (svr *LLDPServer) handleIntfConfig(ifIndex int32, enable bool, rxtxMode uint8) {
+func (svr *LLDPServer) handleIntfConfig(intfCfg *config.IntfConfig) {
+	ifIndex := intfCfg.IfIndex
+	rxtxMode := intfCfg.TxRxMode
 	intf, found := svr.lldpGblInfo[ifIndex]
 	if !found {
 		debug.Logger.Err("No entry for ifIndex", ifIndex, "in runtime information")
 		return
 	}
 	intf.rxtxMode = rxtxMode
-	switch enable {
+	intf.medConfig = intfCfg.Med
+	intf.dcbxConfig = intfCfg.Dcbx
+	if agent := intf.GetAgent(config.AGENT_NEAREST_BRIDGE); agent != nil {
+		agent.TxInfo.SetMedConfig(intfCfg.Med)
+		agent.TxInfo.SetDcbxConfig(intfCfg.Dcbx)
+	}
+	if intfCfg.MaxNeighbors > 0 {
+		// existing neighbors are not deleted if limit is reduced, new neighbors will be discarded
+		intf.maxNeighbors = int(intfCfg.MaxNeighbors)
+		intf.RxLock.Lock()
+		for _, agent := range intf.Agents {
+			agent.RxInfo.MaxNeighbors = intf.maxNeighbors
+		}
+		intf.RxLock.Unlock()
+	}
+	if intfCfg.PortIdSubtype != intf.portIdSubtype {
+		// peer is informed to remove entry with old port id
+		svr.lldpGblInfo[ifIndex] = intf
+		svr.sendIdChangeShutdownFrame(ifIndex)
+		intf, _ = svr.lldpGblInfo[ifIndex]
+		intf.SetPortIdSubtype(intfCfg.PortIdSubtype)
+	}
+	if intfCfg.Agents != 0 && intfCfg.Agents != intf.agentTypes {
+		intf.agentTypes = intfCfg.Agents
+		svr.lldpGblInfo[ifIndex] = intf
+		svr.UpdateAgents(ifIndex)
+		intf, _ = svr.lldpGblInfo[ifIndex]
+	}
+	intf.SetTLVs(intfCfg.TLVs)
+	intf.txIntervalCfg = int(intfCfg.TxInterval)
+	intf.txHoldCfg = int(intfCfg.TxHold)
+	intf.reinitDelayCfg = int(intfCfg.ReinitDelay)
+	svr.updateTxTimers(&intf)
+	// configuration is saved even if lldp stays disabled on the port
+	svr.lldpGblInfo[ifIndex] = intf
+	switch intfCfg.Enable {
 	case true:
 		debug.Logger.Debug("Config Enable for", intf.Port.Name, "ifIndex:", intf.Port.IfIndex)
 		intf.Enable()
@@ -409,21 +593,202 @@ func (svr *LLDPServer) handleIntfConfig(ifIndex int32, enable bool, rxtxMode uin
 			svr.StopRxTx(ifIndex)
 		}
 	}
+	svr.UpdateDcbx(ifIndex)
+}
+
+/*  Apply partial configuration update on top of existing configuration of the port, only the fields
+ *  set in the update are changed. Merge is done here so that concurrent updates of different fields
+ *  do not overwrite each other
+ */
+func (svr *LLDPServer) mergeIntfConfig(update *config.IntfConfig) *config.IntfConfig {
+	intfCfg := svr.getIntfConfig(update.IfIndex)
+	if intfCfg == nil {
+		return nil
+	}
+	if update.Fields&config.INTF_CFG_ENABLE != 0 {
+		intfCfg.Enable = update.Enable
+	}
+	if update.Fields&config.INTF_CFG_TXRX_MODE != 0 {
+		intfCfg.TxRxMode = update.TxRxMode
+	}
+	if update.Fields&config.INTF_CFG_MED != 0 {
+		intfCfg.Med = update.Med
+	}
+	if update.Fields&config.INTF_CFG_MAX_NEIGHBORS != 0 {
+		intfCfg.MaxNeighbors = update.MaxNeighbors
+	}
+	if update.Fields&config.INTF_CFG_AGENTS != 0 {
+		intfCfg.Agents = update.Agents
+	}
+	if update.Fields&config.INTF_CFG_PORT_ID_SUBTYPE != 0 {
+		intfCfg.PortIdSubtype = update.PortIdSubtype
+	}
+	if update.Fields&config.INTF_CFG_TLVS != 0 {
+		intfCfg.TLVs = update.TLVs
+	}
+	if update.Fields&config.INTF_CFG_TX_TIMERS != 0 {
+		intfCfg.TxInterval = update.TxInterval
+		intfCfg.TxHold = update.TxHold
+		intfCfg.ReinitDelay = update.ReinitDelay
+	}
+	if update.Fields&config.INTF_CFG_DCBX != 0 {
+		intfCfg.Dcbx = update.Dcbx
+	}
+	return intfCfg
+}
+
+/*  Transmit timers in use are per port configuration if present, otherwise global transmit interval
+ *  and default values
+ */
+func (svr *LLDPServer) updateTxTimers(intf *LLDPGlobalInfo) {
+	interval := LLDP_DEFAULT_TX_INTERVAL
+	hold := LLDP_DEFAULT_TX_HOLD_MULTIPLIER
+	reinitDelay := packet.LLDP_DEFAULT_REINIT_DELAY
+	if intf.txIntervalCfg > 0 {
+		interval = intf.txIntervalCfg
+	} else if svr.Global != nil && svr.Global.TranmitInterval > 0 {
+		interval = int(svr.Global.TranmitInterval)
+	}
+	if intf.txHoldCfg > 0 {
+		hold = intf.txHoldCfg
+	}
+	if intf.reinitDelayCfg > 0 {
+		reinitDelay = intf.reinitDelayCfg
+	}
+	intf.SetTimers(interval, hold, reinitDelay)
+}
+
+/*  Apply global transmit interval on all the ports which do not have per port transmit interval
+ */
+func (svr *LLDPServer) updateAllTxTimers() {
+	for _, ifIndex := range svr.lldpIntfStateSlice {
+		intf, exists := svr.lldpGblInfo[ifIndex]
+		if !exists {
+			continue
+		}
+		svr.updateTxTimers(&intf)
+		svr.lldpGblInfo[ifIndex] = intf
+	}
+}
+
+/*  Apply chassis id selection on all the ports, peers are informed to remove entry with old chassis id
+ */
+func (svr *LLDPServer) handleChassisIdConfig(chassisId *config.ChassisIdConfig) {
+	if svr.chassisId == *chassisId {
+		return
+	}
+	for _, ifIndex := range svr.lldpIntfStateSlice {
+		svr.sendIdChangeShutdownFrame(ifIndex)
+	}
+	svr.chassisId = *chassisId
+	for _, ifIndex := range svr.lldpIntfStateSlice {
+		intf, exists := svr.lldpGblInfo[ifIndex]
+		if !exists {
+			continue
+		}
+		intf.SetChassisId(svr.chassisId)
+		svr.lldpGblInfo[ifIndex] = intf
+	}
+}
+
+/*  Send shutdown frame with current chassis id & port id from all the transmitting agents on the port,
+ *  transmit state machine keeps running and sends frame with new id right after
+ */
+func (svr *LLDPServer) sendIdChangeShutdownFrame(ifIndex int32) {
+	intf, exists := svr.lldpGblInfo[ifIndex]
+	if !exists || intf.PktHdl == nil {
+		return
+	}
+	for _, agent := range intf.Agents {
+		if !agent.TxInfo.TxEnabled() {
+			continue
+		}
+		if intf.WritePacket(agent.TxInfo.ShutdownFrame(intf.Port, svr.SysInfo)) {
+			intf.counter.Send++
+			svr.counter.Send++
+		}
+	}
+	svr.lldpGblInfo[ifIndex] = intf
+}
+
+/*  Update MED endpoint detection for the port based on all the neighbors learned by nearest bridge
+ *  agent, when MED endpoint is detected we start MED fast start and hence first frame is send right away
+ */
+func (svr *LLDPServer) UpdateMedEndpoint(ifIndex int32) {
+	intf, exists := svr.lldpGblInfo[ifIndex]
+	if !exists {
+		return
+	}
+	agent := intf.GetAgent(config.AGENT_NEAREST_BRIDGE)
+	if agent == nil {
+		return
+	}
+	intf.RxLock.RLock()
+	detected := agent.RxInfo.MedEndpointDetected()
+	intf.RxLock.RUnlock()
+	if !agent.TxInfo.SetMedEndpoint(detected) {
+		return
+	}
+	debug.Logger.Info("MED endpoint detected:", detected, "on port", intf.Port.Name)
+	svr.RunTxMachine(ifIndex, false)
+}
+
+/*  Run transmit & timer state machine for all the agents on the port and send frame if state machine
+ *  asks for it. tick is set only when called on every LLDP_TX_TICK_INTERVAL
+ */
+func (svr *LLDPServer) RunTxMachine(ifIndex int32, tick bool) {
+	intf, exists := svr.lldpGblInfo[ifIndex]
+	if !exists || intf.PktHdl == nil {
+		return
+	}
+	for _, agent := range intf.Agents {
+		if agent.TxInfo.Run(tick) {
+			svr.SendFrame(ifIndex, agent)
+		}
+	}
 }
 
-/*  API to send a frame when tx timer expires per port
+/*  Stop transmit state machine for all the agents on the port and send shutdown frame (TTL 0) so that
+ *  peer deletes our information right away instead of waiting for TTL to expire
  */
-func (svr *LLDPServer) SendFrame(ifIndex int32) {
+func (svr *LLDPServer) SendShutdownFrame(ifIndex int32) {
 	intf, exists := svr.lldpGblInfo[ifIndex]
-	// extra check for pcap handle
-	if exists && intf.PcapHandle != nil {
-		rv := intf.WritePacket(intf.TxInfo.Frame(intf.Port, svr.SysInfo))
+	if !exists {
+		return
+	}
+	for _, agent := range intf.Agents {
+		svr.sendAgentShutdownFrame(ifIndex, agent)
+	}
+}
+
+/*  Stop transmit state machine for the agent and send shutdown frame
+ */
+func (svr *LLDPServer) sendAgentShutdownFrame(ifIndex int32, agent *LLDPAgent) {
+	intf, exists := svr.lldpGblInfo[ifIndex]
+	if !exists || !agent.TxInfo.StopTx() || intf.PktHdl == nil {
+		return
+	}
+	if intf.WritePacket(agent.TxInfo.ShutdownFrame(intf.Port, svr.SysInfo)) {
+		debug.Logger.Info("Shutdown frame send from port:", intf.Port.Name, "agent:",
+			AgentTypeToString(agent.AgentType))
+		intf.counter.Send++
+		svr.counter.Send++
+		svr.lldpGblInfo[ifIndex] = intf
+	}
+}
+
+/*  API to send a frame when transmit state machine of the agent asks for it
+ */
+func (svr *LLDPServer) SendFrame(ifIndex int32, agent *LLDPAgent) {
+	intf, exists := svr.lldpGblInfo[ifIndex]
+	// extra check for packet handle
+	if exists && intf.PktHdl != nil {
+		rv := intf.WritePacket(agent.TxInfo.Frame(intf.Port, svr.SysInfo))
 		if rv == false {
-			intf.TxInfo.SetCache(rv)
+			agent.TxInfo.SetCache(rv)
 		}
 	}
 	debug.Logger.Debug("Frame send from port:", intf.Port.Name)
-	intf.StartTxTimer(svr.lldpTxPktCh)
 	intf.counter.Send++
 	svr.counter.Send++
 	svr.lldpGblInfo[ifIndex] = intf
@@ -435,31 +800,130 @@ func (svr *LLDPServer) ProcessRcvdPkt(rcvdInfo InPktChannel) {
 		return
 	}
 	debug.Logger.Debug("Process Packet Received on port:", intf.Port.Name)
-	var err error
+	agent := intf.GetAgentByDstMAC(packet.GetDstMAC(rcvdInfo.pkt))
+	if agent == nil {
+		debug.Logger.Debug("No agent running for frame received on port:", intf.Port.Name)
+		return
+	}
 	eventInfo := config.EventInfo{}
 	intf.RxLock.Lock()
-	eventInfo.EventType, err = intf.RxInfo.Process(intf.RxInfo, rcvdInfo.pkt)
+	// neighbor information before processing the frame is needed for the event
+	prev := agent.RxInfo.GetNeighbor(rcvdInfo.pkt)
+	eventType, nbr, err := agent.RxInfo.Process(agent.RxInfo, rcvdInfo.pkt)
 	if err != nil {
 		intf.RxLock.Unlock()
+		if err == packet.ErrTooManyNeighbors {
+			svr.remTables.Drops++
+		}
 		debug.Logger.Err("err", err, "while processing rx frame on port",
 			intf.Port.Name)
 		return
 	}
-	intf.pktRcvdTime = time.Now()
 	intf.counter.Rcvd++
 	svr.counter.Rcvd++
+	if nbr != nil && eventType != config.Removed {
+		// reset/start timer for recipient information
+		nbr.CheckPeerEntry(intf.Port.Name, svr.EventCh, rcvdInfo.ifIndex, agent.AgentType)
+	}
 	intf.RxLock.Unlock()
-	// reset/start timer for recipient information
-	intf.RxInfo.CheckPeerEntry(intf.Port.Name, svr.EventCh, rcvdInfo.ifIndex)
 	svr.lldpGblInfo[rcvdInfo.ifIndex] = intf
 	eventInfo.IfIndex = rcvdInfo.ifIndex
+	eventInfo.Agent = agent.AgentType
+	eventInfo.EventType = eventType
+	if nbr != nil {
+		eventInfo.RemIndex = nbr.RemIndex
+	}
+	svr.UpdateRemTablesStats(eventType)
+	svr.checkpointNeighbor(&intf, agent, nbr, eventType)
 
+	if eventInfo.EventType == config.Learned {
+		// new neighbor, start fast transmission so that neighbor learns about us quickly
+		agent.TxInfo.NewNeighbor()
+		svr.RunTxMachine(rcvdInfo.ifIndex, false)
+	}
 	if eventInfo.EventType != config.NoOp {
-		svr.SysPlugin.PublishEvent(eventInfo)
+		svr.UpdateMedEndpoint(rcvdInfo.ifIndex)
+		intf = svr.lldpGblInfo[rcvdInfo.ifIndex]
+		eventInfo.Before = svr.neighborRecord(&intf, agent, prev)
+		if eventType != config.Removed {
+			eventInfo.After = svr.neighborRecord(&intf, agent, nbr)
+		}
+		svr.NotifyEvent(eventInfo)
 	}
 	debug.Logger.Debug("Done Processing Packet for port:", intf.Port.Name)
 }
 
+/*  Neighbor ttl timer expired, delete the neighbor from remote table and publish the event
+ */
+func (svr *LLDPServer) AgeOutNeighbor(eventInfo config.EventInfo) {
+	intf, exists := svr.lldpGblInfo[eventInfo.IfIndex]
+	if !exists {
+		return
+	}
+	agent := intf.GetAgent(eventInfo.Agent)
+	if agent == nil {
+		// agent is deleted along with all its neighbors
+		return
+	}
+	intf.RxLock.Lock()
+	nbr := agent.RxInfo.AgeOutNeighbor(eventInfo.RemIndex)
+	intf.RxLock.Unlock()
+	if nbr == nil {
+		// neighbor refreshed or deleted already
+		return
+	}
+	svr.remTables.Ageouts++
+	svr.UpdateRemTablesStats(config.Removed)
+	svr.checkpointNeighbor(&intf, agent, nbr, config.Removed)
+	svr.UpdateMedEndpoint(eventInfo.IfIndex)
+	intf = svr.lldpGblInfo[eventInfo.IfIndex]
+	eventInfo.Before = svr.neighborRecord(&intf, agent, nbr)
+	svr.NotifyEvent(eventInfo)
+}
+
+/*  Update remote table statistics based on the event
+ */
+func (svr *LLDPServer) UpdateRemTablesStats(eventType int) {
+	switch eventType {
+	case config.Learned:
+		svr.remTables.Inserts++
+	case config.Removed:
+		svr.remTables.Deletes++
+	case config.Updated:
+		// entry is modified, only last change time is updated
+	default:
+		return
+	}
+	svr.remTables.LastChangeTime = time.Now()
+}
+
+/*  Clear statistics for the port or for all the ports including global statistics
+ */
+func (svr *LLDPServer) ClearStatistics(clr *config.ClearStats) {
+	for _, ifIndex := range svr.lldpIntfStateSlice {
+		if !clr.All && ifIndex != clr.IfIndex {
+			continue
+		}
+		intf, exists := svr.lldpGblInfo[ifIndex]
+		if !exists {
+			continue
+		}
+		intf.RxLock.Lock()
+		for _, agent := range intf.Agents {
+			agent.RxInfo.ClearStats()
+		}
+		intf.RxLock.Unlock()
+		intf.counter.Send = 0
+		intf.counter.Rcvd = 0
+		svr.lldpGblInfo[ifIndex] = intf
+	}
+	if clr.All {
+		svr.counter.Send = 0
+		svr.counter.Rcvd = 0
+		svr.remTables = RemTablesStats{}
+	}
+}
+
 /* To handle all the channels in lldp server... For detail look at the
  * LLDPInitGlobalDS api to see which all channels are getting initialized
  */
@@ -472,11 +936,12 @@ func (svr *LLDPServer) ChannelHandler() {
 				continue // rx channel should be closed only on exit
 			}
 			svr.ProcessRcvdPkt(rcvdInfo)
-		case info, ok := <-svr.lldpTxPktCh:
-			if !ok {
-				continue
+		case <-svr.lldpTxTick.C:
+			for _, ifIndex := range svr.lldpUpIntfStateSlice {
+				svr.RunTxMachine(ifIndex, true)
 			}
-			svr.SendFrame(info.ifIndex)
+		case sig := <-svr.lldpSignalCh:
+			svr.SignalHandler(sig)
 		case gbl, ok := <-svr.GblCfgCh: // Change in global config
 			if !ok {
 				debug.Logger.Err("Invalid Value Received on Global Config Channel")
@@ -487,20 +952,33 @@ func (svr *LLDPServer) ChannelHandler() {
 				debug.Logger.Info("Doing Global Config during auto-create")
 				svr.Global = &config.Global{}
 			}
+			snoopChanged := svr.Global.SnoopAndDrop != gbl.SnoopAndDrop
 			svr.Global.Enable = gbl.Enable
 			svr.Global.Vrf = gbl.Vrf
 			svr.Global.TranmitInterval = gbl.TranmitInterval
 			svr.Global.TxRxMode = gbl.TxRxMode
 			svr.Global.SnoopAndDrop = gbl.SnoopAndDrop
+			if snoopChanged {
+				svr.updateSnoopAndDrop()
+				// snooping agents are created/deleted on all the ports
+				for _, ifIndex := range svr.lldpIntfStateSlice {
+					svr.UpdateAgents(ifIndex)
+				}
+			}
+			svr.updateAllTxTimers()
 			// start all interface rx/tx in go routine only
-			// @TODO: jgheewala fixme for update in transmit interval
 			svr.handleGlobalConfig()
 		case intf, ok := <-svr.IntfCfgCh: // Change in interface config
 			if !ok {
 				continue
 			}
 			debug.Logger.Info("Server received Intf Config", intf)
-			svr.handleIntfConfig(intf.IfIndex, intf.Enable, intf.TxRxMode)
+			intfCfg := svr.mergeIntfConfig(intf)
+			if intfCfg == nil {
+				debug.Logger.Err("No entry for ifIndex", intf.IfIndex, "in runtime information")
+				continue
+			}
+			svr.handleIntfConfig(intfCfg)
 		case ifState, ok := <-svr.IfStateCh: // Change in Port State..
 			if !ok {
 				continue
@@ -508,16 +986,65 @@ func (svr *LLDPServer) ChannelHandler() {
 			debug.Logger.Info("Server received L2 Intf State Changes for ifIndex:", ifState.IfIndex,
 				"state:", ifState.IfState)
 			svr.UpdateL2IntfStateChange(ifState.IfIndex, ifState.IfState)
+		case l3State, ok := <-svr.IfL3StateCh: // Change in Port L2/L3 mode
+			if !ok {
+				continue
+			}
+			debug.Logger.Info("Server received L3 Intf State Changes for ifIndex:", l3State.IfIndex,
+				"l3 enabled:", l3State.L3Enabled)
+			svr.UpdateL3IntfStateChange(l3State.IfIndex, l3State.L3Enabled)
+		case vlanState, ok := <-svr.VlanStateCh: // Change in Vlan membership
+			if !ok {
+				continue
+			}
+			debug.Logger.Info("Server received Vlan State Changes for vlan:", vlanState.VlanId)
+			svr.UpdateVlanStateChange(vlanState)
+		case lagState, ok := <-svr.LagStateCh: // Change in Lag membership
+			if !ok {
+				continue
+			}
+			debug.Logger.Info("Server received Lag State Changes for lag ifIndex:", lagState.IfIndex)
+			svr.UpdateLagStateChange(lagState)
 		case sysInfo, ok := <-svr.UpdateCacheCh:
 			if !ok {
 				continue
 			}
 			svr.UpdateCache(sysInfo)
+		case chassisId, ok := <-svr.ChassisIdCfgCh:
+			if !ok {
+				continue
+			}
+			debug.Logger.Info("Server received chassis id config", *chassisId)
+			svr.handleChassisIdConfig(chassisId)
+		case plan, ok := <-svr.CablingPlanCh:
+			if !ok {
+				continue
+			}
+			debug.Logger.Info("Server received cabling plan with", len(plan), "entries")
+			svr.handleCablingPlan(plan)
+		case clr, ok := <-svr.ClearStatsCh:
+			if !ok {
+				continue
+			}
+			debug.Logger.Info("Server received clear statistics", *clr)
+			svr.ClearStatistics(clr)
 		case eventInfo, ok := <-svr.EventCh: //used only for delete
 			if !ok {
 				continue
 			}
-			svr.SysPlugin.PublishEvent(eventInfo)
+			if eventInfo.EventType == config.Removed {
+				svr.AgeOutNeighbor(eventInfo)
+				continue
+			}
+			svr.NotifyEvent(eventInfo)
+		case <-svr.notifyCh: // notification interval expired
+			svr.SendNotifications()
+		case interval, ok := <-svr.NotificationIntervalCh:
+			if !ok {
+				continue
+			}
+			debug.Logger.Info("Server received notification interval", interval)
+			svr.UpdateNotificationInterval(interval)
 		}
 	}
 }
@@ -533,6 +1060,13 @@ func (svr *LLDPServer) RunGlobalConfig() {
 	// channel will create a deadlock as the reader is not yet started... To avoid this we spawn go-routine
 	// for handling Global Config before Channel Handler is started
 	if svr.Global != nil {
+		if svr.Global.SnoopAndDrop {
+			svr.updateSnoopAndDrop()
+			for _, ifIndex := range svr.lldpIntfStateSlice {
+				svr.UpdateAgents(ifIndex)
+			}
+		}
+		svr.updateAllTxTimers()
 		svr.handleGlobalConfig()
 	}
 }
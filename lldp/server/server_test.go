@@ -0,0 +1,672 @@
+//
+//Copyright [2016] [SnapRoute Inc]
+//
+//Licensed under the Apache License, Version 2.0 (the "License");
+//you may not use this file except in compliance with the License.
+//You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+//	 Unless required by applicable law or agreed to in writing, software
+//	 distributed under the License is distributed on an "AS IS" BASIS,
+//	 WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//	 See the License for the specific language governing permissions and
+//	 limitations under the License.
+//
+// _______  __       __________   ___      _______.____    __    ____  __  .___________.  ______  __    __
+// |   ____||  |     |   ____\  \ /  /     /       |\   \  /  \  /   / |  | |           | /      ||  |  |  |
+// |  |__   |  |     |  |__   \  V  /     |   (----` \   \/    \/   /  |  | `---|  |----`|  ,----'|  |__|  |
+// |   __|  |  |     |   __|   >   <       \   \      \            /   |  |     |  |     |  |     |   __   |
+// |  |     |  `----.|  |____ /  .  \  .----)   |      \    /\    /    |  |     |  |     |  `----.|  |  |  |
+// |__|     |_______||_______/__/ \__\ |_______/        \__/  \__/     |__|     |__|      \______||__|  |__|
+//
+
+// lldp server tests, servers are wired back to back using in memory packet io
+// go test
+// go test -coverprofile lldpcov.out
+// go tool cover -html=lldpcov.out
+package server
+
+import (
+	"errors"
+	"fmt"
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"l2/lldp/config"
+	"l2/lldp/packet"
+	"l2/lldp/utils"
+	"sync"
+	"testing"
+	"time"
+	"utils/dbutils"
+	"utils/logging"
+)
+
+type testAsicPlugin struct {
+	ports []*config.PortInfo
+}
+
+func (p *testAsicPlugin) Start() {
+}
+
+func (p *testAsicPlugin) GetPortsInfo() []*config.PortInfo {
+	return p.ports
+}
+
+type testCfgPlugin struct {
+}
+
+func (p *testCfgPlugin) Start() error {
+	return nil
+}
+
+type testSysPlugin struct {
+	sysInfo *config.SystemInfo
+	events  []config.EventInfo
+}
+
+func (p *testSysPlugin) Start() {
+}
+
+func (p *testSysPlugin) GetSystemInfo(dbHdl *dbutils.DBUtil) *config.SystemInfo {
+	return p.sysInfo
+}
+
+func (p *testSysPlugin) PublishEvent(eventInfo config.EventInfo) {
+	p.events = append(p.events, eventInfo)
+}
+
+var loggerOnce sync.Once
+
+func OnlyForTestSetup() {
+	// ttl timers of neighbors use the logger and hence logger is set only once
+	loggerOnce.Do(func() {
+		logger, _ := logging.NewLogger("lldpd", "TEST", false)
+		debug.SetLogger(logger)
+	})
+}
+
+/*  Close all the packet handles and stop ttl timers of all the neighbors
+ */
+func OnlyForTestTeardown(svrs ...*LLDPServer) {
+	for _, svr := range svrs {
+		svr.CloseAllPktHandlers()
+	}
+}
+
+/*  Create server with the given ports on the in memory network, server is initialized the same way as
+ *  LLDPStartServer does but channel handler is not started and hence tests drive it synchronously
+ */
+func newTestServer(mn *MemNetwork, id int, hostname string, portNames ...string) *LLDPServer {
+	var ports []*config.PortInfo
+	for i, name := range portNames {
+		ports = append(ports, &config.PortInfo{
+			IfIndex:     int32(i + 1),
+			Name:        name,
+			OperState:   LLDP_PORT_STATE_UP,
+			MacAddr:     fmt.Sprintf("00:00:00:00:%02x:%02x", id, i+1),
+			Description: hostname + " " + name,
+		})
+	}
+	sysPlugin := &testSysPlugin{
+		sysInfo: &config.SystemInfo{
+			Vrf:         "default",
+			MgmtIp:      fmt.Sprintf("10.0.0.%d", id),
+			Hostname:    hostname,
+			SwitchMac:   fmt.Sprintf("00:00:00:00:%02x:00", id),
+			Description: hostname + " test system",
+		},
+	}
+	svr := LLDPNewServer(&testAsicPlugin{ports}, &testCfgPlugin{}, sysPlugin, nil)
+	svr.SetPktIO(mn.NewPktIO())
+	// events are published right away
+	svr.notificationInterval = 0
+	for _, port := range svr.asicPlugin.GetPortsInfo() {
+		svr.InitL2PortInfo(port)
+	}
+	svr.SysInfo = svr.SysPlugin.GetSystemInfo(nil)
+	return svr
+}
+
+/*  Enable lldp globally with the given rx/tx mode
+ */
+func startTestServer(svr *LLDPServer, rxtxMode uint8) {
+	svr.Global = &config.Global{
+		Vrf:      "default",
+		Enable:   true,
+		TxRxMode: rxtxMode,
+	}
+	svr.RunGlobalConfig()
+}
+
+/*  Process all the frames received by the servers till there is nothing left on the wire
+ */
+func pump(svrs ...*LLDPServer) {
+	for {
+		progressed := false
+		for _, svr := range svrs {
+			select {
+			case rcvdInfo := <-svr.lldpRxPktCh:
+				svr.ProcessRcvdPkt(rcvdInfo)
+				progressed = true
+			default:
+			}
+		}
+		if !progressed {
+			return
+		}
+	}
+}
+
+/*  Run transmit state machine tick on all the up ports cnt times and process received frames
+ */
+func tick(cnt int, svrs ...*LLDPServer) {
+	for i := 0; i < cnt; i++ {
+		for _, svr := range svrs {
+			for _, ifIndex := range svr.lldpUpIntfStateSlice {
+				svr.RunTxMachine(ifIndex, true)
+			}
+		}
+		pump(svrs...)
+	}
+}
+
+func updateIntfConfig(svr *LLDPServer, intfRef string, update func(*config.IntfConfig)) {
+	intfCfg := svr.getIntfConfig(svr.lldpIntfRef2IfIndexMap[intfRef])
+	update(intfCfg)
+	svr.handleIntfConfig(intfCfg)
+}
+
+func lastEvent(svr *LLDPServer) *config.EventInfo {
+	events := svr.SysPlugin.(*testSysPlugin).events
+	if len(events) == 0 {
+		return nil
+	}
+	return &events[len(events)-1]
+}
+
+/*  Two servers connected back to back, server A port eth1 is connected to server B port eth2
+ */
+func newTestPair(t *testing.T) (*MemNetwork, *LLDPServer, *LLDPServer) {
+	OnlyForTestSetup()
+	mn := NewMemNetwork()
+	a := newTestServer(mn, 1, "A", "eth1")
+	b := newTestServer(mn, 2, "B", "eth2")
+	err := mn.Connect(a.pktIO, "eth1", b.pktIO, "eth2")
+	if err != nil {
+		t.Fatal("connecting ports failed", err)
+	}
+	return mn, a, b
+}
+
+func checkNeighbor(t *testing.T, svr *LLDPServer, intfRef string, hostname string, port string) {
+	nbrs := svr.GetIntfNeighbors(intfRef)
+	if len(nbrs) != 1 {
+		t.Fatal("expected one neighbor on", intfRef, "actual", len(nbrs))
+	}
+	if nbrs[0].PeerHostName != hostname {
+		t.Error("expected peer hostname", hostname, "actual", nbrs[0].PeerHostName)
+	}
+	if nbrs[0].PeerPort != port {
+		t.Error("expected peer port", port, "actual", nbrs[0].PeerPort)
+	}
+}
+
+func checkNoNeighbor(t *testing.T, svr *LLDPServer, intfRef string) {
+	nbrs := svr.GetIntfNeighbors(intfRef)
+	if len(nbrs) != 0 {
+		t.Error("expected no neighbor on", intfRef, "actual", len(nbrs), nbrs)
+	}
+}
+
+func TestLLDPLearnNeighbor(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	pump(a, b)
+
+	checkNeighbor(t, a, "eth1", "B", "eth2")
+	checkNeighbor(t, b, "eth2", "A", "eth1")
+	if a.remTables.Inserts != 1 {
+		t.Error("expected remote table inserts 1 actual", a.remTables.Inserts)
+	}
+	nbr := a.GetIntfNeighbors("eth1")[0]
+	if nbr.SystemDescription != "B test system" {
+		t.Error("expected peer system description 'B test system' actual", nbr.SystemDescription)
+	}
+	if nbr.PeerMac != "00:00:00:00:02:00" {
+		t.Error("expected peer chassis id 00:00:00:00:02:00 actual", nbr.PeerMac)
+	}
+	event := lastEvent(a)
+	if event == nil || event.EventType != config.Learned || event.After == nil {
+		t.Fatal("expected learned event actual", event)
+	}
+	if event.After.PeerHostName != "B" {
+		t.Error("expected learned event for peer B actual", event.After.PeerHostName)
+	}
+
+	// periodic frames only refresh the neighbor
+	tick(LLDP_DEFAULT_TX_INTERVAL*2, a, b)
+	checkNeighbor(t, a, "eth1", "B", "eth2")
+	if a.remTables.Inserts != 1 || a.remTables.Deletes != 0 {
+		t.Error("expected remote table inserts/deletes 1/0 actual", a.remTables.Inserts, a.remTables.Deletes)
+	}
+	if len(a.SysPlugin.(*testSysPlugin).events) != 1 {
+		t.Error("expected only learned event actual", a.SysPlugin.(*testSysPlugin).events)
+	}
+	if a.counter.Rcvd < 3 || b.counter.Rcvd < 3 {
+		t.Error("expected periodic frames to be received, actual", a.counter.Rcvd, b.counter.Rcvd)
+	}
+}
+
+func TestLLDPUpdateNeighbor(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	pump(a, b)
+
+	sysInfo := *b.SysInfo
+	sysInfo.Hostname = "B-renamed"
+	b.UpdateCache(&sysInfo)
+	tick(1, a, b)
+
+	checkNeighbor(t, a, "eth1", "B-renamed", "eth2")
+	if a.remTables.Inserts != 1 {
+		t.Error("expected remote table inserts 1 actual", a.remTables.Inserts)
+	}
+	event := lastEvent(a)
+	if event == nil || event.EventType != config.Updated {
+		t.Fatal("expected updated event actual", event)
+	}
+	if event.Before.PeerHostName != "B" || event.After.PeerHostName != "B-renamed" {
+		t.Error("expected hostname change B -> B-renamed actual", event.Before.PeerHostName, "->",
+			event.After.PeerHostName)
+	}
+	if len(event.Changes) != 1 || event.Changes[0] != "PeerHostName" {
+		t.Error("expected changes [PeerHostName] actual", event.Changes)
+	}
+}
+
+func TestLLDPShutdownRemovesNeighbor(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	pump(a, b)
+
+	updateIntfConfig(b, "eth2", func(intfCfg *config.IntfConfig) {
+		intfCfg.Enable = false
+		intfCfg.ReinitDelay = 1
+	})
+	pump(a, b)
+
+	checkNoNeighbor(t, a, "eth1")
+	if a.remTables.Deletes != 1 {
+		t.Error("expected remote table deletes 1 actual", a.remTables.Deletes)
+	}
+	event := lastEvent(a)
+	if event == nil || event.EventType != config.Removed || event.Before == nil {
+		t.Fatal("expected removed event actual", event)
+	}
+	if event.Before.PeerHostName != "B" {
+		t.Error("expected removed event for peer B actual", event.Before.PeerHostName)
+	}
+
+	// port is learned again once lldp is enabled and reinit delay is over
+	updateIntfConfig(b, "eth2", func(intfCfg *config.IntfConfig) {
+		intfCfg.Enable = true
+	})
+	pump(a, b)
+	checkNoNeighbor(t, a, "eth1")
+	time.Sleep(time.Second)
+	tick(1, a, b)
+	checkNeighbor(t, a, "eth1", "B", "eth2")
+}
+
+/*  Wait for ttl timer (CheckPeerEntry) of the neighbor to expire
+ */
+func waitAgeOut(t *testing.T, svr *LLDPServer, timeout time.Duration) config.EventInfo {
+	select {
+	case eventInfo := <-svr.EventCh:
+		if eventInfo.EventType != config.Removed {
+			t.Fatal("expected removed event from ttl timer actual", eventInfo.EventType)
+		}
+		return eventInfo
+	case <-time.After(timeout):
+		t.Fatal("ttl timer did not expire in", timeout)
+	}
+	return config.EventInfo{}
+}
+
+func TestLLDPTTLExpiry(t *testing.T) {
+	mn, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	// ttl is interval * hold + 1 = 2 seconds
+	updateIntfConfig(b, "eth2", func(intfCfg *config.IntfConfig) {
+		intfCfg.TxInterval = 1
+		intfCfg.TxHold = 1
+	})
+	pump(a, b)
+	nbrs := a.GetIntfNeighbors("eth1")
+	if len(nbrs) != 1 {
+		t.Fatal("expected one neighbor actual", len(nbrs))
+	}
+	holdTime, err := time.ParseDuration(nbrs[0].HoldTime)
+	if err != nil || holdTime > 2*time.Second || holdTime < time.Second {
+		t.Fatal("expected neighbor hold time of 2 seconds actual", nbrs[0].HoldTime)
+	}
+
+	// nothing is received from the peer anymore and hence entry ages out
+	mn.Disconnect(a.pktIO, "eth1")
+	start := time.Now()
+	eventInfo := waitAgeOut(t, a, 5*time.Second)
+	if time.Since(start) < time.Second {
+		t.Error("ttl timer expired before ttl, after", time.Since(start))
+	}
+	a.AgeOutNeighbor(eventInfo)
+	checkNoNeighbor(t, a, "eth1")
+	if a.remTables.Ageouts != 1 || a.remTables.Deletes != 1 {
+		t.Error("expected remote table ageouts/deletes 1/1 actual", a.remTables.Ageouts, a.remTables.Deletes)
+	}
+	if state := a.GetIntfState("eth1"); state.AgeoutsTotal != 1 {
+		t.Error("expected port ageouts 1 actual", state.AgeoutsTotal)
+	}
+	event := lastEvent(a)
+	if event == nil || event.EventType != config.Removed {
+		t.Error("expected removed event actual", event)
+	}
+}
+
+func TestLLDPTTLExpiryRefreshed(t *testing.T) {
+	mn, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	updateIntfConfig(b, "eth2", func(intfCfg *config.IntfConfig) {
+		intfCfg.TxInterval = 1
+		intfCfg.TxHold = 1
+	})
+	pump(a, b)
+
+	mn.Disconnect(a.pktIO, "eth1")
+	eventInfo := waitAgeOut(t, a, 5*time.Second)
+	// frame from the peer is received before server handles the expiry
+	mn.Connect(a.pktIO, "eth1", b.pktIO, "eth2")
+	tick(1, a, b)
+	a.AgeOutNeighbor(eventInfo)
+	checkNeighbor(t, a, "eth1", "B", "eth2")
+	if a.remTables.Ageouts != 0 {
+		t.Error("expected remote table ageouts 0 actual", a.remTables.Ageouts)
+	}
+}
+
+func TestLLDPTxRxModes(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    uint8
+		learnB  bool // a learns b
+		learnA  bool // b learns a
+		sendCnt bool
+	}{
+		{"TxRx", config.TXRX, true, true, true},
+		{"TxOnly", config.TX_ONLY, true, false, true},
+		{"RxOnly", config.RX_ONLY, false, true, false},
+	}
+	for _, test := range tests {
+		_, a, b := newTestPair(t)
+		defer OnlyForTestTeardown(a, b)
+		startTestServer(a, config.TXRX)
+		startTestServer(b, test.mode)
+		// first frame is lost when peer is not started yet, it is send again after tx interval
+		tick(LLDP_DEFAULT_TX_INTERVAL, a, b)
+		if test.learnB {
+			checkNeighbor(t, a, "eth1", "B", "eth2")
+		} else {
+			checkNoNeighbor(t, a, "eth1")
+		}
+		if test.learnA {
+			checkNeighbor(t, b, "eth2", "A", "eth1")
+		} else {
+			checkNoNeighbor(t, b, "eth2")
+		}
+		state := b.GetIntfState("eth2")
+		if (state.SendFrames > 0) != test.sendCnt {
+			t.Error(test.name, "unexpected frames send count", state.SendFrames)
+		}
+		if !test.learnA && state.ReceivedFrames != 0 {
+			t.Error(test.name, "expected no frames received actual", state.ReceivedFrames)
+		}
+	}
+}
+
+func TestLLDPTxRxModeChange(t *testing.T) {
+	_, a, b := newTestPair(t)
+	defer OnlyForTestTeardown(a, b)
+	startTestServer(a, config.TXRX)
+	startTestServer(b, config.TXRX)
+	pump(a, b)
+
+	// peer is informed with shutdown frame when transmission is stopped
+	updateIntfConfig(b, "eth2", func(intfCfg *config.IntfConfig) {
+		intfCfg.TxRxMode = config.RX_ONLY
+	})
+	pump(a, b)
+	checkNoNeighbor(t, a, "eth1")
+	checkNeighbor(t, b, "eth2", "A", "eth1")
+
+	// frames from a are not received anymore
+	updateIntfConfig(b, "eth2", func(intfCfg *config.IntfConfig) {
+		intfCfg.TxRxMode = config.TX_ONLY
+	})
+	tick(1, a, b)
+	if state := b.GetIntfState("eth2"); state.ReceivedFrames != 0 {
+		t.Error("expected no frames received in tx only mode actual", state.ReceivedFrames)
+	}
+}
+
+/*  Frame send by port of the given peer, every peer is a different neighbor
+ */
+func testPeerPkt(peer int, shutdown bool) gopacket.Packet {
+	port := config.PortInfo{
+		IfIndex: 1,
+		Name:    "fpPort1",
+		MacAddr: fmt.Sprintf("00:00:00:00:%02x:01", peer),
+	}
+	tx := packet.TxInit(LLDP_DEFAULT_TX_INTERVAL, LLDP_DEFAULT_TX_HOLD_MULTIPLIER,
+		packet.LLDP_PROTO_DST_MAC)
+	sysInfo := &config.SystemInfo{Hostname: fmt.Sprintf("peer%d", peer)}
+	var frame []byte
+	if shutdown {
+		frame = tx.ShutdownFrame(port, sysInfo)
+	} else {
+		frame = tx.Frame(port, sysInfo)
+	}
+	return gopacket.NewPacket(frame, layers.LayerTypeEthernet, gopacket.Default)
+}
+
+/*  Nearest bridge agent of the port
+ */
+func testAgent(svr *LLDPServer, ifIndex int32) *LLDPAgent {
+	intf := svr.lldpGblInfo[ifIndex]
+	return intf.GetAgent(config.AGENT_NEAREST_BRIDGE)
+}
+
+// remote table events applied on the server
+const (
+	remEventRcvd = iota
+	remEventShutdown
+	remEventAgeOut
+)
+
+type remStep struct {
+	ifIndex int32
+	peer    int
+	event   int
+}
+
+func TestRemTablesStatistics(t *testing.T) {
+	OnlyForTestSetup()
+	tests := []struct {
+		name         string
+		maxNeighbors int
+		steps        []remStep
+		remTables    RemTablesStats
+		ageouts      []int32
+	}{
+		{
+			name:      "inserts",
+			steps:     []remStep{{1, 2, remEventRcvd}, {1, 3, remEventRcvd}, {1, 2, remEventRcvd}, {2, 2, remEventRcvd}},
+			remTables: RemTablesStats{Inserts: 3},
+			ageouts:   []int32{0, 0},
+		},
+		{
+			name:      "deletes",
+			steps:     []remStep{{1, 2, remEventRcvd}, {1, 3, remEventRcvd}, {1, 2, remEventShutdown}, {1, 2, remEventShutdown}},
+			remTables: RemTablesStats{Inserts: 2, Deletes: 1},
+			ageouts:   []int32{0, 0},
+		},
+		{
+			name:         "drops",
+			maxNeighbors: 1,
+			steps:        []remStep{{1, 2, remEventRcvd}, {1, 3, remEventRcvd}, {1, 3, remEventRcvd}, {2, 3, remEventRcvd}},
+			remTables:    RemTablesStats{Inserts: 2, Drops: 2},
+			ageouts:      []int32{0, 0},
+		},
+		{
+			name: "ageouts",
+			steps: []remStep{{1, 2, remEventRcvd}, {1, 3, remEventRcvd}, {2, 2, remEventRcvd}, {1, 3, remEventAgeOut},
+				{2, 2, remEventAgeOut}, {1, 3, remEventRcvd}},
+			remTables: RemTablesStats{Inserts: 4, Deletes: 2, Ageouts: 2},
+			ageouts:   []int32{1, 1},
+		},
+	}
+	for _, tc := range tests {
+		svr := newTestServer(NewMemNetwork(), 1, "leaf1", "fpPort1", "fpPort2")
+		for _, step := range tc.steps {
+			agent := testAgent(svr, step.ifIndex)
+			if tc.maxNeighbors > 0 {
+				agent.RxInfo.MaxNeighbors = tc.maxNeighbors
+			}
+			switch step.event {
+			case remEventRcvd, remEventShutdown:
+				svr.ProcessRcvdPkt(InPktChannel{pkt: testPeerPkt(step.peer, step.event == remEventShutdown),
+					ifIndex: step.ifIndex})
+			case remEventAgeOut:
+				for _, nbr := range agent.RxInfo.Neighbors {
+					if nbr.SrcMAC.String() != fmt.Sprintf("00:00:00:00:%02x:01", step.peer) {
+						continue
+					}
+					nbr.RcvdTime = nbr.RcvdTime.Add(-time.Duration(nbr.RxFrame.TTL) * time.Second)
+					svr.AgeOutNeighbor(config.EventInfo{EventType: config.Removed, IfIndex: step.ifIndex,
+						Agent: config.AGENT_NEAREST_BRIDGE, RemIndex: nbr.RemIndex})
+				}
+			}
+		}
+		if tc.remTables.Inserts+tc.remTables.Deletes > 0 && svr.remTables.LastChangeTime.IsZero() {
+			t.Error(tc.name, "remote tables last change time is expected to be set")
+		}
+		tc.remTables.LastChangeTime = svr.remTables.LastChangeTime
+		if svr.remTables != tc.remTables {
+			t.Error(tc.name, "expected remote tables stats", tc.remTables, "actual", svr.remTables)
+		}
+		for idx, ageouts := range tc.ageouts {
+			agent := testAgent(svr, int32(idx+1))
+			if agent.RxInfo.Stats.Ageouts != ageouts {
+				t.Error(tc.name, "expected ageouts", ageouts, "actual", agent.RxInfo.Stats.Ageouts,
+					"on port", idx+1)
+			}
+		}
+		// clear statistics of one port doesn't touch other port & remote tables statistics
+		svr.ClearStatistics(&config.ClearStats{IfIndex: 1})
+		if testAgent(svr, 1).RxInfo.Stats != (packet.RxStats{}) {
+			t.Error(tc.name, "statistics of fpPort1 are expected to be cleared")
+		}
+		if testAgent(svr, 2).RxInfo.Stats.Ageouts != tc.ageouts[1] || svr.remTables != tc.remTables {
+			t.Error(tc.name, "clear statistics of fpPort1 is not expected to clear other statistics")
+		}
+		svr.ClearStatistics(&config.ClearStats{All: true})
+		if testAgent(svr, 2).RxInfo.Stats != (packet.RxStats{}) || svr.remTables != (RemTablesStats{}) ||
+			svr.counter.Rcvd != 0 {
+			t.Error(tc.name, "all the statistics are expected to be cleared, remote tables stats",
+				svr.remTables)
+		}
+		for _, intf := range svr.lldpGblInfo {
+			for _, agent := range intf.Agents {
+				agent.RxInfo.DeleteAllNeighbors()
+			}
+		}
+	}
+}
+
+type testSnoopAsicPlugin struct {
+	testAsicPlugin
+	snoopAndDrop bool
+	err          error
+}
+
+func (p *testSnoopAsicPlugin) SetSnoopAndDrop(enable bool) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.snoopAndDrop = enable
+	return nil
+}
+
+func TestSnoopAndDrop(t *testing.T) {
+	OnlyForTestSetup()
+	svr := newTestServer(NewMemNetwork(), 1, "leaf1", "fpPort1")
+	svr.Global = &config.Global{Vrf: "default", Enable: true, TxRxMode: config.TXRX}
+
+	// asic plugin without trap & drop support
+	if svr.SnoopAndDropSupported() {
+		t.Error("expected snoop and drop to be unsupported")
+	}
+	svr.Global.SnoopAndDrop = true
+	svr.updateSnoopAndDrop()
+	if svr.Global.SnoopAndDrop {
+		t.Error("expected snoop and drop to be disabled when asic plugin cannot drop frames")
+	}
+
+	snoopPlugin := &testSnoopAsicPlugin{}
+	svr.asicPlugin = snoopPlugin
+	if !svr.SnoopAndDropSupported() {
+		t.Error("expected snoop and drop to be supported")
+	}
+	svr.Global.SnoopAndDrop = true
+	svr.updateSnoopAndDrop()
+	if !svr.Global.SnoopAndDrop || !snoopPlugin.snoopAndDrop {
+		t.Error("expected snoop and drop to be programmed actual", svr.Global.SnoopAndDrop, snoopPlugin.snoopAndDrop)
+	}
+	// snooping agents are running for all the lldp addresses, only nearest bridge agent is transmitting
+	svr.UpdateAgents(1)
+	intf := svr.lldpGblInfo[1]
+	if len(intf.Agents) != len(agentTypes)-1 || intf.GetAgent(config.AGENT_CDP) != nil {
+		t.Error("expected agents for all the addresses actual", len(intf.Agents))
+	}
+	for _, agent := range intf.Agents {
+		if agent.txAllowed(config.TXRX) != (agent.AgentType == config.AGENT_NEAREST_BRIDGE) {
+			t.Error("only nearest bridge agent is expected to transmit, agent", AgentTypeToString(agent.AgentType))
+		}
+	}
+	svr.Global.SnoopAndDrop = false
+	svr.updateSnoopAndDrop()
+	svr.UpdateAgents(1)
+	if snoopPlugin.snoopAndDrop {
+		t.Error("expected snoop and drop to be removed from asic plugin")
+	}
+	intf = svr.lldpGblInfo[1]
+	if len(intf.Agents) != 1 {
+		t.Error("expected snooping agents to be deleted actual", len(intf.Agents))
+	}
+
+	snoopPlugin.err = errors.New("no trap resources")
+	svr.Global.SnoopAndDrop = true
+	svr.updateSnoopAndDrop()
+	if svr.Global.SnoopAndDrop {
+		t.Error("expected snoop and drop to be disabled when asic plugin fails")
+	}
+}
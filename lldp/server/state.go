@@ -26,12 +26,13 @@ package server
 import (
 	"fmt"
 	"l2/lldp/config"
+	"l2/lldp/packet"
 	"l2/lldp/utils"
 	"time"
 )
 
 /*  helper function to convert TLV's (chassisID, portID, TTL) from byte
- *  format to string
+ *  format to string. Peer information is populated for the neighbor with lowest remote index
  */
 func (svr *LLDPServer) PopulateTLV(ifIndex int32, entry *config.IntfState) bool {
 	intf, exists := svr.lldpGblInfo[ifIndex]
@@ -41,29 +42,170 @@ func (svr *LLDPServer) PopulateTLV(ifIndex int32, entry *config.IntfState) bool
 	}
 	intf.RxLock.RLock()
 	defer intf.RxLock.RUnlock()
-	entry.LocalPort = intf.Port.Name
-	if intf.RxInfo.RxFrame != nil {
-		entry.PeerMac = intf.GetChassisIdInfo()
-		entry.PeerPort = intf.GetPortIdInfo()
-		rcvdValidity := time.Duration(intf.RxInfo.RxFrame.TTL) * time.Second
-		elapsedTime := time.Since(intf.pktRcvdTime)
+	svr.populateLocalInfo(&intf, entry)
+	for _, agent := range intf.Agents {
+		nbrs := agent.RxInfo.SortedNeighbors()
+		if len(nbrs) > 0 {
+			intf.populatePeerInfo(agent, nbrs[0], entry)
+			break
+		}
+	}
+	return exists
+}
+
+/*  helper function to populate neighbor information received in lldp frame
+ */
+func (intf *LLDPGlobalInfo) populatePeerInfo(agent *LLDPAgent, nbr *packet.Neighbor, entry *config.IntfState) {
+	entry.Agent = AgentTypeToString(agent.AgentType)
+	entry.RemIndex = nbr.RemIndex
+	if nbr.RxFrame != nil {
+		entry.PeerMac = intf.GetChassisIdInfo(nbr)
+		entry.PeerPort = intf.GetPortIdInfo(nbr)
+		entry.PeerChassisIdSubtype = nbr.RxFrame.ChassisID.Subtype.String()
+		entry.PeerPortIdSubtype = nbr.RxFrame.PortID.Subtype.String()
+		entry.PeerMgmtAddrs = packet.DecodeMgmtAddrs(nbr.RxFrame)
+		entry.PeerPlatform = nbr.Platform
+		rcvdValidity := time.Duration(nbr.RxFrame.TTL) * time.Second
+		elapsedTime := time.Since(nbr.RcvdTime)
 		holdTime := rcvdValidity - elapsedTime
 		entry.HoldTime = holdTime.String()
 	}
 
-	if intf.RxInfo.RxLinkInfo != nil {
-		entry.SystemCapabilities = intf.GetSystemCap()
-		entry.EnabledCapabilities = intf.GetEnabledCap()
-		entry.PeerHostName = intf.GetPeerHostName()
-		entry.SystemDescription = intf.GetSystemDescription()
+	if nbr.RxLinkInfo != nil {
+		entry.SystemCapabilities = intf.GetSystemCap(nbr)
+		entry.EnabledCapabilities = intf.GetEnabledCap(nbr)
+		entry.PeerHostName = intf.GetPeerHostName(nbr)
+		entry.SystemDescription = intf.GetSystemDescription(nbr)
+		intf.GetPeerOrgInfo(nbr, entry)
+		intf.GetPeerMedInfo(nbr, entry)
+	}
+}
+
+/*  helper function to populate local port information
+ */
+func (svr *LLDPServer) populateLocalInfo(intf *LLDPGlobalInfo, entry *config.IntfState) {
+	entry.LocalPort = intf.Port.Name
+	if svr.SysInfo != nil {
+		entry.LocalSystemCapabilities, entry.LocalEnabledCapabilities = intf.GetLocalCap(svr.SysInfo)
 	}
 
+	entry.LocalPortVlanId = packet.GetPortVlanId(intf.Port)
+	entry.LocalMaxFrameSize = packet.GetMaxFrameSize(intf.Port)
+	entry.MedMode = MedModeToString(intf.medConfig.Mode)
+	if agent := intf.GetAgent(config.AGENT_NEAREST_BRIDGE); agent != nil {
+		entry.MedEndpoint = agent.TxInfo.MedEndpoint()
+	}
+	entry.MaxNeighbors = int32(intf.maxNeighbors)
+	entry.PortIdSubtype = PortIdSubtypeToString(intf.portIdSubtype)
+	entry.TLVs = TLVsToStrings(intf.tlvs)
+	entry.TxInterval = int32(intf.txInterval)
+	entry.TxHold = int32(intf.txHold)
+	entry.ReinitDelay = int32(intf.reinitDelay)
+	svr.populateCablingInfo(intf.Port.IfIndex, entry)
+	// statistics & neighbors are sum of all the agents running on the port
+	for _, agent := range intf.Agents {
+		entry.Neighbors += int32(len(agent.RxInfo.Neighbors))
+		entry.TooManyNeighbors = entry.TooManyNeighbors || agent.RxInfo.TooManyNeighbors()
+		entry.FramesDiscarded += agent.RxInfo.Stats.FramesDiscarded
+		entry.FramesInErrors += agent.RxInfo.Stats.FramesInErrors
+		entry.TLVsDiscarded += agent.RxInfo.Stats.TLVsDiscarded
+		entry.TLVsUnrecognized += agent.RxInfo.Stats.TLVsUnrecognized
+		entry.AgeoutsTotal += agent.RxInfo.Stats.Ageouts
+		entry.LldpduLengthErrors += agent.RxInfo.Stats.LldpduLengthErrors
+	}
 	entry.IfIndex = intf.Port.IfIndex
 	entry.Enable = intf.enable
 	entry.IntfRef = intf.Port.Name
 	entry.SendFrames = intf.counter.Send
 	entry.ReceivedFrames = intf.counter.Rcvd
-	return exists
+}
+
+/*  Server get lldp interface configuration per interface, partial configuration update is applied
+ *  on top of it
+ */
+func (svr *LLDPServer) getIntfConfig(ifIndex int32) *config.IntfConfig {
+	intf, exists := svr.lldpGblInfo[ifIndex]
+	if !exists {
+		return nil
+	}
+	return &config.IntfConfig{
+		IfIndex:       ifIndex,
+		Enable:        intf.enable,
+		TxRxMode:      intf.rxtxMode,
+		Med:           intf.medConfig,
+		Dcbx:          intf.dcbxConfig,
+		MaxNeighbors:  int32(intf.maxNeighbors),
+		Agents:        intf.agentTypes,
+		PortIdSubtype: intf.portIdSubtype,
+		TLVs:          intf.tlvs,
+		TxInterval:    int32(intf.txIntervalCfg),
+		TxHold:        int32(intf.txHoldCfg),
+		ReinitDelay:   int32(intf.reinitDelayCfg),
+	}
+}
+
+func MedModeToString(mode uint8) string {
+	switch mode {
+	case config.MED_AUTO:
+		return config.MED_MODE_Auto
+	case config.MED_ENABLED:
+		return config.MED_MODE_Enabled
+	}
+	return config.MED_MODE_Disabled
+}
+
+func PortIdSubtypeToString(subtype uint8) string {
+	switch subtype {
+	case config.PORT_ID_IF_ALIAS:
+		return config.PORT_ID_IfAlias
+	case config.PORT_ID_MAC_ADDR:
+		return config.PORT_ID_MacAddr
+	case config.PORT_ID_LOCAL:
+		return config.PORT_ID_Local
+	}
+	return config.PORT_ID_IfName
+}
+
+/*  Optional tlv's bitmap to tlv names
+ */
+func TLVsToStrings(tlvs uint8) []string {
+	var names []string
+	tlvNames := []struct {
+		tlv  uint8
+		name string
+	}{
+		{config.TLV_PORT_DESCRIPTION, config.TLV_PortDescription},
+		{config.TLV_SYSTEM_NAME, config.TLV_SystemName},
+		{config.TLV_SYSTEM_DESCRIPTION, config.TLV_SystemDescription},
+		{config.TLV_SYSTEM_CAP, config.TLV_SystemCapabilities},
+		{config.TLV_MGMT_ADDRESS, config.TLV_ManagementAddress},
+		{config.TLV_DOT1, config.TLV_Dot1},
+		{config.TLV_DOT3, config.TLV_Dot3},
+	}
+	for _, tlvName := range tlvNames {
+		if tlvs&tlvName.tlv != 0 {
+			names = append(names, tlvName.name)
+		}
+	}
+	return names
+}
+
+/*  Chassis id advertised by the system, system mac/hostname is taken from system information
+ */
+func (svr *LLDPServer) getChassisIdInfo() (string, string) {
+	switch svr.chassisId.Subtype {
+	case config.CHASSIS_ID_HOSTNAME:
+		if svr.SysInfo != nil {
+			return config.CHASSIS_ID_Hostname, svr.SysInfo.Hostname
+		}
+		return config.CHASSIS_ID_Hostname, ""
+	case config.CHASSIS_ID_LOCAL:
+		return config.CHASSIS_ID_Local, svr.chassisId.Local
+	}
+	if svr.SysInfo != nil {
+		return config.CHASSIS_ID_SystemMac, svr.SysInfo.SwitchMac
+	}
+	return config.CHASSIS_ID_SystemMac, ""
 }
 
 /*  Server get bulk for lldp up intfs. This is used for Auto-Discovery
@@ -130,6 +272,57 @@ func (svr *LLDPServer) GetIntfStates(idx, cnt int) (int, int, []config.IntfState
 	return nextIdx, count, result
 }
 
+/*  helper function to populate one entry per neighbor learned on the port
+ */
+func (svr *LLDPServer) populateNeighbors(ifIndex int32) []config.IntfState {
+	intf, exists := svr.lldpGblInfo[ifIndex]
+	if !exists {
+		return nil
+	}
+	intf.RxLock.RLock()
+	defer intf.RxLock.RUnlock()
+	var result []config.IntfState
+	for _, agent := range intf.Agents {
+		for _, nbr := range agent.RxInfo.SortedNeighbors() {
+			entry := config.IntfState{}
+			svr.populateLocalInfo(&intf, &entry)
+			intf.populatePeerInfo(agent, nbr, &entry)
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+/*  Server get bulk for all the neighbors learned on lldp up intfs, one entry per neighbor with
+ *  its remote index
+ */
+func (svr *LLDPServer) GetNeighbors(idx, cnt int) (int, int, []config.IntfState) {
+	var nbrs []config.IntfState
+	for _, ifIndex := range svr.lldpUpIntfStateSlice {
+		nbrs = append(nbrs, svr.populateNeighbors(ifIndex)...)
+	}
+	length := len(nbrs)
+	if idx >= length {
+		debug.Logger.Info("No neighbor learned")
+		return 0, 0, nil
+	}
+	end := idx + cnt
+	if end >= length {
+		return 0, length - idx, nbrs[idx:]
+	}
+	return end, cnt, nbrs[idx:end]
+}
+
+/*  Server get all the neighbors learned on the interface
+ */
+func (svr *LLDPServer) GetIntfNeighbors(intfRef string) []config.IntfState {
+	ifIndex, exists := svr.lldpIntfRef2IfIndexMap[intfRef]
+	if !exists {
+		return nil
+	}
+	return svr.populateNeighbors(ifIndex)
+}
+
 /*  Server get lldp interface state per interface
  */
 func (svr *LLDPServer) GetIntfState(intfRef string) *config.IntfState {
@@ -153,9 +346,29 @@ func (svr *LLDPServer) GetGlobalState(vrf string) *config.GlobalState {
 	gblState.Vrf = vrf
 	gblState.TotalRxFrames = svr.counter.Rcvd
 	gblState.TotalTxFrames = svr.counter.Send
-	gblState.Neighbors = int32(len(svr.lldpUpIntfStateSlice))
-	// @TODO: Fixme
-	gblState.Enable = svr.Global.Enable
-	gblState.TranmitInterval = svr.Global.TranmitInterval
+	gblState.RemTablesInserts = svr.remTables.Inserts
+	gblState.RemTablesDeletes = svr.remTables.Deletes
+	gblState.RemTablesDrops = svr.remTables.Drops
+	gblState.RemTablesAgeouts = svr.remTables.Ageouts
+	if !svr.remTables.LastChangeTime.IsZero() {
+		gblState.RemTablesLastChangeTime = svr.remTables.LastChangeTime.String()
+	}
+	for _, ifIndex := range svr.lldpUpIntfStateSlice {
+		intf, exists := svr.lldpGblInfo[ifIndex]
+		if !exists {
+			continue
+		}
+		intf.RxLock.RLock()
+		for _, agent := range intf.Agents {
+			gblState.Neighbors += int32(len(agent.RxInfo.Neighbors))
+		}
+		intf.RxLock.RUnlock()
+	}
+	gblState.ChassisIdSubtype, gblState.ChassisId = svr.getChassisIdInfo()
+	// global config is not present till it is created by config plugin
+	if svr.Global != nil {
+		gblState.Enable = svr.Global.Enable
+		gblState.TranmitInterval = svr.Global.TranmitInterval
+	}
 	return &gblState
 }